@@ -3,24 +3,156 @@ package main
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"teleskope/pkg/eventstore"
 	"teleskope/pkg/k8s"
+	"teleskope/pkg/scheduler"
+	"teleskope/pkg/settings"
+	"teleskope/pkg/templates"
+	"teleskope/pkg/updater"
+	"teleskope/pkg/uptime"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
 	ctx       context.Context
 	k8sClient *k8s.Client
+	settings  *settings.Store
+
+	// mu guards the subscription IDs below, which context switches,
+	// scheduled tasks and frontend calls update concurrently.
+	mu sync.Mutex
+	// startupWatches maps "group/version/plural" to the subscription IDs
+	// of the eager watches started on launch.
+	startupWatches map[string]string
+
+	events *eventstore.Store
+	// eventArchiveSub is the subscription ID of the running event
+	// collector, if any.
+	eventArchiveSub string
+
+	uptime *uptime.Store
+	// uptimeSubs maps uptime keys to the readiness watches feeding them.
+	uptimeSubs    map[string]string
+	uptimeContext string
+
+	scheduler *scheduler.Scheduler
+
+	updater *updater.Updater
 }
 
+// Scheduled task kinds.
+const (
+	TaskRefreshDiscovery   = "refresh-discovery"
+	TaskSnapshotNamespaces = "snapshot-namespaces"
+	TaskOrphanScan         = "orphan-scan"
+)
+
 // NewApp creates a new App application struct
 func NewApp() *App {
 	client, err := k8s.NewK8sClient()
 	if err != nil {
 		fmt.Printf("Error creating k8s client: %v\n", err)
 	}
-	return &App{
+	store := settings.NewStore(settings.DefaultPath())
+	if _, err := store.Load(); err != nil {
+		fmt.Printf("Error loading settings: %v\n", err)
+	}
+	app := &App{
 		k8sClient: client,
+		settings:  store,
+		events:    eventstore.New(eventstore.DefaultDir()),
+		uptime:    uptime.New(uptime.DefaultDir()),
+		scheduler: scheduler.New(scheduler.DefaultDir()),
+		updater:   updater.New(updater.DefaultDir(), version),
+	}
+	if paths := store.Get().KubeconfigPaths; len(paths) > 0 {
+		if err := client.UseKubeconfigPaths(paths); err != nil {
+			fmt.Printf("Error loading kubeconfig files: %v\n", err)
+		}
+	}
+	app.applyOperationPolicies()
+	app.applySSHTunnels()
+	app.applyTokenCertificates()
+	app.applyRequestLimits()
+	app.registerTasks()
+	if err := app.applyScheduledTasks(); err != nil {
+		fmt.Printf("Error loading scheduled tasks: %v\n", err)
+	}
+	return app
+}
+
+// applyOperationPolicies hands the saved policies to the client. A saved
+// timeout of 0, which older versions accepted, keeps the default: no
+// request may run without a deadline.
+func (a *App) applyOperationPolicies() {
+	policies := make(map[k8s.OperationType]k8s.OperationPolicy)
+	defaults := k8s.DefaultOperationPolicies()
+	for op, p := range a.settings.Get().OperationPolicies {
+		policy, ok := defaults[k8s.OperationType(op)]
+		if !ok {
+			continue
+		}
+		if p.TimeoutSeconds > 0 {
+			policy.Timeout = time.Duration(p.TimeoutSeconds) * time.Second
+		}
+		policy.Retries = p.Retries
+		policies[k8s.OperationType(op)] = policy
+	}
+	a.k8sClient.SetOperationPolicies(policies)
+}
+
+func (a *App) applySSHTunnels() {
+	tunnels := make(map[string]k8s.SSHTunnel)
+	for name, t := range a.settings.Get().SSHTunnels {
+		tunnels[name] = k8s.SSHTunnel{
+			Host:           t.Host,
+			Port:           t.Port,
+			User:           t.User,
+			KeyPath:        t.KeyPath,
+			KnownHostsPath: t.KnownHostsPath,
+		}
+	}
+	a.k8sClient.SetSSHTunnels(tunnels)
+}
+
+func (a *App) applyTokenCertificates() {
+	certs := make(map[string]k8s.TokenCertificate)
+	for name, t := range a.settings.Get().TokenCertificates {
+		certs[name] = k8s.TokenCertificate{
+			Module:     t.Module,
+			TokenLabel: t.TokenLabel,
+			KeyID:      t.KeyID,
+			KeyLabel:   t.KeyLabel,
+		}
+	}
+	a.k8sClient.SetTokenCertificates(certs)
+}
+
+func (a *App) applyRequestLimits() {
+	limits := make(map[string]k8s.RequestLimits)
+	for name, l := range a.settings.Get().RequestLimits {
+		limits[name] = k8s.RequestLimits{MaxInFlight: l.MaxInFlight, MaxBackground: l.MaxBackground}
+	}
+	a.k8sClient.SetRequestLimits(limits)
+}
+
+func (a *App) applyScheduledTasks() error {
+	var tasks []scheduler.Task
+	for _, t := range a.settings.Get().ScheduledTasks {
+		interval, err := time.ParseDuration(t.Interval)
+		if err != nil {
+			return fmt.Errorf("task %s: invalid interval %q", t.Name, t.Interval)
+		}
+		tasks = append(tasks, scheduler.Task{Name: t.Name, Kind: t.Kind, Interval: interval, Params: t.Namespaces})
 	}
+	return a.scheduler.SetTasks(tasks)
 }
 
 // startup is called when the app starts. The context is saved
@@ -28,14 +160,442 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	if a.k8sClient != nil {
-		_ = a.k8sClient.Init()
+		a.k8sClient.Emit = func(name string, data interface{}) {
+			runtime.EventsEmit(a.ctx, name, data)
+		}
+		a.connectOnStartup()
+		a.startEagerWatches()
+		a.startEventArchive()
+		a.startUptimeTracking()
+		go a.uptimeHeartbeat()
+		go a.preflightAccess()
+		a.startBadges()
+		a.k8sClient.EmitCertificateWarnings()
+		a.scheduler.Start()
+	}
+	if a.settings.Get().Updates.CheckOnStart {
+		go a.checkForUpdatesOnStart()
+	}
+}
+
+// connectOnStartup connects to the context flagged for auto-connect, else
+// to the context used last, falling back to the kubeconfig's current
+// context.
+func (a *App) connectOnStartup() {
+	name, _, ok := a.settings.Get().AutoConnectContext()
+	if ok {
+		err := a.k8sClient.SetContext(name)
+		if err == nil {
+			return
+		}
+		fmt.Printf("Error auto-connecting to context %s: %v\n", name, err)
+	}
+	if last := a.settings.Get().LastContext; last != "" && a.hasContext(last) {
+		err := a.k8sClient.SetContext(last)
+		if err == nil {
+			return
+		}
+		fmt.Printf("Error reconnecting to context %s: %v\n", last, err)
+	}
+	_ = a.k8sClient.Init()
+}
+
+func (a *App) hasContext(name string) bool {
+	contexts, err := a.k8sClient.GetContexts()
+	if err != nil {
+		return false
+	}
+	for _, c := range contexts {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// startEagerWatches starts the watches configured for the connected
+// context so their lists are warm by the time the UI asks for them.
+func (a *App) startEagerWatches() {
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return
+	}
+	pref := a.settings.Get().ContextStartup[current]
+	watches := make(map[string]string)
+	for _, target := range pref.EagerWatches {
+		parts := strings.Split(target, "/")
+		if len(parts) != 3 {
+			fmt.Printf("Ignoring invalid eager watch %q\n", target)
+			continue
+		}
+		id, err := a.k8sClient.WatchResources(k8s.WatchParams{
+			Group:     parts[0],
+			Version:   parts[1],
+			Plural:    parts[2],
+			Namespace: pref.DefaultNamespace,
+		})
+		if err != nil {
+			fmt.Printf("Error starting eager watch %s: %v\n", target, err)
+			continue
+		}
+		watches[target] = id
+	}
+	a.mu.Lock()
+	a.startupWatches = watches
+	a.mu.Unlock()
+}
+
+// startEventArchive (re)starts the event collector for the connected
+// context if archival is enabled for it.
+func (a *App) startEventArchive() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.eventArchiveSub != "" {
+		_ = a.k8sClient.StopSubscription(a.eventArchiveSub)
+		a.eventArchiveSub = ""
+	}
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return
+	}
+	cfg := a.settings.Get().EventArchive[current]
+	if !cfg.Enabled {
+		return
+	}
+
+	retention := cfg.RetentionDays
+	if retention <= 0 {
+		retention = defaultEventRetentionDays
+	}
+	if err := a.events.Prune(current, time.Duration(retention)*24*time.Hour); err != nil {
+		fmt.Printf("Error pruning event archive: %v\n", err)
+	}
+
+	id, err := a.k8sClient.CollectEvents(cfg.Namespaces, func(ev k8s.ArchivedEvent) {
+		if err := a.events.Append(current, ev); err != nil {
+			fmt.Printf("Error archiving event: %v\n", err)
+		}
+	})
+	if err != nil {
+		fmt.Printf("Error starting event archive: %v\n", err)
+		return
+	}
+	a.eventArchiveSub = id
+}
+
+// startUptimeTracking watches the readiness of every workload tracked
+// for the connected context.
+func (a *App) startUptimeTracking() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, id := range a.uptimeSubs {
+		_ = a.k8sClient.StopSubscription(id)
+	}
+	a.uptimeSubs = make(map[string]string)
+	if a.uptimeContext != "" {
+		a.uptime.EndSession(a.uptimeContext)
+	}
+
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return
+	}
+	a.uptimeContext = current
+	targets, err := a.uptime.Targets(current)
+	if err != nil {
+		fmt.Printf("Error loading uptime targets: %v\n", err)
+		return
+	}
+	for _, ref := range targets {
+		a.watchUptime(current, ref)
+	}
+}
+
+// watchUptime starts the readiness watch of a tracked workload. a.mu must
+// be held.
+func (a *App) watchUptime(contextName string, ref k8s.ResourceRef) {
+	id, err := a.k8sClient.WatchWorkloadReadiness(ref, func(ready bool, at time.Time) {
+		if err := a.uptime.Observe(contextName, ref, ready, at); err != nil {
+			fmt.Printf("Error recording uptime: %v\n", err)
+		}
+	})
+	if err != nil {
+		fmt.Printf("Error watching %s/%s: %v\n", ref.Namespace, ref.Name, err)
+		return
+	}
+	a.uptimeSubs[uptime.Key(ref)] = id
+}
+
+func (a *App) uptimeHeartbeat() {
+	ticker := time.NewTicker(uptime.HeartbeatInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if current, err := a.k8sClient.GetCurrentContext(); err == nil {
+			_ = a.uptime.Heartbeat(current, now)
+		}
+	}
+}
+
+// preflightAccess checks RBAC for the namespaces the user is likely to
+// open first: "default", the context's default namespace and the
+// namespaces selected last time.
+func (a *App) preflightAccess() {
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return
+	}
+	namespaces := []string{"default"}
+	candidates := append([]string{a.settings.Get().ContextStartup[current].DefaultNamespace}, a.settings.Get().SelectedNamespaces[current]...)
+	for _, ns := range candidates {
+		if ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	a.k8sClient.PreflightAccess(namespaces)
+}
+
+// startBadges starts the sidebar badge counters for the connected
+// context. Switching contexts stops the previous watch.
+func (a *App) startBadges() {
+	if _, err := a.k8sClient.WatchNamespaceBadges(); err != nil {
+		fmt.Printf("Error starting namespace badges: %v\n", err)
+	}
+}
+
+// registerTasks sets up the scheduled task kinds. Tasks run against the
+// active context; every run is emitted as "scheduler:run" and failures
+// additionally as "scheduler:failed" so the UI can notify the user.
+func (a *App) registerTasks() {
+	a.scheduler.Context = func() string {
+		current, _ := a.k8sClient.GetCurrentContext()
+		return current
+	}
+	a.scheduler.OnRun = func(run scheduler.Run) {
+		if a.ctx == nil {
+			return
+		}
+		runtime.EventsEmit(a.ctx, "scheduler:run", run)
+		if run.Error != "" {
+			runtime.EventsEmit(a.ctx, "scheduler:failed", run)
+		}
 	}
+
+	a.scheduler.Register(TaskRefreshDiscovery, func(ctx context.Context, task scheduler.Task) (string, error) {
+		n, err := a.k8sClient.RefreshDiscovery()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d resource types", n), nil
+	})
+
+	a.scheduler.Register(TaskSnapshotNamespaces, func(ctx context.Context, task scheduler.Task) (string, error) {
+		if len(task.Params) == 0 {
+			return "", fmt.Errorf("no namespaces configured")
+		}
+		current, err := a.k8sClient.GetCurrentContext()
+		if err != nil {
+			return "", err
+		}
+		dir := filepath.Join(filepath.Dir(scheduler.DefaultDir()), "snapshots", current, time.Now().Format("20060102-150405"))
+		objects := 0
+		var failed []string
+		for _, ns := range task.Params {
+			snapshot, err := a.k8sClient.SnapshotNamespace(ns, filepath.Join(dir, ns))
+			if err != nil {
+				return "", fmt.Errorf("snapshot of %s failed: %v", ns, err)
+			}
+			objects += snapshot.Objects
+			for resource := range snapshot.Errors {
+				failed = append(failed, ns+"/"+resource)
+			}
+		}
+		summary := fmt.Sprintf("%d objects from %d namespaces in %s", objects, len(task.Params), dir)
+		if len(failed) > 0 {
+			return summary, fmt.Errorf("could not snapshot %s", strings.Join(failed, ", "))
+		}
+		return summary, nil
+	})
+
+	a.scheduler.Register(TaskOrphanScan, func(ctx context.Context, task scheduler.Task) (string, error) {
+		namespaces := task.Params
+		if len(namespaces) == 0 {
+			namespaces = []string{""}
+		}
+		orphans := 0
+		for _, ns := range namespaces {
+			report, err := a.k8sClient.FindOrphans(ns)
+			if err != nil {
+				return "", err
+			}
+			orphans += len(report.Orphans)
+		}
+		return fmt.Sprintf("%d orphaned objects", orphans), nil
+	})
 }
 
 // Kubeconfig methods
 
+// GetKubeconfigPaths returns the kubeconfig files in use, merged in order.
+func (a *App) GetKubeconfigPaths() []string {
+	return a.k8sClient.KubeconfigPaths()
+}
+
+// SetKubeconfigPaths switches to the given kubeconfig files, merged like
+// a KUBECONFIG list, and remembers them. An empty list goes back to
+// KUBECONFIG or ~/.kube/config.
+func (a *App) SetKubeconfigPaths(paths []string) error {
+	if err := a.k8sClient.SetKubeconfigPaths(paths); err != nil {
+		return err
+	}
+	return a.settings.Update(func(s *settings.Settings) {
+		s.KubeconfigPaths = paths
+	})
+}
+
+// AddKubeconfig merges a pasted kubeconfig into the default kubeconfig
+// file and returns the contexts it added.
+func (a *App) AddKubeconfig(snippet string, overwrite bool) ([]string, error) {
+	return a.k8sClient.AddKubeconfig(snippet, overwrite)
+}
+
+// RenameContext renames a context in its kubeconfig, carrying its tags
+// over.
+func (a *App) RenameContext(oldName, newName string) error {
+	if err := a.k8sClient.RenameContext(oldName, newName); err != nil {
+		return err
+	}
+	tags, ok := a.settings.Get().ContextTags[oldName]
+	if !ok {
+		return nil
+	}
+	return a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]map[string]string, len(s.ContextTags))
+		for k, v := range s.ContextTags {
+			next[k] = v
+		}
+		delete(next, oldName)
+		next[newName] = tags
+		s.ContextTags = next
+	})
+}
+
+func (a *App) DeleteContext(name string, pruneUnused bool) error {
+	return a.k8sClient.DeleteContext(name, pruneUnused)
+}
+
+func (a *App) SetContextNamespace(name, namespace string) error {
+	return a.k8sClient.SetContextNamespace(name, namespace)
+}
+
 func (a *App) GetKubeContexts() ([]k8s.KubeContext, error) {
-	return a.k8sClient.GetContexts()
+	contexts, err := a.k8sClient.GetContexts()
+	if err != nil {
+		return nil, err
+	}
+	return k8s.ApplyContextTags(contexts, a.settings.Get().ContextTags), nil
+}
+
+func (a *App) SetContextTags(name string, tags map[string]string) error {
+	return a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]map[string]string, len(s.ContextTags)+1)
+		for k, v := range s.ContextTags {
+			next[k] = v
+		}
+		if len(tags) == 0 {
+			delete(next, name)
+		} else {
+			next[name] = tags
+		}
+		s.ContextTags = next
+	})
+}
+
+func (a *App) GetCertificateWarnings() ([]k8s.CertWarning, error) {
+	return a.k8sClient.GetCertificateWarnings()
+}
+
+type StartupState struct {
+	Context      string   `json:"context"`
+	Namespace    string   `json:"namespace"`
+	EagerWatches []string `json:"eager_watches"`
+	AutoConnect  bool     `json:"auto_connect"`
+	// Watches maps each eager watch to its running subscription ID. Their
+	// first snapshot is usually emitted before the frontend listens, so
+	// their lists are fetched with GetWatchSnapshot.
+	Watches map[string]string `json:"watches"`
+}
+
+// GetStartupState tells the frontend which context and namespace to open
+// on launch so it can skip the context picker.
+func (a *App) GetStartupState() (*StartupState, error) {
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return nil, err
+	}
+	pref := a.settings.Get().ContextStartup[current]
+	a.mu.Lock()
+	watches := make(map[string]string, len(a.startupWatches))
+	for target, id := range a.startupWatches {
+		watches[target] = id
+	}
+	a.mu.Unlock()
+	return &StartupState{
+		Context:      current,
+		Namespace:    pref.DefaultNamespace,
+		EagerWatches: pref.EagerWatches,
+		AutoConnect:  pref.AutoConnect,
+		Watches:      watches,
+	}, nil
+}
+
+func (a *App) GetContextStartup(name string) settings.ContextStartup {
+	return a.settings.Get().ContextStartup[name]
+}
+
+func (a *App) SetContextStartup(name string, pref settings.ContextStartup) error {
+	return a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]settings.ContextStartup, len(s.ContextStartup)+1)
+		for k, v := range s.ContextStartup {
+			if pref.AutoConnect && k != name {
+				v.AutoConnect = false
+			}
+			next[k] = v
+		}
+		next[name] = pref
+		s.ContextStartup = next
+	})
+}
+
+func (a *App) FilterKubeContexts(selector map[string]string) ([]k8s.KubeContext, error) {
+	contexts, err := a.GetKubeContexts()
+	if err != nil {
+		return nil, err
+	}
+	return k8s.FilterContextsByTags(contexts, selector), nil
+}
+
+func (a *App) GroupKubeContexts(tagKey string) ([]k8s.ContextGroup, error) {
+	contexts, err := a.GetKubeContexts()
+	if err != nil {
+		return nil, err
+	}
+	return k8s.GroupContextsByTag(contexts, tagKey), nil
+}
+
+// GetTeleportStatus returns the tsh session info for a Teleport-managed
+// context.
+func (a *App) GetTeleportStatus(contextName string) (*k8s.TeleportInfo, error) {
+	return a.k8sClient.GetTeleportStatus(contextName)
+}
+
+// Reauthenticate renews an exec plugin context's credentials after
+// "kubeconfig:reauth-required", e.g. an expired OIDC or cloud session.
+func (a *App) Reauthenticate(contextName string) error {
+	return a.k8sClient.Reauthenticate(contextName)
+}
+
+func (a *App) TeleportLogin(contextName string) error {
+	return a.k8sClient.TeleportLogin(contextName)
 }
 
 func (a *App) GetCurrentContext() (string, error) {
@@ -43,7 +603,27 @@ func (a *App) GetCurrentContext() (string, error) {
 }
 
 func (a *App) SetActiveContext(name string) error {
-	return a.k8sClient.SetContext(name)
+	if err := a.k8sClient.SetContext(name); err != nil {
+		return err
+	}
+	if name != k8s.DemoContext && name != k8s.InClusterContext {
+		// Persisting is best effort: a read-only kubeconfig still switches
+		// for this session.
+		if err := a.k8sClient.SaveCurrentContext(name); err != nil {
+			fmt.Printf("Error saving current context: %v\n", err)
+		}
+	}
+	err := a.settings.Update(func(s *settings.Settings) {
+		s.LastContext = name
+	})
+	if err != nil {
+		fmt.Printf("Error saving settings: %v\n", err)
+	}
+	a.startEventArchive()
+	a.startUptimeTracking()
+	go a.preflightAccess()
+	a.startBadges()
+	return nil
 }
 
 func (a *App) InitDefaultContext() (string, error) {
@@ -60,21 +640,181 @@ func (a *App) GetApiResources() ([]k8s.ApiResourceInfo, error) {
 	return a.k8sClient.GetApiResources()
 }
 
+// GetDiscoveryStatus returns the API groups that were unavailable during
+// the last discovery and whether cached data stands in for them.
+func (a *App) GetDiscoveryStatus() k8s.DiscoveryStatus {
+	return a.k8sClient.GetDiscoveryStatus()
+}
+
 func (a *App) GetNamespaces() ([]string, error) {
 	return a.k8sClient.GetNamespaces()
 }
 
+func (a *App) CreateNamespace(name string, labels map[string]string) error {
+	return a.k8sClient.CreateNamespace(name, labels)
+}
+
+func (a *App) DeleteNamespace(name string) error {
+	return a.k8sClient.DeleteNamespace(name)
+}
+
+// GetTerminatingNamespaces returns namespaces being deleted with what
+// still blocks them.
+func (a *App) GetTerminatingNamespaces() ([]k8s.TerminatingNamespace, error) {
+	return a.k8sClient.GetTerminatingNamespaces()
+}
+
+// RemoveNamespaceFinalizers force-deletes a stuck namespace; confirm must
+// repeat its name.
+func (a *App) RemoveNamespaceFinalizers(name, confirm string) error {
+	return a.k8sClient.RemoveNamespaceFinalizers(name, confirm)
+}
+
+// BuildNetworkPolicy renders a NetworkPolicy manifest from an intent, to
+// be validated and applied like any edit.
+func (a *App) BuildNetworkPolicy(intent k8s.NetworkPolicyIntent) (string, error) {
+	return k8s.BuildNetworkPolicy(intent)
+}
+
+type TrafficParams struct {
+	From     k8s.PodRef `json:"from"`
+	To       k8s.PodRef `json:"to"`
+	Port     int32      `json:"port"`
+	Protocol string     `json:"protocol"`
+}
+
+// SimulateTraffic tells whether the current NetworkPolicies allow a
+// connection between two pods.
+func (a *App) SimulateTraffic(params TrafficParams) (*k8s.TrafficSimulation, error) {
+	return a.k8sClient.SimulateTraffic(params.From, params.To, params.Port, params.Protocol)
+}
+
+// GetNamespaceResourceUsage returns quota hard and used figures,
+// LimitRanges and summed pod requests and limits of a namespace.
+func (a *App) GetNamespaceResourceUsage(namespace string) (*k8s.NamespaceResourceUsage, error) {
+	return a.k8sClient.GetNamespaceResourceUsage(namespace)
+}
+
+// GetNamespaceGroups groups namespaces by Rancher or OpenShift project.
+func (a *App) GetNamespaceGroups() ([]k8s.NamespaceGroup, error) {
+	return a.k8sClient.GetNamespaceGroups()
+}
+
 type ListParams struct {
-	Group         string `json:"group"`
-	Version       string `json:"version"`
-	Kind          string `json:"kind"`
-	Plural        string `json:"plural"`
-	Namespace     string `json:"namespace"`
-	LabelSelector string `json:"label_selector"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Plural    string `json:"plural"`
+	Namespace string `json:"namespace"`
+	// Namespaces, when set, lists in each of these namespaces instead of
+	// Namespace and merges the results.
+	Namespaces    []string `json:"namespaces"`
+	LabelSelector string   `json:"label_selector"`
+	FieldSelector string   `json:"field_selector"`
+	// Name lists only the object with this exact name, filtered by the
+	// API server.
+	Name string `json:"name"`
+	// NamePrefix keeps objects whose name starts with it.
+	NamePrefix string `json:"name_prefix"`
+	// SortBy is "name", "age" or "status"; results are unsorted when
+	// empty.
+	SortBy   string `json:"sort_by"`
+	SortDesc bool   `json:"sort_desc"`
 }
 
+// ListResources lists one namespace, all namespaces, or the set given in
+// Namespaces. With a set, namespaces that fail are skipped as long as one
+// succeeds; use ListResourcesInNamespaces to see their errors. A namespace
+// set is ignored for cluster-scoped types, while a single namespace given
+// for one is an error.
 func (a *App) ListResources(params ListParams) ([]interface{}, error) {
-	return a.k8sClient.ListResources(params.Group, params.Version, params.Kind, params.Plural, params.Namespace, params.LabelSelector)
+	if len(params.Namespaces) > 0 && a.clusterScoped(params) {
+		params.Namespaces = nil
+	}
+	if params.Name != "" {
+		params.FieldSelector = k8s.NameFieldSelector(params.FieldSelector, params.Name)
+	}
+	var items []interface{}
+	var err error
+	if len(params.Namespaces) > 0 {
+		list := a.ListResourcesInNamespaces(params)
+		items, err = list.Items, list.Err(len(params.Namespaces))
+	} else {
+		items, err = a.k8sClient.ListResources(params.Group, params.Version, params.Kind, params.Plural, params.Namespace, params.LabelSelector, params.FieldSelector)
+		sanitizeItems(items)
+	}
+	if err != nil {
+		return items, err
+	}
+	items = k8s.FilterByNamePrefix(items, params.NamePrefix)
+	if params.SortBy != "" {
+		if err := k8s.SortItems(items, params.SortBy, params.SortDesc); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// SearchResources finds objects by name, label or annotation across
+// kinds and namespaces for the jump-to-resource palette.
+func (a *App) SearchResources(query string, namespaces, kinds []string) (*k8s.SearchResult, error) {
+	return a.k8sClient.SearchResources(query, namespaces, kinds)
+}
+
+// GetLabelKeys offers label keys for building selectors of a resource
+// type.
+func (a *App) GetLabelKeys(group, version, plural, namespace string) ([]k8s.LabelCount, error) {
+	return a.k8sClient.GetLabelKeys(group, version, plural, namespace)
+}
+
+// GetLabelValues offers the values of a label key for building selectors.
+func (a *App) GetLabelValues(group, version, plural, namespace, key string) ([]k8s.LabelCount, error) {
+	return a.k8sClient.GetLabelValues(group, version, plural, namespace, key)
+}
+
+// ListResourcesInNamespaces fans a list out over params.Namespaces and
+// reports errors per namespace.
+func (a *App) ListResourcesInNamespaces(params ListParams) *k8s.NamespacedList {
+	list := a.k8sClient.ListResourcesInNamespaces(params.Group, params.Version, params.Kind, params.Plural, params.Namespaces, params.LabelSelector, params.FieldSelector)
+	sanitizeItems(list.Items)
+	return list
+}
+
+// clusterScoped reports whether discovery knows params' type to be
+// cluster-scoped.
+func (a *App) clusterScoped(params ListParams) bool {
+	namespaced, err := a.k8sClient.ResourceScope(params.Group, params.Version, params.Plural)
+	return err == nil && !namespaced
+}
+
+// sanitizeItems blanks Secret values and truncates large ConfigMap values
+// in list results, as GetResource does; the full values are only
+// available through GetSecretData and GetConfigMapData.
+func sanitizeItems(items []interface{}) []interface{} {
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			k8s.RedactSecret(obj)
+			k8s.TruncateConfigMap(obj)
+		}
+	}
+	return items
+}
+
+type ListPageParams struct {
+	ListParams
+	Limit    int64  `json:"limit"`
+	Continue string `json:"continue"`
+}
+
+// ListResourcesPage returns one page of a list for infinite scrolling;
+// pass the returned continue token to fetch the next page.
+func (a *App) ListResourcesPage(params ListPageParams) (*k8s.ResourcePage, error) {
+	page, err := a.k8sClient.ListResourcesPage(params.Group, params.Version, params.Plural, params.Namespace, params.LabelSelector, params.FieldSelector, params.Limit, params.Continue)
+	if err != nil {
+		return nil, err
+	}
+	sanitizeItems(page.Items)
+	return page, nil
 }
 
 type GetParams struct {
@@ -87,7 +827,73 @@ type GetParams struct {
 }
 
 func (a *App) GetResource(params GetParams) (interface{}, error) {
-	return a.k8sClient.GetResource(params.Group, params.Version, params.Kind, params.Plural, params.Namespace, params.Name)
+	res, err := a.k8sClient.GetResource(params.Group, params.Version, params.Kind, params.Plural, params.Namespace, params.Name)
+	if obj, ok := res.(map[string]interface{}); ok {
+		k8s.RedactSecret(obj)
+		k8s.TruncateConfigMap(obj)
+	}
+	return res, err
+}
+
+// GetResourceSchema returns the schema of a kind for form-based editing
+// and field documentation.
+func (a *App) GetResourceSchema(group, version, kind string) (*k8s.ResourceSchema, error) {
+	return a.k8sClient.GetResourceSchema(group, version, kind)
+}
+
+type SecretDataParams struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Reveal    []string `json:"reveal"`
+}
+
+// GetSecretData lists a Secret's keys; values are included only for the
+// keys in Reveal.
+func (a *App) GetSecretData(params SecretDataParams) (*k8s.SecretData, error) {
+	return a.k8sClient.GetSecretData(params.Namespace, params.Name, params.Reveal)
+}
+
+// CopySecretKey puts one decoded Secret value on the clipboard without
+// sending it to the frontend.
+func (a *App) CopySecretKey(namespace, name, key string) error {
+	value, err := a.k8sClient.GetSecretValue(namespace, name, key)
+	if err != nil {
+		return err
+	}
+	return runtime.ClipboardSetText(a.ctx, value)
+}
+
+// UpsertSecret creates or updates a Secret from a key-value form.
+func (a *App) UpsertSecret(form k8s.SecretForm) (*k8s.UpsertResult, error) {
+	return a.k8sClient.UpsertSecret(form)
+}
+
+// GetConfigMapData lists a ConfigMap's keys with their size and content
+// type; binary values are never inlined.
+func (a *App) GetConfigMapData(namespace, name string) (*k8s.ConfigMapData, error) {
+	return a.k8sClient.GetConfigMapData(namespace, name)
+}
+
+// GetConfigMapKey returns one full ConfigMap value as text, base64 or a
+// hex dump.
+func (a *App) GetConfigMapKey(namespace, name, key, format string) (string, error) {
+	return a.k8sClient.GetConfigMapKey(namespace, name, key, format)
+}
+
+// SaveConfigMapKey downloads one ConfigMap value to a local file.
+func (a *App) SaveConfigMapKey(namespace, name, key, localPath string) error {
+	return a.k8sClient.SaveConfigMapKey(namespace, name, key, localPath)
+}
+
+// UpsertConfigMap creates or updates a ConfigMap from a key-value form.
+func (a *App) UpsertConfigMap(form k8s.ConfigMapForm) (*k8s.UpsertResult, error) {
+	return a.k8sClient.UpsertConfigMap(form)
+}
+
+// TestPullSecret checks whether an imagePullSecret's credentials still
+// work, optionally by pulling the manifest of image.
+func (a *App) TestPullSecret(namespace, name, image string) (*k8s.PullSecretTest, error) {
+	return a.k8sClient.TestPullSecret(namespace, name, image)
 }
 
 func (a *App) CopyToClipboard(text string) error {
@@ -99,34 +905,1171 @@ func (a *App) ExecPod(namespace, podName, containerName string) error {
 	return a.k8sClient.ExecPod(namespace, podName, containerName)
 }
 
+// EditResource opens `kubectl edit` in an external terminal. The in-app
+// editor uses GetResourceYAML, ValidateManifest and UpdateResourceFromYAML
+// instead.
 func (a *App) EditResource(group, version, kind, plural, namespace, name string) error {
 	return a.k8sClient.EditResource(group, version, kind, plural, namespace, name)
 }
 
-type RelatedParams struct {
-	Group     string `json:"group"`
-	Version   string `json:"version"`
-	Kind      string `json:"kind"`
-	Namespace string `json:"namespace"`
-	Name      string `json:"name"`
+// ListKubectlPlugins returns the kubectl-* plugins installed on PATH.
+func (a *App) ListKubectlPlugins() []k8s.KubectlPlugin {
+	return k8s.ListKubectlPlugins()
 }
 
-func (a *App) GetRelatedResources(params RelatedParams) ([]interface{}, error) {
-	return a.k8sClient.GetRelatedResources(params.Group, params.Version, params.Kind, params.Namespace, params.Name)
+// RunKubectlPlugin runs a plugin against the active context; output
+// arrives as "plugin:output" and "plugin:exit" events.
+func (a *App) RunKubectlPlugin(run k8s.PluginRun) (string, error) {
+	return a.k8sClient.RunKubectlPlugin(run)
 }
 
-func (a *App) DeleteResource(group, version, kind, plural, namespace, name string) error {
-	return a.k8sClient.DeleteResource(group, version, kind, plural, namespace, name)
+func (a *App) GetResourceYAML(ref k8s.ResourceRef) (*k8s.ResourceYAML, error) {
+	return a.k8sClient.GetResourceYAML(ref)
 }
 
-type LogsParams struct {
-	Namespace     string `json:"namespace"`
-	PodName       string `json:"podName"`
-	ContainerName string `json:"containerName"`
-	Follow        bool   `json:"follow"`
-	TailLines     int64  `json:"tailLines"`
+type ExportParams struct {
+	Ref k8s.ResourceRef `json:"ref"`
+	// Substitute applies the export rules from settings.
+	Substitute bool `json:"substitute"`
 }
 
-func (a *App) GetPodLogs(params LogsParams) (string, error) {
-	return a.k8sClient.GetPodLogs(params.Namespace, params.PodName, params.ContainerName, params.Follow, params.TailLines)
+// ExportResourceYAML returns a portable manifest, optionally with
+// environment-specific values replaced by ${VAR} placeholders.
+func (a *App) ExportResourceYAML(params ExportParams) (*k8s.ExportResult, error) {
+	var rules []k8s.ExportRule
+	if params.Substitute {
+		for _, r := range a.settings.Get().ExportRules {
+			rules = append(rules, k8s.ExportRule{Kind: r.Kind, Variable: r.Variable, Pattern: r.Pattern})
+		}
+	}
+	return a.k8sClient.ExportYAML(params.Ref, rules)
+}
+
+// ExportResource returns an object as YAML for a Git repository, with
+// server-owned fields stripped when cleaned is set.
+func (a *App) ExportResource(ref k8s.ResourceRef, cleaned bool) (string, error) {
+	return a.k8sClient.ExportResource(ref, cleaned)
+}
+
+// ExportNamespace writes every object of a namespace to a directory, or
+// to a zip archive when path ends in .zip.
+func (a *App) ExportNamespace(namespace, path string, cleaned bool) (*k8s.NamespaceSnapshot, error) {
+	return a.k8sClient.ExportNamespaceManifests(namespace, path, cleaned)
+}
+
+func (a *App) GetExportRules() []settings.ExportRule {
+	return a.settings.Get().ExportRules
+}
+
+func (a *App) SetExportRules(rules []settings.ExportRule) error {
+	for _, r := range rules {
+		if r.Variable == "" {
+			return fmt.Errorf("export rule %q needs a variable name", r.Kind)
+		}
+	}
+	return a.settings.Update(func(s *settings.Settings) {
+		s.ExportRules = rules
+	})
+}
+
+// resourceViewKey returns the settings key of a resource's list view,
+// "<plural>.<group>" like CRD names.
+func resourceViewKey(plural, group string) string {
+	if group == "" {
+		return plural
+	}
+	return plural + "." + group
+}
+
+func (a *App) GetResourceViews() map[string]settings.ResourceView {
+	return a.settings.Get().ResourceViews
+}
+
+// SetResourceView stores the list columns and health rules for a resource,
+// keyed by resourceViewKey. An empty view removes it.
+func (a *App) SetResourceView(key string, view settings.ResourceView) error {
+	if _, err := k8s.ApplyResourceView(nil, toK8sResourceView(view)); err != nil {
+		return err
+	}
+	for _, rule := range view.Health {
+		if rule.ConditionType == "" && rule.JSONPath == "" {
+			return fmt.Errorf("health rule needs a condition type or a JSONPath")
+		}
+	}
+	return a.settings.Update(func(s *settings.Settings) {
+		if len(view.Columns) == 0 && len(view.Health) == 0 {
+			delete(s.ResourceViews, key)
+			return
+		}
+		if s.ResourceViews == nil {
+			s.ResourceViews = make(map[string]settings.ResourceView)
+		}
+		s.ResourceViews[key] = view
+	})
+}
+
+// ListResourceRows lists resources like ListResources and evaluates the
+// stored view for them, giving each row its custom column values and
+// health. Without a stored view rows carry only name and namespace.
+func (a *App) ListResourceRows(params ListParams) ([]k8s.ResourceRow, error) {
+	items, err := a.ListResources(params)
+	if err != nil {
+		return nil, err
+	}
+	view := a.settings.Get().ResourceViews[resourceViewKey(params.Plural, params.Group)]
+	return k8s.ApplyResourceView(items, toK8sResourceView(view))
+}
+
+func toK8sResourceView(view settings.ResourceView) k8s.ResourceView {
+	var out k8s.ResourceView
+	for _, c := range view.Columns {
+		out.Columns = append(out.Columns, k8s.ViewColumn{Name: c.Name, JSONPath: c.JSONPath})
+	}
+	for _, h := range view.Health {
+		out.Health = append(out.Health, k8s.HealthRule{
+			ConditionType:   h.ConditionType,
+			ConditionStatus: h.ConditionStatus,
+			JSONPath:        h.JSONPath,
+			Values:          h.Values,
+		})
+	}
+	return out
+}
+
+type EditParams struct {
+	Ref             k8s.ResourceRef `json:"ref"`
+	YAML            string          `json:"yaml"`
+	ResourceVersion string          `json:"resourceVersion"`
+}
+
+// ValidateManifest dry-runs an edit on the server and returns the diff and
+// any immutable field changes.
+func (a *App) ValidateManifest(params EditParams) (*k8s.ManifestValidation, error) {
+	return a.k8sClient.ValidateManifest(params.Ref, params.YAML)
+}
+
+// UpdateResourceFromYAML saves an edit, reporting a conflict if the object
+// changed since ResourceVersion was loaded.
+func (a *App) UpdateResourceFromYAML(params EditParams) (*k8s.UpdateResult, error) {
+	return a.k8sClient.UpdateResourceFromYAML(params.Ref, params.YAML, params.ResourceVersion)
+}
+
+type DiffParams struct {
+	Group   string       `json:"group"`
+	Version string       `json:"version"`
+	Plural  string       `json:"plural"`
+	Left    k8s.DiffSide `json:"left"`
+	Right   k8s.DiffSide `json:"right"`
+}
+
+// DiffResources compares the objects of a kind between two contexts or
+// namespaces, matched by name.
+func (a *App) DiffResources(params DiffParams) (*k8s.ResourceDiffReport, error) {
+	return a.k8sClient.DiffResources(params.Group, params.Version, params.Plural, params.Left, params.Right)
+}
+
+// StartTerminal opens an embedded interactive shell in a container and
+// returns its session ID; output arrives as "terminal:output" events.
+func (a *App) StartTerminal(opts k8s.TerminalOptions) (string, error) {
+	return a.k8sClient.StartTerminal(opts)
+}
+
+func (a *App) WriteTerminal(sessionID, data string) error {
+	return a.k8sClient.WriteTerminal(sessionID, data)
+}
+
+func (a *App) ResizeTerminal(sessionID string, cols, rows uint16) error {
+	return a.k8sClient.ResizeTerminal(sessionID, cols, rows)
+}
+
+type RelatedParams struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Plural    string `json:"plural"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Direction is "all" (the default) or "owners" for just the chain of
+	// parents, e.g. for "go to owner".
+	Direction string `json:"direction"`
+}
+
+// GetRelatedResources returns the owner, selector and routing graph
+// around an object.
+func (a *App) GetRelatedResources(params RelatedParams) (*k8s.ResourceGraph, error) {
+	return a.k8sClient.GetRelatedResources(params.Group, params.Version, params.Kind, params.Plural, params.Namespace, params.Name, params.Direction)
+}
+
+func (a *App) DeleteResource(group, version, kind, plural, namespace, name string) error {
+	return a.k8sClient.DeleteResource(group, version, kind, plural, namespace, name)
+}
+
+func (a *App) ScaleResource(group, version, plural, namespace, name string, replicas int) error {
+	return a.k8sClient.ScaleResource(group, version, plural, namespace, name, replicas)
+}
+
+type HPAParams struct {
+	Target      k8s.ResourceRef `json:"target"`
+	MinReplicas int32           `json:"min_replicas"`
+	MaxReplicas int32           `json:"max_replicas"`
+	// CPUTarget is the average CPU utilization to keep, in percent of
+	// the pods' requests.
+	CPUTarget int32 `json:"cpu_target"`
+}
+
+// CreateHPA creates a CPU-based HorizontalPodAutoscaler for a workload,
+// after checking that the metrics API is available.
+func (a *App) CreateHPA(params HPAParams) (*k8s.HPAResult, error) {
+	return a.k8sClient.CreateHPA(params.Target, params.MinReplicas, params.MaxReplicas, params.CPUTarget)
+}
+
+// GetHPAs lists autoscalers with their current and target metrics.
+func (a *App) GetHPAs(namespace string) ([]k8s.HPADetail, error) {
+	return a.k8sClient.GetHPAs(namespace)
+}
+
+// GetHPA returns an autoscaler with its conditions and events.
+func (a *App) GetHPA(namespace, name string) (*k8s.HPADetail, error) {
+	return a.k8sClient.GetHPA(namespace, name)
+}
+
+func (a *App) SetHPAReplicas(namespace, name string, minReplicas, maxReplicas int32) error {
+	return a.k8sClient.SetHPAReplicas(namespace, name, minReplicas, maxReplicas)
+}
+
+// PauseHPA pins an autoscaler to the current replica count, or restores
+// its bounds.
+func (a *App) PauseHPA(namespace, name string, paused bool) error {
+	return a.k8sClient.PauseHPA(namespace, name, paused)
+}
+
+// TriggerCronJob runs a CronJob immediately and returns the new Job's
+// name.
+func (a *App) TriggerCronJob(namespace, name string) (string, error) {
+	return a.k8sClient.TriggerCronJob(namespace, name)
+}
+
+func (a *App) SetCronJobSuspend(namespace, name string, suspended bool) error {
+	return a.k8sClient.SetCronJobSuspend(namespace, name, suspended)
+}
+
+// RetryJob re-runs a Job as a copy with a new name.
+func (a *App) RetryJob(namespace, name string) (string, error) {
+	return a.k8sClient.RetryJob(namespace, name)
+}
+
+func (a *App) GetJobLogs(namespace, name, container string, tailLines int64) (*k8s.JobLogs, error) {
+	return a.k8sClient.GetJobLogs(namespace, name, container, tailLines)
+}
+
+func (a *App) CleanupJobs(namespace string, opts k8s.JobCleanupOptions) (*k8s.JobCleanupResult, error) {
+	return a.k8sClient.CleanupJobs(namespace, opts)
+}
+
+type RolloutParams struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func (a *App) RestartRollout(params RolloutParams) error {
+	return a.k8sClient.RestartRollout(params.Kind, params.Namespace, params.Name)
+}
+
+func (a *App) GetRolloutStatus(params RolloutParams) (*k8s.RolloutStatus, error) {
+	return a.k8sClient.GetRolloutStatus(params.Kind, params.Namespace, params.Name)
+}
+
+func (a *App) GetRolloutHistory(params RolloutParams) ([]k8s.RolloutRevision, error) {
+	return a.k8sClient.GetRolloutHistory(params.Kind, params.Namespace, params.Name)
+}
+
+// GetRestartHistory lists a workload's restarts with their probable cause.
+// params.Kind may also be Pod.
+func (a *App) GetRestartHistory(params RolloutParams) (*k8s.RestartHistory, error) {
+	return a.k8sClient.GetRestartHistory(params.Kind, params.Namespace, params.Name)
+}
+
+// UndoRollout rolls back to toRevision, or to the previous revision when
+// toRevision is 0.
+func (a *App) UndoRollout(params RolloutParams, toRevision int64) error {
+	return a.k8sClient.UndoRollout(params.Kind, params.Namespace, params.Name, toRevision)
+}
+
+type ObjectEventsParams struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+}
+
+// GetEventsForObject returns the deduplicated events about one object for
+// the detail pane.
+func (a *App) GetEventsForObject(params ObjectEventsParams) ([]k8s.ObjectEvent, error) {
+	return a.k8sClient.GetEventsForObject(params.Namespace, params.Kind, params.Name, params.UID)
+}
+
+type PatchParams struct {
+	Ref       k8s.ResourceRef `json:"ref"`
+	PatchType string          `json:"patchType"`
+	Patch     string          `json:"patch"`
+}
+
+// PatchResource applies a json, merge or strategic patch, for inline
+// edits of labels, annotations, replicas and images.
+func (a *App) PatchResource(params PatchParams) (interface{}, error) {
+	return a.k8sClient.PatchResource(params.Ref, params.PatchType, params.Patch)
+}
+
+// SetLabels adds, changes and removes labels of an object.
+func (a *App) SetLabels(ref k8s.ResourceRef, labels map[string]string, remove []string) error {
+	return a.k8sClient.SetLabels(ref, labels, remove)
+}
+
+// GetOperations lists recent mutations, newest first.
+func (a *App) GetOperations() []k8s.Operation {
+	return a.k8sClient.GetOperations()
+}
+
+// UndoLastOperation reverts the latest scale, label or patch action.
+func (a *App) UndoLastOperation() (*k8s.Operation, error) {
+	return a.k8sClient.UndoLastOperation()
+}
+
+// DescribeResource returns kubectl-describe style output as sections the
+// detail pane renders as collapsible panels.
+func (a *App) DescribeResource(ref k8s.ResourceRef) (*k8s.ResourceDescription, error) {
+	return a.k8sClient.DescribeResource(ref)
+}
+
+// GetDeletionPreview lists the dependents a cascading delete would remove.
+func (a *App) GetDeletionPreview(ref k8s.ResourceRef) (*k8s.DeletionPreview, error) {
+	return a.k8sClient.GetDeletionPreview(ref)
+}
+
+func (a *App) DeleteResourceWithPropagation(ref k8s.ResourceRef, propagation string) error {
+	return a.k8sClient.DeleteResourceWithPropagation(ref, propagation)
+}
+
+// CheckImmutableFields reports edits the API server would reject because
+// they touch immutable fields.
+func (a *App) CheckImmutableFields(ref k8s.ResourceRef, edited map[string]interface{}) ([]k8s.ImmutableFieldChange, error) {
+	return a.k8sClient.CheckImmutableFields(ref, edited)
+}
+
+// RecreateResource deletes and recreates an object with the edited content.
+func (a *App) RecreateResource(ref k8s.ResourceRef, edited map[string]interface{}) (map[string]interface{}, error) {
+	return a.k8sClient.RecreateResource(ref, edited)
+}
+
+// GetPodContainers lists a pod's init, sidecar, app and ephemeral
+// containers with their status. Any of them can be passed to
+// StreamPodLogs by name.
+func (a *App) GetPodContainers(namespace, name string) (*k8s.PodContainers, error) {
+	return a.k8sClient.GetPodContainers(namespace, name)
+}
+
+type LogsParams struct {
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"podName"`
+	ContainerName string `json:"containerName"`
+	Follow        bool   `json:"follow"`
+	TailLines     int64  `json:"tailLines"`
+}
+
+func (a *App) GetPodLogs(params LogsParams) (string, error) {
+	return a.k8sClient.GetPodLogs(params.Namespace, params.PodName, params.ContainerName, params.Follow, params.TailLines)
+}
+
+// StreamPodLogs starts a log stream delivered through "pod:logs" events and
+// returns its subscription ID. The log settings can turn ANSI stripping
+// and multiline grouping on for every stream.
+func (a *App) StreamPodLogs(params k8s.LogStreamOptions) (string, error) {
+	prefs := a.settings.Get().Logs
+	params.StripANSI = params.StripANSI || prefs.StripANSI
+	params.GroupMultiline = params.GroupMultiline || prefs.GroupMultiline
+	return a.k8sClient.StreamPodLogs(params)
+}
+
+type ApplyParams struct {
+	Manifest     string `json:"manifest"`
+	FieldManager string `json:"fieldManager"`
+	DryRun       bool   `json:"dryRun"`
+}
+
+// ApplyManifest server-side applies a multi-document manifest and returns
+// a per-object report.
+func (a *App) ApplyManifest(params ApplyParams) ([]k8s.ApplyResult, error) {
+	return a.k8sClient.ApplyManifest(params.Manifest, params.FieldManager, params.DryRun)
+}
+
+// GetServiceBackends resolves a Service to its EndpointSlices and backing
+// pods, explaining why it has no ready endpoints.
+func (a *App) GetServiceBackends(namespace, name string) (*k8s.ServiceBackends, error) {
+	return a.k8sClient.GetServiceBackends(namespace, name)
+}
+
+// Port-forward methods
+
+func (a *App) StartPortForward(params k8s.PortForwardRequest) (*k8s.PortForwardInfo, error) {
+	return a.k8sClient.StartPortForward(params)
+}
+
+func (a *App) StopPortForward(id string) error {
+	return a.k8sClient.StopPortForward(id)
+}
+
+func (a *App) ListPortForwards() []k8s.PortForwardInfo {
+	return a.k8sClient.ListPortForwards()
+}
+
+// Storage methods
+
+func (a *App) GetStorageOverview() (*k8s.StorageOverview, error) {
+	return a.k8sClient.GetStorageOverview()
+}
+
+func (a *App) ExpandPVC(namespace, name, newSize string) error {
+	return a.k8sClient.ExpandPVC(namespace, name, newSize)
+}
+
+func (a *App) GetPVCResizeStatus(namespace, name string) (*k8s.PVCResizeStatus, error) {
+	return a.k8sClient.GetPVCResizeStatus(namespace, name)
+}
+
+// Container file browser methods
+
+type FileParams struct {
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"podName"`
+	ContainerName string `json:"containerName"`
+	Path          string `json:"path"`
+	MaxBytes      int64  `json:"maxBytes"`
+}
+
+func (a *App) ListContainerDir(params FileParams) ([]k8s.FileEntry, error) {
+	return a.k8sClient.ListContainerDir(params.Namespace, params.PodName, params.ContainerName, params.Path)
+}
+
+func (a *App) StatContainerFile(params FileParams) (*k8s.FileEntry, error) {
+	return a.k8sClient.StatContainerFile(params.Namespace, params.PodName, params.ContainerName, params.Path)
+}
+
+func (a *App) ReadContainerFile(params FileParams) (*k8s.FileContent, error) {
+	return a.k8sClient.ReadContainerFile(params.Namespace, params.PodName, params.ContainerName, params.Path, params.MaxBytes)
+}
+
+// CopyToPod uploads a local file or directory into a container in the
+// background and returns a copy ID; progress arrives as pod:copy events.
+func (a *App) CopyToPod(opts k8s.CopyOptions) (string, error) {
+	return a.k8sClient.CopyToPod(opts)
+}
+
+// CopyFromPod downloads a file or directory from a container, like
+// CopyToPod in reverse.
+func (a *App) CopyFromPod(opts k8s.CopyOptions) (string, error) {
+	return a.k8sClient.CopyFromPod(opts)
+}
+
+// Template methods
+
+func (a *App) ListTemplates() []templates.Template {
+	return templates.List()
+}
+
+func (a *App) RenderTemplate(kind string, params map[string]string) (string, error) {
+	return templates.Render(kind, params)
+}
+
+// Saved view methods
+
+func (a *App) ListSavedViews() []settings.SavedView {
+	return a.settings.Get().SavedViews
+}
+
+// SaveView stores a view, replacing any existing view with the same name.
+func (a *App) SaveView(view settings.SavedView) error {
+	if view.Name == "" {
+		return fmt.Errorf("view name is required")
+	}
+	if view.Version == "" || view.Plural == "" {
+		return fmt.Errorf("view %s must specify a version and plural resource name", view.Name)
+	}
+	return a.settings.Update(func(s *settings.Settings) {
+		var next []settings.SavedView
+		for _, v := range s.SavedViews {
+			if v.Name != view.Name {
+				next = append(next, v)
+			}
+		}
+		s.SavedViews = append(next, view)
+	})
+}
+
+func (a *App) DeleteSavedView(name string) error {
+	return a.settings.Update(func(s *settings.Settings) {
+		var next []settings.SavedView
+		for _, v := range s.SavedViews {
+			if v.Name != name {
+				next = append(next, v)
+			}
+		}
+		s.SavedViews = next
+	})
+}
+
+type SavedViewResult struct {
+	View  settings.SavedView `json:"view"`
+	Items []interface{}      `json:"items"`
+}
+
+func (a *App) RunSavedView(name string) (*SavedViewResult, error) {
+	view, ok := a.settings.Get().SavedView(name)
+	if !ok {
+		return nil, fmt.Errorf("saved view %s not found", name)
+	}
+	items, err := a.k8sClient.ListResources(view.Group, view.Version, view.Kind, view.Plural, view.Namespace, view.LabelSelector, view.FieldSelector)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedViewResult{View: view, Items: sanitizeItems(items)}, nil
+}
+
+func (a *App) GetResourceLinks(params GetParams) ([]k8s.ResourceLink, error) {
+	return a.k8sClient.GetResourceLinks(params.Group, params.Version, params.Kind, params.Plural, params.Namespace, params.Name)
+}
+
+// Node methods
+
+// ListNodes returns the node list with OS, architecture and container
+// runtime, so Windows nodes can be told apart.
+func (a *App) ListNodes() ([]k8s.NodeSummary, error) {
+	return a.k8sClient.ListNodes()
+}
+
+// GetNodeDetail returns the data of the node view: conditions, resource
+// usage by requests, taints, labels, images and pods.
+func (a *App) GetNodeDetail(name string) (*k8s.NodeDetail, error) {
+	return a.k8sClient.GetNodeDetail(name)
+}
+
+// GetNodePools groups nodes by cloud node group or Karpenter NodePool.
+func (a *App) GetNodePools() ([]k8s.NodePool, error) {
+	return a.k8sClient.GetNodePools()
+}
+
+// GetDisruptionAnalysis maps PodDisruptionBudgets to their pods and
+// reports the ones that would block node drains or are misconfigured.
+func (a *App) GetDisruptionAnalysis(namespace string) (*k8s.DisruptionAnalysis, error) {
+	return a.k8sClient.GetDisruptionAnalysis(namespace)
+}
+
+func (a *App) CordonNode(name string) error {
+	return a.k8sClient.CordonNode(name)
+}
+
+func (a *App) UncordonNode(name string) error {
+	return a.k8sClient.UncordonNode(name)
+}
+
+// DrainNode cordons a node and evicts its pods, streaming "node:drain"
+// progress events. The returned ID can be passed to StopSubscription.
+func (a *App) DrainNode(name string, opts k8s.DrainOptions) (string, error) {
+	return a.k8sClient.DrainNode(name, opts)
+}
+
+func (a *App) GetKubeletConfig(nodeName string) (map[string]interface{}, error) {
+	return a.k8sClient.GetKubeletConfig(nodeName)
+}
+
+func (a *App) CompareKubeletConfigs(nodeNames []string) (*k8s.KubeletConfigReport, error) {
+	return a.k8sClient.CompareKubeletConfigs(nodeNames)
+}
+
+// GetNodeDiskUsage reports node filesystem usage against the kubelet's
+// eviction thresholds, for anticipating DiskPressure.
+func (a *App) GetNodeDiskUsage(nodeNames []string) (*k8s.NodeDiskReport, error) {
+	return a.k8sClient.GetNodeDiskUsage(nodeNames)
+}
+
+func (a *App) GetNodeReservations(nodeName string) (*k8s.NodeReservations, error) {
+	return a.k8sClient.GetNodeReservations(nodeName)
+}
+
+// GetSchedulingLatency reports creation-to-scheduled and creation-to-ready
+// times for the pods of a namespace.
+func (a *App) GetSchedulingLatency(namespace string) (*k8s.SchedulingReport, error) {
+	return a.k8sClient.GetSchedulingLatency(namespace)
+}
+
+// GetPodDistribution shows how a workload's pods spread over nodes, zones
+// and architectures.
+func (a *App) GetPodDistribution(ref k8s.ResourceRef) (*k8s.PodDistribution, error) {
+	return a.k8sClient.GetPodDistribution(ref)
+}
+
+// GetDualStackReport shows the IP families of a namespace's Services and
+// flags mismatches with their pods.
+func (a *App) GetDualStackReport(namespace string) (*k8s.DualStackReport, error) {
+	return a.k8sClient.GetDualStackReport(namespace)
+}
+
+// GetImageDrift reports workloads whose pods don't run the image their
+// template asks for; checkRegistry also resolves tags in their registries.
+func (a *App) GetImageDrift(namespace string, checkRegistry bool) (*k8s.ImageDriftReport, error) {
+	return a.k8sClient.GetImageDrift(namespace, checkRegistry)
+}
+
+// Access methods
+
+// GetResourceAccess lists the resources and verbs the user may use in a
+// namespace, from the cached SelfSubjectRulesReview.
+func (a *App) GetResourceAccess(namespace string) ([]k8s.ResourceAccess, error) {
+	return a.k8sClient.GetResourceAccess(namespace)
+}
+
+// RefreshAccess re-runs the access review for the given namespaces.
+func (a *App) RefreshAccess(namespaces []string) []*k8s.NamespaceAccess {
+	return a.k8sClient.PreflightAccess(namespaces)
+}
+
+// CanI checks a single action with a SelfSubjectAccessReview, so the UI
+// can disable it and show the authorizer's reason.
+func (a *App) CanI(verb, group, resource, namespace, name string) (*k8s.AccessDecision, error) {
+	return a.k8sClient.CanI(verb, group, resource, namespace, name)
+}
+
+// GetEffectivePermissions merges every role bound to a user, group or
+// ServiceAccount into a permission matrix for audits.
+func (a *App) GetEffectivePermissions(subject k8s.RBACSubject) (*k8s.EffectivePermissions, error) {
+	return a.k8sClient.GetEffectivePermissions(subject)
+}
+
+// WhoCan lists the RBAC subjects allowed to perform verb on resource.
+func (a *App) WhoCan(verb, resource, namespace string) (*k8s.WhoCanResult, error) {
+	return a.k8sClient.WhoCan(verb, resource, namespace)
+}
+
+// Governance methods
+
+// GetLabelTaxonomyReport checks workloads for the recommended labels and
+// the required labels configured in settings.
+func (a *App) GetLabelTaxonomyReport(namespace string) (*k8s.LabelReport, error) {
+	return a.k8sClient.GetLabelTaxonomyReport(namespace, a.settings.Get().RequiredLabels)
+}
+
+func (a *App) GetRequiredLabels() []string {
+	return a.settings.Get().RequiredLabels
+}
+
+func (a *App) SetRequiredLabels(labels []string) error {
+	var cleaned []string
+	for _, l := range labels {
+		if l = strings.TrimSpace(l); l != "" {
+			cleaned = append(cleaned, l)
+		}
+	}
+	return a.settings.Update(func(s *settings.Settings) {
+		s.RequiredLabels = cleaned
+	})
+}
+
+// GetSecurityBaselineReport lists the workloads whose containers deviate
+// from the securityContext baseline configured in settings.
+func (a *App) GetSecurityBaselineReport(namespace string) ([]k8s.WorkloadSecurityReport, error) {
+	b := a.settings.Get().SecurityBaseline
+	return a.k8sClient.GetSecurityBaselineReport(namespace, k8s.SecurityBaseline{
+		RunAsNonRoot:             b.RunAsNonRoot,
+		ReadOnlyRootFilesystem:   b.ReadOnlyRootFilesystem,
+		AllowPrivilegeEscalation: b.AllowPrivilegeEscalation,
+		Privileged:               b.Privileged,
+		DropCapabilities:         b.DropCapabilities,
+		AllowedCapabilities:      b.AllowedCapabilities,
+		SeccompProfile:           b.SeccompProfile,
+	})
+}
+
+func (a *App) GetSecurityBaseline() settings.SecurityBaseline {
+	return a.settings.Get().SecurityBaseline
+}
+
+func (a *App) SetSecurityBaseline(baseline settings.SecurityBaseline) error {
+	switch baseline.SeccompProfile {
+	case "", "RuntimeDefault", "Localhost":
+	default:
+		return fmt.Errorf("unknown seccomp profile type %q", baseline.SeccompProfile)
+	}
+	return a.settings.Update(func(s *settings.Settings) {
+		s.SecurityBaseline = baseline
+	})
+}
+
+// GetTokenAudit reports unnecessary token automounts and legacy token
+// Secrets in a namespace (all when empty).
+func (a *App) GetTokenAudit(namespace string) (*k8s.TokenAuditReport, error) {
+	return a.k8sClient.GetTokenAudit(namespace)
+}
+
+func (a *App) ApplyTokenRemediation(remediation k8s.TokenRemediation) error {
+	return a.k8sClient.ApplyTokenRemediation(remediation)
+}
+
+// GetEtcdPressureReport flags objects near the etcd size limit and kinds
+// with very many objects.
+func (a *App) GetEtcdPressureReport() (*k8s.EtcdPressureReport, error) {
+	return a.k8sClient.GetEtcdPressureReport()
+}
+
+// GetDeprecatedAPIReport lists objects whose manifests use deprecated or
+// removed API versions, for planning cluster upgrades.
+func (a *App) GetDeprecatedAPIReport() (*k8s.DeprecatedAPIReport, error) {
+	return a.k8sClient.GetDeprecatedAPIReport()
+}
+
+// GetCAPIFleet lists the Cluster API clusters and machines managed from
+// the connected cluster, with the ones stuck provisioning.
+func (a *App) GetCAPIFleet(namespace string) (*k8s.CAPIFleet, error) {
+	return a.k8sClient.GetCAPIFleet(namespace)
+}
+
+// Metrics methods
+
+func (a *App) GetPodMetrics(namespace string) ([]k8s.PodMetrics, error) {
+	return a.k8sClient.GetPodMetrics(namespace)
+}
+
+func (a *App) GetNodeMetrics() ([]k8s.NodeMetrics, error) {
+	return a.k8sClient.GetNodeMetrics()
+}
+
+func (a *App) GetPodUtilization(namespace string) ([]k8s.PodUtilization, error) {
+	return a.k8sClient.GetPodUtilization(namespace)
+}
+
+// Subscription methods
+
+// GetNamespaceBadges returns the current sidebar badge counters; updates
+// arrive as "badges:update" events.
+func (a *App) GetNamespaceBadges() []k8s.NamespaceBadge {
+	return a.k8sClient.GetNamespaceBadges()
+}
+
+func (a *App) WatchWorkloadActivity(ref k8s.ResourceRef) (string, error) {
+	return a.k8sClient.WatchWorkloadActivity(ref)
+}
+
+// WatchResources keeps a resource list live via "resources:sync" and
+// "resources:change" events and returns the subscription ID.
+func (a *App) WatchResources(params k8s.WatchParams) (string, error) {
+	return a.k8sClient.WatchResources(params)
+}
+
+// GetWatchSnapshot returns the current list of a WatchResources
+// subscription, for lists whose "resources:sync" was missed.
+func (a *App) GetWatchSnapshot(id string) (*k8s.ResourceSync, error) {
+	return a.k8sClient.GetWatchSnapshot(id)
+}
+
+func (a *App) StopSubscription(id string) error {
+	return a.k8sClient.StopSubscription(id)
+}
+
+// Event archive methods
+
+const defaultEventRetentionDays = 30
+
+func (a *App) GetEventArchive(contextName string) settings.EventArchive {
+	return a.settings.Get().EventArchive[contextName]
+}
+
+// SetEventArchive saves the archive configuration for a context and
+// restarts the collector when it is the connected one.
+func (a *App) SetEventArchive(contextName string, cfg settings.EventArchive) error {
+	err := a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]settings.EventArchive, len(s.EventArchive)+1)
+		for k, v := range s.EventArchive {
+			next[k] = v
+		}
+		if cfg.Enabled || len(cfg.Namespaces) > 0 || cfg.RetentionDays > 0 {
+			next[contextName] = cfg
+		} else {
+			delete(next, contextName)
+		}
+		s.EventArchive = next
+	})
+	if err != nil {
+		return err
+	}
+	if current, err := a.k8sClient.GetCurrentContext(); err == nil && current == contextName {
+		a.startEventArchive()
+	}
+	return nil
+}
+
+// QueryArchivedEvents returns archived events in a time range. The
+// connected context is used when the query doesn't name one.
+func (a *App) QueryArchivedEvents(query eventstore.Query) ([]k8s.ArchivedEvent, error) {
+	if query.Context == "" {
+		current, err := a.k8sClient.GetCurrentContext()
+		if err != nil {
+			return nil, err
+		}
+		query.Context = current
+	}
+	return a.events.Query(query)
+}
+
+// Uptime methods
+
+// TrackWorkloadUptime starts recording availability of a workload in the
+// connected context.
+func (a *App) TrackWorkloadUptime(ref k8s.ResourceRef) error {
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return err
+	}
+	if err := a.uptime.Track(current, ref); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.uptimeSubs[uptime.Key(ref)]; !ok {
+		a.watchUptime(current, ref)
+	}
+	return nil
+}
+
+func (a *App) UntrackWorkloadUptime(ref k8s.ResourceRef) error {
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	if id, ok := a.uptimeSubs[uptime.Key(ref)]; ok {
+		_ = a.k8sClient.StopSubscription(id)
+		delete(a.uptimeSubs, uptime.Key(ref))
+	}
+	a.mu.Unlock()
+	return a.uptime.Untrack(current, ref)
+}
+
+func (a *App) ListUptimeTargets() ([]k8s.ResourceRef, error) {
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return nil, err
+	}
+	return a.uptime.Targets(current)
+}
+
+// GetWorkloadUptime returns availability over the last 24 hours and 7 days.
+func (a *App) GetWorkloadUptime(ref k8s.ResourceRef) (*uptime.Report, error) {
+	current, err := a.k8sClient.GetCurrentContext()
+	if err != nil {
+		return nil, err
+	}
+	return a.uptime.Report(current, ref, []time.Duration{24 * time.Hour, 7 * 24 * time.Hour}, time.Now())
+}
+
+// Settings methods
+
+// GetSettings returns everything persisted in the settings file, so the
+// UI can restore its selected namespaces, column layouts and refresh
+// intervals on launch.
+func (a *App) GetSettings() settings.Settings {
+	return a.settings.Get()
+}
+
+// SaveSettings replaces the whole settings file and applies the parts the
+// backend uses. If they can't be applied the previous settings are kept.
+func (a *App) SaveSettings(next settings.Settings) error {
+	for view, seconds := range next.RefreshIntervals {
+		if seconds < 0 {
+			return fmt.Errorf("refresh interval of %s must not be negative", view)
+		}
+	}
+	previous := a.settings.Get()
+	if err := a.settings.Update(func(s *settings.Settings) { *s = next }); err != nil {
+		return err
+	}
+	if err := a.applySettings(previous); err != nil {
+		_ = a.settings.Update(func(s *settings.Settings) { *s = previous })
+		_ = a.applySettings(next)
+		return err
+	}
+	return nil
+}
+
+// applySettings pushes the current settings to the client and scheduler,
+// switching kubeconfig files only if they differ from previous.
+func (a *App) applySettings(previous settings.Settings) error {
+	current := a.settings.Get()
+	if strings.Join(current.KubeconfigPaths, "\n") != strings.Join(previous.KubeconfigPaths, "\n") {
+		if err := a.k8sClient.SetKubeconfigPaths(current.KubeconfigPaths); err != nil {
+			return err
+		}
+	}
+	if err := a.applyScheduledTasks(); err != nil {
+		return err
+	}
+	a.applyOperationPolicies()
+	a.applySSHTunnels()
+	a.applyTokenCertificates()
+	a.applyRequestLimits()
+	return nil
+}
+
+// SetSelectedNamespaces remembers the namespaces selected for a context.
+func (a *App) SetSelectedNamespaces(contextName string, namespaces []string) error {
+	return a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string][]string, len(s.SelectedNamespaces)+1)
+		for k, v := range s.SelectedNamespaces {
+			next[k] = v
+		}
+		if len(namespaces) == 0 {
+			delete(next, contextName)
+		} else {
+			next[contextName] = namespaces
+		}
+		s.SelectedNamespaces = next
+	})
+}
+
+// ProbeContext checks what a context can do before connecting to it, for
+// first-run onboarding.
+func (a *App) ProbeContext(contextName string) (*k8s.ProbeReport, error) {
+	return a.k8sClient.ProbeContext(contextName)
+}
+
+// SSH tunnel methods
+
+func (a *App) GetSSHTunnel(contextName string) settings.SSHTunnel {
+	return a.settings.Get().SSHTunnels[contextName]
+}
+
+// SetSSHTunnel saves the bastion configuration for a context; an empty
+// host removes it. It takes effect the next time the context connects.
+func (a *App) SetSSHTunnel(contextName string, tunnel settings.SSHTunnel) error {
+	err := a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]settings.SSHTunnel, len(s.SSHTunnels)+1)
+		for k, v := range s.SSHTunnels {
+			next[k] = v
+		}
+		if tunnel.Host == "" {
+			delete(next, contextName)
+		} else {
+			next[contextName] = tunnel
+		}
+		s.SSHTunnels = next
+	})
+	if err != nil {
+		return err
+	}
+	a.applySSHTunnels()
+	return nil
+}
+
+// Token certificate methods
+
+func (a *App) GetTokenCertificate(contextName string) settings.TokenCertificate {
+	return a.settings.Get().TokenCertificates[contextName]
+}
+
+// SetTokenCertificate saves the PKCS#11 token holding a context's client
+// certificate; an empty module removes it. It takes effect the next time
+// the context connects.
+func (a *App) SetTokenCertificate(contextName string, cert settings.TokenCertificate) error {
+	err := a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]settings.TokenCertificate, len(s.TokenCertificates)+1)
+		for k, v := range s.TokenCertificates {
+			next[k] = v
+		}
+		if cert.Module == "" {
+			delete(next, contextName)
+		} else {
+			next[contextName] = cert
+		}
+		s.TokenCertificates = next
+	})
+	if err != nil {
+		return err
+	}
+	a.applyTokenCertificates()
+	return nil
+}
+
+// SetTokenPIN unlocks a context's token for this session; the PIN is
+// kept in memory only.
+func (a *App) SetTokenPIN(contextName, pin string) {
+	a.k8sClient.SetTokenPIN(contextName, pin)
+}
+
+// Operation policy methods
+
+func (a *App) GetOperationPolicies() map[string]settings.OperationPolicy {
+	result := make(map[string]settings.OperationPolicy)
+	for op, p := range a.k8sClient.GetOperationPolicies() {
+		result[string(op)] = settings.OperationPolicy{
+			TimeoutSeconds: int(p.Timeout / time.Second),
+			Retries:        p.Retries,
+		}
+	}
+	return result
+}
+
+func (a *App) SetOperationPolicy(op string, policy settings.OperationPolicy) error {
+	if _, ok := k8s.DefaultOperationPolicies()[k8s.OperationType(op)]; !ok {
+		return fmt.Errorf("unknown operation type %s", op)
+	}
+	if policy.TimeoutSeconds < 1 {
+		return fmt.Errorf("timeout must be at least 1 second")
+	}
+	if policy.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	err := a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]settings.OperationPolicy, len(s.OperationPolicies)+1)
+		for k, v := range s.OperationPolicies {
+			next[k] = v
+		}
+		next[op] = policy
+		s.OperationPolicies = next
+	})
+	if err != nil {
+		return err
+	}
+	a.applyOperationPolicies()
+	return nil
+}
+
+// Request limit methods
+
+// GetRequestStats reports in-flight and queued API requests per context.
+func (a *App) GetRequestStats() []k8s.RequestStats {
+	return a.k8sClient.GetRequestStats()
+}
+
+// SetRequestLimits sets how many requests may be in flight to a context,
+// and how many of those may be background ones; zero limits go back to
+// the defaults.
+func (a *App) SetRequestLimits(contextName string, limits settings.RequestLimits) error {
+	if limits.MaxInFlight < 0 || limits.MaxBackground < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if limits.MaxInFlight > 0 && limits.MaxBackground > limits.MaxInFlight {
+		return fmt.Errorf("background limit %d exceeds the in-flight limit %d", limits.MaxBackground, limits.MaxInFlight)
+	}
+	err := a.settings.Update(func(s *settings.Settings) {
+		next := make(map[string]settings.RequestLimits, len(s.RequestLimits)+1)
+		for k, v := range s.RequestLimits {
+			next[k] = v
+		}
+		if limits == (settings.RequestLimits{}) {
+			delete(next, contextName)
+		} else {
+			next[contextName] = limits
+		}
+		s.RequestLimits = next
+	})
+	if err != nil {
+		return err
+	}
+	a.applyRequestLimits()
+	return nil
+}
+
+// Scheduled task methods
+
+// GetScheduledTasks lists the configured tasks with their last and next
+// run.
+func (a *App) GetScheduledTasks() ([]scheduler.TaskStatus, error) {
+	return a.scheduler.Status()
+}
+
+func (a *App) GetScheduledTaskKinds() []string {
+	return a.scheduler.Kinds()
+}
+
+// SetScheduledTasks replaces the task definitions; invalid ones are
+// rejected before anything is saved.
+func (a *App) SetScheduledTasks(tasks []settings.ScheduledTask) error {
+	previous := a.settings.Get().ScheduledTasks
+	err := a.settings.Update(func(s *settings.Settings) {
+		s.ScheduledTasks = tasks
+	})
+	if err != nil {
+		return err
+	}
+	if err := a.applyScheduledTasks(); err != nil {
+		_ = a.settings.Update(func(s *settings.Settings) {
+			s.ScheduledTasks = previous
+		})
+		return err
+	}
+	return nil
+}
+
+func (a *App) GetScheduledTaskHistory(name string) ([]scheduler.Run, error) {
+	return a.scheduler.History(name)
+}
+
+func (a *App) RunScheduledTask(name string) error {
+	return a.scheduler.RunNow(name)
+}
+
+// FindOrphans lists objects whose owners no longer exist.
+func (a *App) FindOrphans(namespace string) (*k8s.OrphanReport, error) {
+	return a.k8sClient.FindOrphans(namespace)
+}
+
+// Update methods
+
+// GetVersion returns the running version, "dev" for development builds.
+func (a *App) GetVersion() string {
+	return version
+}
+
+// CheckForUpdates looks for a newer release on the configured channel.
+func (a *App) CheckForUpdates() (*updater.UpdateInfo, error) {
+	return a.updater.Check(a.ctx, a.settings.Get().Updates.Channel)
+}
+
+// DownloadUpdate downloads and stages the release found by the last
+// check, emitting "update:progress" as it goes.
+func (a *App) DownloadUpdate() (*updater.StagedUpdate, error) {
+	return a.updater.Download(a.ctx, func(downloaded, total int64) {
+		runtime.EventsEmit(a.ctx, "update:progress", map[string]int64{"downloaded": downloaded, "total": total})
+	})
+}
+
+// GetStagedUpdate returns the downloaded update waiting to be installed,
+// or nil.
+func (a *App) GetStagedUpdate() (*updater.StagedUpdate, error) {
+	return a.updater.Staged()
+}
+
+func (a *App) DiscardStagedUpdate() error {
+	return a.updater.Discard()
+}
+
+// checkForUpdatesOnStart emits "update:available" when a newer release
+// exists and, if enabled, stages it and emits "update:staged".
+func (a *App) checkForUpdatesOnStart() {
+	info, err := a.CheckForUpdates()
+	if err != nil {
+		fmt.Printf("Error checking for updates: %v\n", err)
+		return
+	}
+	if !info.Available {
+		return
+	}
+	runtime.EventsEmit(a.ctx, "update:available", info)
+	if !a.settings.Get().Updates.AutoDownload {
+		return
+	}
+	staged, err := a.DownloadUpdate()
+	if err != nil {
+		fmt.Printf("Error downloading update: %v\n", err)
+		return
+	}
+	runtime.EventsEmit(a.ctx, "update:staged", staged)
 }
@@ -56,18 +56,24 @@ export function ResourceDetail({
     initialData,
   );
 
-  const { data: related } = useRelatedResources(
+  const { data: graph } = useRelatedResources(
     data
       ? {
         group: resource.group,
         version: resource.version,
         kind: resource.kind,
+        plural: resource.name,
         namespace: namespace || "",
         name: resourceName || "",
       }
       : null,
   );
 
+  const related = useMemo(
+    () => (graph ? graph.nodes.filter((node) => node.uid !== graph.root) : []),
+    [graph],
+  );
+
   const profile = useMemo(
     () =>
       resolveResourceProfile({
@@ -247,44 +253,32 @@ export function ResourceDetail({
             ))}
 
             {/* Related Resources */}
-            {related && related.length > 0 && (
+            {related.length > 0 && (
               <section className="detail-section">
                 <h3 className="detail-section-title">Related Resources</h3>
                 <div className="detail-section-content">
-                  {related.map((res: any, i: number) => (
-                    <div key={i} className="detail-field">
+                  {related.map((node) => (
+                    <div key={node.uid} className="detail-field">
                       <span
                         className="detail-field-value resource-link clickable"
                         onClick={() => {
-                          if (onNavigate) {
-                            // We need the ApiResourceInfo for this resource
-                            // For now, we'll construct a partial one or just rely on the navigate function
-                            // A better way would be to pass the list of all API resources to lookup
-                            const resKind = res.kind;
-                            const resName = res.metadata.name;
-                            const resNs = res.metadata.namespace;
-
-                            // Construction for Pod is common
+                          if (onNavigate && node.plural) {
                             const info: ApiResourceInfo = {
-                              group:
-                                res.apiVersion.split("/")[0] === res.apiVersion
-                                  ? ""
-                                  : res.apiVersion.split("/")[0],
-                              version:
-                                res.apiVersion.split("/")[1] || res.apiVersion,
-                              kind: resKind,
-                              name: resKind.toLowerCase() + "s", // Crude pluralization
-                              namespaced: !!resNs,
+                              group: node.group,
+                              version: node.version,
+                              kind: node.kind,
+                              name: node.plural,
+                              namespaced: !!node.namespace,
                               verbs: ["get", "list", "watch"],
                               short_names: [],
                               category: "Workloads",
                             };
-                            onNavigate(info, resName, resNs);
+                            onNavigate(info, node.name, node.namespace || undefined);
                           }
                         }}
                       >
-                        {res.metadata.name}{" "}
-                        <span style={{ opacity: 0.5 }}>({res.kind})</span>
+                        {node.name}{" "}
+                        <span style={{ opacity: 0.5 }}>({node.kind})</span>
                       </span>
                     </div>
                   ))}
@@ -40,6 +40,29 @@ export interface ApiResourceInfo {
     category: string;
 }
 
+export interface GraphNode {
+    group: string;
+    version: string;
+    kind: string;
+    plural: string;
+    namespace: string;
+    name: string;
+    uid: string;
+}
+
+export interface GraphEdge {
+    from: string;
+    to: string;
+    type: "owns" | "selects" | "routes";
+}
+
+export interface ResourceGraph {
+    root: string;
+    nodes: GraphNode[];
+    edges: GraphEdge[];
+    errors?: Record<string, string>;
+}
+
 export interface ListResourcesParams {
     group: string;
     version: string;
@@ -47,6 +70,7 @@ export interface ListResourcesParams {
     plural: string;
     namespace?: string;
     label_selector?: string;
+    field_selector?: string;
 }
 
 // ============================================
@@ -214,14 +238,14 @@ export function useEditResource() {
 }
 
 /**
- * Fetch related resources (e.g. Deployment -> Pods)
+ * Fetch the graph of related resources (owners, dependents, selectors, routes)
  */
-export function useRelatedResources(params: { group: string; version: string; kind: string; namespace: string; name: string } | null) {
-    return useQuery<Record<string, unknown>[], Error>({
+export function useRelatedResources(params: { group: string; version: string; kind: string; plural: string; namespace: string; name: string } | null) {
+    return useQuery<ResourceGraph, Error>({
         queryKey: ["related-resources", params],
         queryFn: () => {
             if (!params) throw new Error("No params provided");
-            return wailsInvoke<Record<string, unknown>[]>("GetRelatedResources", params);
+            return wailsInvoke<ResourceGraph>("GetRelatedResources", params);
         },
         enabled: !!params,
     });
@@ -11,6 +11,9 @@ import (
 //go:embed all:frontend/dist
 var assets embed.FS
 
+// version is set by release builds with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
 func main() {
 	// Create an instance of the app structure
 	app := NewApp()
@@ -0,0 +1,40 @@
+// Package appdir locates the files teleskope keeps in the user's config
+// directory and writes them safely.
+package appdir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Path returns name inside the teleskope config directory, e.g.
+// ~/.config/teleskope/uptime.
+func Path(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "teleskope", name)
+}
+
+// contextNameReplacer maps the characters of context names that can't
+// appear in a file name. Context names may contain path separators (e.g.
+// EKS ARNs).
+var contextNameReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+
+// SafeName turns a kubeconfig context name into a file name.
+func SafeName(context string) string {
+	return contextNameReplacer.Replace(context)
+}
+
+// WriteFile writes data to a temporary file next to path and renames it
+// into place, so a crash never leaves a truncated file behind.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,39 @@
+package appdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeName(t *testing.T) {
+	tests := map[string]string{
+		"kind-dev": "kind-dev",
+		"arn:aws:eks:eu-west-1:123456789012:cluster/prod": "arn_aws_eks_eu-west-1_123456789012_cluster_prod",
+		`corp\admin@cluster`:                              "corp_admin@cluster",
+	}
+	for context, want := range tests {
+		if got := SafeName(context); got != want {
+			t.Errorf("SafeName(%q) = %q, want %q", context, got, want)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("file holds %q, want %q", data, content)
+		}
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package eventstore keeps a local archive of cluster events so they stay
+// queryable after the API server's event TTL (one hour by default) expires.
+package eventstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"teleskope/pkg/appdir"
+	"teleskope/pkg/k8s"
+)
+
+const dayLayout = "2006-01-02"
+
+// Store appends events as JSON lines to one file per context and day:
+// <dir>/<context>/<yyyy-mm-dd>.jsonl.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// DefaultDir returns the events directory next to the settings file.
+func DefaultDir() string {
+	return appdir.Path("events")
+}
+
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+type Query struct {
+	Context    string    `json:"context"`
+	Namespace  string    `json:"namespace"`
+	ObjectKind string    `json:"object_kind"`
+	ObjectName string    `json:"object_name"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+}
+
+func (s *Store) contextDir(context string) string {
+	return filepath.Join(s.dir, appdir.SafeName(context))
+}
+
+// Append records one observation of an event. Updated events (higher
+// count, later timestamp) are appended again; Query keeps the latest.
+func (s *Store) Append(context string, ev k8s.ArchivedEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.contextDir(context)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, ev.LastTimestamp.UTC().Format(dayLayout)+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Query returns archived events whose last occurrence falls in [From, To],
+// deduplicated by UID and sorted by last timestamp. A zero To means now.
+func (s *Store) Query(q Query) ([]k8s.ArchivedEvent, error) {
+	to := q.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := os.ReadDir(s.contextDir(q.Context))
+	if os.IsNotExist(err) {
+		return []k8s.ArchivedEvent{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fromDay := q.From.UTC().Format(dayLayout)
+	toDay := to.UTC().Format(dayLayout)
+	latest := make(map[string]k8s.ArchivedEvent)
+	for _, f := range files {
+		day := strings.TrimSuffix(f.Name(), ".jsonl")
+		if day == f.Name() || (!q.From.IsZero() && day < fromDay) || day > toDay {
+			continue
+		}
+		if err := s.scan(filepath.Join(s.contextDir(q.Context), f.Name()), func(ev k8s.ArchivedEvent) {
+			if ev.LastTimestamp.Before(q.From) || ev.LastTimestamp.After(to) {
+				return
+			}
+			if q.Namespace != "" && ev.Namespace != q.Namespace {
+				return
+			}
+			if q.ObjectKind != "" && ev.ObjectKind != q.ObjectKind {
+				return
+			}
+			if q.ObjectName != "" && ev.ObjectName != q.ObjectName {
+				return
+			}
+			if prev, ok := latest[ev.UID]; !ok || !ev.LastTimestamp.Before(prev.LastTimestamp) {
+				latest[ev.UID] = ev
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]k8s.ArchivedEvent, 0, len(latest))
+	for _, ev := range latest {
+		result = append(result, ev)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastTimestamp.Before(result[j].LastTimestamp)
+	})
+	return result, nil
+}
+
+func (s *Store) scan(path string, fn func(k8s.ArchivedEvent)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var ev k8s.ArchivedEvent
+		// Skip lines torn by a crash mid-write.
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue
+		}
+		fn(ev)
+	}
+	return scanner.Err()
+}
+
+// Prune deletes a context's day files older than retention.
+func (s *Store) Prune(context string, retention time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.contextDir(context)
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-retention).UTC().Format(dayLayout)
+	for _, f := range files {
+		day := strings.TrimSuffix(f.Name(), ".jsonl")
+		if day != f.Name() && day < cutoff {
+			if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
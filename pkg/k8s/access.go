@@ -0,0 +1,220 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EventAccessReady is emitted after a pre-flight finishes, with the
+	// namespaces it covered.
+	EventAccessReady = "access:ready"
+
+	accessReviewWorkers = 4
+)
+
+type AccessRule struct {
+	Verbs         []string `json:"verbs"`
+	APIGroups     []string `json:"api_groups"`
+	Resources     []string `json:"resources"`
+	ResourceNames []string `json:"resource_names,omitempty"`
+}
+
+// NamespaceAccess is the result of a SelfSubjectRulesReview. Incomplete is
+// set when the authorizer can't enumerate every rule (e.g. webhook
+// authorizers); the UI should then treat missing rules as unknown.
+type NamespaceAccess struct {
+	Namespace       string       `json:"namespace"`
+	Rules           []AccessRule `json:"rules"`
+	Incomplete      bool         `json:"incomplete"`
+	EvaluationError string       `json:"evaluation_error,omitempty"`
+	CheckedAt       time.Time    `json:"checked_at"`
+}
+
+type ResourceAccess struct {
+	Group      string   `json:"group"`
+	Version    string   `json:"version"`
+	Kind       string   `json:"kind"`
+	Plural     string   `json:"plural"`
+	Namespaced bool     `json:"namespaced"`
+	Verbs      []string `json:"verbs"`
+}
+
+type AccessReady struct {
+	Namespaces []string `json:"namespaces"`
+}
+
+type accessCache struct {
+	mu         sync.Mutex
+	namespaces map[string]*NamespaceAccess
+}
+
+func (a *accessCache) get(namespace string) *NamespaceAccess {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.namespaces[namespace]
+}
+
+func (a *accessCache) set(access *NamespaceAccess) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.namespaces == nil {
+		a.namespaces = make(map[string]*NamespaceAccess)
+	}
+	a.namespaces[access.Namespace] = access
+}
+
+func (a *accessCache) clear() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.namespaces = nil
+}
+
+// PreflightAccess runs a SelfSubjectRulesReview for each namespace
+// concurrently, at background priority, and caches the results.
+// Cluster-scoped rules are part of every review. It emits
+// EventAccessReady when done.
+func (c *Client) PreflightAccess(namespaces []string) []*NamespaceAccess {
+	results := make([]*NamespaceAccess, len(namespaces))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, accessReviewWorkers)
+	for i, ns := range namespaces {
+		wg.Add(1)
+		go func(i int, ns string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.reviewAccess(ns, PriorityBackground)
+			c.access.set(results[i])
+		}(i, ns)
+	}
+	wg.Wait()
+
+	c.emit(EventAccessReady, AccessReady{Namespaces: namespaces})
+	return results
+}
+
+func (c *Client) reviewAccess(namespace string, priority RequestPriority) *NamespaceAccess {
+	access := &NamespaceAccess{Namespace: namespace, CheckedAt: time.Now()}
+	review := &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	}
+
+	var result *authorizationv1.SelfSubjectRulesReview
+	err := c.doWithPriority(OpGet, priority, func(ctx context.Context) error {
+		var err error
+		result, err = c.Clientset.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, review, metav1.CreateOptions{})
+		return err
+	})
+	if err != nil {
+		access.Incomplete = true
+		access.EvaluationError = err.Error()
+		return access
+	}
+
+	access.Incomplete = result.Status.Incomplete
+	access.EvaluationError = result.Status.EvaluationError
+	for _, r := range result.Status.ResourceRules {
+		access.Rules = append(access.Rules, AccessRule{
+			Verbs:         r.Verbs,
+			APIGroups:     r.APIGroups,
+			Resources:     r.Resources,
+			ResourceNames: r.ResourceNames,
+		})
+	}
+	return access
+}
+
+// GetAccess returns the cached review for a namespace, running it first
+// if the namespace hasn't been checked since the context connected.
+func (c *Client) GetAccess(namespace string) *NamespaceAccess {
+	if access := c.access.get(namespace); access != nil {
+		return access
+	}
+	access := c.reviewAccess(namespace, PriorityInteractive)
+	c.access.set(access)
+	return access
+}
+
+// GetResourceAccess lists every API resource with the verbs the user may
+// use on it in a namespace, so the UI can hide kinds and actions that
+// would only fail with Forbidden. Resources with no allowed verbs are
+// omitted unless the review was incomplete.
+func (c *Client) GetResourceAccess(namespace string) ([]ResourceAccess, error) {
+	resources, err := c.GetApiResources()
+	if err != nil {
+		return nil, err
+	}
+	access := c.GetAccess(namespace)
+
+	var result []ResourceAccess
+	for _, res := range resources {
+		var verbs []string
+		for _, verb := range res.Verbs {
+			if access.Incomplete || rulesAllow(access.Rules, res.Group, res.Name, verb) {
+				verbs = append(verbs, verb)
+			}
+		}
+		if len(verbs) == 0 {
+			continue
+		}
+		result = append(result, ResourceAccess{
+			Group:      res.Group,
+			Version:    res.Version,
+			Kind:       res.Kind,
+			Plural:     res.Name,
+			Namespaced: res.Namespaced,
+			Verbs:      verbs,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Group != result[j].Group {
+			return result[i].Group < result[j].Group
+		}
+		return result[i].Plural < result[j].Plural
+	})
+	return result, nil
+}
+
+// rulesAllow reports whether any rule grants verb on every object of the
+// resource. Rules limited to specific resource names don't count.
+func rulesAllow(rules []AccessRule, group, resource, verb string) bool {
+	for _, r := range rules {
+		if len(r.ResourceNames) > 0 {
+			continue
+		}
+		if matchesAny(r.APIGroups, group) && matchesResource(r.Resources, resource) && matchesAny(r.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(values []string, want string) bool {
+	for _, v := range values {
+		if v == "*" || v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesResource also understands "*/subresource" and "resource/*".
+func matchesResource(values []string, resource string) bool {
+	base, sub, hasSub := strings.Cut(resource, "/")
+	for _, v := range values {
+		if v == "*" || v == resource {
+			return true
+		}
+		if hasSub && (v == "*/"+sub || v == base+"/*") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,284 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const EventWorkloadActivity = "workload:activity"
+
+type ActivityEntry struct {
+	SubscriptionID string    `json:"subscription_id"`
+	Time           time.Time `json:"time"`
+	Source         string    `json:"source"`
+	Kind           string    `json:"kind"`
+	Name           string    `json:"name"`
+	Action         string    `json:"action"`
+	Type           string    `json:"type"`
+	Message        string    `json:"message"`
+}
+
+// activityTracker holds the UIDs that belong to a workload: the workload
+// itself, its ReplicaSets and their pods.
+type activityTracker struct {
+	mu   sync.RWMutex
+	uids map[types.UID]bool
+}
+
+func (t *activityTracker) has(uid types.UID) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.uids[uid]
+}
+
+func (t *activityTracker) add(uid types.UID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.uids[uid] = true
+}
+
+func (t *activityTracker) ownedBy(refs []metav1.OwnerReference) bool {
+	for _, ref := range refs {
+		if t.has(ref.UID) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchWorkloadActivity merges watch updates of a workload, its
+// ReplicaSets and pods, and the events for all of them into a single
+// stream of EventWorkloadActivity events. It returns a subscription ID
+// for StopSubscription.
+//
+// The current ReplicaSets, pods and events are listed first: they seed the
+// set of tracked objects, so a pod isn't missed for arriving before its
+// ReplicaSet, and are replayed oldest first before the watches resume from
+// the listed versions.
+func (c *Client) WatchWorkloadActivity(ref ResourceRef) (string, error) {
+	var obj *unstructured.Unstructured
+	var replicaSets *appsv1.ReplicaSetList
+	var pods *corev1.PodList
+	var events *corev1.EventList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if obj, err = c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{}); err != nil {
+			return err
+		}
+		replicaSets = &appsv1.ReplicaSetList{}
+		if ref.Kind == "Deployment" {
+			if replicaSets, err = c.Clientset.AppsV1().ReplicaSets(ref.Namespace).List(ctx, metav1.ListOptions{}); err != nil {
+				return err
+			}
+		}
+		if pods, err = c.Clientset.CoreV1().Pods(ref.Namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			return err
+		}
+		events, err = c.Clientset.CoreV1().Events(ref.Namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	tracker := &activityTracker{uids: map[types.UID]bool{obj.GetUID(): true}}
+	initial := []ActivityEntry{workloadEntry(watch.Added, obj)}
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if tracker.ownedBy(rs.OwnerReferences) {
+			tracker.add(rs.UID)
+			initial = append(initial, replicaSetEntry(watch.Added, rs))
+		}
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if tracker.ownedBy(pod.OwnerReferences) {
+			tracker.add(pod.UID)
+			initial = append(initial, podEntry(watch.Added, pod))
+		}
+	}
+	for i := range events.Items {
+		if e := &events.Items[i]; tracker.has(e.InvolvedObject.UID) {
+			initial = append(initial, eventEntry(e))
+		}
+	}
+	sort.SliceStable(initial, func(i, j int) bool { return initial[i].Time.Before(initial[j].Time) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	id := c.subs.add("activity", cancel, ref.GVR())
+
+	send := func(entry ActivityEntry) {
+		entry.SubscriptionID = id
+		c.emit(EventWorkloadActivity, entry)
+	}
+	nameSelector := fields.OneTermEqualSelector("metadata.name", ref.Name).String()
+
+	go func() {
+		for _, entry := range initial {
+			send(entry)
+		}
+
+		go c.watchLoopFrom(ctx, id, obj.GetResourceVersion(), func(ctx context.Context, rv string) (watch.Interface, error) {
+			return c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Watch(ctx, metav1.ListOptions{
+				FieldSelector:   nameSelector,
+				ResourceVersion: rv,
+			})
+		}, func(ev watch.Event) {
+			if u, ok := asUnstructured(ev.Object); ok {
+				send(workloadEntry(ev.Type, u))
+			}
+		}, func() { c.resourceGone(ref.GVR()) })
+
+		if ref.Kind == "Deployment" {
+			go c.watchLoopFrom(ctx, id, replicaSets.ResourceVersion, func(ctx context.Context, rv string) (watch.Interface, error) {
+				return c.Clientset.AppsV1().ReplicaSets(ref.Namespace).Watch(ctx, metav1.ListOptions{ResourceVersion: rv})
+			}, func(ev watch.Event) {
+				rs, ok := ev.Object.(*appsv1.ReplicaSet)
+				if !ok || !tracker.ownedBy(rs.OwnerReferences) {
+					return
+				}
+				tracker.add(rs.UID)
+				send(replicaSetEntry(ev.Type, rs))
+			}, nil)
+		}
+
+		go c.watchLoopFrom(ctx, id, pods.ResourceVersion, func(ctx context.Context, rv string) (watch.Interface, error) {
+			return c.Clientset.CoreV1().Pods(ref.Namespace).Watch(ctx, metav1.ListOptions{ResourceVersion: rv})
+		}, func(ev watch.Event) {
+			pod, ok := ev.Object.(*corev1.Pod)
+			if !ok || !tracker.ownedBy(pod.OwnerReferences) {
+				return
+			}
+			tracker.add(pod.UID)
+			send(podEntry(ev.Type, pod))
+		}, nil)
+
+		go c.watchLoopFrom(ctx, id, events.ResourceVersion, func(ctx context.Context, rv string) (watch.Interface, error) {
+			return c.Clientset.CoreV1().Events(ref.Namespace).Watch(ctx, metav1.ListOptions{ResourceVersion: rv})
+		}, func(ev watch.Event) {
+			e, ok := ev.Object.(*corev1.Event)
+			if !ok || ev.Type == watch.Deleted || !tracker.has(e.InvolvedObject.UID) {
+				return
+			}
+			send(eventEntry(e))
+		}, nil)
+	}()
+
+	return id, nil
+}
+
+func workloadEntry(t watch.EventType, u *unstructured.Unstructured) ActivityEntry {
+	return ActivityEntry{
+		Time:    objectEventTime(t, u.GetCreationTimestamp()),
+		Source:  "workload",
+		Kind:    u.GetKind(),
+		Name:    u.GetName(),
+		Action:  string(t),
+		Message: workloadStatusSummary(u),
+	}
+}
+
+func replicaSetEntry(t watch.EventType, rs *appsv1.ReplicaSet) ActivityEntry {
+	desired := int32(0)
+	if rs.Spec.Replicas != nil {
+		desired = *rs.Spec.Replicas
+	}
+	return ActivityEntry{
+		Time:   objectEventTime(t, rs.CreationTimestamp),
+		Source: "replicaset",
+		Kind:   "ReplicaSet",
+		Name:   rs.Name,
+		Action: string(t),
+		Message: fmt.Sprintf("revision %s: %d/%d ready",
+			rs.Annotations["deployment.kubernetes.io/revision"], rs.Status.ReadyReplicas, desired),
+	}
+}
+
+func podEntry(t watch.EventType, pod *corev1.Pod) ActivityEntry {
+	return ActivityEntry{
+		Time:    objectEventTime(t, pod.CreationTimestamp),
+		Source:  "pod",
+		Kind:    "Pod",
+		Name:    pod.Name,
+		Action:  string(t),
+		Message: podStatusSummary(pod),
+	}
+}
+
+func eventEntry(e *corev1.Event) ActivityEntry {
+	return ActivityEntry{
+		Time:    eventTimestamp(e),
+		Source:  "event",
+		Kind:    e.InvolvedObject.Kind,
+		Name:    e.InvolvedObject.Name,
+		Action:  e.Reason,
+		Type:    e.Type,
+		Message: e.Message,
+	}
+}
+
+// objectEventTime uses the creation time for additions so the initial
+// replay of existing objects sorts correctly, and the current time for
+// later changes, which carry no timestamp of their own.
+func objectEventTime(t watch.EventType, created metav1.Time) time.Time {
+	if t == watch.Added && !created.IsZero() {
+		return created.Time
+	}
+	return time.Now()
+}
+
+func eventTimestamp(e *corev1.Event) time.Time {
+	switch {
+	case e.Series != nil && !e.Series.LastObservedTime.IsZero():
+		return e.Series.LastObservedTime.Time
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	case !e.FirstTimestamp.IsZero():
+		return e.FirstTimestamp.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+func workloadStatusSummary(u *unstructured.Unstructured) string {
+	replicas, _, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+	ready, _, _ := unstructured.NestedInt64(u.Object, "status", "readyReplicas")
+	updated, _, _ := unstructured.NestedInt64(u.Object, "status", "updatedReplicas")
+	if u.GetKind() == "DaemonSet" {
+		replicas, _, _ = unstructured.NestedInt64(u.Object, "status", "desiredNumberScheduled")
+		ready, _, _ = unstructured.NestedInt64(u.Object, "status", "numberReady")
+		updated, _, _ = unstructured.NestedInt64(u.Object, "status", "updatedNumberScheduled")
+	}
+	return fmt.Sprintf("generation %d: %d desired, %d updated, %d ready", u.GetGeneration(), replicas, updated, ready)
+}
+
+func podStatusSummary(pod *corev1.Pod) string {
+	parts := []string{string(pod.Status.Phase)}
+	for _, cs := range pod.Status.ContainerStatuses {
+		switch {
+		case cs.State.Waiting != nil:
+			parts = append(parts, fmt.Sprintf("%s waiting (%s)", cs.Name, cs.State.Waiting.Reason))
+		case cs.State.Terminated != nil:
+			parts = append(parts, fmt.Sprintf("%s terminated (%s)", cs.Name, cs.State.Terminated.Reason))
+		case !cs.Ready:
+			parts = append(parts, cs.Name+" not ready")
+		}
+	}
+	if pod.DeletionTimestamp != nil {
+		parts = append(parts, "terminating")
+	}
+	return strings.Join(parts, ", ")
+}
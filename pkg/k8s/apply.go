@@ -0,0 +1,223 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+const defaultFieldManager = "teleskope"
+
+const (
+	ApplyCreated    = "created"
+	ApplyConfigured = "configured"
+	ApplyUnchanged  = "unchanged"
+	ApplyError      = "error"
+)
+
+type ApplyResult struct {
+	// Document is the zero-based position of the object in the manifest.
+	Document  int    `json:"document"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ApplyManifest server-side applies every object in a multi-document YAML
+// (or JSON) manifest and reports the outcome per object. Objects are
+// applied in order and a failing object doesn't stop the rest.
+func (c *Client) ApplyManifest(manifest, fieldManager string, dryRun bool) ([]ApplyResult, error) {
+	objects, err := decodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("manifest contains no objects")
+	}
+	if fieldManager == "" {
+		fieldManager = defaultFieldManager
+	}
+
+	groups, err := restmapper.GetAPIGroupResources(c.DiscoveryClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover API resources: %v", err)
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groups)
+	defaultNamespace := c.defaultNamespace()
+
+	results := make([]ApplyResult, 0, len(objects))
+	for i, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		result := ApplyResult{
+			Document:  i,
+			Group:     gvk.Group,
+			Version:   gvk.Version,
+			Kind:      gvk.Kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+		}
+
+		status, err := c.applyObject(mapper, obj, defaultNamespace, fieldManager, dryRun)
+		if err != nil {
+			result.Status = ApplyError
+			result.Error = err.Error()
+		} else {
+			result.Status = status
+		}
+		result.Namespace = obj.GetNamespace()
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (c *Client) applyObject(mapper meta.RESTMapper, obj *unstructured.Unstructured, defaultNamespace, fieldManager string, dryRun bool) (string, error) {
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" {
+		return "", errors.New("object is missing apiVersion or kind")
+	}
+	if obj.GetName() == "" {
+		return "", errors.New("object is missing metadata.name")
+	}
+
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return "", fmt.Errorf("unknown resource type %s: %v", gvk, err)
+	}
+
+	var resource dynamic.ResourceInterface = c.DynamicClient.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(defaultNamespace)
+		}
+		resource = c.DynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	} else {
+		obj.SetNamespace("")
+	}
+
+	var existing *unstructured.Unstructured
+	err = c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		existing, err = resource.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		return err
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	opts := metav1.PatchOptions{FieldManager: fieldManager}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	var applied *unstructured.Unstructured
+	patch := func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			var err error
+			applied, err = resource.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, opts)
+			return err
+		})
+	}
+	if dryRun {
+		err = patch()
+	} else {
+		ref := ResourceRef{
+			Group:     mapping.Resource.Group,
+			Version:   mapping.Resource.Version,
+			Kind:      gvk.Kind,
+			Plural:    mapping.Resource.Resource,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+		}
+		err = c.mutate(ActionApply, ref, fmt.Sprintf("apply %s/%s", ref.Plural, ref.Name), patch)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case existing == nil:
+		return ApplyCreated, nil
+	case sameApplyContent(existing, applied):
+		return ApplyUnchanged, nil
+	default:
+		return ApplyConfigured, nil
+	}
+}
+
+// sameApplyContent compares two versions of an object, ignoring the
+// bookkeeping fields every write touches.
+func sameApplyContent(a, b *unstructured.Unstructured) bool {
+	strip := func(u *unstructured.Unstructured) map[string]interface{} {
+		c := u.DeepCopy()
+		unstructured.RemoveNestedField(c.Object, "metadata", "managedFields")
+		unstructured.RemoveNestedField(c.Object, "metadata", "resourceVersion")
+		return c.Object
+	}
+	return reflect.DeepEqual(strip(a), strip(b))
+}
+
+// decodeManifest splits a multi-document manifest into objects, expanding
+// kind: List documents and skipping empty ones.
+func decodeManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	var objects []*unstructured.Unstructured
+	for doc := 0; ; doc++ {
+		var raw map[string]interface{}
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", doc, err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: raw}
+		if obj.IsList() {
+			list, err := obj.ToList()
+			if err != nil {
+				return nil, fmt.Errorf("document %d: %v", doc, err)
+			}
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// defaultNamespace is the namespace of the active kubeconfig context,
+// falling back to "default".
+func (c *Client) defaultNamespace() string {
+	if c.Config != nil && !c.demo {
+		if ns, _, err := c.Config.Namespace(); err == nil && ns != "" {
+			return ns
+		}
+	}
+	return "default"
+}
@@ -0,0 +1,183 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// EventBadgesUpdate carries the badge counts of namespaces whose counts
+	// changed since the last update.
+	EventBadgesUpdate = "badges:update"
+
+	badgeFlushInterval = time.Second
+)
+
+var badgeWorkloads = []schema.GroupVersionResource{
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+}
+
+// NamespaceBadge holds the counters shown next to a namespace in the
+// sidebar: live Warning events and workloads that aren't available.
+type NamespaceBadge struct {
+	Namespace          string `json:"namespace"`
+	Warnings           int    `json:"warnings"`
+	UnhealthyWorkloads int    `json:"unhealthy_workloads"`
+}
+
+// badgeBoard keeps the sets behind the badge counters so repeated watch
+// events for the same object don't inflate them.
+type badgeBoard struct {
+	mu        sync.Mutex
+	warnings  map[string]map[string]bool
+	unhealthy map[string]map[string]bool
+	dirty     map[string]bool
+}
+
+func (b *badgeBoard) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.warnings = make(map[string]map[string]bool)
+	b.unhealthy = make(map[string]map[string]bool)
+	b.dirty = make(map[string]bool)
+}
+
+func (b *badgeBoard) markWarning(namespace, uid string, on bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mark(b.warnings, namespace, uid, on)
+}
+
+func (b *badgeBoard) markUnhealthy(namespace, workload string, on bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mark(b.unhealthy, namespace, workload, on)
+}
+
+// mark adds or removes key from the namespace's set and flags the
+// namespace when its count changed. b.mu must be held.
+func (b *badgeBoard) mark(sets map[string]map[string]bool, namespace, key string, on bool) {
+	if sets == nil {
+		return
+	}
+	set, ok := sets[namespace]
+	if !ok {
+		set = make(map[string]bool)
+		sets[namespace] = set
+	}
+	if set[key] == on {
+		return
+	}
+	if on {
+		set[key] = true
+	} else {
+		delete(set, key)
+	}
+	b.dirty[namespace] = true
+}
+
+func (b *badgeBoard) badge(namespace string) NamespaceBadge {
+	return NamespaceBadge{
+		Namespace:          namespace,
+		Warnings:           len(b.warnings[namespace]),
+		UnhealthyWorkloads: len(b.unhealthy[namespace]),
+	}
+}
+
+// takeDirty returns the badges changed since the last call.
+func (b *badgeBoard) takeDirty() []NamespaceBadge {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var result []NamespaceBadge
+	for ns := range b.dirty {
+		result = append(result, b.badge(ns))
+	}
+	b.dirty = make(map[string]bool)
+	sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+	return result
+}
+
+func (b *badgeBoard) snapshot() []NamespaceBadge {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seen := make(map[string]bool)
+	var result []NamespaceBadge
+	for _, sets := range []map[string]map[string]bool{b.warnings, b.unhealthy} {
+		for ns := range sets {
+			if !seen[ns] {
+				seen[ns] = true
+				result = append(result, b.badge(ns))
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Namespace < result[j].Namespace })
+	return result
+}
+
+// WatchNamespaceBadges watches events and workloads in all namespaces and
+// emits EventBadgesUpdate, at most once per second, for namespaces whose
+// warning or unhealthy workload counts changed. It returns a subscription
+// ID for StopSubscription.
+func (c *Client) WatchNamespaceBadges() (string, error) {
+	c.badges.reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	eventsGVR := schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	id := c.subs.add("badges", cancel, append([]schema.GroupVersionResource{eventsGVR}, badgeWorkloads...)...)
+
+	go c.watchLoop(ctx, id, func(ctx context.Context, rv string) (watch.Interface, error) {
+		return c.Clientset.CoreV1().Events("").Watch(ctx, metav1.ListOptions{ResourceVersion: rv})
+	}, func(ev watch.Event) {
+		e, ok := ev.Object.(*corev1.Event)
+		if !ok {
+			return
+		}
+		active := ev.Type != watch.Deleted && e.Type == corev1.EventTypeWarning
+		c.badges.markWarning(e.Namespace, string(e.UID), active)
+	}, nil)
+
+	for _, gvr := range badgeWorkloads {
+		gvr := gvr
+		go c.watchLoop(ctx, id, func(ctx context.Context, rv string) (watch.Interface, error) {
+			return c.DynamicClient.Resource(gvr).Watch(ctx, metav1.ListOptions{ResourceVersion: rv})
+		}, func(ev watch.Event) {
+			u, ok := asUnstructured(ev.Object)
+			if !ok {
+				return
+			}
+			unhealthy := ev.Type != watch.Deleted && !workloadAvailable(u)
+			c.badges.markUnhealthy(u.GetNamespace(), u.GetKind()+"/"+u.GetName(), unhealthy)
+		}, func() { c.resourceGone(gvr) })
+	}
+
+	go func() {
+		defer c.subs.remove(id)
+		ticker := time.NewTicker(badgeFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if changed := c.badges.takeDirty(); len(changed) > 0 {
+					c.emit(EventBadgesUpdate, changed)
+				}
+			}
+		}
+	}()
+	return id, nil
+}
+
+// GetNamespaceBadges returns the current counters of every namespace seen
+// by WatchNamespaceBadges, for views mounted after the initial updates.
+func (c *Client) GetNamespaceBadges() []NamespaceBadge {
+	return c.badges.snapshot()
+}
@@ -0,0 +1,214 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccessDecision is the answer to a single "can I" question. Reason is
+// the authorizer's explanation, if it gave one.
+type AccessDecision struct {
+	Allowed         bool   `json:"allowed"`
+	Denied          bool   `json:"denied"`
+	Reason          string `json:"reason,omitempty"`
+	EvaluationError string `json:"evaluation_error,omitempty"`
+}
+
+// AccessGrant is one way a subject gets a permission: a binding to a role
+// with a matching rule.
+type AccessGrant struct {
+	SubjectKind      string `json:"subject_kind"`
+	SubjectName      string `json:"subject_name"`
+	SubjectNamespace string `json:"subject_namespace,omitempty"`
+	BindingKind      string `json:"binding_kind"`
+	BindingName      string `json:"binding_name"`
+	BindingNamespace string `json:"binding_namespace,omitempty"`
+	RoleKind         string `json:"role_kind"`
+	RoleName         string `json:"role_name"`
+	// ResourceNames is set when the rule only covers specific objects.
+	ResourceNames []string `json:"resource_names,omitempty"`
+}
+
+type WhoCanResult struct {
+	Verb      string        `json:"verb"`
+	Group     string        `json:"group"`
+	Resource  string        `json:"resource"`
+	Namespace string        `json:"namespace,omitempty"`
+	Grants    []AccessGrant `json:"grants"`
+	// Errors lists RBAC objects that couldn't be read, which makes the
+	// result incomplete.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// CanI asks the API server whether the current user may perform verb on
+// the resource, like `kubectl auth can-i`. name may be empty to ask about
+// all objects, namespace empty for cluster scope. resource may name a
+// subresource, e.g. "pods/exec".
+func (c *Client) CanI(verb, group, resource, namespace, name string) (*AccessDecision, error) {
+	attrs := &authorizationv1.ResourceAttributes{
+		Verb:      verb,
+		Group:     group,
+		Namespace: namespace,
+		Name:      name,
+	}
+	attrs.Resource, attrs.Subresource, _ = strings.Cut(resource, "/")
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+	}
+	var result *authorizationv1.SelfSubjectAccessReview
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		result, err = c.Clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("access review failed: %v", err)
+	}
+	return &AccessDecision{
+		Allowed:         result.Status.Allowed,
+		Denied:          result.Status.Denied,
+		Reason:          result.Status.Reason,
+		EvaluationError: result.Status.EvaluationError,
+	}, nil
+}
+
+// WhoCan lists the subjects that RBAC allows to perform verb on resource,
+// like `kubectl who-can`. resource may carry its group as in
+// "deployments.apps". With a namespace, RoleBindings in it count as well
+// as ClusterRoleBindings; without one only ClusterRoleBindings do. Other
+// authorizers (webhooks, ABAC) are not considered.
+func (c *Client) WhoCan(verb, resource, namespace string) (*WhoCanResult, error) {
+	resourceName, group, _ := strings.Cut(resource, ".")
+	result := &WhoCanResult{
+		Verb:      verb,
+		Group:     group,
+		Resource:  resourceName,
+		Namespace: namespace,
+		Grants:    []AccessGrant{},
+		Errors:    make(map[string]string),
+	}
+	rbac := c.Clientset.RbacV1()
+
+	var clusterRoles *rbacv1.ClusterRoleList
+	var clusterBindings *rbacv1.ClusterRoleBindingList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		clusterRoles, err = rbac.ClusterRoles().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster roles: %v", err)
+	}
+	err = c.do(OpList, func(ctx context.Context) error {
+		var err error
+		clusterBindings, err = rbac.ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster role bindings: %v", err)
+	}
+
+	// matching maps "Kind/name" of roles to the resource names their
+	// matching rule is limited to (nil for all objects).
+	matching := make(map[string][]string)
+	for _, role := range clusterRoles.Items {
+		if names, ok := rbacRulesMatch(role.Rules, verb, group, resourceName); ok {
+			matching["ClusterRole/"+role.Name] = names
+		}
+	}
+
+	grant := func(bindingKind string, binding metav1.ObjectMeta, roleRef rbacv1.RoleRef, subjects []rbacv1.Subject) {
+		names, ok := matching[roleRef.Kind+"/"+roleRef.Name]
+		if !ok {
+			return
+		}
+		for _, s := range subjects {
+			result.Grants = append(result.Grants, AccessGrant{
+				SubjectKind:      s.Kind,
+				SubjectName:      s.Name,
+				SubjectNamespace: s.Namespace,
+				BindingKind:      bindingKind,
+				BindingName:      binding.Name,
+				BindingNamespace: binding.Namespace,
+				RoleKind:         roleRef.Kind,
+				RoleName:         roleRef.Name,
+				ResourceNames:    names,
+			})
+		}
+	}
+	for _, b := range clusterBindings.Items {
+		grant("ClusterRoleBinding", b.ObjectMeta, b.RoleRef, b.Subjects)
+	}
+
+	if namespace != "" {
+		var roles *rbacv1.RoleList
+		var bindings *rbacv1.RoleBindingList
+		err := c.do(OpList, func(ctx context.Context) error {
+			var err error
+			roles, err = rbac.Roles(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			result.Errors["roles"] = err.Error()
+		} else {
+			for _, role := range roles.Items {
+				if names, ok := rbacRulesMatch(role.Rules, verb, group, resourceName); ok {
+					matching["Role/"+role.Name] = names
+				}
+			}
+		}
+		err = c.do(OpList, func(ctx context.Context) error {
+			var err error
+			bindings, err = rbac.RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			result.Errors["rolebindings"] = err.Error()
+		} else {
+			for _, b := range bindings.Items {
+				grant("RoleBinding", b.ObjectMeta, b.RoleRef, b.Subjects)
+			}
+		}
+	}
+
+	sort.Slice(result.Grants, func(i, j int) bool {
+		a, b := result.Grants[i], result.Grants[j]
+		if a.SubjectKind != b.SubjectKind {
+			return a.SubjectKind < b.SubjectKind
+		}
+		if a.SubjectNamespace+"/"+a.SubjectName != b.SubjectNamespace+"/"+b.SubjectName {
+			return a.SubjectNamespace+"/"+a.SubjectName < b.SubjectNamespace+"/"+b.SubjectName
+		}
+		return a.BindingName < b.BindingName
+	})
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	return result, nil
+}
+
+// rbacRulesMatch reports whether any rule grants verb on the resource. A
+// rule covering all objects wins over rules limited to resource names,
+// whose names are returned otherwise.
+func rbacRulesMatch(rules []rbacv1.PolicyRule, verb, group, resource string) ([]string, bool) {
+	var names []string
+	found := false
+	for _, r := range rules {
+		if !matchesAny(r.APIGroups, group) || !matchesResource(r.Resources, resource) || !matchesAny(r.Verbs, verb) {
+			continue
+		}
+		if len(r.ResourceNames) == 0 {
+			return nil, true
+		}
+		found = true
+		names = append(names, r.ResourceNames...)
+	}
+	return names, found
+}
@@ -0,0 +1,398 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	capiGroup = "cluster.x-k8s.io"
+
+	// capiClusterLabel and capiDeploymentLabel are set by Cluster API on
+	// the objects of a cluster and on the Machines of a MachineDeployment.
+	capiClusterLabel    = "cluster.x-k8s.io/cluster-name"
+	capiDeploymentLabel = "cluster.x-k8s.io/deployment-name"
+
+	// capiStuckAfter is how long a Cluster or Machine may stay in a
+	// provisioning or deleting phase before it is reported as stuck.
+	capiStuckAfter = 15 * time.Minute
+)
+
+// Phases in which Cluster API objects are expected to move on by
+// themselves. A Machine is Provisioned until its Node joins.
+var capiTransientPhases = map[string]bool{
+	"Pending":      true,
+	"Provisioning": true,
+	"Provisioned":  true,
+	"Deleting":     true,
+	"ScalingUp":    true,
+	"ScalingDown":  true,
+}
+
+type CAPICondition struct {
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Severity string `json:"severity,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Since    string `json:"since,omitempty"`
+}
+
+type CAPICluster struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	// Version and Class are set for ClusterClass-based clusters.
+	Version             string          `json:"version,omitempty"`
+	Class               string          `json:"class,omitempty"`
+	ControlPlaneReady   bool            `json:"control_plane_ready"`
+	InfrastructureReady bool            `json:"infrastructure_ready"`
+	Machines            int             `json:"machines"`
+	ReadyMachines       int             `json:"ready_machines"`
+	Created             string          `json:"created"`
+	Conditions          []CAPICondition `json:"conditions"`
+}
+
+type CAPIMachineDeployment struct {
+	Namespace         string          `json:"namespace"`
+	Name              string          `json:"name"`
+	Cluster           string          `json:"cluster"`
+	Phase             string          `json:"phase"`
+	Version           string          `json:"version,omitempty"`
+	Replicas          int64           `json:"replicas"`
+	ReadyReplicas     int64           `json:"ready_replicas"`
+	UpdatedReplicas   int64           `json:"updated_replicas"`
+	AvailableReplicas int64           `json:"available_replicas"`
+	Conditions        []CAPICondition `json:"conditions"`
+}
+
+type CAPIMachine struct {
+	Namespace         string `json:"namespace"`
+	Name              string `json:"name"`
+	Cluster           string `json:"cluster"`
+	MachineDeployment string `json:"machine_deployment,omitempty"`
+	Phase             string `json:"phase"`
+	Version           string `json:"version,omitempty"`
+	ProviderID        string `json:"provider_id,omitempty"`
+	// NodeName is the Node the Machine became, in the workload cluster.
+	NodeName string `json:"node_name,omitempty"`
+	// NodeFound and NodeReady are only set when the Node is in the
+	// connected cluster, as with self-managed clusters.
+	NodeFound  bool            `json:"node_found"`
+	NodeReady  bool            `json:"node_ready"`
+	Created    string          `json:"created"`
+	Conditions []CAPICondition `json:"conditions"`
+}
+
+// CAPIIssue is a Cluster or Machine that has been provisioning or
+// deleting for longer than capiStuckAfter.
+type CAPIIssue struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	Phase     string `json:"phase"`
+	// Since is when the object entered its phase, as far as can be told.
+	Since string `json:"since"`
+	// Condition is the failing condition that best explains the delay.
+	Condition *CAPICondition `json:"condition,omitempty"`
+	Message   string         `json:"message"`
+}
+
+type CAPIFleet struct {
+	// Available is false when the Cluster API CRDs aren't installed; the
+	// lists are empty then.
+	Available          bool                    `json:"available"`
+	APIVersion         string                  `json:"api_version,omitempty"`
+	Clusters           []CAPICluster           `json:"clusters"`
+	MachineDeployments []CAPIMachineDeployment `json:"machine_deployments"`
+	Machines           []CAPIMachine           `json:"machines"`
+	Issues             []CAPIIssue             `json:"issues"`
+}
+
+// capiVersion returns the served version of the Cluster API group, or ""
+// when it isn't installed.
+func (c *Client) capiVersion() (string, error) {
+	groups, err := c.DiscoveryClient.ServerGroups()
+	if err != nil {
+		return "", err
+	}
+	for _, g := range groups.Groups {
+		if g.Name == capiGroup {
+			return g.PreferredVersion.Version, nil
+		}
+	}
+	return "", nil
+}
+
+// GetCAPIFleet lists the Cluster API Clusters, MachineDeployments and
+// Machines in a namespace ("" for all) of a management cluster, links
+// Machines to their Nodes and reports objects stuck provisioning or
+// deleting. Conditions are the ones that aren't True.
+func (c *Client) GetCAPIFleet(namespace string) (*CAPIFleet, error) {
+	fleet := &CAPIFleet{
+		Clusters:           []CAPICluster{},
+		MachineDeployments: []CAPIMachineDeployment{},
+		Machines:           []CAPIMachine{},
+		Issues:             []CAPIIssue{},
+	}
+	version, err := c.capiVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		return fleet, nil
+	}
+	fleet.Available = true
+	fleet.APIVersion = capiGroup + "/" + version
+
+	list := func(resource string) ([]unstructured.Unstructured, error) {
+		gvr := schema.GroupVersionResource{Group: capiGroup, Version: version, Resource: resource}
+		var items []unstructured.Unstructured
+		err := c.do(OpList, func(ctx context.Context) error {
+			l, err := c.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			items = l.Items
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", resource, err)
+		}
+		return items, nil
+	}
+	clusters, err := list("clusters")
+	if err != nil {
+		return nil, err
+	}
+	deployments, err := list("machinedeployments")
+	if err != nil {
+		return nil, err
+	}
+	machines, err := list("machines")
+	if err != nil {
+		return nil, err
+	}
+	nodes := c.capiLocalNodes()
+	now := time.Now()
+
+	machineCounts := make(map[string][2]int)
+	for _, u := range machines {
+		m := CAPIMachine{
+			Namespace:         u.GetNamespace(),
+			Name:              u.GetName(),
+			Cluster:           capiClusterName(&u),
+			MachineDeployment: u.GetLabels()[capiDeploymentLabel],
+			Phase:             capiPhase(&u),
+			Created:           u.GetCreationTimestamp().UTC().Format(time.RFC3339),
+			Conditions:        capiConditions(&u),
+		}
+		m.Version, _, _ = unstructured.NestedString(u.Object, "spec", "version")
+		m.ProviderID, _, _ = unstructured.NestedString(u.Object, "spec", "providerID")
+		m.NodeName, _, _ = unstructured.NestedString(u.Object, "status", "nodeRef", "name")
+		if ready, ok := nodes[m.NodeName]; ok && m.NodeName != "" {
+			m.NodeFound, m.NodeReady = true, ready
+		}
+		fleet.Machines = append(fleet.Machines, m)
+
+		key := m.Namespace + "/" + m.Cluster
+		counts := machineCounts[key]
+		counts[0]++
+		if m.Phase == "Running" && m.NodeName != "" {
+			counts[1]++
+		}
+		machineCounts[key] = counts
+		if issue, ok := capiStuck(&u, "Machine", m.Cluster, m.Phase, now); ok {
+			fleet.Issues = append(fleet.Issues, issue)
+		}
+	}
+
+	for _, u := range clusters {
+		cl := CAPICluster{
+			Namespace:  u.GetNamespace(),
+			Name:       u.GetName(),
+			Phase:      capiPhase(&u),
+			Created:    u.GetCreationTimestamp().UTC().Format(time.RFC3339),
+			Conditions: capiConditions(&u),
+		}
+		cl.Version, _, _ = unstructured.NestedString(u.Object, "spec", "topology", "version")
+		cl.Class, _, _ = unstructured.NestedString(u.Object, "spec", "topology", "class")
+		if cl.Class == "" {
+			cl.Class, _, _ = unstructured.NestedString(u.Object, "spec", "topology", "classRef", "name")
+		}
+		cl.ControlPlaneReady = capiReady(&u, "controlPlaneReady", "ControlPlaneReady", "ControlPlaneAvailable")
+		cl.InfrastructureReady = capiReady(&u, "infrastructureReady", "InfrastructureReady", "InfrastructureReady")
+		counts := machineCounts[cl.Namespace+"/"+cl.Name]
+		cl.Machines, cl.ReadyMachines = counts[0], counts[1]
+		fleet.Clusters = append(fleet.Clusters, cl)
+		if issue, ok := capiStuck(&u, "Cluster", cl.Name, cl.Phase, now); ok {
+			fleet.Issues = append(fleet.Issues, issue)
+		}
+	}
+
+	for _, u := range deployments {
+		md := CAPIMachineDeployment{
+			Namespace:  u.GetNamespace(),
+			Name:       u.GetName(),
+			Cluster:    capiClusterName(&u),
+			Phase:      capiPhase(&u),
+			Conditions: capiConditions(&u),
+		}
+		md.Version, _, _ = unstructured.NestedString(u.Object, "spec", "template", "spec", "version")
+		md.Replicas, _, _ = unstructured.NestedInt64(u.Object, "spec", "replicas")
+		md.ReadyReplicas, _, _ = unstructured.NestedInt64(u.Object, "status", "readyReplicas")
+		md.UpdatedReplicas, _, _ = unstructured.NestedInt64(u.Object, "status", "updatedReplicas")
+		md.AvailableReplicas, _, _ = unstructured.NestedInt64(u.Object, "status", "availableReplicas")
+		fleet.MachineDeployments = append(fleet.MachineDeployments, md)
+	}
+
+	sort.Slice(fleet.Clusters, func(i, j int) bool {
+		a, b := fleet.Clusters[i], fleet.Clusters[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	sort.Slice(fleet.MachineDeployments, func(i, j int) bool {
+		a, b := fleet.MachineDeployments[i], fleet.MachineDeployments[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		return a.Name < b.Name
+	})
+	sort.Slice(fleet.Machines, func(i, j int) bool {
+		a, b := fleet.Machines[i], fleet.Machines[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		return a.Name < b.Name
+	})
+	sort.Slice(fleet.Issues, func(i, j int) bool {
+		return fleet.Issues[i].Since < fleet.Issues[j].Since
+	})
+	return fleet, nil
+}
+
+// capiLocalNodes returns the Ready state of the connected cluster's Nodes.
+// Machines of other clusters have their Nodes elsewhere, and not being
+// able to list Nodes only loses the link.
+func (c *Client) capiLocalNodes() map[string]bool {
+	ready := make(map[string]bool)
+	var nodes *corev1.NodeList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		nodes, err = c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return ready
+	}
+	for _, n := range nodes.Items {
+		ready[n.Name] = false
+		for _, cond := range n.Status.Conditions {
+			if cond.Type == corev1.NodeReady {
+				ready[n.Name] = cond.Status == corev1.ConditionTrue
+			}
+		}
+	}
+	return ready
+}
+
+func capiClusterName(u *unstructured.Unstructured) string {
+	if name, _, _ := unstructured.NestedString(u.Object, "spec", "clusterName"); name != "" {
+		return name
+	}
+	return u.GetLabels()[capiClusterLabel]
+}
+
+func capiPhase(u *unstructured.Unstructured) string {
+	phase, _, _ := unstructured.NestedString(u.Object, "status", "phase")
+	if phase == "" && u.GetDeletionTimestamp() != nil {
+		return "Deleting"
+	}
+	return phase
+}
+
+// capiReady reads a readiness flag from the v1beta1 status field or, on
+// v1beta2, from its condition.
+func capiReady(u *unstructured.Unstructured, field, v1beta1Condition, v1beta2Condition string) bool {
+	if ready, found, _ := unstructured.NestedBool(u.Object, "status", field); found {
+		return ready
+	}
+	return conditionStatus(u, v1beta1Condition) == "True" || conditionStatus(u, v1beta2Condition) == "True"
+}
+
+// capiConditions returns the conditions that aren't True, errors first.
+func capiConditions(u *unstructured.Unstructured) []CAPICondition {
+	result := []CAPICondition{}
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, raw := range conditions {
+		cond, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		c := CAPICondition{}
+		c.Type, _ = cond["type"].(string)
+		c.Status, _ = cond["status"].(string)
+		c.Severity, _ = cond["severity"].(string)
+		c.Reason, _ = cond["reason"].(string)
+		c.Message, _ = cond["message"].(string)
+		c.Since, _ = cond["lastTransitionTime"].(string)
+		if c.Status != "True" {
+			result = append(result, c)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Severity == "Error" && result[j].Severity != "Error"
+	})
+	return result
+}
+
+// capiStuck reports an object that has been in a transient phase for
+// longer than capiStuckAfter, measured from its deletion or creation.
+func capiStuck(u *unstructured.Unstructured, kind, cluster, phase string, now time.Time) (CAPIIssue, bool) {
+	if !capiTransientPhases[phase] {
+		return CAPIIssue{}, false
+	}
+	since := u.GetCreationTimestamp().Time
+	if deleted := u.GetDeletionTimestamp(); deleted != nil {
+		since = deleted.Time
+	}
+	if now.Sub(since) < capiStuckAfter {
+		return CAPIIssue{}, false
+	}
+	issue := CAPIIssue{
+		Kind:      kind,
+		Namespace: u.GetNamespace(),
+		Name:      u.GetName(),
+		Cluster:   cluster,
+		Phase:     phase,
+		Since:     since.UTC().Format(time.RFC3339),
+		Message:   fmt.Sprintf("%s has been %s for %s", kind, phase, now.Sub(since).Round(time.Minute)),
+	}
+	if conditions := capiConditions(u); len(conditions) > 0 {
+		cond := conditions[0]
+		issue.Condition = &cond
+		if cond.Reason != "" {
+			issue.Message += ": " + cond.Type + " " + cond.Reason
+		}
+		if cond.Message != "" {
+			issue.Message += " (" + cond.Message + ")"
+		}
+	}
+	return issue, true
+}
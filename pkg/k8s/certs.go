@@ -0,0 +1,124 @@
+package k8s
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	EventCertExpiry = "kubeconfig:cert-expiry"
+
+	// CertExpiryWarning is how far ahead of expiry a client certificate is
+	// reported as expiring soon.
+	CertExpiryWarning = 14 * 24 * time.Hour
+)
+
+type CertificateInfo struct {
+	Subject      string    `json:"subject"`
+	NotAfter     time.Time `json:"not_after"`
+	Expired      bool      `json:"expired"`
+	ExpiringSoon bool      `json:"expiring_soon"`
+}
+
+type CertWarning struct {
+	Context  string    `json:"context"`
+	User     string    `json:"user"`
+	NotAfter time.Time `json:"not_after"`
+	Expired  bool      `json:"expired"`
+	Message  string    `json:"message"`
+}
+
+// GetCertificateWarnings lists contexts whose client certificates are
+// expired or expire within CertExpiryWarning.
+func (c *Client) GetCertificateWarnings() ([]CertWarning, error) {
+	contexts, err := c.GetContexts()
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []CertWarning
+	for _, ctx := range contexts {
+		cert := ctx.ClientCertificate
+		if cert == nil || (!cert.Expired && !cert.ExpiringSoon) {
+			continue
+		}
+		msg := fmt.Sprintf("client certificate for context %s expires %s", ctx.Name, cert.NotAfter.Format(time.RFC3339))
+		if cert.Expired {
+			msg = fmt.Sprintf("client certificate for context %s expired %s", ctx.Name, cert.NotAfter.Format(time.RFC3339))
+		}
+		warnings = append(warnings, CertWarning{
+			Context:  ctx.Name,
+			User:     ctx.User,
+			NotAfter: cert.NotAfter,
+			Expired:  cert.Expired,
+			Message:  msg,
+		})
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].NotAfter.Before(warnings[j].NotAfter)
+	})
+	return warnings, nil
+}
+
+// EmitCertificateWarnings publishes certificate warnings to the frontend.
+func (c *Client) EmitCertificateWarnings() {
+	warnings, err := c.GetCertificateWarnings()
+	if err != nil || len(warnings) == 0 {
+		return
+	}
+	c.emit(EventCertExpiry, warnings)
+}
+
+// clientCertificateInfo parses the leaf client certificate of an auth
+// info, either inline or from a file. It returns nil when the user does
+// not authenticate with a client certificate or it can't be read.
+func clientCertificateInfo(auth *clientcmdapi.AuthInfo, now time.Time) *CertificateInfo {
+	if auth == nil {
+		return nil
+	}
+
+	data := auth.ClientCertificateData
+	if len(data) == 0 && auth.ClientCertificate != "" {
+		path := auth.ClientCertificate
+		if !filepath.IsAbs(path) && auth.LocationOfOrigin != "" {
+			path = filepath.Join(filepath.Dir(auth.LocationOfOrigin), path)
+		}
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		return &CertificateInfo{
+			Subject:      cert.Subject.CommonName,
+			NotAfter:     cert.NotAfter,
+			Expired:      now.After(cert.NotAfter),
+			ExpiringSoon: now.Add(CertExpiryWarning).After(cert.NotAfter),
+		}
+	}
+}
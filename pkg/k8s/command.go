@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// kubectlCommand builds a kubectl argv pinned to the active context and to
+// the kubeconfig files in use. The result is always executed directly,
+// never through a shell, so object names can't be interpreted as shell
+// syntax.
+func (c *Client) kubectlCommand(args ...string) ([]string, error) {
+	if c.demo {
+		return nil, errors.New("kubectl is not available for the demo context")
+	}
+	if c.contextName == InClusterContext {
+		return nil, errors.New("kubectl is not available for the in-cluster context")
+	}
+	var argv []string
+	if kubeconfig := c.kubeconfigEnv(); kubeconfig != "" {
+		// kubectl's --kubeconfig takes a single file; KUBECONFIG merges
+		// several the way the app does.
+		argv = append(argv, "env", "KUBECONFIG="+kubeconfig)
+	}
+	argv = append(argv, "kubectl")
+	if currentContext, _ := c.GetCurrentContext(); currentContext != "" {
+		argv = append(argv, "--context", currentContext)
+	}
+	return append(argv, args...), nil
+}
+
+// kubeconfigEnv returns the KUBECONFIG value naming the kubeconfig files
+// the client loaded, or "" when it uses the default loading rules.
+func (c *Client) kubeconfigEnv() string {
+	return strings.Join(c.kubeconfigPaths, string(filepath.ListSeparator))
+}
+
+// runInTerminal starts argv in a new terminal emulator window.
+func runInTerminal(argv []string) error {
+	term, args := findTerminal()
+	if term == "" {
+		return fmt.Errorf("no terminal emulator found")
+	}
+	fullArgs := append(append([]string{}, args...), argv...)
+	cmd := exec.Command(term, fullArgs...)
+	return cmd.Start()
+}
+
+// findTerminal returns the first installed terminal emulator together
+// with the arguments that make it execute the argv that follows them.
+func findTerminal() (string, []string) {
+	terminals := []struct {
+		name string
+		args []string
+	}{
+		{"alacritty", []string{"-e"}},
+		{"kitty", nil},
+		{"konsole", []string{"-e"}},
+		{"gnome-terminal", []string{"--"}},
+		{"xfce4-terminal", []string{"-x"}},
+		{"xterm", []string{"-e"}},
+	}
+	for _, t := range terminals {
+		path, err := exec.LookPath(t.name)
+		if err == nil {
+			return path, t.args
+		}
+	}
+	return "", nil
+}
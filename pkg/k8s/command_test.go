@@ -0,0 +1,47 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKubectlCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *Client
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "default kubeconfig",
+			client: &Client{contextName: "prod"},
+			want:   []string{"kubectl", "--context", "prod", "get", "pods"},
+		},
+		{
+			name:   "selected kubeconfig files",
+			client: &Client{contextName: "prod", kubeconfigPaths: []string{"/a/config", "/b/config"}},
+			want:   []string{"env", "KUBECONFIG=/a/config:/b/config", "kubectl", "--context", "prod", "get", "pods"},
+		},
+		{
+			name:    "in-cluster",
+			client:  &Client{contextName: InClusterContext},
+			wantErr: true,
+		},
+		{
+			name:    "demo",
+			client:  &Client{demo: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.client.kubectlCommand("get", "pods")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("argv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package k8s
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// ConfigValue is one key of a Secret or ConfigMap form: either literal
+// text, a local file to read, or base64 for binary values.
+type ConfigValue struct {
+	Value  string `json:"value,omitempty"`
+	File   string `json:"file,omitempty"`
+	Base64 bool   `json:"base64,omitempty"`
+}
+
+type DockerRegistryAuth struct {
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+}
+
+type TLSKeyPair struct {
+	Cert ConfigValue `json:"cert"`
+	Key  ConfigValue `json:"key"`
+}
+
+// SecretForm describes a Secret to create or update. Type defaults to
+// Opaque; docker-registry and TLS secrets take their data from Registry
+// and TLS instead of Data. ResourceVersion, when set, is the version the
+// form was loaded from, and the update fails on a conflict instead of
+// overwriting someone else's change.
+type SecretForm struct {
+	Namespace       string                 `json:"namespace"`
+	Name            string                 `json:"name"`
+	Type            string                 `json:"type"`
+	Data            map[string]ConfigValue `json:"data"`
+	Labels          map[string]string      `json:"labels,omitempty"`
+	Registry        *DockerRegistryAuth    `json:"registry,omitempty"`
+	TLS             *TLSKeyPair            `json:"tls,omitempty"`
+	ResourceVersion string                 `json:"resource_version,omitempty"`
+}
+
+type ConfigMapForm struct {
+	Namespace       string                 `json:"namespace"`
+	Name            string                 `json:"name"`
+	Data            map[string]ConfigValue `json:"data"`
+	Labels          map[string]string      `json:"labels,omitempty"`
+	ResourceVersion string                 `json:"resource_version,omitempty"`
+}
+
+type UpsertResult struct {
+	Created         bool   `json:"created"`
+	ResourceVersion string `json:"resource_version"`
+}
+
+// bytes resolves a form value to its raw content.
+func (v ConfigValue) bytes() ([]byte, error) {
+	switch {
+	case v.File != "":
+		return os.ReadFile(v.File)
+	case v.Base64:
+		return base64.StdEncoding.DecodeString(v.Value)
+	}
+	return []byte(v.Value), nil
+}
+
+func formData(values map[string]ConfigValue) (map[string][]byte, error) {
+	data := make(map[string][]byte, len(values))
+	for key, v := range values {
+		b, err := v.bytes()
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", key, err)
+		}
+		data[key] = b
+	}
+	return data, nil
+}
+
+// secretFormData builds the data of a Secret according to its type.
+func secretFormData(form SecretForm) (corev1.SecretType, map[string][]byte, error) {
+	switch corev1.SecretType(form.Type) {
+	case "", corev1.SecretTypeOpaque:
+		data, err := formData(form.Data)
+		return corev1.SecretTypeOpaque, data, err
+
+	case corev1.SecretTypeDockerConfigJson:
+		r := form.Registry
+		if r == nil || r.Server == "" || r.Username == "" {
+			return "", nil, fmt.Errorf("docker-registry secrets need a server and username")
+		}
+		auth := base64.StdEncoding.EncodeToString([]byte(r.Username + ":" + r.Password))
+		config, err := json.Marshal(map[string]interface{}{
+			"auths": map[string]interface{}{
+				r.Server: map[string]string{
+					"username": r.Username,
+					"password": r.Password,
+					"email":    r.Email,
+					"auth":     auth,
+				},
+			},
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		return corev1.SecretTypeDockerConfigJson, map[string][]byte{corev1.DockerConfigJsonKey: config}, nil
+
+	case corev1.SecretTypeTLS:
+		if form.TLS == nil {
+			return "", nil, fmt.Errorf("TLS secrets need a certificate and key")
+		}
+		cert, err := form.TLS.Cert.bytes()
+		if err != nil {
+			return "", nil, fmt.Errorf("certificate: %v", err)
+		}
+		key, err := form.TLS.Key.bytes()
+		if err != nil {
+			return "", nil, fmt.Errorf("key: %v", err)
+		}
+		if _, err := tls.X509KeyPair(cert, key); err != nil {
+			return "", nil, fmt.Errorf("invalid TLS key pair: %v", err)
+		}
+		return corev1.SecretTypeTLS, map[string][]byte{corev1.TLSCertKey: cert, corev1.TLSPrivateKeyKey: key}, nil
+	}
+	return "", nil, fmt.Errorf("unsupported secret type %q", form.Type)
+}
+
+// UpsertSecret creates a Secret or replaces the data of an existing one.
+// Labels are merged into the existing ones. The type of an existing
+// Secret can't change.
+func (c *Client) UpsertSecret(form SecretForm) (*UpsertResult, error) {
+	secretType, data, err := secretFormData(form)
+	if err != nil {
+		return nil, err
+	}
+	secrets := c.Clientset.CoreV1().Secrets(form.Namespace)
+
+	var result *UpsertResult
+	ref := ResourceRef{Version: "v1", Kind: "Secret", Plural: "secrets", Namespace: form.Namespace, Name: form.Name}
+	err = c.upsert(ref, form.ResourceVersion, func(ctx context.Context) error {
+		existing, err := secrets.Get(ctx, form.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			created, err := secrets.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: form.Name, Namespace: form.Namespace, Labels: form.Labels},
+				Type:       secretType,
+				Data:       data,
+			}, metav1.CreateOptions{})
+			if err == nil {
+				result = &UpsertResult{Created: true, ResourceVersion: created.ResourceVersion}
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if existing.Type != secretType {
+			return fmt.Errorf("secret %s is of type %s, not %s", form.Name, existing.Type, secretType)
+		}
+		if form.ResourceVersion != "" {
+			existing.ResourceVersion = form.ResourceVersion
+		}
+		existing.Labels = mergeLabels(existing.Labels, form.Labels)
+		existing.Data = data
+		existing.StringData = nil
+		updated, err := secrets.Update(ctx, existing, metav1.UpdateOptions{})
+		if err == nil {
+			result = &UpsertResult{ResourceVersion: updated.ResourceVersion}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpsertConfigMap creates a ConfigMap or replaces the data of an existing
+// one. Values that aren't valid UTF-8 are stored in binaryData.
+func (c *Client) UpsertConfigMap(form ConfigMapForm) (*UpsertResult, error) {
+	raw, err := formData(form.Data)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string)
+	binary := make(map[string][]byte)
+	for key, b := range raw {
+		if utf8.Valid(b) {
+			data[key] = string(b)
+		} else {
+			binary[key] = b
+		}
+	}
+	configMaps := c.Clientset.CoreV1().ConfigMaps(form.Namespace)
+
+	var result *UpsertResult
+	ref := ResourceRef{Version: "v1", Kind: "ConfigMap", Plural: "configmaps", Namespace: form.Namespace, Name: form.Name}
+	err = c.upsert(ref, form.ResourceVersion, func(ctx context.Context) error {
+		existing, err := configMaps.Get(ctx, form.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			created, err := configMaps.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: form.Name, Namespace: form.Namespace, Labels: form.Labels},
+				Data:       data,
+				BinaryData: binary,
+			}, metav1.CreateOptions{})
+			if err == nil {
+				result = &UpsertResult{Created: true, ResourceVersion: created.ResourceVersion}
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if form.ResourceVersion != "" {
+			existing.ResourceVersion = form.ResourceVersion
+		}
+		existing.Labels = mergeLabels(existing.Labels, form.Labels)
+		existing.Data = data
+		existing.BinaryData = binary
+		updated, err := configMaps.Update(ctx, existing, metav1.UpdateOptions{})
+		if err == nil {
+			result = &UpsertResult{ResourceVersion: updated.ResourceVersion}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// upsert runs fn as a queued mutation of ref. Without a resourceVersion from the caller
+// conflicts are retried against the latest version; with one, a conflict
+// means the object changed under the user and is reported instead.
+func (c *Client) upsert(ref ResourceRef, resourceVersion string, fn func(ctx context.Context) error) error {
+	return c.mutate(ActionUpdate, ref, fmt.Sprintf("save %s/%s", ref.Plural, ref.Name), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			if resourceVersion != "" {
+				err := fn(ctx)
+				if apierrors.IsConflict(err) {
+					return fmt.Errorf("the object was changed by someone else since it was loaded; reload and try again")
+				}
+				return err
+			}
+			return retry.RetryOnConflict(retry.DefaultRetry, func() error { return fn(ctx) })
+		})
+	})
+}
+
+func mergeLabels(existing, added map[string]string) map[string]string {
+	if len(added) == 0 {
+		return existing
+	}
+	merged := make(map[string]string, len(existing)+len(added))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	for k, v := range added {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,197 @@
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// configMapInlineLimit is the largest ConfigMap value sent inline in
+// generic resource responses; longer values are cut and fetched with
+// GetConfigMapKey.
+const configMapInlineLimit = 16 << 10
+
+// TruncatedKeysField is added to ConfigMaps in generic resource responses
+// when values were left out. It maps each shortened data key, and every
+// binaryData key, to the value's full size in bytes.
+const TruncatedKeysField = "truncatedKeys"
+
+// Formats for GetConfigMapKey.
+const (
+	ValueFormatText   = "text"
+	ValueFormatBase64 = "base64"
+	ValueFormatHex    = "hex"
+)
+
+// ConfigMapKey describes one entry of a ConfigMap's data or binaryData.
+type ConfigMapKey struct {
+	Key    string `json:"key"`
+	Size   int    `json:"size"`
+	Binary bool   `json:"binary"`
+	// ContentType is sniffed from the value, e.g. "application/x-gzip".
+	ContentType string `json:"content_type"`
+	// Preview is the start of text values; binary values are never
+	// inlined.
+	Preview   string `json:"preview,omitempty"`
+	Truncated bool   `json:"truncated"`
+}
+
+type ConfigMapData struct {
+	Namespace string         `json:"namespace"`
+	Name      string         `json:"name"`
+	Keys      []ConfigMapKey `json:"keys"`
+}
+
+// TruncateConfigMap shortens a core/v1 ConfigMap in place for generic
+// resource responses: data values over configMapInlineLimit are cut and
+// binaryData values are left out, each recorded under TruncatedKeysField.
+// Giant CA bundles or embedded archives would otherwise slow the UI
+// down. Other objects are left alone.
+func TruncateConfigMap(obj map[string]interface{}) {
+	if obj["kind"] != "ConfigMap" || obj["apiVersion"] != "v1" {
+		return
+	}
+	truncated := make(map[string]interface{})
+	if data, ok := obj["data"].(map[string]interface{}); ok {
+		for key, v := range data {
+			s, ok := v.(string)
+			if !ok || len(s) <= configMapInlineLimit {
+				continue
+			}
+			data[key] = cutUTF8(s, configMapInlineLimit)
+			truncated[key] = int64(len(s))
+		}
+	}
+	if binary, ok := obj["binaryData"].(map[string]interface{}); ok {
+		for key, v := range binary {
+			s, _ := v.(string)
+			binary[key] = ""
+			truncated[key] = int64(base64.StdEncoding.DecodedLen(len(s)))
+		}
+	}
+	if len(truncated) > 0 {
+		obj[TruncatedKeysField] = truncated
+	}
+	// The last-applied annotation holds the values as well.
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if s, ok := annotations[corev1.LastAppliedConfigAnnotation].(string); ok && len(s) > configMapInlineLimit {
+				delete(annotations, corev1.LastAppliedConfigAnnotation)
+			}
+		}
+	}
+}
+
+// cutUTF8 shortens s to at most n bytes without splitting a rune.
+func cutUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// GetConfigMapData lists the keys of a ConfigMap's data and binaryData
+// with their size and sniffed content type, previewing text values up to
+// configMapInlineLimit.
+func (c *Client) GetConfigMapData(namespace, name string) (*ConfigMapData, error) {
+	cm, err := c.getConfigMap(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	result := &ConfigMapData{Namespace: namespace, Name: name, Keys: []ConfigMapKey{}}
+	for key, value := range cm.Data {
+		entry := ConfigMapKey{
+			Key:         key,
+			Size:        len(value),
+			Binary:      isBinary([]byte(value)),
+			ContentType: http.DetectContentType([]byte(value)),
+			Truncated:   len(value) > configMapInlineLimit,
+		}
+		if !entry.Binary {
+			entry.Preview = cutUTF8(value, configMapInlineLimit)
+		}
+		result.Keys = append(result.Keys, entry)
+	}
+	for key, value := range cm.BinaryData {
+		result.Keys = append(result.Keys, ConfigMapKey{
+			Key:         key,
+			Size:        len(value),
+			Binary:      true,
+			ContentType: http.DetectContentType(value),
+			Truncated:   true,
+		})
+	}
+	sort.Slice(result.Keys, func(i, j int) bool { return result.Keys[i].Key < result.Keys[j].Key })
+	return result, nil
+}
+
+// GetConfigMapKey returns one full value of a ConfigMap's data or
+// binaryData. format is ValueFormatText (the default; refused for binary
+// values), ValueFormatBase64 or ValueFormatHex, the latter as a hex dump
+// with offsets.
+func (c *Client) GetConfigMapKey(namespace, name, key, format string) (string, error) {
+	value, err := c.configMapValue(namespace, name, key)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "", ValueFormatText:
+		if isBinary(value) {
+			return "", fmt.Errorf("key %q holds binary data; fetch it as %s or %s", key, ValueFormatBase64, ValueFormatHex)
+		}
+		return string(value), nil
+	case ValueFormatBase64:
+		return base64.StdEncoding.EncodeToString(value), nil
+	case ValueFormatHex:
+		return hex.Dump(value), nil
+	}
+	return "", fmt.Errorf("unknown format %q", format)
+}
+
+// SaveConfigMapKey writes one value of a ConfigMap to a local file, for
+// values too large or binary to show.
+func (c *Client) SaveConfigMapKey(namespace, name, key, localPath string) error {
+	value, err := c.configMapValue(namespace, name, key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(localPath, value, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", localPath, err)
+	}
+	return nil
+}
+
+func (c *Client) configMapValue(namespace, name, key string) ([]byte, error) {
+	cm, err := c.getConfigMap(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := cm.Data[key]; ok {
+		return []byte(value), nil
+	}
+	if value, ok := cm.BinaryData[key]; ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("configmap %s/%s has no key %q", namespace, name, key)
+}
+
+func (c *Client) getConfigMap(namespace, name string) (*corev1.ConfigMap, error) {
+	var cm *corev1.ConfigMap
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		cm, err = c.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	return cm, err
+}
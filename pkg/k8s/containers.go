@@ -0,0 +1,256 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Container types.
+const (
+	ContainerInit = "init"
+	// ContainerSidecar is a native sidecar: an init container with
+	// restartPolicy Always, which keeps running alongside the app
+	// containers.
+	ContainerSidecar   = "sidecar"
+	ContainerApp       = "app"
+	ContainerEphemeral = "ephemeral"
+)
+
+// Container states.
+const (
+	StateWaiting    = "waiting"
+	StateRunning    = "running"
+	StateTerminated = "terminated"
+)
+
+type PodContainer struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Image string `json:"image"`
+	// State is waiting, running or terminated; Reason and Message detail
+	// waiting and terminated states.
+	State        string `json:"state"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	ExitCode     *int32 `json:"exit_code,omitempty"`
+	StartedAt    string `json:"started_at,omitempty"`
+	FinishedAt   string `json:"finished_at,omitempty"`
+	Ready        bool   `json:"ready"`
+	RestartCount int32  `json:"restart_count"`
+	// TargetContainer is the container an ephemeral container was attached
+	// to, sharing its process namespace.
+	TargetContainer string `json:"target_container,omitempty"`
+	// HasLogs is set once the container ran; HasPreviousLogs when logs of
+	// a previous instance can be requested with Previous.
+	HasLogs         bool `json:"has_logs"`
+	HasPreviousLogs bool `json:"has_previous_logs"`
+
+	// ImageID is the digest the running image resolved to.
+	ImageID         string                `json:"image_id,omitempty"`
+	LastTermination *ContainerTermination `json:"last_termination,omitempty"`
+	Requests        map[string]string     `json:"requests,omitempty"`
+	Limits          map[string]string     `json:"limits,omitempty"`
+	LivenessProbe   *ProbeInfo            `json:"liveness_probe,omitempty"`
+	ReadinessProbe  *ProbeInfo            `json:"readiness_probe,omitempty"`
+	StartupProbe    *ProbeInfo            `json:"startup_probe,omitempty"`
+}
+
+// ContainerTermination is how the previous instance of a restarted
+// container ended.
+type ContainerTermination struct {
+	Reason     string `json:"reason"`
+	Message    string `json:"message,omitempty"`
+	ExitCode   int32  `json:"exit_code"`
+	Signal     int32  `json:"signal,omitempty"`
+	StartedAt  string `json:"started_at,omitempty"`
+	FinishedAt string `json:"finished_at,omitempty"`
+}
+
+// ProbeInfo summarises a probe. Handler is http, tcp, grpc or exec and
+// Target what it checks, e.g. "GET http://:8080/healthz".
+type ProbeInfo struct {
+	Handler             string `json:"handler"`
+	Target              string `json:"target"`
+	InitialDelaySeconds int32  `json:"initial_delay_seconds"`
+	PeriodSeconds       int32  `json:"period_seconds"`
+	TimeoutSeconds      int32  `json:"timeout_seconds"`
+	SuccessThreshold    int32  `json:"success_threshold"`
+	FailureThreshold    int32  `json:"failure_threshold"`
+}
+
+// PodContainers lists a pod's containers in lifecycle order: init
+// containers and sidecars in spec order, then app containers, then
+// ephemeral containers.
+type PodContainers struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	// InitDone and InitTotal count the init containers (not sidecars)
+	// that completed, like the Init:1/3 status of kubectl.
+	InitDone   int            `json:"init_done"`
+	InitTotal  int            `json:"init_total"`
+	Containers []PodContainer `json:"containers"`
+}
+
+// GetPodContainers returns the state, restarts, last termination, image
+// digest, probes and resources of every container of a pod.
+func (c *Client) GetPodContainers(namespace, name string) (*PodContainers, error) {
+	var pod *corev1.Pod
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		pod, err = c.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return podContainers(pod), nil
+}
+
+func podContainers(pod *corev1.Pod) *PodContainers {
+	result := &PodContainers{
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		Phase:      string(pod.Status.Phase),
+		Containers: []PodContainer{},
+	}
+
+	statuses := make(map[string]corev1.ContainerStatus)
+	for _, list := range [][]corev1.ContainerStatus{
+		pod.Status.InitContainerStatuses,
+		pod.Status.ContainerStatuses,
+		pod.Status.EphemeralContainerStatuses,
+	} {
+		for _, s := range list {
+			statuses[s.Name] = s
+		}
+	}
+
+	for _, ctr := range pod.Spec.InitContainers {
+		entry := containerEntry(ctr, ContainerInit, statuses)
+		if ctr.RestartPolicy != nil && *ctr.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			entry.Type = ContainerSidecar
+		} else {
+			result.InitTotal++
+			if entry.State == StateTerminated && entry.ExitCode != nil && *entry.ExitCode == 0 {
+				result.InitDone++
+			}
+		}
+		result.Containers = append(result.Containers, entry)
+	}
+	for _, ctr := range pod.Spec.Containers {
+		result.Containers = append(result.Containers, containerEntry(ctr, ContainerApp, statuses))
+	}
+	for _, ctr := range pod.Spec.EphemeralContainers {
+		entry := containerEntry(corev1.Container(ctr.EphemeralContainerCommon), ContainerEphemeral, statuses)
+		entry.TargetContainer = ctr.TargetContainerName
+		result.Containers = append(result.Containers, entry)
+	}
+	return result
+}
+
+func containerEntry(ctr corev1.Container, containerType string, statuses map[string]corev1.ContainerStatus) PodContainer {
+	entry := PodContainer{
+		Name:           ctr.Name,
+		Type:           containerType,
+		Image:          ctr.Image,
+		State:          StateWaiting,
+		Requests:       resourceStrings(ctr.Resources.Requests),
+		Limits:         resourceStrings(ctr.Resources.Limits),
+		LivenessProbe:  probeInfo(ctr.LivenessProbe),
+		ReadinessProbe: probeInfo(ctr.ReadinessProbe),
+		StartupProbe:   probeInfo(ctr.StartupProbe),
+	}
+	s, ok := statuses[ctr.Name]
+	if !ok {
+		return entry
+	}
+	entry.Ready = s.Ready
+	entry.RestartCount = s.RestartCount
+	entry.HasPreviousLogs = s.RestartCount > 0
+	entry.ImageID = s.ImageID
+	if t := s.LastTerminationState.Terminated; t != nil {
+		entry.LastTermination = &ContainerTermination{
+			Reason:     t.Reason,
+			Message:    t.Message,
+			ExitCode:   t.ExitCode,
+			Signal:     t.Signal,
+			StartedAt:  t.StartedAt.Format(time.RFC3339),
+			FinishedAt: t.FinishedAt.Format(time.RFC3339),
+		}
+	}
+
+	switch {
+	case s.State.Running != nil:
+		entry.State = StateRunning
+		entry.StartedAt = s.State.Running.StartedAt.Format(time.RFC3339)
+		entry.HasLogs = true
+	case s.State.Terminated != nil:
+		t := s.State.Terminated
+		entry.State = StateTerminated
+		entry.Reason = t.Reason
+		entry.Message = t.Message
+		entry.ExitCode = &t.ExitCode
+		entry.StartedAt = t.StartedAt.Format(time.RFC3339)
+		entry.FinishedAt = t.FinishedAt.Format(time.RFC3339)
+		entry.HasLogs = true
+	case s.State.Waiting != nil:
+		entry.Reason = s.State.Waiting.Reason
+		entry.Message = s.State.Waiting.Message
+		// A crash-looping container waits between runs; its last run
+		// still has logs.
+		entry.HasLogs = s.LastTerminationState.Terminated != nil
+	}
+	return entry
+}
+
+func resourceStrings(list corev1.ResourceList) map[string]string {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(list))
+	for name, q := range list {
+		out[string(name)] = q.String()
+	}
+	return out
+}
+
+func probeInfo(p *corev1.Probe) *ProbeInfo {
+	if p == nil {
+		return nil
+	}
+	info := &ProbeInfo{
+		InitialDelaySeconds: p.InitialDelaySeconds,
+		PeriodSeconds:       p.PeriodSeconds,
+		TimeoutSeconds:      p.TimeoutSeconds,
+		SuccessThreshold:    p.SuccessThreshold,
+		FailureThreshold:    p.FailureThreshold,
+	}
+	switch h := p.ProbeHandler; {
+	case h.HTTPGet != nil:
+		scheme := strings.ToLower(string(h.HTTPGet.Scheme))
+		if scheme == "" {
+			scheme = "http"
+		}
+		info.Handler = "http"
+		info.Target = fmt.Sprintf("GET %s://%s:%s%s", scheme, h.HTTPGet.Host, h.HTTPGet.Port.String(), h.HTTPGet.Path)
+	case h.TCPSocket != nil:
+		info.Handler = "tcp"
+		info.Target = fmt.Sprintf("%s:%s", h.TCPSocket.Host, h.TCPSocket.Port.String())
+	case h.GRPC != nil:
+		info.Handler = "grpc"
+		info.Target = fmt.Sprintf(":%d", h.GRPC.Port)
+		if h.GRPC.Service != nil && *h.GRPC.Service != "" {
+			info.Target += " " + *h.GRPC.Service
+		}
+	case h.Exec != nil:
+		info.Handler = "exec"
+		info.Target = strings.Join(h.Exec.Command, " ")
+	}
+	return info
+}
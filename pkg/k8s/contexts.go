@@ -0,0 +1,63 @@
+package k8s
+
+import "sort"
+
+type ContextGroup struct {
+	Value    string        `json:"value"`
+	Contexts []KubeContext `json:"contexts"`
+}
+
+// ApplyContextTags attaches user-defined tags (keyed by context name) to
+// the given contexts.
+func ApplyContextTags(contexts []KubeContext, tags map[string]map[string]string) []KubeContext {
+	for i := range contexts {
+		if t, ok := tags[contexts[i].Name]; ok {
+			contexts[i].Tags = t
+		}
+	}
+	return contexts
+}
+
+// FilterContextsByTags keeps the contexts whose tags match every key/value
+// in selector. An empty value matches any context that has the key.
+func FilterContextsByTags(contexts []KubeContext, selector map[string]string) []KubeContext {
+	var result []KubeContext
+	for _, ctx := range contexts {
+		if matchesTags(ctx.Tags, selector) {
+			result = append(result, ctx)
+		}
+	}
+	return result
+}
+
+// GroupContextsByTag buckets contexts by the value of the given tag key.
+// Contexts without the tag end up in a group with an empty value, sorted last.
+func GroupContextsByTag(contexts []KubeContext, key string) []ContextGroup {
+	groups := make(map[string][]KubeContext)
+	for _, ctx := range contexts {
+		value := ctx.Tags[key]
+		groups[value] = append(groups[value], ctx)
+	}
+
+	var result []ContextGroup
+	for value, ctxs := range groups {
+		result = append(result, ContextGroup{Value: value, Contexts: ctxs})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].Value == "") != (result[j].Value == "") {
+			return result[j].Value == ""
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
+
+func matchesTags(tags, selector map[string]string) bool {
+	for k, v := range selector {
+		actual, ok := tags[k]
+		if !ok || (v != "" && actual != v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+package k8s
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EventPodCopy is emitted with CopyProgress while a copy runs and once
+	// more with Done set when it ends.
+	EventPodCopy = "pod:copy"
+
+	copyProgressInterval = 250 * time.Millisecond
+)
+
+type CopyOptions struct {
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"podName"`
+	ContainerName string `json:"containerName"`
+	// LocalPath is the file or directory on this machine: the source for
+	// CopyToPod and the destination for CopyFromPod.
+	LocalPath string `json:"localPath"`
+	// RemotePath is the file or directory in the container.
+	RemotePath string `json:"remotePath"`
+}
+
+type CopyProgress struct {
+	CopyID string `json:"copy_id"`
+	// Direction is "to" or "from" the pod.
+	Direction string `json:"direction"`
+	Bytes     int64  `json:"bytes"`
+	// Total is 0 when the size isn't known up front.
+	Total int64  `json:"total"`
+	Done  bool   `json:"done"`
+	Error string `json:"error,omitempty"`
+}
+
+// CopyToPod copies a local file or directory into a container over an
+// exec'd tar, like `kubectl cp`, creating missing parent directories. It
+// needs tar in the container. The copy runs in the background and reports
+// EventPodCopy progress; the returned ID can be passed to
+// StopSubscription to cancel it.
+func (c *Client) CopyToPod(opts CopyOptions) (string, error) {
+	if err := c.requireLinuxPod(opts.Namespace, opts.PodName); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(opts.LocalPath)
+	if err != nil {
+		return "", err
+	}
+	var total int64
+	if info.IsDir() {
+		err = filepath.Walk(opts.LocalPath, func(_ string, fi os.FileInfo, err error) error {
+			if err == nil && fi.Mode().IsRegular() {
+				total += fi.Size()
+			}
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	} else {
+		total = info.Size()
+	}
+
+	remote := cleanContainerPath(opts.RemotePath)
+	if remote == "/" {
+		return "", fmt.Errorf("copying over / is not allowed; name a destination path")
+	}
+	dir, base := path.Split(remote)
+
+	return c.runCopy("to", total, func(ctx context.Context, counter *atomic.Int64) error {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(writeTar(pw, opts.LocalPath, base, counter))
+		}()
+		stderr, err := c.execStream(ctx, opts.Namespace, opts.PodName, opts.ContainerName,
+			[]string{"sh", "-c", `mkdir -p "$1" && tar -xmf - -C "$1"`, "sh", dir}, pr, io.Discard)
+		pr.Close()
+		if err != nil {
+			return execError(err, stderr)
+		}
+		return nil
+	})
+}
+
+// CopyFromPod copies a file or directory out of a container to
+// opts.LocalPath, like `kubectl cp`. Entries that would land outside
+// LocalPath, and links, are skipped. Progress is reported like CopyToPod.
+func (c *Client) CopyFromPod(opts CopyOptions) (string, error) {
+	entry, err := c.StatContainerFile(opts.Namespace, opts.PodName, opts.ContainerName, opts.RemotePath)
+	if err != nil {
+		return "", err
+	}
+	total := entry.Size
+	if entry.IsDir {
+		total = c.remoteDirSize(opts, entry.Path)
+	}
+	dir, base := path.Split(entry.Path)
+	if base == "" {
+		return "", fmt.Errorf("copying all of / is not supported; pick a directory")
+	}
+
+	return c.runCopy("from", total, func(ctx context.Context, counter *atomic.Int64) error {
+		pr, pw := io.Pipe()
+		extracted := make(chan error, 1)
+		go func() {
+			err := extractTar(pr, base, opts.LocalPath, counter)
+			// Drain the rest so tar in the container isn't blocked.
+			_, _ = io.Copy(io.Discard, pr)
+			extracted <- err
+		}()
+		stderr, err := c.execStream(ctx, opts.Namespace, opts.PodName, opts.ContainerName,
+			[]string{"tar", "-cf", "-", "-C", dir, base}, nil, pw)
+		pw.Close()
+		extractErr := <-extracted
+		if err != nil {
+			return execError(err, stderr)
+		}
+		return extractErr
+	})
+}
+
+// runCopy runs fn in the background, emitting progress from the byte
+// counter it updates.
+func (c *Client) runCopy(direction string, total int64, fn func(ctx context.Context, counter *atomic.Int64) error) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	id := c.subs.add("copy", cancel)
+	go func() {
+		defer c.subs.remove(id)
+		defer cancel()
+
+		var counter atomic.Int64
+		done := make(chan error, 1)
+		go func() { done <- fn(ctx, &counter) }()
+
+		ticker := time.NewTicker(copyProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.emit(EventPodCopy, CopyProgress{CopyID: id, Direction: direction, Bytes: counter.Load(), Total: total})
+			case err := <-done:
+				final := CopyProgress{CopyID: id, Direction: direction, Bytes: counter.Load(), Total: total, Done: true}
+				if err != nil {
+					final.Error = err.Error()
+					if ctx.Err() != nil {
+						final.Error = "copy cancelled"
+					}
+				}
+				c.emit(EventPodCopy, final)
+				return
+			}
+		}
+	}()
+	return id, nil
+}
+
+// remoteDirSize returns the size of a directory in the container, or 0 if
+// du isn't available.
+func (c *Client) remoteDirSize(opts CopyOptions, dir string) int64 {
+	ctx, cancel := c.opContext(OpExec)
+	defer cancel()
+	stdout, _, err := c.execCapture(ctx, opts.Namespace, opts.PodName, opts.ContainerName, []string{"du", "-sk", dir}, nil)
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(stdout))
+	if len(fields) == 0 {
+		return 0
+	}
+	kb, _ := strconv.ParseInt(fields[0], 10, 64)
+	return kb * 1024
+}
+
+// writeTar archives src under the name base, counting file bytes.
+func writeTar(w io.Writer, src, base string, counter *atomic.Int64) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(src, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		name := path.Join(base, filepath.ToSlash(rel))
+
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, &countingReader{r: f, n: counter})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// extractTar writes the entries of an archive made from base into dest,
+// so base itself becomes dest.
+func extractTar(r io.Reader, base, dest string, counter *atomic.Int64) error {
+	dest = filepath.Clean(dest)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		rel, ok := strings.CutPrefix(name, base)
+		if !ok || (rel != "" && !strings.HasPrefix(rel, "/")) {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+		if target != dest && !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, &countingReader{r: tr, n: counter})
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExtractTar(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	entries := []struct {
+		name     string
+		typeflag byte
+		body     string
+	}{
+		{"data", tar.TypeDir, ""},
+		{"data/app.log", tar.TypeReg, "hello"},
+		{"data/nested/", tar.TypeDir, ""},
+		{"data/nested/state.json", tar.TypeReg, "{}"},
+		{"database/other.txt", tar.TypeReg, "sibling sharing the prefix"},
+		{"data/../../escape.txt", tar.TypeReg, "outside"},
+		{"etc/passwd", tar.TypeReg, "outside base"},
+		{"data/link", tar.TypeSymlink, ""},
+	}
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0o644, Size: int64(len(e.body))}
+		if e.typeflag == tar.TypeSymlink {
+			hdr.Linkname = "/etc/passwd"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+
+	root := t.TempDir()
+	dest := filepath.Join(root, "out")
+	var counter atomic.Int64
+	if err := extractTar(&buf, "data", dest, &counter); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"app.log":           "hello",
+		"nested/state.json": "{}",
+	}
+	for name, body := range want {
+		got, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(got) != body {
+			t.Errorf("%s = %q, want %q", name, got, body)
+		}
+	}
+	for _, name := range []string{"../escape.txt", "escape.txt", "other.txt", "passwd", "link"} {
+		if _, err := os.Lstat(filepath.Join(dest, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was extracted", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("an entry escaped the destination")
+	}
+	if got := counter.Load(); got != int64(len("hello")+len("{}")) {
+		t.Errorf("counted %d bytes, want %d", got, len("hello")+len("{}"))
+	}
+}
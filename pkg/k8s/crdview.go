@@ -0,0 +1,155 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// ViewColumn is a list column filled from a JSONPath such as
+// "{.status.phase}" or ".status.phase".
+type ViewColumn struct {
+	Name     string `json:"name"`
+	JSONPath string `json:"json_path"`
+}
+
+// HealthRule is one check an object must pass to be healthy. A condition
+// rule requires status.conditions[type=ConditionType] to have
+// ConditionStatus ("True" by default); a field rule requires the value at
+// JSONPath to be one of Values.
+type HealthRule struct {
+	ConditionType   string   `json:"condition_type,omitempty"`
+	ConditionStatus string   `json:"condition_status,omitempty"`
+	JSONPath        string   `json:"json_path,omitempty"`
+	Values          []string `json:"values,omitempty"`
+}
+
+// ResourceView tells how to list a custom resource whose CRD has no (or
+// unhelpful) printer columns.
+type ResourceView struct {
+	Columns []ViewColumn `json:"columns"`
+	Health  []HealthRule `json:"health"`
+}
+
+type ResourceRow struct {
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Values    []string `json:"values"`
+	// Healthy is nil when the view has no health rules.
+	Healthy *bool `json:"healthy,omitempty"`
+	// Problems lists the health rules the object fails.
+	Problems []string    `json:"problems,omitempty"`
+	Object   interface{} `json:"object"`
+}
+
+// ApplyResourceView evaluates a view's columns and health rules for each
+// listed object.
+func ApplyResourceView(items []interface{}, view ResourceView) ([]ResourceRow, error) {
+	columns := make([]*jsonpath.JSONPath, len(view.Columns))
+	for i, col := range view.Columns {
+		jp, err := parseViewPath(col.JSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", col.Name, err)
+		}
+		columns[i] = jp
+	}
+	fields := make([]*jsonpath.JSONPath, len(view.Health))
+	for i, rule := range view.Health {
+		if rule.JSONPath == "" {
+			continue
+		}
+		jp, err := parseViewPath(rule.JSONPath)
+		if err != nil {
+			return nil, fmt.Errorf("health rule %s: %v", rule.JSONPath, err)
+		}
+		fields[i] = jp
+	}
+
+	rows := make([]ResourceRow, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		u := unstructured.Unstructured{Object: obj}
+		row := ResourceRow{Namespace: u.GetNamespace(), Name: u.GetName(), Values: make([]string, len(columns)), Object: obj}
+		for i, jp := range columns {
+			row.Values[i] = evalViewPath(jp, obj)
+		}
+
+		if len(view.Health) > 0 {
+			healthy := true
+			for i, rule := range view.Health {
+				if problem := checkHealthRule(rule, fields[i], obj); problem != "" {
+					healthy = false
+					row.Problems = append(row.Problems, problem)
+				}
+			}
+			row.Healthy = &healthy
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func checkHealthRule(rule HealthRule, field *jsonpath.JSONPath, obj map[string]interface{}) string {
+	if rule.ConditionType != "" {
+		want := rule.ConditionStatus
+		if want == "" {
+			want = "True"
+		}
+		conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if !ok || cond["type"] != rule.ConditionType {
+				continue
+			}
+			if cond["status"] == want {
+				return ""
+			}
+			msg := fmt.Sprintf("%s is %v", rule.ConditionType, cond["status"])
+			if m, ok := cond["message"].(string); ok && m != "" {
+				msg += ": " + m
+			}
+			return msg
+		}
+		return rule.ConditionType + " condition missing"
+	}
+	if field != nil {
+		value := evalViewPath(field, obj)
+		for _, v := range rule.Values {
+			if value == v {
+				return ""
+			}
+		}
+		if value == "" {
+			return rule.JSONPath + " is not set"
+		}
+		return fmt.Sprintf("%s is %s", rule.JSONPath, value)
+	}
+	return ""
+}
+
+// parseViewPath accepts kubectl-style "{.status.phase}" as well as the
+// bare ".status.phase" used by CRD printer columns.
+func parseViewPath(path string) (*jsonpath.JSONPath, error) {
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	jp := jsonpath.New("view").AllowMissingKeys(true)
+	if err := jp.Parse(path); err != nil {
+		return nil, err
+	}
+	return jp, nil
+}
+
+func evalViewPath(jp *jsonpath.JSONPath, obj map[string]interface{}) string {
+	var buf bytes.Buffer
+	if err := jp.Execute(&buf, obj); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,88 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/ptr"
+)
+
+// instantiateAnnotation marks Jobs created by hand from a CronJob, as
+// kubectl create job --from does.
+const instantiateAnnotation = "cronjob.kubernetes.io/instantiate"
+
+// TriggerCronJob runs a CronJob now by creating a Job from its
+// jobTemplate, like `kubectl create job --from=cronjob/<name>`. The Job is
+// owned by the CronJob, so it shows up in its history and is cleaned up
+// with it. It returns the name of the new Job.
+func (c *Client) TriggerCronJob(namespace, name string) (string, error) {
+	var cronJob *batchv1.CronJob
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		cronJob, err = c.Clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Job names are limited to 63 characters so the pod label derived
+	// from them stays valid.
+	prefix := name
+	if len(prefix) > 50 {
+		prefix = prefix[:50]
+	}
+	annotations := map[string]string{instantiateAnnotation: "manual"}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        prefix + "-manual-" + utilrand.String(5),
+			Namespace:   namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch/v1",
+				Kind:       "CronJob",
+				Name:       cronJob.Name,
+				UID:        cronJob.UID,
+				Controller: ptr.To(true),
+			}},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+
+	ref := ResourceRef{Group: "batch", Version: "v1", Kind: "Job", Plural: "jobs", Namespace: namespace, Name: job.Name}
+	err = c.mutate(ActionCreate, ref, "trigger cronjobs/"+name, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+			return err
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create job from cronjob %s: %v", name, err)
+	}
+	return job.Name, nil
+}
+
+// SetCronJobSuspend suspends or resumes a CronJob's schedule. Running Jobs
+// are not affected.
+func (c *Client) SetCronJobSuspend(namespace, name string, suspended bool) error {
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspended)
+	ref := ResourceRef{Group: "batch", Version: "v1", Kind: "CronJob", Plural: "cronjobs", Namespace: namespace, Name: name}
+	description := "resume cronjobs/" + name
+	if suspended {
+		description = "suspend cronjobs/" + name
+	}
+	return c.mutate(ActionPatch, ref, description, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.BatchV1().CronJobs(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+			return err
+		})
+	})
+}
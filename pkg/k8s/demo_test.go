@@ -0,0 +1,166 @@
+package k8s
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func itemNames(items []interface{}) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, (&unstructured.Unstructured{Object: item.(map[string]interface{})}).GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDemoClientListAndGet(t *testing.T) {
+	c := NewDemoClient()
+
+	tests := []struct {
+		name      string
+		version   string
+		group     string
+		kind      string
+		plural    string
+		namespace string
+		selector  string
+		// want holds name prefixes, as pod names carry generated suffixes.
+		want []string
+	}{
+		{name: "namespaced", group: "apps", version: "v1", kind: "Deployment", plural: "deployments", namespace: "shop", want: []string{"api", "frontend"}},
+		{name: "all namespaces", group: "apps", version: "v1", kind: "Deployment", plural: "deployments", want: []string{"api", "frontend", "grafana"}},
+		{name: "label selector", version: "v1", kind: "Pod", plural: "pods", namespace: "shop", selector: "app.kubernetes.io/name=api", want: []string{"api-", "api-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := c.ListResources(tt.group, tt.version, tt.kind, tt.plural, tt.namespace, tt.selector, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := itemNames(items)
+			if len(got) != len(tt.want) {
+				t.Fatalf("listed %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Fatalf("listed %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	obj, err := c.GetResource("apps", "v1", "Deployment", "deployments", "shop", "frontend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas, _, _ := unstructured.NestedInt64(obj.(map[string]interface{}), "spec", "replicas")
+	if replicas != 3 {
+		t.Errorf("frontend has %d replicas, want 3", replicas)
+	}
+	if _, err := c.GetResource("apps", "v1", "Deployment", "deployments", "shop", "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("getting a missing deployment: err = %v, want NotFound", err)
+	}
+}
+
+func TestDemoClientScaleAndDelete(t *testing.T) {
+	c := NewDemoClient()
+
+	if err := c.ScaleResource("apps", "v1", "deployments", "shop", "api", 5); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := c.GetResource("apps", "v1", "Deployment", "deployments", "shop", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(obj.(map[string]interface{}), "spec", "replicas"); replicas != 5 {
+		t.Errorf("api has %d replicas after scaling, want 5", replicas)
+	}
+	if err := c.ScaleResource("apps", "v1", "deployments", "shop", "api", -1); err == nil {
+		t.Error("scaling to -1 replicas succeeded")
+	}
+
+	if err := c.DeleteResource("apps", "v1", "Deployment", "deployments", "shop", "api"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetResource("apps", "v1", "Deployment", "deployments", "shop", "api"); !apierrors.IsNotFound(err) {
+		t.Errorf("deleted deployment still readable: err = %v", err)
+	}
+	if err := c.DeleteResource("apps", "v1", "Deployment", "deployments", "shop", "api"); err == nil {
+		t.Error("deleting a deployment twice succeeded")
+	}
+
+	ops := c.GetOperations()
+	if len(ops) != 3 {
+		t.Fatalf("recorded %d operations, want 3: %+v", len(ops), ops)
+	}
+	if ops[0].Action != ActionDelete || ops[0].Error == "" {
+		t.Errorf("newest operation = %+v, want the failed delete", ops[0])
+	}
+	if ops[2].Action != ActionScale || !ops[2].Undoable {
+		t.Errorf("oldest operation = %+v, want an undoable scale", ops[2])
+	}
+}
+
+func TestDemoClientWatch(t *testing.T) {
+	c := NewDemoClient()
+	events := make(chan interface{}, 16)
+	c.Emit = func(name string, data interface{}) {
+		if name == EventResourcesSync || name == EventResourcesChange {
+			events <- data
+		}
+	}
+
+	id, err := c.WatchResources(WatchParams{Group: "apps", Version: "v1", Plural: "deployments", Namespace: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.StopSubscription(id)
+
+	sync, ok := (<-events).(ResourceSync)
+	if !ok || sync.SubscriptionID != id {
+		t.Fatalf("first event = %+v, want the sync of %s", sync, id)
+	}
+	if got := itemNames(sync.Items); len(got) != 2 {
+		t.Fatalf("sync listed %v, want api and frontend", got)
+	}
+
+	// The watch is started right after the synchronous list; give it a
+	// moment to be registered with the fake tracker.
+	time.Sleep(50 * time.Millisecond)
+	if err := c.ScaleResource("apps", "v1", "deployments", "shop", "frontend", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteResource("apps", "v1", "Deployment", "deployments", "shop", "api"); err != nil {
+		t.Fatal(err)
+	}
+
+	var changes []ResourceChange
+	timeout := time.After(5 * time.Second)
+	for len(changes) < 2 {
+		select {
+		case ev := <-events:
+			if batch, ok := ev.(ResourceChanges); ok && batch.SubscriptionID == id {
+				changes = append(changes, batch.Changes...)
+			}
+		case <-timeout:
+			t.Fatalf("got changes %+v, want a modification and a deletion", changes)
+		}
+	}
+	if changes[0].Type != "MODIFIED" || changes[1].Type != "DELETED" {
+		t.Errorf("change types = %s, %s, want MODIFIED, DELETED", changes[0].Type, changes[1].Type)
+	}
+
+	snapshot, err := c.GetWatchSnapshot(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := itemNames(snapshot.Items); len(got) != 1 || got[0] != "frontend" {
+		t.Errorf("snapshot lists %v, want [frontend]", got)
+	}
+}
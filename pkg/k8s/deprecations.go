@@ -0,0 +1,321 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DeprecatedAPI is an API version of a kind that is deprecated or has
+// been removed. Versions are Kubernetes minor releases, e.g. "1.25"; CRD
+// versions have no release and leave them empty.
+type DeprecatedAPI struct {
+	APIVersion   string `json:"api_version"`
+	Kind         string `json:"kind"`
+	DeprecatedIn string `json:"deprecated_in,omitempty"`
+	RemovedIn    string `json:"removed_in,omitempty"`
+	// Replacement is the apiVersion to migrate to; empty when the API has
+	// no successor, as with PodSecurityPolicy.
+	Replacement string `json:"replacement,omitempty"`
+	// Warning is the deprecation warning of a CRD version.
+	Warning string `json:"warning,omitempty"`
+}
+
+// deprecatedAPIs lists the built-in API versions removed since 1.16.
+var deprecatedAPIs = []DeprecatedAPI{
+	{"extensions/v1beta1", "Deployment", "1.9", "1.16", "apps/v1", ""},
+	{"extensions/v1beta1", "DaemonSet", "1.9", "1.16", "apps/v1", ""},
+	{"extensions/v1beta1", "ReplicaSet", "1.9", "1.16", "apps/v1", ""},
+	{"extensions/v1beta1", "NetworkPolicy", "1.9", "1.16", "networking.k8s.io/v1", ""},
+	{"extensions/v1beta1", "PodSecurityPolicy", "1.11", "1.16", "policy/v1beta1", ""},
+	{"extensions/v1beta1", "Ingress", "1.14", "1.22", "networking.k8s.io/v1", ""},
+	{"apps/v1beta1", "Deployment", "1.9", "1.16", "apps/v1", ""},
+	{"apps/v1beta1", "StatefulSet", "1.9", "1.16", "apps/v1", ""},
+	{"apps/v1beta2", "Deployment", "1.9", "1.16", "apps/v1", ""},
+	{"apps/v1beta2", "StatefulSet", "1.9", "1.16", "apps/v1", ""},
+	{"apps/v1beta2", "DaemonSet", "1.9", "1.16", "apps/v1", ""},
+	{"apps/v1beta2", "ReplicaSet", "1.9", "1.16", "apps/v1", ""},
+	{"networking.k8s.io/v1beta1", "Ingress", "1.19", "1.22", "networking.k8s.io/v1", ""},
+	{"networking.k8s.io/v1beta1", "IngressClass", "1.19", "1.22", "networking.k8s.io/v1", ""},
+	{"admissionregistration.k8s.io/v1beta1", "MutatingWebhookConfiguration", "1.16", "1.22", "admissionregistration.k8s.io/v1", ""},
+	{"admissionregistration.k8s.io/v1beta1", "ValidatingWebhookConfiguration", "1.16", "1.22", "admissionregistration.k8s.io/v1", ""},
+	{"apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "1.16", "1.22", "apiextensions.k8s.io/v1", ""},
+	{"apiregistration.k8s.io/v1beta1", "APIService", "1.19", "1.22", "apiregistration.k8s.io/v1", ""},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRole", "1.17", "1.22", "rbac.authorization.k8s.io/v1", ""},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRoleBinding", "1.17", "1.22", "rbac.authorization.k8s.io/v1", ""},
+	{"rbac.authorization.k8s.io/v1beta1", "Role", "1.17", "1.22", "rbac.authorization.k8s.io/v1", ""},
+	{"rbac.authorization.k8s.io/v1beta1", "RoleBinding", "1.17", "1.22", "rbac.authorization.k8s.io/v1", ""},
+	{"certificates.k8s.io/v1beta1", "CertificateSigningRequest", "1.19", "1.22", "certificates.k8s.io/v1", ""},
+	{"coordination.k8s.io/v1beta1", "Lease", "1.19", "1.22", "coordination.k8s.io/v1", ""},
+	{"scheduling.k8s.io/v1beta1", "PriorityClass", "1.14", "1.22", "scheduling.k8s.io/v1", ""},
+	{"storage.k8s.io/v1beta1", "CSIDriver", "1.19", "1.22", "storage.k8s.io/v1", ""},
+	{"storage.k8s.io/v1beta1", "CSINode", "1.17", "1.22", "storage.k8s.io/v1", ""},
+	{"storage.k8s.io/v1beta1", "StorageClass", "1.19", "1.22", "storage.k8s.io/v1", ""},
+	{"storage.k8s.io/v1beta1", "VolumeAttachment", "1.19", "1.22", "storage.k8s.io/v1", ""},
+	{"batch/v1beta1", "CronJob", "1.21", "1.25", "batch/v1", ""},
+	{"discovery.k8s.io/v1beta1", "EndpointSlice", "1.21", "1.25", "discovery.k8s.io/v1", ""},
+	{"policy/v1beta1", "PodDisruptionBudget", "1.21", "1.25", "policy/v1", ""},
+	{"policy/v1beta1", "PodSecurityPolicy", "1.21", "1.25", "", ""},
+	{"node.k8s.io/v1beta1", "RuntimeClass", "1.20", "1.25", "node.k8s.io/v1", ""},
+	{"autoscaling/v2beta1", "HorizontalPodAutoscaler", "1.22", "1.25", "autoscaling/v2", ""},
+	{"autoscaling/v2beta2", "HorizontalPodAutoscaler", "1.23", "1.26", "autoscaling/v2", ""},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "FlowSchema", "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1", ""},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "PriorityLevelConfiguration", "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1", ""},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "FlowSchema", "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1", ""},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "PriorityLevelConfiguration", "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1", ""},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "FlowSchema", "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1", ""},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "PriorityLevelConfiguration", "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1", ""},
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", "1.24", "1.27", "storage.k8s.io/v1", ""},
+}
+
+// Where a DeprecatedObject's API version was found.
+const (
+	DeprecationSourceLastApplied   = "last-applied"
+	DeprecationSourceManagedFields = "managed-fields"
+)
+
+// DeprecatedObject is an object last written through a deprecated API
+// version, meaning the manifest or tool that manages it still uses it.
+type DeprecatedObject struct {
+	DeprecatedAPI
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Source    string `json:"source"`
+	// Managers are the field managers that wrote through the version,
+	// e.g. "helm" or "kubectl-client-side-apply".
+	Managers []string `json:"managers,omitempty"`
+	// Removed is set when the cluster no longer serves the version, so
+	// re-applying the manifest already fails; BlocksUpgrade when the
+	// next minor release removes it.
+	Removed       bool `json:"removed"`
+	BlocksUpgrade bool `json:"blocks_upgrade"`
+}
+
+// ServedDeprecatedAPI is a deprecated version the cluster still serves.
+type ServedDeprecatedAPI struct {
+	DeprecatedAPI
+	BlocksUpgrade bool `json:"blocks_upgrade"`
+}
+
+type DeprecatedAPIReport struct {
+	ServerVersion string `json:"server_version"`
+	// NextVersion is the minor release an upgrade would go to.
+	NextVersion string                `json:"next_version"`
+	Served      []ServedDeprecatedAPI `json:"served"`
+	Objects     []DeprecatedObject    `json:"objects"`
+	// MustMigrate counts the objects whose manifests need migrating
+	// before upgrading to NextVersion.
+	MustMigrate int               `json:"must_migrate"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// GetDeprecatedAPIReport flags objects written through deprecated or
+// removed API versions, from the built-in table and the deprecated
+// versions of CRDs. Objects are stored in a newer version, so the version
+// a manifest uses is read from the last-applied annotation and the
+// managed fields. Objects whose version is removed by the next minor
+// release must be migrated before upgrading.
+func (c *Client) GetDeprecatedAPIReport() (*DeprecatedAPIReport, error) {
+	v, err := c.DiscoveryClient.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	major, _ := strconv.Atoi(v.Major)
+	minor, _ := strconv.Atoi(strings.TrimRight(v.Minor, "+"))
+	report := &DeprecatedAPIReport{
+		ServerVersion: fmt.Sprintf("%d.%d", major, minor),
+		NextVersion:   fmt.Sprintf("%d.%d", major, minor+1),
+		Served:        []ServedDeprecatedAPI{},
+		Objects:       []DeprecatedObject{},
+	}
+	addError := func(key string, err error) {
+		if report.Errors == nil {
+			report.Errors = make(map[string]string)
+		}
+		report.Errors[key] = err.Error()
+	}
+	// removedBy reports whether a release removes the API by minor.
+	removedBy := func(api DeprecatedAPI, minor int) bool {
+		if api.RemovedIn == "" {
+			return false
+		}
+		_, m, ok := strings.Cut(api.RemovedIn, ".")
+		removed, err := strconv.Atoi(m)
+		return ok && err == nil && removed <= minor
+	}
+
+	apis := append([]DeprecatedAPI{}, deprecatedAPIs...)
+	crdAPIs, err := c.deprecatedCRDVersions()
+	if err != nil {
+		addError("customresourcedefinitions", err)
+	}
+	apis = append(apis, crdAPIs...)
+
+	_, lists, err := c.DiscoveryClient.ServerGroupsAndResources()
+	if err != nil && len(lists) == 0 {
+		return nil, err
+	}
+	served := make(map[string]bool)
+	for _, list := range lists {
+		for _, res := range list.APIResources {
+			served[list.GroupVersion+"/"+res.Kind] = true
+		}
+	}
+	for _, api := range apis {
+		if served[api.APIVersion+"/"+api.Kind] {
+			report.Served = append(report.Served, ServedDeprecatedAPI{DeprecatedAPI: api, BlocksUpgrade: removedBy(api, minor+1)})
+		}
+	}
+
+	// Objects of a kind are listed once, preferably through the version
+	// replacing the deprecated one, and checked against all its deprecated
+	// versions.
+	byKind := make(map[schema.GroupKind][]DeprecatedAPI)
+	for _, api := range apis {
+		for _, av := range []string{api.Replacement, api.APIVersion} {
+			gv, err := schema.ParseGroupVersion(av)
+			if av == "" || err != nil || !served[av+"/"+api.Kind] {
+				continue
+			}
+			gk := schema.GroupKind{Group: gv.Group, Kind: api.Kind}
+			byKind[gk] = append(byKind[gk], api)
+			break
+		}
+	}
+	resources, err := c.GetApiResources()
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[schema.GroupKind]bool)
+	for _, res := range resources {
+		gk := schema.GroupKind{Group: res.Group, Kind: res.Kind}
+		candidates := byKind[gk]
+		if len(candidates) == 0 || listed[gk] || strings.Contains(res.Name, "/") {
+			continue
+		}
+		listed[gk] = true
+		gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}
+		var items []unstructured.Unstructured
+		err := c.do(OpList, func(ctx context.Context) error {
+			list, err := c.DynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			items = list.Items
+			return nil
+		})
+		if err != nil {
+			addError(gvr.String(), err)
+			continue
+		}
+		for _, item := range items {
+			for _, api := range candidates {
+				obj, ok := deprecatedUse(&item, api)
+				if !ok {
+					continue
+				}
+				obj.Removed = removedBy(api, minor) || !served[api.APIVersion+"/"+api.Kind]
+				obj.BlocksUpgrade = removedBy(api, minor+1)
+				if obj.Removed || obj.BlocksUpgrade {
+					report.MustMigrate++
+				}
+				report.Objects = append(report.Objects, obj)
+			}
+		}
+	}
+
+	sort.Slice(report.Served, func(i, j int) bool {
+		a, b := report.Served[i], report.Served[j]
+		if a.APIVersion != b.APIVersion {
+			return a.APIVersion < b.APIVersion
+		}
+		return a.Kind < b.Kind
+	})
+	sort.Slice(report.Objects, func(i, j int) bool {
+		a, b := report.Objects[i], report.Objects[j]
+		if (a.Removed || a.BlocksUpgrade) != (b.Removed || b.BlocksUpgrade) {
+			return a.Removed || a.BlocksUpgrade
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// deprecatedUse checks whether an object was applied or written through
+// the deprecated version of api.
+func deprecatedUse(item *unstructured.Unstructured, api DeprecatedAPI) (DeprecatedObject, bool) {
+	obj := DeprecatedObject{DeprecatedAPI: api, Namespace: item.GetNamespace(), Name: item.GetName()}
+	if applied := item.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; applied != "" {
+		var head struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if json.Unmarshal([]byte(applied), &head) == nil && head.APIVersion == api.APIVersion && head.Kind == api.Kind {
+			obj.Source = DeprecationSourceLastApplied
+		}
+	}
+	for _, mf := range item.GetManagedFields() {
+		if mf.APIVersion == api.APIVersion && !contains(obj.Managers, mf.Manager) {
+			obj.Managers = append(obj.Managers, mf.Manager)
+		}
+	}
+	if obj.Source == "" && len(obj.Managers) > 0 {
+		obj.Source = DeprecationSourceManagedFields
+	}
+	sort.Strings(obj.Managers)
+	return obj, obj.Source != ""
+}
+
+// deprecatedCRDVersions returns the versions CRDs mark as deprecated.
+func (c *Client) deprecatedCRDVersions() ([]DeprecatedAPI, error) {
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var apis []DeprecatedAPI
+	for _, crd := range list.Items {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		storage := ""
+		for _, raw := range versions {
+			v, _ := raw.(map[string]interface{})
+			if isStorage, _ := v["storage"].(bool); isStorage {
+				storage, _ = v["name"].(string)
+			}
+		}
+		for _, raw := range versions {
+			v, _ := raw.(map[string]interface{})
+			if deprecated, _ := v["deprecated"].(bool); !deprecated {
+				continue
+			}
+			name, _ := v["name"].(string)
+			api := DeprecatedAPI{APIVersion: group + "/" + name, Kind: kind}
+			api.Warning, _ = v["deprecationWarning"].(string)
+			if storage != "" && storage != name {
+				api.Replacement = group + "/" + storage
+			}
+			apis = append(apis, api)
+		}
+	}
+	return apis, nil
+}
@@ -0,0 +1,479 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const maxOwnerDepth = 10
+
+// DescribeField is one "Name: value" line of a section.
+type DescribeField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DescribeSection is a collapsible panel of describe output. A section has
+// either Fields (key/value pairs) or Columns and Rows (a table).
+type DescribeSection struct {
+	ID      string          `json:"id"`
+	Title   string          `json:"title"`
+	Fields  []DescribeField `json:"fields,omitempty"`
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]string      `json:"rows,omitempty"`
+}
+
+type ResourceDescription struct {
+	Kind       string            `json:"kind"`
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	OwnerChain []ObjectNode      `json:"owner_chain"`
+	Sections   []DescribeSection `json:"sections"`
+}
+
+// DescribeResource gathers what `kubectl describe` shows for an object:
+// metadata, the owner chain, status conditions, containers, tolerations,
+// volumes with their mounts, and recent events. Sections that would be
+// empty are left out.
+func (c *Client) DescribeResource(ref ResourceRef) (*ResourceDescription, error) {
+	res, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: res.(map[string]interface{})}
+
+	desc := &ResourceDescription{
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		OwnerChain: c.ownerChain(obj),
+	}
+	add := func(s DescribeSection) {
+		if len(s.Fields) > 0 || len(s.Rows) > 0 {
+			desc.Sections = append(desc.Sections, s)
+		}
+	}
+
+	add(overviewSection(obj))
+	add(mapSection("labels", "Labels", obj.GetLabels()))
+	add(mapSection("annotations", "Annotations", obj.GetAnnotations()))
+	add(ownersSection(desc.OwnerChain))
+	add(conditionsSection(obj.Object))
+
+	if spec, ok := describedPodSpec(obj.Object); ok {
+		statuses := containerStatuses(obj.Object)
+		add(containersSection("init-containers", "Init Containers", spec, "initContainers", statuses))
+		add(containersSection("containers", "Containers", spec, "containers", statuses))
+		add(tolerationsSection(spec))
+		add(volumesSection(spec))
+	}
+
+	if events, err := c.GetEventsForObject(obj.GetNamespace(), obj.GetKind(), obj.GetName(), string(obj.GetUID())); err == nil {
+		add(eventsSection(events))
+	}
+	return desc, nil
+}
+
+// ownerChain follows controller references (or the first owner when none
+// is marked controller) up to the top-level owner, nearest first.
+func (c *Client) ownerChain(obj *unstructured.Unstructured) []ObjectNode {
+	chain := []ObjectNode{}
+	resources, err := c.GetApiResources()
+	if err != nil {
+		return chain
+	}
+	byKind := make(map[schema.GroupKind]ApiResourceInfo)
+	for _, res := range resources {
+		byKind[schema.GroupKind{Group: res.Group, Kind: res.Kind}] = res
+	}
+
+	current := obj
+	for depth := 0; depth < maxOwnerDepth; depth++ {
+		refs := current.GetOwnerReferences()
+		if len(refs) == 0 {
+			break
+		}
+		owner := refs[0]
+		if controller := metav1.GetControllerOfNoCopy(current); controller != nil {
+			owner = *controller
+		}
+
+		gv, _ := schema.ParseGroupVersion(owner.APIVersion)
+		info, ok := byKind[schema.GroupKind{Group: gv.Group, Kind: owner.Kind}]
+		node := ObjectNode{
+			Group:   gv.Group,
+			Version: gv.Version,
+			Kind:    owner.Kind,
+			Name:    owner.Name,
+			UID:     string(owner.UID),
+		}
+		if ok {
+			node.Plural = info.Name
+			if info.Namespaced {
+				node.Namespace = current.GetNamespace()
+			}
+		}
+		chain = append(chain, node)
+		if !ok {
+			break
+		}
+
+		res, err := c.GetResource(node.Group, node.Version, node.Kind, node.Plural, node.Namespace, node.Name)
+		if err != nil {
+			break
+		}
+		current = &unstructured.Unstructured{Object: res.(map[string]interface{})}
+	}
+	return chain
+}
+
+func overviewSection(obj *unstructured.Unstructured) DescribeSection {
+	s := DescribeSection{ID: "overview", Title: "Overview"}
+	field := func(name, value string) {
+		if value != "" {
+			s.Fields = append(s.Fields, DescribeField{Name: name, Value: value})
+		}
+	}
+	field("Name", obj.GetName())
+	field("Namespace", obj.GetNamespace())
+	field("Kind", obj.GetKind())
+	field("API Version", obj.GetAPIVersion())
+	field("UID", string(obj.GetUID()))
+	field("Created", describeTime(obj.GetCreationTimestamp().Time))
+	if ts := obj.GetDeletionTimestamp(); ts != nil {
+		field("Deletion Requested", describeTime(ts.Time))
+	}
+	field("Finalizers", strings.Join(obj.GetFinalizers(), ", "))
+
+	str := func(path ...string) string {
+		v, _, _ := unstructured.NestedFieldNoCopy(obj.Object, path...)
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	switch obj.GetKind() {
+	case "Pod":
+		field("Node", str("spec", "nodeName"))
+		field("Service Account", str("spec", "serviceAccountName"))
+		field("Priority Class", str("spec", "priorityClassName"))
+		field("Phase", str("status", "phase"))
+		field("Reason", str("status", "reason"))
+		field("Pod IP", str("status", "podIP"))
+		field("Host IP", str("status", "hostIP"))
+		field("QoS Class", str("status", "qosClass"))
+		field("Started", str("status", "startTime"))
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		field("Desired Replicas", str("spec", "replicas"))
+		field("Ready Replicas", str("status", "readyReplicas"))
+		field("Updated Replicas", str("status", "updatedReplicas"))
+		field("Available Replicas", str("status", "availableReplicas"))
+		field("Strategy", str("spec", "strategy", "type")+str("spec", "updateStrategy", "type"))
+		field("Selector", selectorString(obj.Object))
+	case "DaemonSet":
+		field("Desired Scheduled", str("status", "desiredNumberScheduled"))
+		field("Current Scheduled", str("status", "currentNumberScheduled"))
+		field("Ready", str("status", "numberReady"))
+		field("Update Strategy", str("spec", "updateStrategy", "type"))
+		field("Selector", selectorString(obj.Object))
+	case "Job":
+		field("Completions", str("spec", "completions"))
+		field("Parallelism", str("spec", "parallelism"))
+		field("Succeeded", str("status", "succeeded"))
+		field("Failed", str("status", "failed"))
+		field("Active", str("status", "active"))
+	case "CronJob":
+		field("Schedule", str("spec", "schedule"))
+		field("Suspend", str("spec", "suspend"))
+		field("Concurrency Policy", str("spec", "concurrencyPolicy"))
+		field("Last Schedule", str("status", "lastScheduleTime"))
+	case "Service":
+		field("Type", str("spec", "type"))
+		field("Cluster IP", str("spec", "clusterIP"))
+		field("Session Affinity", str("spec", "sessionAffinity"))
+		selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+		field("Selector", joinMap(selector))
+	case "Node":
+		field("Unschedulable", str("spec", "unschedulable"))
+		field("Pod CIDR", str("spec", "podCIDR"))
+		field("Kubelet Version", str("status", "nodeInfo", "kubeletVersion"))
+		field("OS Image", str("status", "nodeInfo", "osImage"))
+		field("Container Runtime", str("status", "nodeInfo", "containerRuntimeVersion"))
+	}
+	return s
+}
+
+func selectorString(obj map[string]interface{}) string {
+	labels, _, _ := unstructured.NestedStringMap(obj, "spec", "selector", "matchLabels")
+	return joinMap(labels)
+}
+
+func joinMap(m map[string]string) string {
+	parts := make([]string, 0, len(m))
+	for k, v := range m {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func mapSection(id, title string, m map[string]string) DescribeSection {
+	s := DescribeSection{ID: id, Title: title, Columns: []string{"Key", "Value"}}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.Rows = append(s.Rows, []string{k, m[k]})
+	}
+	return s
+}
+
+func ownersSection(chain []ObjectNode) DescribeSection {
+	s := DescribeSection{ID: "owners", Title: "Owner Chain", Columns: []string{"Kind", "Name", "API Group"}}
+	for _, n := range chain {
+		s.Rows = append(s.Rows, []string{n.Kind, n.Name, n.Group})
+	}
+	return s
+}
+
+func conditionsSection(obj map[string]interface{}) DescribeSection {
+	s := DescribeSection{
+		ID:      "conditions",
+		Title:   "Conditions",
+		Columns: []string{"Type", "Status", "Reason", "Message", "Last Transition"},
+	}
+	conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+	for _, raw := range conditions {
+		cond, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		get := func(key string) string {
+			v, _ := cond[key].(string)
+			return v
+		}
+		s.Rows = append(s.Rows, []string{get("type"), get("status"), get("reason"), get("message"), get("lastTransitionTime")})
+	}
+	return s
+}
+
+// describedPodSpec returns the pod spec of a Pod or of a workload's pod
+// template.
+func describedPodSpec(obj map[string]interface{}) (map[string]interface{}, bool) {
+	for _, path := range [][]string{
+		{"spec", "jobTemplate", "spec", "template", "spec"},
+		{"spec", "template", "spec"},
+	} {
+		if spec, ok, _ := unstructured.NestedMap(obj, path...); ok {
+			return spec, true
+		}
+	}
+	if kind, _, _ := unstructured.NestedString(obj, "kind"); kind == "Pod" {
+		spec, ok, _ := unstructured.NestedMap(obj, "spec")
+		return spec, ok
+	}
+	return nil, false
+}
+
+// containerStatuses indexes a Pod's init and regular container statuses
+// by container name. Workloads have none.
+func containerStatuses(obj map[string]interface{}) map[string]map[string]interface{} {
+	statuses := make(map[string]map[string]interface{})
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		list, _, _ := unstructured.NestedSlice(obj, "status", field)
+		for _, raw := range list {
+			if st, ok := raw.(map[string]interface{}); ok {
+				name, _ := st["name"].(string)
+				statuses[name] = st
+			}
+		}
+	}
+	return statuses
+}
+
+func containersSection(id, title string, spec map[string]interface{}, field string, statuses map[string]map[string]interface{}) DescribeSection {
+	s := DescribeSection{
+		ID:      id,
+		Title:   title,
+		Columns: []string{"Name", "Image", "Ports", "Requests", "Limits", "State", "Ready", "Restarts"},
+	}
+	containers, _, _ := unstructured.NestedSlice(spec, field)
+	for _, raw := range containers {
+		ctr, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := ctr["name"].(string)
+		image, _ := ctr["image"].(string)
+
+		var ports []string
+		list, _, _ := unstructured.NestedSlice(ctr, "ports")
+		for _, p := range list {
+			port, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			protocol, _ := port["protocol"].(string)
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			ports = append(ports, fmt.Sprintf("%v/%s", port["containerPort"], protocol))
+		}
+		requests, _, _ := unstructured.NestedStringMap(ctr, "resources", "requests")
+		limits, _, _ := unstructured.NestedStringMap(ctr, "resources", "limits")
+
+		state, ready, restarts := "", "", ""
+		if st, ok := statuses[name]; ok {
+			state = describeContainerState(st)
+			ready = fmt.Sprint(st["ready"])
+			restarts = fmt.Sprint(st["restartCount"])
+		}
+		s.Rows = append(s.Rows, []string{name, image, strings.Join(ports, ", "), joinMap(requests), joinMap(limits), state, ready, restarts})
+	}
+	return s
+}
+
+func describeContainerState(status map[string]interface{}) string {
+	state, _, _ := unstructured.NestedMap(status, "state")
+	for _, key := range []string{"running", "waiting", "terminated"} {
+		detail, ok := state[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		label := strings.ToUpper(key[:1]) + key[1:]
+		if reason, _ := detail["reason"].(string); reason != "" {
+			return label + " (" + reason + ")"
+		}
+		return label
+	}
+	return ""
+}
+
+func tolerationsSection(spec map[string]interface{}) DescribeSection {
+	s := DescribeSection{
+		ID:      "tolerations",
+		Title:   "Tolerations",
+		Columns: []string{"Key", "Operator", "Value", "Effect", "Seconds"},
+	}
+	tolerations, _, _ := unstructured.NestedSlice(spec, "tolerations")
+	for _, raw := range tolerations {
+		t, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		get := func(key string) string {
+			if v, ok := t[key]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}
+		operator := get("operator")
+		if operator == "" {
+			operator = "Equal"
+		}
+		s.Rows = append(s.Rows, []string{get("key"), operator, get("value"), get("effect"), get("tolerationSeconds")})
+	}
+	return s
+}
+
+// volumeSourceKeys are the volume source fields whose "name"-like field
+// identifies the backing object.
+var volumeSourceKeys = map[string]string{
+	"configMap":             "name",
+	"secret":                "secretName",
+	"persistentVolumeClaim": "claimName",
+	"hostPath":              "path",
+	"nfs":                   "path",
+}
+
+func volumesSection(spec map[string]interface{}) DescribeSection {
+	s := DescribeSection{
+		ID:      "volumes",
+		Title:   "Volumes",
+		Columns: []string{"Name", "Type", "Source", "Mounted By"},
+	}
+
+	mounts := make(map[string][]string)
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(spec, field)
+		for _, raw := range containers {
+			ctr, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := ctr["name"].(string)
+			list, _, _ := unstructured.NestedSlice(ctr, "volumeMounts")
+			for _, m := range list {
+				mount, ok := m.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				volume, _ := mount["name"].(string)
+				path, _ := mount["mountPath"].(string)
+				entry := name + ":" + path
+				if ro, _ := mount["readOnly"].(bool); ro {
+					entry += " (ro)"
+				}
+				mounts[volume] = append(mounts[volume], entry)
+			}
+		}
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(spec, "volumes")
+	for _, raw := range volumes {
+		vol, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := vol["name"].(string)
+		var kind, source string
+		for key, value := range vol {
+			if key == "name" {
+				continue
+			}
+			kind = key
+			if detail, ok := value.(map[string]interface{}); ok {
+				if field, ok := volumeSourceKeys[key]; ok {
+					source, _ = detail[field].(string)
+				}
+			}
+			break
+		}
+		s.Rows = append(s.Rows, []string{name, kind, source, strings.Join(mounts[name], ", ")})
+	}
+	return s
+}
+
+func eventsSection(events []ObjectEvent) DescribeSection {
+	s := DescribeSection{
+		ID:      "events",
+		Title:   "Events",
+		Columns: []string{"Type", "Reason", "Last Seen", "Count", "From", "Message"},
+	}
+	for _, ev := range events {
+		s.Rows = append(s.Rows, []string{
+			ev.Type,
+			ev.Reason,
+			describeTime(ev.LastTimestamp),
+			fmt.Sprint(ev.Count),
+			ev.Source,
+			ev.Message,
+		})
+	}
+	return s
+}
+
+func describeTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+)
+
+// discoveredGroups keeps the last successfully discovered resource list
+// of every group version, so a group whose aggregated API server is down
+// (metrics-server is the usual one) can still be shown, marked stale.
+type discoveredGroups struct {
+	mu     sync.Mutex
+	lists  map[string]discoveredGroupVersion
+	status DiscoveryStatus
+}
+
+type discoveredGroupVersion struct {
+	list *metav1.APIResourceList
+	at   time.Time
+	// stale is set on cached lists served in place of a failed discovery.
+	stale bool
+}
+
+// DiscoveryStatus describes the last API discovery. Warnings name the
+// group versions that couldn't be discovered and whether cached data was
+// used in their place.
+type DiscoveryStatus struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// FailedGroups are the unavailable group versions, e.g.
+	// "metrics.k8s.io/v1beta1".
+	FailedGroups []string `json:"failed_groups"`
+	Warnings     []string `json:"warnings"`
+}
+
+func (d *discoveredGroups) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lists = nil
+	d.status = DiscoveryStatus{}
+}
+
+// discoverResources runs discovery and merges its result with the cache.
+// Group versions that failed are served from the cache, with the time they
+// were last discovered; when the whole discovery fails everything cached
+// is. An error is only returned when nothing can be served.
+func (c *Client) discoverResources() ([]discoveredGroupVersion, error) {
+	_, lists, err := c.DiscoveryClient.ServerGroupsAndResources()
+	now := time.Now()
+	status := DiscoveryStatus{CheckedAt: now, FailedGroups: []string{}, Warnings: []string{}}
+
+	var failed map[string]error
+	var groupErr *discovery.ErrGroupDiscoveryFailed
+	switch {
+	case err == nil:
+	case errors.As(err, &groupErr):
+		failed = make(map[string]error, len(groupErr.Groups))
+		for gv, gvErr := range groupErr.Groups {
+			failed[gv.String()] = gvErr
+		}
+	default:
+		return c.cachedDiscovery(status, err)
+	}
+
+	d := &c.discovered
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lists == nil {
+		d.lists = make(map[string]discoveredGroupVersion)
+	}
+	result := make([]discoveredGroupVersion, 0, len(lists)+len(failed))
+	for _, list := range lists {
+		if list == nil || failed[list.GroupVersion] != nil {
+			continue
+		}
+		entry := discoveredGroupVersion{list: list, at: now}
+		d.lists[list.GroupVersion] = entry
+		result = append(result, entry)
+	}
+	for gv, gvErr := range failed {
+		status.FailedGroups = append(status.FailedGroups, gv)
+		if cached, ok := d.lists[gv]; ok {
+			cached.stale = true
+			result = append(result, cached)
+			status.Warnings = append(status.Warnings, fmt.Sprintf("%s is unavailable, showing resources discovered %s: %v", gv, cached.at.Format(time.RFC3339), gvErr))
+		} else {
+			status.Warnings = append(status.Warnings, fmt.Sprintf("%s is unavailable and its resources are missing: %v", gv, gvErr))
+		}
+	}
+	sort.Strings(status.FailedGroups)
+	sort.Strings(status.Warnings)
+	d.status = status
+	return result, nil
+}
+
+// cachedDiscovery serves every cached group version when discovery failed
+// as a whole, e.g. while the API server is briefly unreachable.
+func (c *Client) cachedDiscovery(status DiscoveryStatus, err error) ([]discoveredGroupVersion, error) {
+	d := &c.discovered
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.lists) == 0 {
+		return nil, err
+	}
+	result := make([]discoveredGroupVersion, 0, len(d.lists))
+	for gv, cached := range d.lists {
+		cached.stale = true
+		result = append(result, cached)
+		status.FailedGroups = append(status.FailedGroups, gv)
+	}
+	sort.Strings(status.FailedGroups)
+	status.Warnings = append(status.Warnings, fmt.Sprintf("discovery failed, showing cached API resources: %v", err))
+	d.status = status
+	return result, nil
+}
+
+// GetDiscoveryStatus returns the outcome of the last API discovery, as
+// done by GetApiResources.
+func (c *Client) GetDiscoveryStatus() DiscoveryStatus {
+	d := &c.discovered
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := d.status
+	status.FailedGroups = append([]string{}, status.FailedGroups...)
+	status.Warnings = append([]string{}, status.Warnings...)
+	return status
+}
@@ -0,0 +1,235 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DisruptionPod is a pod selected by a PodDisruptionBudget.
+type DisruptionPod struct {
+	Name  string `json:"name"`
+	Node  string `json:"node"`
+	Ready bool   `json:"ready"`
+}
+
+// BudgetAnalysis is a PodDisruptionBudget with the pods it selects and the
+// workloads owning them. Blocking means evictions of its pods are refused
+// right now, so draining their nodes (and rolling node upgrades, which
+// drain) stalls until more pods are healthy.
+type BudgetAnalysis struct {
+	Namespace          string          `json:"namespace"`
+	Name               string          `json:"name"`
+	MinAvailable       string          `json:"min_available,omitempty"`
+	MaxUnavailable     string          `json:"max_unavailable,omitempty"`
+	Pods               []DisruptionPod `json:"pods"`
+	Workloads          []string        `json:"workloads"`
+	ExpectedPods       int32           `json:"expected_pods"`
+	CurrentHealthy     int32           `json:"current_healthy"`
+	DesiredHealthy     int32           `json:"desired_healthy"`
+	DisruptionsAllowed int32           `json:"disruptions_allowed"`
+	Blocking           bool            `json:"blocking"`
+	Issues             []string        `json:"issues"`
+	// Status is "ok", "warning" when the budget is blocking or selects no
+	// pods, or "critical" when it is misconfigured so that evictions are
+	// never allowed.
+	Status string `json:"status"`
+}
+
+type DisruptionAnalysis struct {
+	Budgets []BudgetAnalysis `json:"budgets"`
+	// BlockedNodes are the nodes running a pod of a blocking budget; a
+	// drain of any of them won't complete for now.
+	BlockedNodes []string `json:"blocked_nodes"`
+}
+
+// GetDisruptionAnalysis maps the PodDisruptionBudgets of a namespace (all
+// when empty) to the pods they select and reports which would block node
+// drains, along with budgets that can never allow a disruption or that
+// overlap, which the Eviction API rejects outright.
+func (c *Client) GetDisruptionAnalysis(namespace string) (*DisruptionAnalysis, error) {
+	var pdbs *policyv1.PodDisruptionBudgetList
+	var pods *corev1.PodList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if pdbs, err = c.Clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			return err
+		}
+		pods, err = c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Which budgets select each pod, by namespace/name.
+	selectedBy := make(map[string][]string)
+	selected := make([][]*corev1.Pod, len(pdbs.Items))
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		for j := range pods.Items {
+			pod := &pods.Items[j]
+			if pod.Namespace != pdb.Namespace || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			if selector.Matches(labels.Set(pod.Labels)) {
+				selected[i] = append(selected[i], pod)
+				key := pod.Namespace + "/" + pod.Name
+				selectedBy[key] = append(selectedBy[key], pdb.Name)
+			}
+		}
+	}
+
+	analysis := &DisruptionAnalysis{Budgets: []BudgetAnalysis{}, BlockedNodes: []string{}}
+	blockedNodes := make(map[string]bool)
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		budget := BudgetAnalysis{
+			Namespace:          pdb.Namespace,
+			Name:               pdb.Name,
+			Pods:               []DisruptionPod{},
+			Workloads:          []string{},
+			ExpectedPods:       pdb.Status.ExpectedPods,
+			CurrentHealthy:     pdb.Status.CurrentHealthy,
+			DesiredHealthy:     pdb.Status.DesiredHealthy,
+			DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+			Issues:             []string{},
+			Status:             "ok",
+		}
+		if pdb.Spec.MinAvailable != nil {
+			budget.MinAvailable = pdb.Spec.MinAvailable.String()
+		}
+		if pdb.Spec.MaxUnavailable != nil {
+			budget.MaxUnavailable = pdb.Spec.MaxUnavailable.String()
+		}
+
+		workloads := make(map[string]bool)
+		overlaps := make(map[string]bool)
+		for _, pod := range selected[i] {
+			budget.Pods = append(budget.Pods, DisruptionPod{Name: pod.Name, Node: pod.Spec.NodeName, Ready: isPodReady(pod)})
+			if w := podWorkload(pod); w != "" {
+				workloads[w] = true
+			}
+			for _, other := range selectedBy[pod.Namespace+"/"+pod.Name] {
+				if other != pdb.Name {
+					overlaps[other] = true
+				}
+			}
+		}
+		for w := range workloads {
+			budget.Workloads = append(budget.Workloads, w)
+		}
+		sort.Strings(budget.Workloads)
+		sort.Slice(budget.Pods, func(a, b int) bool { return budget.Pods[a].Name < budget.Pods[b].Name })
+
+		var critical, warning bool
+		expected := int(pdb.Status.ExpectedPods)
+		if expected == 0 {
+			expected = len(budget.Pods)
+		}
+		switch {
+		case pdb.Spec.Selector != nil && len(pdb.Spec.Selector.MatchLabels)+len(pdb.Spec.Selector.MatchExpressions) == 0:
+			budget.Issues = append(budget.Issues, "empty selector matches every pod in the namespace")
+			warning = true
+		case len(budget.Pods) == 0:
+			budget.Issues = append(budget.Issues, "selects no pods")
+			warning = true
+		}
+		if len(budget.Pods) > 0 {
+			if never := neverDisrupts(pdb.Spec, expected); never != "" {
+				budget.Issues = append(budget.Issues, never)
+				critical = true
+			}
+		}
+		if len(overlaps) > 0 {
+			names := make([]string, 0, len(overlaps))
+			for name := range overlaps {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			budget.Issues = append(budget.Issues, fmt.Sprintf("pods are also selected by %s; evicting them fails", strings.Join(names, ", ")))
+			critical = true
+		}
+
+		budget.Blocking = len(budget.Pods) > 0 && (pdb.Status.DisruptionsAllowed == 0 || len(overlaps) > 0)
+		if budget.Blocking {
+			if !critical && pdb.Status.CurrentHealthy < pdb.Status.DesiredHealthy {
+				budget.Issues = append(budget.Issues, fmt.Sprintf("%d of %d required pods healthy", pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy))
+			}
+			warning = true
+			for _, pod := range budget.Pods {
+				if pod.Node != "" {
+					blockedNodes[pod.Node] = true
+				}
+			}
+		}
+
+		switch {
+		case critical:
+			budget.Status = "critical"
+		case warning:
+			budget.Status = "warning"
+		}
+		analysis.Budgets = append(analysis.Budgets, budget)
+	}
+
+	for node := range blockedNodes {
+		analysis.BlockedNodes = append(analysis.BlockedNodes, node)
+	}
+	sort.Strings(analysis.BlockedNodes)
+	sort.Slice(analysis.Budgets, func(i, j int) bool {
+		a, b := analysis.Budgets[i], analysis.Budgets[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return analysis, nil
+}
+
+// neverDisrupts explains why a budget allows no disruption even with all
+// of its expected pods healthy, or returns "".
+func neverDisrupts(spec policyv1.PodDisruptionBudgetSpec, expected int) string {
+	if spec.MaxUnavailable != nil {
+		n, err := intstr.GetScaledValueFromIntOrPercent(spec.MaxUnavailable, expected, true)
+		if err == nil && n <= 0 {
+			return fmt.Sprintf("maxUnavailable %s never allows an eviction", spec.MaxUnavailable.String())
+		}
+	}
+	if spec.MinAvailable != nil {
+		n, err := intstr.GetScaledValueFromIntOrPercent(spec.MinAvailable, expected, true)
+		if err == nil && n >= expected {
+			return fmt.Sprintf("minAvailable %s requires all %d pods, so no eviction is ever allowed", spec.MinAvailable.String(), expected)
+		}
+	}
+	return ""
+}
+
+// podWorkload names the workload owning a pod as "Kind/name", resolving
+// ReplicaSets to their Deployment through the pod-template-hash suffix.
+func podWorkload(pod *corev1.Pod) string {
+	controller := metav1.GetControllerOf(pod)
+	if controller == nil {
+		return ""
+	}
+	if controller.Kind == "ReplicaSet" {
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" && strings.HasSuffix(controller.Name, "-"+hash) {
+			return "Deployment/" + strings.TrimSuffix(controller.Name, "-"+hash)
+		}
+	}
+	return controller.Kind + "/" + controller.Name
+}
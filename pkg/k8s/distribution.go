@@ -0,0 +1,159 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	zoneLabel       = "topology.kubernetes.io/zone"
+	legacyZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+	archLabel       = "kubernetes.io/arch"
+)
+
+type DistributionBucket struct {
+	Key  string   `json:"key"`
+	Pods []string `json:"pods"`
+}
+
+// PodDistribution shows how a workload's pods spread over nodes, zones and
+// CPU architectures. Pending pods aren't placed yet and are only counted.
+type PodDistribution struct {
+	Kind          string               `json:"kind"`
+	Name          string               `json:"name"`
+	Namespace     string               `json:"namespace"`
+	Replicas      int64                `json:"replicas"`
+	ScheduledPods int                  `json:"scheduled_pods"`
+	PendingPods   int                  `json:"pending_pods"`
+	Nodes         []DistributionBucket `json:"nodes"`
+	Zones         []DistributionBucket `json:"zones"`
+	Architectures []DistributionBucket `json:"architectures"`
+	Warnings      []string             `json:"warnings"`
+}
+
+// GetPodDistribution groups the pods selected by a workload by node, zone
+// and architecture (from node labels). Workloads with more than one
+// replica are flagged when all pods share a node or a zone, since a
+// single failure would then take them all down.
+func (c *Client) GetPodDistribution(ref ResourceRef) (*PodDistribution, error) {
+	res, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	obj := res.(map[string]interface{})
+
+	rawSelector, found, _ := unstructured.NestedMap(obj, "spec", "selector")
+	if !found {
+		return nil, fmt.Errorf("%s %s has no pod selector", ref.Kind, ref.Name)
+	}
+	var labelSelector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawSelector, &labelSelector); err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+
+	var pods *corev1.PodList
+	var nodes *corev1.NodeList
+	err = c.do(OpList, func(ctx context.Context) error {
+		var err error
+		pods, err = c.Clientset.CoreV1().Pods(ref.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return err
+		}
+		nodes, err = c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	nodeLabels := make(map[string]map[string]string, len(nodes.Items))
+	for _, n := range nodes.Items {
+		nodeLabels[n.Name] = n.Labels
+	}
+
+	dist := &PodDistribution{Kind: ref.Kind, Name: ref.Name, Namespace: ref.Namespace, Warnings: []string{}}
+	dist.Replicas, found, _ = unstructured.NestedInt64(obj, "spec", "replicas")
+	if !found {
+		dist.Replicas = int64(len(pods.Items))
+	}
+
+	byNode := make(map[string][]string)
+	byZone := make(map[string][]string)
+	byArch := make(map[string][]string)
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if pod.Spec.NodeName == "" {
+			dist.PendingPods++
+			continue
+		}
+		dist.ScheduledPods++
+		labels := nodeLabels[pod.Spec.NodeName]
+		zone := labels[zoneLabel]
+		if zone == "" {
+			zone = labels[legacyZoneLabel]
+		}
+		if zone == "" {
+			zone = "unknown"
+		}
+		arch := labels[archLabel]
+		if arch == "" {
+			arch = "unknown"
+		}
+		byNode[pod.Spec.NodeName] = append(byNode[pod.Spec.NodeName], pod.Name)
+		byZone[zone] = append(byZone[zone], pod.Name)
+		byArch[arch] = append(byArch[arch], pod.Name)
+	}
+	dist.Nodes = distributionBuckets(byNode)
+	dist.Zones = distributionBuckets(byZone)
+	dist.Architectures = distributionBuckets(byArch)
+
+	if dist.Replicas > 1 && dist.ScheduledPods > 1 {
+		if len(dist.Nodes) == 1 {
+			dist.Warnings = append(dist.Warnings, fmt.Sprintf("all %d pods run on node %s", dist.ScheduledPods, dist.Nodes[0].Key))
+		}
+		if len(dist.Zones) == 1 && dist.Zones[0].Key != "unknown" && len(zonesOf(nodeLabels)) > 1 {
+			dist.Warnings = append(dist.Warnings, fmt.Sprintf("all %d pods run in zone %s", dist.ScheduledPods, dist.Zones[0].Key))
+		}
+	}
+	return dist, nil
+}
+
+// zonesOf returns the distinct zones of the cluster's nodes, so single-zone
+// clusters aren't flagged for something the workload can't avoid.
+func zonesOf(nodeLabels map[string]map[string]string) map[string]bool {
+	zones := make(map[string]bool)
+	for _, labels := range nodeLabels {
+		if zone := labels[zoneLabel]; zone != "" {
+			zones[zone] = true
+		} else if zone := labels[legacyZoneLabel]; zone != "" {
+			zones[zone] = true
+		}
+	}
+	return zones
+}
+
+func distributionBuckets(groups map[string][]string) []DistributionBucket {
+	buckets := make([]DistributionBucket, 0, len(groups))
+	for key, pods := range groups {
+		sort.Strings(pods)
+		buckets = append(buckets, DistributionBucket{Key: key, Pods: pods})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if len(buckets[i].Pods) != len(buckets[j].Pods) {
+			return len(buckets[i].Pods) > len(buckets[j].Pods)
+		}
+		return buckets[i].Key < buckets[j].Key
+	})
+	return buckets
+}
@@ -0,0 +1,247 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	EventNodeDrain = "node:drain"
+
+	defaultDrainTimeout = 10 * time.Minute
+	// evictionRetryInterval is how long to wait before retrying an
+	// eviction refused by a PodDisruptionBudget.
+	evictionRetryInterval = 5 * time.Second
+	mirrorPodAnnotation   = "kubernetes.io/config.mirror"
+)
+
+// Drain progress phases of a pod.
+const (
+	DrainEvicting = "evicting"
+	DrainBlocked  = "blocked"
+	DrainEvicted  = "evicted"
+	DrainFailed   = "failed"
+)
+
+// DrainOptions mirror the flags of `kubectl drain`.
+type DrainOptions struct {
+	// GracePeriodSeconds overrides the pods' terminationGracePeriodSeconds
+	// when set.
+	GracePeriodSeconds *int64 `json:"grace_period_seconds,omitempty"`
+	// IgnoreDaemonSets skips DaemonSet pods instead of refusing to drain;
+	// the DaemonSet controller would recreate them anyway.
+	IgnoreDaemonSets bool `json:"ignore_daemonsets"`
+	// DeleteEmptyDirData allows evicting pods with emptyDir volumes, whose
+	// data is lost.
+	DeleteEmptyDirData bool `json:"delete_emptydir_data"`
+	// Force allows evicting pods not managed by a controller, which won't
+	// be recreated.
+	Force          bool `json:"force"`
+	TimeoutSeconds int  `json:"timeout_seconds,omitempty"`
+}
+
+// DrainProgress is emitted as EventNodeDrain for every pod state change
+// and once more with Done set when the drain finishes.
+type DrainProgress struct {
+	DrainID   string `json:"drain_id"`
+	Node      string `json:"node"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Phase     string `json:"phase,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Remaining int    `json:"remaining"`
+	Done      bool   `json:"done"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (c *Client) CordonNode(name string) error {
+	return c.setUnschedulable(name, true)
+}
+
+func (c *Client) UncordonNode(name string) error {
+	return c.setUnschedulable(name, false)
+}
+
+func (c *Client) setUnschedulable(name string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	ref := ResourceRef{Version: "v1", Kind: "Node", Plural: "nodes", Name: name}
+	description := "uncordon " + name
+	if unschedulable {
+		description = "cordon " + name
+	}
+	return c.mutate(ActionCordon, ref, description, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.CoreV1().Nodes().Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+			return err
+		})
+	})
+}
+
+// DrainNode cordons a node and evicts its pods through the Eviction API, so
+// PodDisruptionBudgets are respected: evictions they refuse are retried
+// until the timeout. Pods that kubectl drain would refuse to remove
+// without a flag are reported up front and nothing is changed. Progress is
+// streamed as EventNodeDrain; the returned ID is a subscription ID, so
+// StopSubscription aborts the drain (the node stays cordoned).
+func (c *Client) DrainNode(name string, opts DrainOptions) (string, error) {
+	var pods *corev1.PodList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		pods, err = c.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+		})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var evict []corev1.Pod
+	var problems []string
+	for _, pod := range pods.Items {
+		skip, problem := drainFilter(pod, opts)
+		if problem != "" {
+			problems = append(problems, fmt.Sprintf("%s/%s (%s)", pod.Namespace, pod.Name, problem))
+		}
+		if !skip {
+			evict = append(evict, pod)
+		}
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("cannot drain %s: %s", name, strings.Join(problems, ", "))
+	}
+
+	if err := c.CordonNode(name); err != nil {
+		return "", fmt.Errorf("failed to cordon %s: %v", name, err)
+	}
+
+	timeout := defaultDrainTimeout
+	if opts.TimeoutSeconds > 0 {
+		timeout = time.Duration(opts.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	id := c.subs.add("drain", cancel)
+
+	go func() {
+		defer c.subs.remove(id)
+		defer cancel()
+		err := c.evictPods(ctx, id, name, evict, opts.GracePeriodSeconds)
+		done := DrainProgress{DrainID: id, Node: name, Done: true}
+		if err != nil {
+			done.Error = err.Error()
+		}
+		c.emit(EventNodeDrain, done)
+	}()
+	return id, nil
+}
+
+// drainFilter decides whether a pod is left alone and, if it can't be
+// evicted with the given options, why.
+func drainFilter(pod corev1.Pod, opts DrainOptions) (skip bool, problem string) {
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return true, ""
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false, ""
+	}
+	controller := metav1.GetControllerOf(&pod)
+	if controller != nil && controller.Kind == "DaemonSet" {
+		if opts.IgnoreDaemonSets {
+			return true, ""
+		}
+		return true, "DaemonSet pod"
+	}
+	if controller == nil && !opts.Force {
+		return true, "not managed by a controller"
+	}
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir != nil && !opts.DeleteEmptyDirData {
+			return true, "uses emptyDir " + v.Name
+		}
+	}
+	return false, ""
+}
+
+// evictPods evicts every pod and waits for them to be gone.
+func (c *Client) evictPods(ctx context.Context, id, node string, pods []corev1.Pod, gracePeriod *int64) error {
+	remaining := make(map[types.UID]corev1.Pod, len(pods))
+	for _, pod := range pods {
+		remaining[pod.UID] = pod
+	}
+	progress := func(pod corev1.Pod, phase, message string) {
+		c.emit(EventNodeDrain, DrainProgress{
+			DrainID: id, Node: node, Namespace: pod.Namespace, Pod: pod.Name,
+			Phase: phase, Message: message, Remaining: len(remaining),
+		})
+	}
+
+	pending := pods
+	evicting := make(map[types.UID]bool)
+	for len(remaining) > 0 {
+		var blocked []corev1.Pod
+		for _, pod := range pending {
+			ref := ResourceRef{Version: "v1", Kind: "Pod", Plural: "pods", Namespace: pod.Namespace, Name: pod.Name}
+			err := c.mutate(ActionEvict, ref, fmt.Sprintf("evict pods/%s from %s", pod.Name, node), func() error {
+				return c.Clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, &policyv1.Eviction{
+					ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+					DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: gracePeriod},
+				})
+			})
+			switch {
+			case err == nil:
+				evicting[pod.UID] = true
+				progress(pod, DrainEvicting, "")
+			case apierrors.IsNotFound(err):
+				delete(remaining, pod.UID)
+				progress(pod, DrainEvicted, "")
+			case apierrors.IsTooManyRequests(err):
+				// A PodDisruptionBudget doesn't allow the disruption yet.
+				blocked = append(blocked, pod)
+				progress(pod, DrainBlocked, err.Error())
+			default:
+				if ctx.Err() != nil {
+					return drainAborted(ctx)
+				}
+				progress(pod, DrainFailed, err.Error())
+				return fmt.Errorf("failed to evict %s/%s: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+		pending = blocked
+
+		select {
+		case <-ctx.Done():
+			return drainAborted(ctx)
+		case <-time.After(evictionRetryInterval):
+		}
+
+		// Check which evicted pods are gone. A pod replaced by one with
+		// the same name has a new UID and counts as gone.
+		for uid, pod := range remaining {
+			if !evicting[uid] {
+				continue
+			}
+			current, err := c.Clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || (err == nil && current.UID != uid) {
+				delete(remaining, uid)
+				progress(pod, DrainEvicted, "")
+			}
+		}
+	}
+	return nil
+}
+
+func drainAborted(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out; the node stays cordoned")
+	}
+	return fmt.Errorf("drain stopped; the node stays cordoned")
+}
@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDrainFilter(t *testing.T) {
+	controlled := func(kind string) []metav1.OwnerReference {
+		controller := true
+		return []metav1.OwnerReference{{Kind: kind, Name: "owner", Controller: &controller}}
+	}
+	emptyDir := []corev1.Volume{{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}
+
+	tests := []struct {
+		name        string
+		pod         corev1.Pod
+		opts        DrainOptions
+		wantSkip    bool
+		wantProblem string
+	}{
+		{
+			name: "replicaset pod",
+			pod:  corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("ReplicaSet")}},
+		},
+		{
+			name:     "mirror pod",
+			pod:      corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{mirrorPodAnnotation: "x"}}},
+			wantSkip: true,
+		},
+		{
+			name: "finished bare pod",
+			pod:  corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}},
+		},
+		{
+			name:        "daemonset pod",
+			pod:         corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("DaemonSet")}},
+			wantSkip:    true,
+			wantProblem: "DaemonSet pod",
+		},
+		{
+			name:     "daemonset pod ignored",
+			pod:      corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("DaemonSet")}},
+			opts:     DrainOptions{IgnoreDaemonSets: true},
+			wantSkip: true,
+		},
+		{
+			name:        "bare pod",
+			pod:         corev1.Pod{},
+			wantSkip:    true,
+			wantProblem: "not managed by a controller",
+		},
+		{
+			name: "bare pod forced",
+			pod:  corev1.Pod{},
+			opts: DrainOptions{Force: true},
+		},
+		{
+			name:        "emptyDir",
+			pod:         corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("ReplicaSet")}, Spec: corev1.PodSpec{Volumes: emptyDir}},
+			wantSkip:    true,
+			wantProblem: "uses emptyDir cache",
+		},
+		{
+			name: "emptyDir allowed",
+			pod:  corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: controlled("ReplicaSet")}, Spec: corev1.PodSpec{Volumes: emptyDir}},
+			opts: DrainOptions{DeleteEmptyDirData: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, problem := drainFilter(tt.pod, tt.opts)
+			if skip != tt.wantSkip || problem != tt.wantProblem {
+				t.Errorf("drainFilter() = (%v, %q), want (%v, %q)", skip, problem, tt.wantSkip, tt.wantProblem)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	familyIPv4 = "IPv4"
+	familyIPv6 = "IPv6"
+)
+
+type ServiceNetworking struct {
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`
+	IPFamilies     []string `json:"ip_families"`
+	IPFamilyPolicy string   `json:"ip_family_policy"`
+	ClusterIPs     []string `json:"cluster_ips"`
+	ExternalIPs    []string `json:"external_ips,omitempty"`
+	// PodFamilies are the IP families the selected pods have addresses
+	// in; empty for Services without a selector or without pods.
+	PodFamilies []string `json:"pod_families"`
+	Pods        int      `json:"pods"`
+	Warnings    []string `json:"warnings"`
+}
+
+type DualStackReport struct {
+	Namespace string `json:"namespace"`
+	// ClusterFamilies are the families found in node pod CIDRs; two of
+	// them mean the cluster is dual-stack.
+	ClusterFamilies []string            `json:"cluster_families"`
+	DualStack       bool                `json:"dual_stack"`
+	Services        []ServiceNetworking `json:"services"`
+}
+
+// GetDualStackReport lists the IP families and ClusterIPs of every
+// Service in a namespace and flags Services whose families don't match
+// the addresses of the pods behind them, e.g. an IPv6 ClusterIP in front
+// of IPv4-only pods.
+func (c *Client) GetDualStackReport(namespace string) (*DualStackReport, error) {
+	var services *corev1.ServiceList
+	var pods *corev1.PodList
+	var nodes *corev1.NodeList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		services, err = c.Clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.do(OpList, func(ctx context.Context) error {
+		var err error
+		pods, err = c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DualStackReport{Namespace: namespace, ClusterFamilies: []string{}, Services: []ServiceNetworking{}}
+	clusterFamilies := make(map[string]bool)
+	// Listing nodes may be forbidden; Services with two ClusterIPs still
+	// reveal a dual-stack cluster.
+	err = c.do(OpList, func(ctx context.Context) error {
+		var err error
+		nodes, err = c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err == nil {
+		for _, node := range nodes.Items {
+			for _, cidr := range node.Spec.PodCIDRs {
+				if ip, _, err := net.ParseCIDR(cidr); err == nil {
+					clusterFamilies[ipFamily(ip.String())] = true
+				}
+			}
+		}
+	}
+	for _, svc := range services.Items {
+		for _, ip := range svc.Spec.ClusterIPs {
+			if f := ipFamily(ip); f != "" {
+				clusterFamilies[f] = true
+			}
+		}
+	}
+	report.ClusterFamilies = sortedKeys(clusterFamilies)
+	report.DualStack = len(report.ClusterFamilies) > 1
+
+	for _, svc := range services.Items {
+		entry := ServiceNetworking{
+			Name:        svc.Name,
+			Type:        string(svc.Spec.Type),
+			IPFamilies:  []string{},
+			ClusterIPs:  svc.Spec.ClusterIPs,
+			ExternalIPs: svc.Spec.ExternalIPs,
+			PodFamilies: []string{},
+			Warnings:    []string{},
+		}
+		if entry.ClusterIPs == nil {
+			entry.ClusterIPs = []string{}
+		}
+		for _, f := range svc.Spec.IPFamilies {
+			entry.IPFamilies = append(entry.IPFamilies, string(f))
+		}
+		if svc.Spec.IPFamilyPolicy != nil {
+			entry.IPFamilyPolicy = string(*svc.Spec.IPFamilyPolicy)
+		}
+		for _, ing := range svc.Status.LoadBalancer.Ingress {
+			if ing.IP != "" {
+				entry.ExternalIPs = append(entry.ExternalIPs, ing.IP)
+			}
+		}
+
+		if len(svc.Spec.Selector) > 0 {
+			selector := labels.SelectorFromSet(svc.Spec.Selector)
+			podFamilies := make(map[string]bool)
+			for _, pod := range pods.Items {
+				if pod.Status.Phase != corev1.PodRunning || !selector.Matches(labels.Set(pod.Labels)) {
+					continue
+				}
+				entry.Pods++
+				for _, ip := range pod.Status.PodIPs {
+					if f := ipFamily(ip.IP); f != "" {
+						podFamilies[f] = true
+					}
+				}
+			}
+			entry.PodFamilies = sortedKeys(podFamilies)
+			if entry.Pods > 0 {
+				for _, f := range entry.IPFamilies {
+					if !podFamilies[f] {
+						entry.Warnings = append(entry.Warnings, fmt.Sprintf("the Service has an %s ClusterIP but its pods have no %s address; that traffic can't reach them", f, f))
+					}
+				}
+			}
+		}
+
+		if report.DualStack && len(entry.IPFamilies) == 1 && entry.IPFamilyPolicy == string(corev1.IPFamilyPolicyPreferDualStack) {
+			entry.Warnings = append(entry.Warnings, "PreferDualStack on a dual-stack cluster but only one family is assigned; the Service was probably created before dual-stack was enabled")
+		}
+		if !report.DualStack && entry.IPFamilyPolicy == string(corev1.IPFamilyPolicyRequireDualStack) {
+			entry.Warnings = append(entry.Warnings, "RequireDualStack on a cluster that doesn't look dual-stack")
+		}
+		report.Services = append(report.Services, entry)
+	}
+	sort.Slice(report.Services, func(i, j int) bool { return report.Services[i].Name < report.Services[j].Name })
+	return report, nil
+}
+
+// ipFamily returns IPv4 or IPv6 for an address, or "" if it isn't one
+// (e.g. the "None" ClusterIP of headless Services).
+func ipFamily(addr string) string {
+	ip := net.ParseIP(addr)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return familyIPv4
+	default:
+		return familyIPv6
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
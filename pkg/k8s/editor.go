@@ -0,0 +1,248 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Field diff operations.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// ignoredDiffPaths are maintained by the server and never part of an
+// edit.
+var ignoredDiffPaths = map[string]bool{
+	"metadata.managedFields":     true,
+	"metadata.resourceVersion":   true,
+	"metadata.generation":        true,
+	"metadata.uid":               true,
+	"metadata.creationTimestamp": true,
+	"status":                     true,
+}
+
+type ResourceYAML struct {
+	YAML            string `json:"yaml"`
+	ResourceVersion string `json:"resource_version"`
+}
+
+type FieldDiff struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ManifestValidation is the outcome of a server-side dry run of an edit.
+type ManifestValidation struct {
+	Valid     bool                   `json:"valid"`
+	Error     string                 `json:"error,omitempty"`
+	Immutable []ImmutableFieldChange `json:"immutable"`
+	Diff      []FieldDiff            `json:"diff"`
+}
+
+// UpdateResult reports an edit. When Conflict is set the object changed on
+// the server since it was loaded; Diff then compares the live object with
+// the edit so the user can merge by hand.
+type UpdateResult struct {
+	Object   interface{} `json:"object,omitempty"`
+	Conflict bool        `json:"conflict"`
+	Diff     []FieldDiff `json:"diff"`
+}
+
+// GetResourceYAML returns an object as YAML for the in-app editor, without
+// managedFields, along with the resourceVersion to send back on update.
+// Secret values are blanked; saving puts the unchanged ones back.
+func (c *Client) GetResourceYAML(ref ResourceRef) (*ResourceYAML, error) {
+	res, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: res.(map[string]interface{})}
+	obj.SetManagedFields(nil)
+	RedactSecret(obj.Object)
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceYAML{YAML: string(data), ResourceVersion: obj.GetResourceVersion()}, nil
+}
+
+// ValidateManifest checks an edited object without persisting it: it
+// reports immutable field changes, runs the update as a server-side dry
+// run (schema validation, admission webhooks) and diffs the edit against
+// the live object.
+func (c *Client) ValidateManifest(ref ResourceRef, manifest string) (*ManifestValidation, error) {
+	edited, err := decodeEdit(ref, manifest)
+	if err != nil {
+		return &ManifestValidation{Error: err.Error(), Immutable: []ImmutableFieldChange{}, Diff: []FieldDiff{}}, nil
+	}
+	current, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	live := current.(map[string]interface{})
+	restoreRedactedSecret(edited.Object, live)
+
+	result := &ManifestValidation{
+		Immutable: FindImmutableFieldChanges(live, edited.Object),
+		Diff:      redactSecretDiffs(ref, DiffObjects(live, edited.Object)),
+	}
+	if result.Immutable == nil {
+		result.Immutable = []ImmutableFieldChange{}
+	}
+	if _, err := c.updateObject(ref, edited, true); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Valid = len(result.Immutable) == 0
+	return result, nil
+}
+
+// UpdateResourceFromYAML saves an edited object. resourceVersion is the
+// version the editor loaded; if the object changed since, nothing is
+// written and a conflict is reported. Edits touching immutable fields are
+// refused; RecreateResource handles those.
+func (c *Client) UpdateResourceFromYAML(ref ResourceRef, manifest, resourceVersion string) (*UpdateResult, error) {
+	edited, err := decodeEdit(ref, manifest)
+	if err != nil {
+		return nil, err
+	}
+	current, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	live := current.(map[string]interface{})
+	restoreRedactedSecret(edited.Object, live)
+
+	if changes := FindImmutableFieldChanges(live, edited.Object); len(changes) > 0 {
+		paths := make([]string, len(changes))
+		for i, ch := range changes {
+			paths[i] = ch.Path
+		}
+		return nil, fmt.Errorf("immutable fields changed: %s", strings.Join(paths, ", "))
+	}
+
+	if resourceVersion != "" {
+		edited.SetResourceVersion(resourceVersion)
+	}
+	var updated *unstructured.Unstructured
+	err = c.mutate(ActionUpdate, ref, fmt.Sprintf("edit %s/%s", ref.Plural, ref.Name), func() error {
+		var err error
+		updated, err = c.updateObject(ref, edited, false)
+		return err
+	})
+	if apierrors.IsConflict(err) {
+		return &UpdateResult{Conflict: true, Diff: redactSecretDiffs(ref, DiffObjects(live, edited.Object))}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	diff := redactSecretDiffs(ref, DiffObjects(live, updated.Object))
+	RedactSecret(updated.Object)
+	return &UpdateResult{Object: updated.Object, Diff: diff}, nil
+}
+
+// decodeEdit parses a single-object manifest and makes sure it still
+// names the object being edited.
+func decodeEdit(ref ResourceRef, manifest string) (*unstructured.Unstructured, error) {
+	objects, err := decodeManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) != 1 {
+		return nil, fmt.Errorf("expected exactly one object, got %d", len(objects))
+	}
+	obj := objects[0]
+	if obj.GetName() != ref.Name {
+		return nil, fmt.Errorf("metadata.name must stay %q", ref.Name)
+	}
+	if ns := obj.GetNamespace(); ns != "" && ns != ref.Namespace {
+		return nil, fmt.Errorf("metadata.namespace must stay %q", ref.Namespace)
+	}
+	if obj.GetKind() != "" && ref.Kind != "" && obj.GetKind() != ref.Kind {
+		return nil, fmt.Errorf("kind must stay %q", ref.Kind)
+	}
+	return obj, nil
+}
+
+func (c *Client) updateObject(ref ResourceRef, obj *unstructured.Unstructured, dryRun bool) (*unstructured.Unstructured, error) {
+	opts := metav1.UpdateOptions{}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	var updated *unstructured.Unstructured
+	err := c.do(OpMutate, func(ctx context.Context) error {
+		var err error
+		if ref.Namespace != "" {
+			updated, err = c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Update(ctx, obj, opts)
+		} else {
+			updated, err = c.DynamicClient.Resource(ref.GVR()).Update(ctx, obj, opts)
+		}
+		return err
+	})
+	return updated, err
+}
+
+// DiffObjects lists the fields that differ between two versions of an
+// object, ignoring server-maintained metadata and status. Lists of equal
+// length are compared element by element; otherwise the whole list is
+// reported as changed.
+func DiffObjects(from, to map[string]interface{}) []FieldDiff {
+	diffs := []FieldDiff{}
+	diffValue("", from, to, &diffs)
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+func diffValue(path string, from, to interface{}, diffs *[]FieldDiff) {
+	if ignoredDiffPaths[path] || reflect.DeepEqual(from, to) {
+		return
+	}
+	switch a := from.(type) {
+	case map[string]interface{}:
+		if b, ok := to.(map[string]interface{}); ok {
+			for key, av := range a {
+				child := joinFieldPath(path, key)
+				if bv, ok := b[key]; ok {
+					diffValue(child, av, bv, diffs)
+				} else if !ignoredDiffPaths[child] {
+					*diffs = append(*diffs, FieldDiff{Path: child, Op: DiffRemoved, Old: av})
+				}
+			}
+			for key, bv := range b {
+				child := joinFieldPath(path, key)
+				if _, ok := a[key]; !ok && !ignoredDiffPaths[child] {
+					*diffs = append(*diffs, FieldDiff{Path: child, Op: DiffAdded, New: bv})
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if b, ok := to.([]interface{}); ok && len(a) == len(b) {
+			for i := range a {
+				diffValue(path+"["+strconv.Itoa(i)+"]", a[i], b[i], diffs)
+			}
+			return
+		}
+	}
+	*diffs = append(*diffs, FieldDiff{Path: path, Op: DiffChanged, Old: from, New: to})
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,254 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// etcdObjectLimit is the practical size limit of one object: the API
+	// server rejects ConfigMaps and Secrets over 1 MiB, and etcd's default
+	// request limit of 1.5 MiB leaves little room above that.
+	etcdObjectLimit = 1024 * 1024
+
+	// Objects above these fractions of the limit are reported.
+	largeObjectRatio    = 0.5
+	criticalObjectRatio = 0.9
+
+	// highObjectCount is the count above which a kind is flagged.
+	highObjectCount = 5000
+
+	etcdScanPageSize = 250
+)
+
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+type LargeObject struct {
+	Group     string  `json:"group"`
+	Version   string  `json:"version"`
+	Kind      string  `json:"kind"`
+	Plural    string  `json:"plural"`
+	Namespace string  `json:"namespace"`
+	Name      string  `json:"name"`
+	Bytes     int     `json:"bytes"`
+	Percent   float64 `json:"percent"`
+	Severity  string  `json:"severity"`
+}
+
+type KindCount struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	Plural  string `json:"plural"`
+	Count   int64  `json:"count"`
+	High    bool   `json:"high"`
+}
+
+// EtcdPressureReport approximates what an etcd operator would see:
+// objects close to the size limit and kinds with many objects.
+type EtcdPressureReport struct {
+	LimitBytes   int               `json:"limit_bytes"`
+	LargeObjects []LargeObject     `json:"large_objects"`
+	KindCounts   []KindCount       `json:"kind_counts"`
+	TotalObjects int64             `json:"total_objects"`
+	Warnings     []string          `json:"warnings"`
+	Errors       map[string]string `json:"errors,omitempty"`
+}
+
+// GetEtcdPressureReport scans ConfigMaps, Secrets and custom resources
+// for objects over half of the 1 MiB limit, and counts the objects of
+// every listable kind. Sizes are those of the JSON encoding; built-in
+// kinds are stored as protobuf and are somewhat smaller in etcd. Counts
+// come from the server's remaining-item estimate, so only one object per
+// kind is transferred. Each kind is counted and scanned in its preferred
+// version only, as every version serves the same stored objects.
+func (c *Client) GetEtcdPressureReport() (*EtcdPressureReport, error) {
+	resources, err := c.preferredApiResources()
+	if err != nil {
+		return nil, err
+	}
+	scan := []ApiResourceInfo{}
+	crds := c.customResourcePlurals()
+	for _, res := range resources {
+		if (res.Group == "" && (res.Name == "configmaps" || res.Name == "secrets")) || crds[schema.GroupResource{Group: res.Group, Resource: res.Name}] {
+			scan = append(scan, res)
+		}
+	}
+
+	report := &EtcdPressureReport{
+		LimitBytes:   etcdObjectLimit,
+		LargeObjects: []LargeObject{},
+		KindCounts:   []KindCount{},
+		Warnings:     []string{},
+		Errors:       make(map[string]string),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, ownerIndexWorkers)
+	run := func(res ApiResourceInfo, fn func(res ApiResourceInfo) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := fn(res); err != nil {
+				mu.Lock()
+				report.Errors[schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}.String()] = err.Error()
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, res := range resources {
+		run(res, func(res ApiResourceInfo) error {
+			count, err := c.countObjects(res)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.TotalObjects += count
+			report.KindCounts = append(report.KindCounts, KindCount{
+				Group:   res.Group,
+				Version: res.Version,
+				Kind:    res.Kind,
+				Plural:  res.Name,
+				Count:   count,
+				High:    count > highObjectCount,
+			})
+			return nil
+		})
+	}
+	for _, res := range scan {
+		run(res, func(res ApiResourceInfo) error {
+			large, err := c.findLargeObjects(res)
+			mu.Lock()
+			report.LargeObjects = append(report.LargeObjects, large...)
+			mu.Unlock()
+			return err
+		})
+	}
+	wg.Wait()
+
+	sort.Slice(report.LargeObjects, func(i, j int) bool {
+		return report.LargeObjects[i].Bytes > report.LargeObjects[j].Bytes
+	})
+	sort.Slice(report.KindCounts, func(i, j int) bool {
+		if report.KindCounts[i].Count != report.KindCounts[j].Count {
+			return report.KindCounts[i].Count > report.KindCounts[j].Count
+		}
+		return report.KindCounts[i].Kind < report.KindCounts[j].Kind
+	})
+
+	for _, o := range report.LargeObjects {
+		if o.Severity == "critical" {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s %s/%s is at %.0f%% of the object size limit", o.Kind, o.Namespace, o.Name, o.Percent))
+		}
+	}
+	for _, k := range report.KindCounts {
+		if k.High {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%d %s objects stored; large kinds slow down lists, watches and etcd compaction", k.Count, k.Kind))
+		}
+	}
+	if len(report.Errors) == 0 {
+		report.Errors = nil
+	}
+	return report, nil
+}
+
+// customResourcePlurals returns the resources defined by CRDs. It is
+// empty when CRDs can't be listed.
+func (c *Client) customResourcePlurals() map[schema.GroupResource]bool {
+	result := make(map[schema.GroupResource]bool)
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return result
+	}
+	for _, crd := range list.Items {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+		result[schema.GroupResource{Group: group, Resource: plural}] = true
+	}
+	return result
+}
+
+// countObjects lists a single object and adds the server's estimate of
+// the remaining ones.
+func (c *Client) countObjects(res ApiResourceInfo) (int64, error) {
+	gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{Limit: 1})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	count := int64(len(list.Items))
+	if remaining := list.GetRemainingItemCount(); remaining != nil {
+		count += *remaining
+	}
+	return count, nil
+}
+
+// findLargeObjects pages through a resource and returns the objects over
+// largeObjectRatio of the limit.
+func (c *Client) findLargeObjects(res ApiResourceInfo) ([]LargeObject, error) {
+	gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}
+	var result []LargeObject
+	continueToken := ""
+	for {
+		var list *unstructured.UnstructuredList
+		err := c.do(OpList, func(ctx context.Context) error {
+			var err error
+			list, err = c.DynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{Limit: etcdScanPageSize, Continue: continueToken})
+			return err
+		})
+		if err != nil {
+			return result, err
+		}
+		for _, item := range list.Items {
+			// managedFields are stored too, so they count.
+			data, err := json.Marshal(item.Object)
+			if err != nil {
+				continue
+			}
+			ratio := float64(len(data)) / etcdObjectLimit
+			if ratio < largeObjectRatio {
+				continue
+			}
+			severity := "warning"
+			if ratio >= criticalObjectRatio {
+				severity = "critical"
+			}
+			result = append(result, LargeObject{
+				Group:     res.Group,
+				Version:   res.Version,
+				Kind:      res.Kind,
+				Plural:    res.Name,
+				Namespace: item.GetNamespace(),
+				Name:      item.GetName(),
+				Bytes:     len(data),
+				Percent:   ratio * 100,
+				Severity:  severity,
+			})
+		}
+		continueToken = list.GetContinue()
+		if continueToken == "" {
+			return result, nil
+		}
+	}
+}
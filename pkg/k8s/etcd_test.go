@@ -0,0 +1,18 @@
+package k8s
+
+import "testing"
+
+func TestEtcdPressureReportCountsEachKindOnce(t *testing.T) {
+	c := twoVersionClient()
+
+	report, err := c.GetEtcdPressureReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalObjects != 1 {
+		t.Errorf("TotalObjects = %d, want 1", report.TotalObjects)
+	}
+	if len(report.KindCounts) != 1 || report.KindCounts[0].Version != "v2" || report.KindCounts[0].Count != 1 {
+		t.Errorf("KindCounts = %+v, want one autoscaling/v2 entry counting 1", report.KindCounts)
+	}
+}
@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ArchivedEvent is the subset of a core Event kept by the local event
+// archive.
+type ArchivedEvent struct {
+	UID            string    `json:"uid"`
+	Namespace      string    `json:"namespace"`
+	Type           string    `json:"type"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Count          int32     `json:"count"`
+	Source         string    `json:"source,omitempty"`
+	ObjectKind     string    `json:"object_kind"`
+	ObjectName     string    `json:"object_name"`
+	FirstTimestamp time.Time `json:"first_timestamp"`
+	LastTimestamp  time.Time `json:"last_timestamp"`
+}
+
+func archivedEvent(e *corev1.Event) ArchivedEvent {
+	first := e.FirstTimestamp.Time
+	if first.IsZero() {
+		first = e.EventTime.Time
+	}
+	count := e.Count
+	if e.Series != nil {
+		count = e.Series.Count
+	}
+	source := e.Source.Component
+	if source == "" {
+		source = e.ReportingController
+	}
+	return ArchivedEvent{
+		UID:            string(e.UID),
+		Namespace:      e.Namespace,
+		Type:           e.Type,
+		Reason:         e.Reason,
+		Message:        e.Message,
+		Count:          count,
+		Source:         source,
+		ObjectKind:     e.InvolvedObject.Kind,
+		ObjectName:     e.InvolvedObject.Name,
+		FirstTimestamp: first,
+		LastTimestamp:  eventTimestamp(e),
+	}
+}
+
+// CollectEvents watches events in the given namespaces (all namespaces
+// when the list is empty or contains "") and hands every added or updated
+// event to sink. It returns a subscription ID for StopSubscription.
+func (c *Client) CollectEvents(namespaces []string, sink func(ArchivedEvent)) (string, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	for _, ns := range namespaces {
+		if ns == "" {
+			namespaces = []string{""}
+			break
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	id := c.subs.add("events", cancel, gvr)
+
+	for _, ns := range namespaces {
+		ns := ns
+		go c.watchLoop(ctx, id, func(ctx context.Context, rv string) (watch.Interface, error) {
+			return c.Clientset.CoreV1().Events(ns).Watch(ctx, metav1.ListOptions{ResourceVersion: rv})
+		}, func(ev watch.Event) {
+			if ev.Type != watch.Added && ev.Type != watch.Modified {
+				return
+			}
+			if e, ok := ev.Object.(*corev1.Event); ok {
+				sink(archivedEvent(e))
+			}
+		}, nil)
+	}
+	return id, nil
+}
@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// execCapture runs a non-interactive command in a container and returns
+// its stdout and stderr.
+func (c *Client) execCapture(ctx context.Context, namespace, podName, containerName string, command []string, stdin io.Reader) ([]byte, []byte, error) {
+	var stdout bytes.Buffer
+	stderr, err := c.execStream(ctx, namespace, podName, containerName, command, stdin, &stdout)
+	return stdout.Bytes(), stderr, err
+}
+
+// execStream runs a non-interactive command in a container, streaming its
+// stdout to w, and returns its stderr.
+func (c *Client) execStream(ctx context.Context, namespace, podName, containerName string, command []string, stdin io.Reader, stdout io.Writer) ([]byte, error) {
+	if c.RestConfig == nil {
+		return nil, errors.New("exec is not available for this context")
+	}
+
+	req := c.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := newSPDYExecutor(c.RestConfig, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: &stderr,
+	})
+	return stderr.Bytes(), err
+}
@@ -0,0 +1,264 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// EventExecCredentialError reports a failing or hanging kubeconfig exec
+	// credential plugin, with its stderr.
+	EventExecCredentialError = "kubeconfig:exec-error"
+
+	execPluginTimeout = 30 * time.Second
+	// execLoginTimeout bounds an interactive plugin run, which may wait
+	// for the user to finish a browser login.
+	execLoginTimeout = 5 * time.Minute
+	// execCredentialSkew refreshes credentials slightly before they expire.
+	execCredentialSkew = 30 * time.Second
+)
+
+type ExecCredentialError struct {
+	Context string `json:"context"`
+	Command string `json:"command"`
+	Stderr  string `json:"stderr"`
+	Error   string `json:"error"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+type execCredential struct {
+	token    string
+	certData []byte
+	keyData  []byte
+	// expiry is zero when the plugin didn't set one; such credentials are
+	// kept until the API server rejects them.
+	expiry time.Time
+}
+
+func (cred *execCredential) valid(now time.Time) bool {
+	return cred.expiry.IsZero() || now.Add(execCredentialSkew).Before(cred.expiry)
+}
+
+// execCredentials caches plugin output per context name.
+type execCredentials struct {
+	mu      sync.Mutex
+	entries map[string]*execCredential
+	// running serialises plugin runs per context so concurrent requests
+	// don't each spawn the plugin.
+	running map[string]*sync.Mutex
+}
+
+func (e *execCredentials) lock(contextName string) *sync.Mutex {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running == nil {
+		e.running = make(map[string]*sync.Mutex)
+	}
+	m, ok := e.running[contextName]
+	if !ok {
+		m = &sync.Mutex{}
+		e.running[contextName] = m
+	}
+	return m
+}
+
+func (e *execCredentials) get(contextName string) *execCredential {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cred := e.entries[contextName]
+	if cred == nil || !cred.valid(time.Now()) {
+		return nil
+	}
+	return cred
+}
+
+func (e *execCredentials) set(contextName string, cred *execCredential) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.entries == nil {
+		e.entries = make(map[string]*execCredential)
+	}
+	e.entries[contextName] = cred
+}
+
+func (e *execCredentials) invalidate(contextName string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.entries, contextName)
+}
+
+// execCredential returns a cached credential or runs the plugin for a fresh
+// one.
+func (c *Client) execCredential(contextName string, cfg *clientcmdapi.ExecConfig) (*execCredential, error) {
+	if cred := c.execCreds.get(contextName); cred != nil {
+		return cred, nil
+	}
+	lock := c.execCreds.lock(contextName)
+	lock.Lock()
+	defer lock.Unlock()
+	if cred := c.execCreds.get(contextName); cred != nil {
+		return cred, nil
+	}
+
+	cred, err := c.runExecPlugin(contextName, cfg, false)
+	if err != nil {
+		return nil, err
+	}
+	c.execCreds.set(contextName, cred)
+	return cred, nil
+}
+
+// runExecPlugin runs a kubeconfig exec credential plugin with a timeout
+// and parses the ExecCredential it prints. Only Reauthenticate runs it
+// interactively, letting e.g. oidc-login open a browser.
+func (c *Client) runExecPlugin(contextName string, cfg *clientcmdapi.ExecConfig, interactive bool) (*execCredential, error) {
+	timeout := execPluginTimeout
+	if interactive {
+		timeout = execLoginTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range cfg.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	info, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": cfg.APIVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]interface{}{"interactive": interactive},
+	})
+	cmd.Env = append(cmd.Env, "KUBERNETES_EXEC_INFO="+string(info))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	fail := func(err error) error {
+		report := ExecCredentialError{
+			Context: contextName,
+			Command: strings.Join(append([]string{cfg.Command}, cfg.Args...), " "),
+			Stderr:  strings.TrimSpace(stderr.String()),
+			Error:   err.Error(),
+			Hint:    cfg.InstallHint,
+		}
+		if c.reportTeleportLogin(contextName, cfg, report.Stderr) {
+			return fmt.Errorf("teleport login required for %s: %s", contextName, report.Stderr)
+		}
+		c.emit(EventExecCredentialError, report)
+		if !interactive && !errors.Is(err, exec.ErrNotFound) {
+			c.reportUnauthorized(contextName, "credential plugin "+cfg.Command+" failed: "+err.Error())
+		}
+		if report.Stderr != "" {
+			return fmt.Errorf("credential plugin %s failed: %v: %s", cfg.Command, err, report.Stderr)
+		}
+		return fmt.Errorf("credential plugin %s failed: %v", cfg.Command, err)
+	}
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fail(fmt.Errorf("timed out after %s", timeout))
+		}
+		return nil, fail(err)
+	}
+
+	var out struct {
+		Status struct {
+			Token                 string    `json:"token"`
+			ClientCertificateData string    `json:"clientCertificateData"`
+			ClientKeyData         string    `json:"clientKeyData"`
+			ExpirationTimestamp   time.Time `json:"expirationTimestamp"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fail(fmt.Errorf("invalid ExecCredential output: %v", err))
+	}
+	if out.Status.Token == "" && out.Status.ClientCertificateData == "" {
+		return nil, fail(errors.New("ExecCredential has neither a token nor a client certificate"))
+	}
+	return &execCredential{
+		token:    out.Status.Token,
+		certData: []byte(out.Status.ClientCertificateData),
+		keyData:  []byte(out.Status.ClientKeyData),
+		expiry:   out.Status.ExpirationTimestamp,
+	}, nil
+}
+
+// useCachedExecProvider replaces client-go's exec plugin handling, which
+// has no timeout and hides stderr, with our own. Tokens are injected per
+// request and refreshed on expiry or a 401. Client certificates are
+// fixed at Init, so an expired one needs a reconnect.
+func (c *Client) useCachedExecProvider(restConfig *rest.Config, contextName string) error {
+	cfg := restConfig.ExecProvider
+	restConfig.ExecProvider = nil
+
+	cred, err := c.execCredential(contextName, cfg)
+	if err != nil {
+		return err
+	}
+	if len(cred.certData) > 0 {
+		restConfig.TLSClientConfig.CertData = cred.certData
+		restConfig.TLSClientConfig.KeyData = cred.keyData
+	}
+	if cred.token == "" {
+		return nil
+	}
+
+	restConfig.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return &execTokenRoundTripper{client: c, contextName: contextName, cfg: cfg, next: rt}
+	}
+	return nil
+}
+
+type execTokenRoundTripper struct {
+	client      *Client
+	contextName string
+	cfg         *clientcmdapi.ExecConfig
+	next        http.RoundTripper
+}
+
+func (rt *execTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	cred, err := rt.client.execCredential(rt.contextName, rt.cfg)
+	if err != nil {
+		return nil, err
+	}
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", "Bearer "+cred.token)
+
+	resp, err := rt.next.RoundTrip(authorized)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	rt.client.execCreds.invalidate(rt.contextName)
+	// The token may have been revoked before its expiry; retry once with
+	// a fresh one if the body can be sent again.
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	cred, err = rt.client.execCredential(rt.contextName, rt.cfg)
+	if err != nil {
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	retry.Header.Set("Authorization", "Bearer "+cred.token)
+	return rt.next.RoundTrip(retry)
+}
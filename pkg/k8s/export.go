@@ -0,0 +1,235 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Export rule kinds.
+const (
+	ExportNamespace = "namespace"
+	ExportImageTag  = "image-tag"
+	ExportHost      = "host"
+	ExportRegex     = "regex"
+)
+
+// ExportRule replaces environment-specific values with a ${Variable}
+// placeholder. Pattern is only used by regex rules; for image-tag rules
+// it optionally limits the rule to images whose repository contains it.
+type ExportRule struct {
+	Kind     string `json:"kind"`
+	Variable string `json:"variable"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+// ExportResult is a manifest ready for another environment. Variables maps
+// each placeholder to the values it replaced, as defaults for the target.
+type ExportResult struct {
+	YAML      string              `json:"yaml"`
+	Variables map[string][]string `json:"variables"`
+}
+
+// strippedMetadata are metadata fields set by the server that mean
+// nothing in another cluster.
+var strippedMetadata = []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink"}
+
+// assignedFields are set by the API server or a controller when an object
+// is created, and are either meaningless or rejected elsewhere.
+var assignedFields = [][]string{
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+	{"metadata", "annotations", "pv.kubernetes.io/bind-completed"},
+	{"metadata", "annotations", "pv.kubernetes.io/bound-by-controller"},
+	{"spec", "clusterIP"},
+	{"spec", "clusterIPs"},
+	{"spec", "volumeName"},
+}
+
+// ExportYAML returns an object as a portable manifest: server-populated
+// fields and status are removed and the rules replace namespaces, image
+// tags, hostnames or arbitrary values with ${VAR} placeholders, in the
+// syntax understood by envsubst. Secret values are redacted.
+func (c *Client) ExportYAML(ref ResourceRef, rules []ExportRule) (*ExportResult, error) {
+	res, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	obj := res.(map[string]interface{})
+	RedactSecret(obj)
+	return ApplyExportRules(cleanManifest(obj), rules)
+}
+
+// ExportResource returns an object as YAML for a Git repository. cleaned
+// strips status, server-owned metadata and values the cluster assigned;
+// otherwise only managedFields are dropped. Secret values are redacted
+// either way.
+func (c *Client) ExportResource(ref ResourceRef, cleaned bool) (string, error) {
+	res, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return "", err
+	}
+	data, err := exportManifest(res.(map[string]interface{}), cleaned)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func exportManifest(obj map[string]interface{}, cleaned bool) ([]byte, error) {
+	RedactSecret(obj)
+	if cleaned {
+		cleanManifest(obj)
+	} else {
+		unstructured.RemoveNestedField(obj, "metadata", "managedFields")
+	}
+	return yaml.Marshal(obj)
+}
+
+// cleanManifest removes status, strippedMetadata and assignedFields from
+// obj so it can be applied to another cluster.
+func cleanManifest(obj map[string]interface{}) map[string]interface{} {
+	for _, field := range strippedMetadata {
+		unstructured.RemoveNestedField(obj, "metadata", field)
+	}
+	for _, path := range assignedFields {
+		unstructured.RemoveNestedField(obj, path...)
+	}
+	unstructured.RemoveNestedField(obj, "status")
+	if annotations, found, _ := unstructured.NestedMap(obj, "metadata", "annotations"); found && len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj, "metadata", "annotations")
+	}
+	return obj
+}
+
+// ApplyExportRules substitutes placeholders in obj according to rules and
+// renders it as YAML.
+func ApplyExportRules(obj map[string]interface{}, rules []ExportRule) (*ExportResult, error) {
+	vars := make(map[string]map[string]bool)
+	record := func(variable, value string) string {
+		if vars[variable] == nil {
+			vars[variable] = make(map[string]bool)
+		}
+		vars[variable][value] = true
+		return "${" + variable + "}"
+	}
+
+	for _, rule := range rules {
+		if rule.Variable == "" {
+			return nil, fmt.Errorf("%s rule has no variable name", rule.Kind)
+		}
+		switch rule.Kind {
+		case ExportNamespace:
+			if ns, _, _ := unstructured.NestedString(obj, "metadata", "namespace"); ns != "" {
+				_ = unstructured.SetNestedField(obj, record(rule.Variable, ns), "metadata", "namespace")
+			}
+		case ExportImageTag:
+			walkStrings(obj, nil, func(path []string, value string) (string, bool) {
+				if len(path) == 0 || path[len(path)-1] != "image" {
+					return value, false
+				}
+				repo, tag := splitImageTag(value)
+				if tag == "" || (rule.Pattern != "" && !strings.Contains(repo, rule.Pattern)) {
+					return value, false
+				}
+				return repo + ":" + record(rule.Variable, tag), true
+			})
+		case ExportHost:
+			walkStrings(obj, nil, func(path []string, value string) (string, bool) {
+				if !isHostField(path) || value == "" {
+					return value, false
+				}
+				return record(rule.Variable, value), true
+			})
+		case ExportRegex:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %v", rule.Variable, err)
+			}
+			walkStrings(obj, nil, func(path []string, value string) (string, bool) {
+				if !re.MatchString(value) {
+					return value, false
+				}
+				return re.ReplaceAllStringFunc(value, func(m string) string { return record(rule.Variable, m) }), true
+			})
+		default:
+			return nil, fmt.Errorf("unknown export rule kind %q", rule.Kind)
+		}
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	result := &ExportResult{YAML: string(data), Variables: make(map[string][]string)}
+	for variable, values := range vars {
+		for v := range values {
+			result.Variables[variable] = append(result.Variables[variable], v)
+		}
+		sort.Strings(result.Variables[variable])
+	}
+	return result, nil
+}
+
+// walkStrings calls fn for every string value in v with its path of map
+// keys (list indexes are skipped) and stores the replacement if fn
+// reports a change.
+func walkStrings(v interface{}, path []string, fn func(path []string, value string) (string, bool)) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, child := range t {
+			childPath := append(path[:len(path):len(path)], key)
+			if s, ok := child.(string); ok {
+				if replaced, changed := fn(childPath, s); changed {
+					t[key] = replaced
+				}
+				continue
+			}
+			walkStrings(child, childPath, fn)
+		}
+	case []interface{}:
+		for i, child := range t {
+			if s, ok := child.(string); ok {
+				if replaced, changed := fn(path, s); changed {
+					t[i] = replaced
+				}
+				continue
+			}
+			walkStrings(child, path, fn)
+		}
+	}
+}
+
+// isHostField matches Ingress rules[].host and tls[].hosts[] and OpenShift
+// Route spec.host.
+func isHostField(path []string) bool {
+	if len(path) < 2 || path[0] != "spec" {
+		return false
+	}
+	last := path[len(path)-1]
+	switch {
+	case last == "host" && (path[1] == "rules" || len(path) == 2):
+		return true
+	case last == "hosts" && path[1] == "tls":
+		return true
+	}
+	return false
+}
+
+// splitImageTag splits "registry:5000/app:1.2@sha256:..." into repository
+// and tag. Digests are dropped since they don't carry over environments.
+func splitImageTag(image string) (string, string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	slash := strings.LastIndex(image, "/")
+	colon := strings.LastIndex(image, ":")
+	if colon <= slash {
+		return image, ""
+	}
+	return image[:colon], image[colon+1:]
+}
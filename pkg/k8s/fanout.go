@@ -0,0 +1,66 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const listFanOutWorkers = 4
+
+// NamespacedList is the merged result of listing one resource type in
+// several namespaces. Errors maps each namespace whose list failed to
+// its error, so one forbidden namespace doesn't hide the others.
+type NamespacedList struct {
+	Items  []interface{}     `json:"items"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ListResourcesInNamespaces lists a resource type in each of the given
+// namespaces concurrently, with at most listFanOutWorkers requests in
+// flight. Items keep the order of the namespaces.
+func (c *Client) ListResourcesInNamespaces(group, version, kind, plural string, namespaces []string, labelSelector, fieldSelector string) *NamespacedList {
+	results := make([][]interface{}, len(namespaces))
+	errs := make([]error, len(namespaces))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, listFanOutWorkers)
+	for i, ns := range namespaces {
+		wg.Add(1)
+		go func(i int, ns string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = c.ListResources(group, version, kind, plural, ns, labelSelector, fieldSelector)
+		}(i, ns)
+	}
+	wg.Wait()
+
+	list := &NamespacedList{Items: []interface{}{}}
+	for i, ns := range namespaces {
+		if errs[i] != nil {
+			if list.Errors == nil {
+				list.Errors = make(map[string]string)
+			}
+			list.Errors[ns] = errs[i].Error()
+			continue
+		}
+		list.Items = append(list.Items, results[i]...)
+	}
+	return list
+}
+
+// Err summarizes the per-namespace errors, or returns nil when at least
+// one namespace could be listed.
+func (l *NamespacedList) Err(namespaces int) error {
+	if len(l.Errors) == 0 || len(l.Errors) < namespaces {
+		return nil
+	}
+	var parts []string
+	for ns, err := range l.Errors {
+		parts = append(parts, ns+": "+err)
+	}
+	sort.Strings(parts)
+	return fmt.Errorf("listing failed in every namespace: %s", strings.Join(parts, "; "))
+}
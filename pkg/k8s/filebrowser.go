@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultMaxFileBytes = 1 << 20
+
+// listDirScript prints one stat line per directory entry, including
+// dotfiles. The directory is passed as $1 so it never needs quoting.
+const listDirScript = `for f in "$1"/* "$1"/.[!.]* "$1"/..?*; do
+  [ -e "$f" ] || [ -L "$f" ] || continue
+  stat -c '%F|%s|%a|%Y|%n' "$f"
+done`
+
+type FileEntry struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	Type    string    `json:"type"`
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	IsDir   bool      `json:"is_dir"`
+}
+
+type FileContent struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated"`
+	Binary    bool   `json:"binary"`
+}
+
+func (c *Client) ListContainerDir(namespace, podName, containerName, dir string) ([]FileEntry, error) {
+	if err := c.requireLinuxPod(namespace, podName); err != nil {
+		return nil, err
+	}
+	dir = cleanContainerPath(dir)
+	ctx, cancel := c.opContext(OpExec)
+	defer cancel()
+	stdout, stderr, err := c.execCapture(ctx, namespace, podName, containerName,
+		[]string{"sh", "-c", listDirScript, "sh", dir}, nil)
+	if err != nil {
+		return nil, execError(err, stderr)
+	}
+
+	var entries []FileEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(stdout)), "\n") {
+		if line == "" {
+			continue
+		}
+		entry, err := parseStatLine(line)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+func (c *Client) StatContainerFile(namespace, podName, containerName, filePath string) (*FileEntry, error) {
+	if err := c.requireLinuxPod(namespace, podName); err != nil {
+		return nil, err
+	}
+	filePath = cleanContainerPath(filePath)
+	ctx, cancel := c.opContext(OpExec)
+	defer cancel()
+	stdout, stderr, err := c.execCapture(ctx, namespace, podName, containerName,
+		[]string{"stat", "-c", "%F|%s|%a|%Y|%n", filePath}, nil)
+	if err != nil {
+		return nil, execError(err, stderr)
+	}
+	entry, err := parseStatLine(strings.TrimSpace(string(stdout)))
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ReadContainerFile returns at most maxBytes of a file. Binary content is
+// flagged rather than returned so the frontend doesn't render garbage.
+func (c *Client) ReadContainerFile(namespace, podName, containerName, filePath string, maxBytes int64) (*FileContent, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFileBytes
+	}
+
+	entry, err := c.StatContainerFile(namespace, podName, containerName, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir {
+		return nil, fmt.Errorf("%s is a directory", entry.Path)
+	}
+
+	ctx, cancel := c.opContext(OpExec)
+	defer cancel()
+	stdout, stderr, err := c.execCapture(ctx, namespace, podName, containerName,
+		[]string{"head", "-c", strconv.FormatInt(maxBytes, 10), entry.Path}, nil)
+	if err != nil {
+		return nil, execError(err, stderr)
+	}
+
+	content := &FileContent{
+		Path:      entry.Path,
+		Size:      entry.Size,
+		Truncated: entry.Size > int64(len(stdout)),
+	}
+	if content.Truncated {
+		// The cut may split a multi-byte character of a text file.
+		stdout = trimPartialRune(stdout)
+	}
+	if utf8.Valid(stdout) {
+		content.Content = string(stdout)
+	} else {
+		content.Binary = true
+	}
+	return content, nil
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence from the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+func parseStatLine(line string) (FileEntry, error) {
+	parts := strings.SplitN(line, "|", 5)
+	if len(parts) != 5 {
+		return FileEntry{}, fmt.Errorf("unexpected stat output: %q", line)
+	}
+	size, _ := strconv.ParseInt(parts[1], 10, 64)
+	mtime, _ := strconv.ParseInt(parts[3], 10, 64)
+	fullPath := path.Clean(parts[4])
+	return FileEntry{
+		Name:    path.Base(fullPath),
+		Path:    fullPath,
+		Type:    parts[0],
+		Size:    size,
+		Mode:    parts[2],
+		ModTime: time.Unix(mtime, 0),
+		IsDir:   parts[0] == "directory",
+	}, nil
+}
+
+func cleanContainerPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return path.Clean("/" + p)
+}
+
+func execError(err error, stderr []byte) error {
+	if msg := strings.TrimSpace(string(stderr)); msg != "" {
+		return fmt.Errorf("%v: %s", err, msg)
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package k8s
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTrimPartialRune(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"ascii", "hello", "hello"},
+		{"complete multi-byte", "grüße", "grüße"},
+		{"cut two-byte rune", "gr\xc3", "gr"},
+		{"cut three-byte rune", "price: \xe2\x82", "price: "},
+		{"cut four-byte rune", "ok \xf0\x9f\x98", "ok "},
+		{"complete four-byte rune", "ok 😀", "ok 😀"},
+		{"binary stays invalid", "\x00\xff\xfe", "\x00\xff\xfe"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(trimPartialRune([]byte(tt.in)))
+			if got != tt.want {
+				t.Errorf("trimPartialRune(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+	if utf8.Valid(trimPartialRune([]byte("\x00\xff\xfe"))) {
+		t.Error("binary content became valid UTF-8")
+	}
+}
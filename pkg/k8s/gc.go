@@ -0,0 +1,254 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const ownerIndexWorkers = 8
+
+// ObjectNode is a lightweight reference to an object found while walking
+// ownerReferences.
+type ObjectNode struct {
+	Group              string `json:"group"`
+	Version            string `json:"version"`
+	Kind               string `json:"kind"`
+	Plural             string `json:"plural"`
+	Namespace          string `json:"namespace"`
+	Name               string `json:"name"`
+	UID                string `json:"uid"`
+	BlockOwnerDeletion bool   `json:"block_owner_deletion"`
+}
+
+type Dependent struct {
+	ObjectNode
+	Depth      int         `json:"depth"`
+	Dependents []Dependent `json:"dependents,omitempty"`
+}
+
+type DeletionPreview struct {
+	Target     ObjectNode  `json:"target"`
+	Dependents []Dependent `json:"dependents"`
+	Total      int         `json:"total"`
+	// Errors lists resource types that couldn't be scanned, so the preview
+	// may be incomplete.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type ownerIndex struct {
+	children map[types.UID][]ObjectNode
+	// uids holds every object seen, to tell dangling owner references
+	// apart.
+	uids   map[types.UID]bool
+	errors map[string]string
+}
+
+// buildOwnerIndex lists every listable resource type of the given scope
+// and indexes objects by the UIDs of their owners. namespace limits the
+// scan of namespaced types; cluster-scoped types are only included when
+// includeCluster is set.
+func (c *Client) buildOwnerIndex(namespace string, includeCluster bool) (*ownerIndex, error) {
+	resources, err := c.preferredApiResources()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &ownerIndex{
+		children: make(map[types.UID][]ObjectNode),
+		uids:     make(map[types.UID]bool),
+		errors:   make(map[string]string),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, ownerIndexWorkers)
+
+	for _, res := range resources {
+		if !res.Namespaced && !includeCluster {
+			continue
+		}
+		wg.Add(1)
+		go func(res ApiResourceInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}
+			var list *unstructured.UnstructuredList
+			err := c.do(OpList, func(ctx context.Context) error {
+				var err error
+				if res.Namespaced && namespace != "" {
+					list, err = c.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+				} else {
+					list, err = c.DynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+				}
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				idx.errors[gvr.String()] = err.Error()
+				return
+			}
+			for _, item := range list.Items {
+				idx.uids[item.GetUID()] = true
+				for _, ref := range item.GetOwnerReferences() {
+					idx.children[ref.UID] = append(idx.children[ref.UID], ObjectNode{
+						Group:              res.Group,
+						Version:            res.Version,
+						Kind:               res.Kind,
+						Plural:             res.Name,
+						Namespace:          item.GetNamespace(),
+						Name:               item.GetName(),
+						UID:                string(item.GetUID()),
+						BlockOwnerDeletion: ref.BlockOwnerDeletion != nil && *ref.BlockOwnerDeletion,
+					})
+				}
+			}
+		}(res)
+	}
+	wg.Wait()
+
+	return idx, nil
+}
+
+// preferredApiResources is GetApiResources with one version of every
+// resource, for scans that would otherwise see each object once per served
+// version.
+func (c *Client) preferredApiResources() ([]ApiResourceInfo, error) {
+	resources, err := c.GetApiResources()
+	if err != nil {
+		return nil, err
+	}
+	preferred := make(map[string]string)
+	if groups, err := c.DiscoveryClient.ServerGroups(); err == nil {
+		for _, g := range groups.Groups {
+			preferred[g.Name] = g.PreferredVersion.Version
+		}
+	}
+	return preferredVersions(resources, preferred), nil
+}
+
+// preferredVersions keeps one version of every resource: the preferred
+// version of its group, given as group to version, or else the first one
+// listed, for groups without a known preference or resources missing from
+// the preferred version. Every version serves the same objects, so listing
+// each would only repeat them.
+func preferredVersions(resources []ApiResourceInfo, preferred map[string]string) []ApiResourceInfo {
+	seen := make(map[string]bool)
+	for _, res := range resources {
+		if v, ok := preferred[res.Group]; ok && v == res.Version {
+			seen[res.Group+"/"+res.Name] = true
+		}
+	}
+	result := make([]ApiResourceInfo, 0, len(resources))
+	for _, res := range resources {
+		key := res.Group + "/" + res.Name
+		if v, ok := preferred[res.Group]; ok && v == res.Version {
+			result = append(result, res)
+		} else if !seen[key] {
+			seen[key] = true
+			result = append(result, res)
+		}
+	}
+	return result
+}
+
+// GetDeletionPreview lists every object the garbage collector would delete
+// along with ref (all transitive dependents via ownerReferences), so the
+// blast radius of a cascading delete is visible beforehand.
+func (c *Client) GetDeletionPreview(ref ResourceRef) (*DeletionPreview, error) {
+	var target *unstructured.Unstructured
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		if ref.Namespace != "" {
+			target, err = c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		} else {
+			target, err = c.DynamicClient.Resource(ref.GVR()).Get(ctx, ref.Name, metav1.GetOptions{})
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Namespaced owners can only have dependents in their own namespace;
+	// cluster-scoped owners may own objects anywhere.
+	idx, err := c.buildOwnerIndex(ref.Namespace, ref.Namespace == "")
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &DeletionPreview{
+		Target: ObjectNode{
+			Group:     ref.Group,
+			Version:   ref.Version,
+			Kind:      target.GetKind(),
+			Plural:    ref.Plural,
+			Namespace: target.GetNamespace(),
+			Name:      target.GetName(),
+			UID:       string(target.GetUID()),
+		},
+	}
+	if len(idx.errors) > 0 {
+		preview.Errors = idx.errors
+	}
+
+	seen := map[types.UID]bool{target.GetUID(): true}
+	preview.Dependents = collectDependents(idx, target.GetUID(), 1, seen, &preview.Total)
+	return preview, nil
+}
+
+func collectDependents(idx *ownerIndex, owner types.UID, depth int, seen map[types.UID]bool, total *int) []Dependent {
+	var result []Dependent
+	for _, child := range idx.children[owner] {
+		uid := types.UID(child.UID)
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+		*total++
+		result = append(result, Dependent{
+			ObjectNode: child,
+			Depth:      depth,
+			Dependents: collectDependents(idx, uid, depth+1, seen, total),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// DeleteResourceWithPropagation deletes an object with an explicit
+// propagation policy ("Foreground", "Background" or "Orphan").
+func (c *Client) DeleteResourceWithPropagation(ref ResourceRef, propagation string) error {
+	opts := metav1.DeleteOptions{}
+	switch metav1.DeletionPropagation(propagation) {
+	case "":
+	case metav1.DeletePropagationForeground, metav1.DeletePropagationBackground, metav1.DeletePropagationOrphan:
+		policy := metav1.DeletionPropagation(propagation)
+		opts.PropagationPolicy = &policy
+	default:
+		return fmt.Errorf("unknown propagation policy %q", propagation)
+	}
+
+	return c.mutate(ActionDelete, ref, fmt.Sprintf("delete %s/%s", ref.Plural, ref.Name), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			if ref.Namespace != "" {
+				return c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Delete(ctx, ref.Name, opts)
+			}
+			return c.DynamicClient.Resource(ref.GVR()).Delete(ctx, ref.Name, opts)
+		})
+	})
+}
@@ -0,0 +1,35 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreferredVersions(t *testing.T) {
+	res := func(group, version, name string) ApiResourceInfo {
+		return ApiResourceInfo{Group: group, Version: version, Name: name}
+	}
+	resources := []ApiResourceInfo{
+		res("autoscaling", "v1", "horizontalpodautoscalers"),
+		res("autoscaling", "v2", "horizontalpodautoscalers"),
+		res("example.com", "v1beta1", "widgets"),
+		res("example.com", "v1", "widgets"),
+		res("example.com", "v1beta1", "gadgets"),
+		res("metrics.k8s.io", "v1beta1", "pods"),
+		res("", "v1", "pods"),
+	}
+	preferred := map[string]string{"autoscaling": "v2", "example.com": "v1", "": "v1"}
+
+	want := []ApiResourceInfo{
+		res("autoscaling", "v2", "horizontalpodautoscalers"),
+		res("example.com", "v1", "widgets"),
+		// Only served in a version that isn't preferred.
+		res("example.com", "v1beta1", "gadgets"),
+		// No known preference, e.g. a stale group.
+		res("metrics.k8s.io", "v1beta1", "pods"),
+		res("", "v1", "pods"),
+	}
+	if got := preferredVersions(resources, preferred); !reflect.DeepEqual(got, want) {
+		t.Errorf("preferredVersions() =\n%+v\nwant\n%+v", got, want)
+	}
+}
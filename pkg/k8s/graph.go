@@ -0,0 +1,374 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Edge types of a ResourceGraph. Edges point from the owner, selector or
+// frontend to the object it owns, selects or routes to.
+const (
+	EdgeOwns    = "owns"
+	EdgeSelects = "selects"
+	EdgeRoutes  = "routes"
+)
+
+// Directions of GetRelatedResources. RelatedOwners only walks up the
+// ownerReferences, which is cheap since no other objects are listed.
+const (
+	RelatedAll    = "all"
+	RelatedOwners = "owners"
+)
+
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// ResourceGraph is the neighbourhood of one object. Nodes and edges are
+// keyed by UID; Root is the UID of the object the graph was built for.
+type ResourceGraph struct {
+	Root  string       `json:"root"`
+	Nodes []ObjectNode `json:"nodes"`
+	Edges []GraphEdge  `json:"edges"`
+	// Owners is the chain of controllers above the root, nearest first,
+	// e.g. the ReplicaSet and then the Deployment of a Pod.
+	Owners []ObjectNode `json:"owners"`
+	// Errors lists resource types that couldn't be scanned for dependents.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type graphBuilder struct {
+	c      *Client
+	byKind map[schema.GroupKind]ApiResourceInfo
+	nodes  map[string]ObjectNode
+	edges  map[GraphEdge]bool
+	// controllers maps a UID to the UID of its controlling owner.
+	controllers map[string]string
+
+	namespace string
+	pods      []corev1.Pod
+	services  []corev1.Service
+	pdbs      []policyv1.PodDisruptionBudget
+	listed    bool
+}
+
+// GetRelatedResources builds the graph around an object: its owners up to
+// the top-level controller, everything it transitively owns, Services and
+// PodDisruptionBudgets selecting its pods (or the pods they select), and
+// the Ingresses and Routes in front of Services. plural may be empty, in
+// which case it is looked up from discovery. With direction RelatedOwners
+// only the owners are included.
+func (c *Client) GetRelatedResources(group, version, kind, plural, namespace, name, direction string) (*ResourceGraph, error) {
+	switch direction {
+	case "", RelatedAll, RelatedOwners:
+	default:
+		return nil, fmt.Errorf("unknown direction %q", direction)
+	}
+	resources, err := c.GetApiResources()
+	if err != nil {
+		return nil, err
+	}
+	b := &graphBuilder{
+		c:           c,
+		byKind:      make(map[schema.GroupKind]ApiResourceInfo),
+		nodes:       make(map[string]ObjectNode),
+		edges:       make(map[GraphEdge]bool),
+		controllers: make(map[string]string),
+		namespace:   namespace,
+	}
+	for _, res := range resources {
+		b.byKind[schema.GroupKind{Group: res.Group, Kind: res.Kind}] = res
+	}
+	if plural == "" {
+		info, ok := b.byKind[schema.GroupKind{Group: group, Kind: kind}]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource kind %s", schema.GroupKind{Group: group, Kind: kind})
+		}
+		plural = info.Name
+	}
+
+	res, err := c.GetResource(group, version, kind, plural, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	root := &unstructured.Unstructured{Object: res.(map[string]interface{})}
+	rootNode := objectNode(root, plural)
+	b.nodes[rootNode.UID] = rootNode
+
+	b.walkOwners(root, 0)
+
+	graph := &ResourceGraph{Root: rootNode.UID, Nodes: []ObjectNode{}, Edges: []GraphEdge{}, Owners: []ObjectNode{}}
+	for uid, seen := b.controllers[rootNode.UID], map[string]bool{}; uid != "" && !seen[uid]; uid = b.controllers[uid] {
+		seen[uid] = true
+		graph.Owners = append(graph.Owners, b.nodes[uid])
+	}
+
+	if direction != RelatedOwners {
+		idx, err := c.buildOwnerIndex(namespace, namespace == "")
+		if err != nil {
+			return nil, err
+		}
+		b.walkDependents(idx, rootNode.UID, map[string]bool{rootNode.UID: true})
+
+		if namespace != "" {
+			if err := b.addSelectorEdges(); err != nil {
+				return nil, err
+			}
+			b.addRouteEdges(rootNode)
+		}
+		if len(idx.errors) > 0 {
+			graph.Errors = idx.errors
+		}
+	}
+	for _, n := range b.nodes {
+		graph.Nodes = append(graph.Nodes, n)
+	}
+	for e := range b.edges {
+		graph.Edges = append(graph.Edges, e)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if graph.Nodes[i].Kind != graph.Nodes[j].Kind {
+			return graph.Nodes[i].Kind < graph.Nodes[j].Kind
+		}
+		return graph.Nodes[i].Name < graph.Nodes[j].Name
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph, nil
+}
+
+func objectNode(obj *unstructured.Unstructured, plural string) ObjectNode {
+	gv, _ := schema.ParseGroupVersion(obj.GetAPIVersion())
+	return ObjectNode{
+		Group:     gv.Group,
+		Version:   gv.Version,
+		Kind:      obj.GetKind(),
+		Plural:    plural,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		UID:       string(obj.GetUID()),
+	}
+}
+
+func (b *graphBuilder) edge(from, to, edgeType string) {
+	b.edges[GraphEdge{From: from, To: to, Type: edgeType}] = true
+}
+
+// walkOwners follows every ownerReference upwards. Owners whose kind
+// isn't served or that can't be read end the walk on that branch.
+func (b *graphBuilder) walkOwners(obj *unstructured.Unstructured, depth int) {
+	if depth >= maxOwnerDepth {
+		return
+	}
+	refs := obj.GetOwnerReferences()
+	for _, ref := range refs {
+		uid := string(ref.UID)
+		b.edge(uid, string(obj.GetUID()), EdgeOwns)
+		if (ref.Controller != nil && *ref.Controller) || len(refs) == 1 {
+			b.controllers[string(obj.GetUID())] = uid
+		}
+		if _, seen := b.nodes[uid]; seen {
+			continue
+		}
+
+		gv, _ := schema.ParseGroupVersion(ref.APIVersion)
+		node := ObjectNode{Group: gv.Group, Version: gv.Version, Kind: ref.Kind, Name: ref.Name, UID: uid}
+		info, ok := b.byKind[schema.GroupKind{Group: gv.Group, Kind: ref.Kind}]
+		if !ok {
+			b.nodes[uid] = node
+			continue
+		}
+		node.Plural = info.Name
+		if info.Namespaced {
+			node.Namespace = obj.GetNamespace()
+		}
+		b.nodes[uid] = node
+
+		res, err := b.c.GetResource(node.Group, node.Version, node.Kind, node.Plural, node.Namespace, node.Name)
+		if err != nil {
+			continue
+		}
+		b.walkOwners(&unstructured.Unstructured{Object: res.(map[string]interface{})}, depth+1)
+	}
+}
+
+func (b *graphBuilder) walkDependents(idx *ownerIndex, owner string, seen map[string]bool) {
+	for _, child := range idx.children[types.UID(owner)] {
+		b.edge(owner, child.UID, EdgeOwns)
+		if seen[child.UID] {
+			continue
+		}
+		seen[child.UID] = true
+		child.BlockOwnerDeletion = false
+		b.nodes[child.UID] = child
+		b.walkDependents(idx, child.UID, seen)
+	}
+}
+
+func (b *graphBuilder) list() error {
+	if b.listed {
+		return nil
+	}
+	b.listed = true
+	return b.c.do(OpList, func(ctx context.Context) error {
+		pods, err := b.c.Clientset.CoreV1().Pods(b.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		services, err := b.c.Clientset.CoreV1().Services(b.namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		b.pods, b.services = pods.Items, services.Items
+		// PDBs are optional for the graph; RBAC often hides them.
+		if pdbs, err := b.c.Clientset.PolicyV1().PodDisruptionBudgets(b.namespace).List(ctx, metav1.ListOptions{}); err == nil {
+			b.pdbs = pdbs.Items
+		}
+		return nil
+	})
+}
+
+// addSelectorEdges connects the Services and PDBs already in the graph to
+// the pods they select, and the pods already in the graph to the Services
+// and PDBs selecting them. It only goes one hop so the graph stays small.
+func (b *graphBuilder) addSelectorEdges() error {
+	var pods, services, pdbs []string
+	for uid, n := range b.nodes {
+		switch {
+		case n.Group == "" && n.Kind == "Pod":
+			pods = append(pods, uid)
+		case n.Group == "" && n.Kind == "Service":
+			services = append(services, uid)
+		case n.Group == "policy" && n.Kind == "PodDisruptionBudget":
+			pdbs = append(pdbs, uid)
+		}
+	}
+	if len(pods)+len(services)+len(pdbs) == 0 {
+		return nil
+	}
+	if err := b.list(); err != nil {
+		return err
+	}
+
+	inGraph := func(uids []string, uid types.UID) bool {
+		for _, u := range uids {
+			if u == string(uid) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range b.services {
+		svc := &b.services[i]
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		b.selectPods(string(svc.UID), selector, inGraph(services, svc.UID), pods, func() ObjectNode {
+			return ObjectNode{Version: "v1", Kind: "Service", Plural: "services", Namespace: svc.Namespace, Name: svc.Name, UID: string(svc.UID)}
+		})
+	}
+	for i := range b.pdbs {
+		pdb := &b.pdbs[i]
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		b.selectPods(string(pdb.UID), selector, inGraph(pdbs, pdb.UID), pods, func() ObjectNode {
+			return ObjectNode{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget", Plural: "poddisruptionbudgets", Namespace: pdb.Namespace, Name: pdb.Name, UID: string(pdb.UID)}
+		})
+	}
+	return nil
+}
+
+// selectPods adds selects-edges from a selector object. When the selector
+// is in the graph every matching pod is added; otherwise the selector is
+// added only if it matches one of the graph's pods.
+func (b *graphBuilder) selectPods(from string, selector labels.Selector, selectorInGraph bool, graphPods []string, node func() ObjectNode) {
+	for i := range b.pods {
+		pod := &b.pods[i]
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		uid := string(pod.UID)
+		if selectorInGraph {
+			if _, ok := b.nodes[uid]; !ok {
+				b.nodes[uid] = ObjectNode{Version: "v1", Kind: "Pod", Plural: "pods", Namespace: pod.Namespace, Name: pod.Name, UID: uid}
+			}
+			b.edge(from, uid, EdgeSelects)
+			continue
+		}
+		for _, p := range graphPods {
+			if p == uid {
+				b.nodes[from] = node()
+				b.edge(from, uid, EdgeSelects)
+			}
+		}
+	}
+}
+
+// addRouteEdges adds the Ingresses and Routes in front of the graph's
+// Services, and the Services behind a root Ingress or Route.
+func (b *graphBuilder) addRouteEdges(root ObjectNode) {
+	switch {
+	case root.Group == ingressGVR.Group && root.Kind == "Ingress":
+		b.addFrontendServices(root, ingressGVR)
+		return
+	case root.Group == routeGVR.Group && root.Kind == "Route":
+		b.addFrontendServices(root, routeGVR)
+		return
+	}
+
+	var services []ObjectNode
+	for _, n := range b.nodes {
+		if n.Group == "" && n.Kind == "Service" {
+			services = append(services, n)
+		}
+	}
+	for _, svc := range services {
+		frontends, err := b.c.serviceFrontends(svc.Namespace, svc.Name)
+		if err != nil {
+			continue
+		}
+		for _, f := range frontends {
+			obj := &unstructured.Unstructured{Object: f.(map[string]interface{})}
+			plural := ingressGVR.Resource
+			if obj.GetKind() == "Route" {
+				plural = routeGVR.Resource
+			}
+			node := objectNode(obj, plural)
+			b.nodes[node.UID] = node
+			b.edge(node.UID, svc.UID, EdgeRoutes)
+		}
+	}
+}
+
+func (b *graphBuilder) addFrontendServices(root ObjectNode, gvr schema.GroupVersionResource) {
+	services, err := b.c.frontendServices(gvr, root.Namespace, root.Name)
+	if err != nil {
+		return
+	}
+	for _, s := range services {
+		node := objectNode(&unstructured.Unstructured{Object: s.(map[string]interface{})}, "services")
+		b.nodes[node.UID] = node
+		b.edge(root.UID, node.UID, EdgeRoutes)
+	}
+	// Pull in the pods behind the newly added Services.
+	_ = b.addSelectorEdges()
+}
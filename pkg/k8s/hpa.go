@@ -0,0 +1,448 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+)
+
+// HPAResult is a created HorizontalPodAutoscaler. Warnings are problems
+// that won't fail the creation but keep the autoscaler from working.
+type HPAResult struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Warnings  []string `json:"warnings"`
+}
+
+// CreateHPA creates an autoscaling/v2 HorizontalPodAutoscaler for a
+// workload, named after it, that keeps average CPU utilization at
+// cpuTarget percent of the pods' requests. It refuses when the metrics
+// API isn't served, when the workload has no scale subresource, or when
+// another autoscaler already targets it, since two would fight.
+func (c *Client) CreateHPA(target ResourceRef, minReplicas, maxReplicas, cpuTarget int32) (*HPAResult, error) {
+	switch {
+	case target.Kind == "" || target.Name == "" || target.Namespace == "":
+		return nil, fmt.Errorf("the target needs a kind, namespace and name")
+	case minReplicas < 1:
+		return nil, fmt.Errorf("minimum replicas must be at least 1, got %d", minReplicas)
+	case maxReplicas < minReplicas:
+		return nil, fmt.Errorf("maximum replicas (%d) must not be below the minimum (%d)", maxReplicas, minReplicas)
+	case cpuTarget < 1:
+		return nil, fmt.Errorf("CPU target must be a positive percentage, got %d", cpuTarget)
+	}
+	if err := c.checkPodMetrics(target.Namespace); err != nil {
+		return nil, err
+	}
+
+	var workload *unstructured.Unstructured
+	err := c.do(OpGet, func(ctx context.Context) error {
+		resource := c.DynamicClient.Resource(target.GVR()).Namespace(target.Namespace)
+		if _, err := resource.Get(ctx, target.Name, metav1.GetOptions{}, "scale"); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Errorf("%s %s has no scale subresource or doesn't exist: %v", target.Kind, target.Name, err)
+			}
+			return err
+		}
+		var err error
+		workload, err = resource.Get(ctx, target.Name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	apiVersion := schema.GroupVersion{Group: target.Group, Version: target.Version}.String()
+	var existing *autoscalingv2.HorizontalPodAutoscalerList
+	err = c.do(OpList, func(ctx context.Context) error {
+		var err error
+		existing, err = c.Clientset.AutoscalingV2().HorizontalPodAutoscalers(target.Namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, hpa := range existing.Items {
+		if scalesTarget(hpa.Spec.ScaleTargetRef, target) {
+			return nil, fmt.Errorf("%s %s is already scaled by HorizontalPodAutoscaler %s", target.Kind, target.Name, hpa.Name)
+		}
+	}
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: target.Name, Namespace: target.Namespace},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: apiVersion, Kind: target.Kind, Name: target.Name},
+			MinReplicas:    ptr.To(minReplicas),
+			MaxReplicas:    maxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: ptr.To(cpuTarget),
+					},
+				},
+			}},
+		},
+	}
+	ref := ResourceRef{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler", Plural: "horizontalpodautoscalers", Namespace: target.Namespace, Name: hpa.Name}
+	err = c.mutate(ActionCreate, ref, fmt.Sprintf("autoscale %s/%s between %d and %d", target.Plural, target.Name, minReplicas, maxReplicas), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.AutoscalingV2().HorizontalPodAutoscalers(target.Namespace).Create(ctx, hpa, metav1.CreateOptions{})
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create autoscaler for %s %s: %v", target.Kind, target.Name, err)
+	}
+	return &HPAResult{Namespace: target.Namespace, Name: hpa.Name, Warnings: hpaWarnings(workload, maxReplicas)}, nil
+}
+
+// checkPodMetrics fails unless the resource metrics API answers for
+// namespace; without it a CPU autoscaler never scales.
+func (c *Client) checkPodMetrics(namespace string) error {
+	err := c.do(OpList, func(ctx context.Context) error {
+		_, err := c.DynamicClient.Resource(podMetricsGVR).Namespace(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+		return err
+	})
+	if err != nil {
+		return metricsError(err)
+	}
+	return nil
+}
+
+// hpaWarnings lists containers of the workload's pod template without a
+// CPU request, which utilization targets are computed against, and a
+// manual replica count above the autoscaler's maximum.
+func hpaWarnings(workload *unstructured.Unstructured, maxReplicas int32) []string {
+	warnings := []string{}
+	containers, found, _ := unstructured.NestedSlice(workload.Object, "spec", "template", "spec", "containers")
+	if !found {
+		warnings = append(warnings, "the workload has no pod template; make sure its pods request CPU")
+	}
+	for _, raw := range containers {
+		container, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok, _ := unstructured.NestedFieldNoCopy(container, "resources", "requests", "cpu"); !ok {
+			name, _, _ := unstructured.NestedString(container, "name")
+			warnings = append(warnings, fmt.Sprintf("container %s has no CPU request, so utilization can't be computed", name))
+		}
+	}
+	if replicas, ok, _ := unstructured.NestedInt64(workload.Object, "spec", "replicas"); ok && replicas > int64(maxReplicas) {
+		warnings = append(warnings, fmt.Sprintf("the workload runs %d replicas; the autoscaler will scale it down to %d", replicas, maxReplicas))
+	}
+	return warnings
+}
+
+// hpaPausedAnnotation holds the replica bounds of a paused autoscaler, as
+// JSON, until it is resumed.
+const hpaPausedAnnotation = "teleskope.io/hpa-paused"
+
+// hpaBounds are the min and max replicas saved while paused.
+type hpaBounds struct {
+	Min int32 `json:"min"`
+	Max int32 `json:"max"`
+}
+
+// HPAMetric pairs a metric of an autoscaler's spec with its current value.
+// Current is empty when the controller couldn't fetch it.
+type HPAMetric struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Target  string `json:"target"`
+	Current string `json:"current"`
+}
+
+type HPACondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type HPADetail struct {
+	Namespace       string         `json:"namespace"`
+	Name            string         `json:"name"`
+	TargetKind      string         `json:"target_kind"`
+	TargetName      string         `json:"target_name"`
+	MinReplicas     int32          `json:"min_replicas"`
+	MaxReplicas     int32          `json:"max_replicas"`
+	CurrentReplicas int32          `json:"current_replicas"`
+	DesiredReplicas int32          `json:"desired_replicas"`
+	LastScaleTime   *time.Time     `json:"last_scale_time,omitempty"`
+	Metrics         []HPAMetric    `json:"metrics"`
+	Conditions      []HPACondition `json:"conditions"`
+	// Paused is set while PauseHPA holds the replica count; PausedMin and
+	// PausedMax are the bounds restored on resume.
+	Paused    bool          `json:"paused"`
+	PausedMin int32         `json:"paused_min,omitempty"`
+	PausedMax int32         `json:"paused_max,omitempty"`
+	Events    []ObjectEvent `json:"events,omitempty"`
+}
+
+// GetHPAs returns the autoscalers of a namespace (all when empty) with
+// their metrics and conditions.
+func (c *Client) GetHPAs(namespace string) ([]HPADetail, error) {
+	var list *autoscalingv2.HorizontalPodAutoscalerList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.Clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]HPADetail, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, hpaDetail(&list.Items[i]))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// GetHPA returns one autoscaler with the events explaining its scaling
+// decisions.
+func (c *Client) GetHPA(namespace, name string) (*HPADetail, error) {
+	hpa, err := c.getHPA(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	detail := hpaDetail(hpa)
+	events, err := c.GetEventsForObject(namespace, "HorizontalPodAutoscaler", name, string(hpa.UID))
+	if err != nil {
+		return nil, err
+	}
+	detail.Events = events
+	return &detail, nil
+}
+
+func (c *Client) getHPA(namespace, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	var hpa *autoscalingv2.HorizontalPodAutoscaler
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		hpa, err = c.Clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	return hpa, err
+}
+
+// scalesTarget reports whether an autoscaler's scaleTargetRef points at
+// target. The version is ignored, since every version of a kind names the
+// same object, but the group isn't: kinds of different groups may share a
+// name.
+func scalesTarget(ref autoscalingv2.CrossVersionObjectReference, target ResourceRef) bool {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false
+	}
+	return gv.Group == target.Group && ref.Kind == target.Kind && ref.Name == target.Name
+}
+
+func hpaDetail(hpa *autoscalingv2.HorizontalPodAutoscaler) HPADetail {
+	detail := HPADetail{
+		Namespace:       hpa.Namespace,
+		Name:            hpa.Name,
+		TargetKind:      hpa.Spec.ScaleTargetRef.Kind,
+		TargetName:      hpa.Spec.ScaleTargetRef.Name,
+		MinReplicas:     ptr.Deref(hpa.Spec.MinReplicas, 1),
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		Metrics:         []HPAMetric{},
+		Conditions:      []HPACondition{},
+	}
+	if hpa.Status.LastScaleTime != nil {
+		detail.LastScaleTime = &hpa.Status.LastScaleTime.Time
+	}
+	if bounds, ok := pausedBounds(hpa); ok {
+		detail.Paused, detail.PausedMin, detail.PausedMax = true, bounds.Min, bounds.Max
+	}
+
+	current := make(map[string]string)
+	for _, m := range hpa.Status.CurrentMetrics {
+		name, value := metricStatus(m)
+		current[string(m.Type)+"/"+name] = value
+	}
+	for _, m := range hpa.Spec.Metrics {
+		name, target := metricSpec(m)
+		detail.Metrics = append(detail.Metrics, HPAMetric{Type: string(m.Type), Name: name, Target: target, Current: current[string(m.Type)+"/"+name]})
+	}
+	for _, cond := range hpa.Status.Conditions {
+		detail.Conditions = append(detail.Conditions, HPACondition{Type: string(cond.Type), Status: string(cond.Status), Reason: cond.Reason, Message: cond.Message})
+	}
+	return detail
+}
+
+// metricSpec returns the name and target of a spec metric. Container
+// resource metrics are named "<container>/<resource>", object metrics
+// "<kind>/<name>/<metric>".
+func metricSpec(m autoscalingv2.MetricSpec) (string, string) {
+	switch {
+	case m.Resource != nil:
+		return string(m.Resource.Name), formatMetricTarget(m.Resource.Target)
+	case m.ContainerResource != nil:
+		return m.ContainerResource.Container + "/" + string(m.ContainerResource.Name), formatMetricTarget(m.ContainerResource.Target)
+	case m.Pods != nil:
+		return m.Pods.Metric.Name, formatMetricTarget(m.Pods.Target)
+	case m.Object != nil:
+		return m.Object.DescribedObject.Kind + "/" + m.Object.DescribedObject.Name + "/" + m.Object.Metric.Name, formatMetricTarget(m.Object.Target)
+	case m.External != nil:
+		return m.External.Metric.Name, formatMetricTarget(m.External.Target)
+	}
+	return "", ""
+}
+
+func metricStatus(m autoscalingv2.MetricStatus) (string, string) {
+	switch {
+	case m.Resource != nil:
+		return string(m.Resource.Name), formatMetricValue(m.Resource.Current)
+	case m.ContainerResource != nil:
+		return m.ContainerResource.Container + "/" + string(m.ContainerResource.Name), formatMetricValue(m.ContainerResource.Current)
+	case m.Pods != nil:
+		return m.Pods.Metric.Name, formatMetricValue(m.Pods.Current)
+	case m.Object != nil:
+		return m.Object.DescribedObject.Kind + "/" + m.Object.DescribedObject.Name + "/" + m.Object.Metric.Name, formatMetricValue(m.Object.Current)
+	case m.External != nil:
+		return m.External.Metric.Name, formatMetricValue(m.External.Current)
+	}
+	return "", ""
+}
+
+// formatMetricTarget renders a target like kubectl: "80%" for
+// utilization, "500m (avg)" for per-pod averages, else the total.
+func formatMetricTarget(t autoscalingv2.MetricTarget) string {
+	switch {
+	case t.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *t.AverageUtilization)
+	case t.AverageValue != nil:
+		return t.AverageValue.String() + " (avg)"
+	case t.Value != nil:
+		return t.Value.String()
+	}
+	return ""
+}
+
+func formatMetricValue(v autoscalingv2.MetricValueStatus) string {
+	switch {
+	case v.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *v.AverageUtilization)
+	case v.AverageValue != nil:
+		return v.AverageValue.String() + " (avg)"
+	case v.Value != nil:
+		return v.Value.String()
+	}
+	return ""
+}
+
+func pausedBounds(hpa *autoscalingv2.HorizontalPodAutoscaler) (hpaBounds, bool) {
+	var bounds hpaBounds
+	value, ok := hpa.Annotations[hpaPausedAnnotation]
+	if !ok || json.Unmarshal([]byte(value), &bounds) != nil {
+		return bounds, false
+	}
+	return bounds, true
+}
+
+// SetHPAReplicas changes the replica bounds of an autoscaler. While it is
+// paused the bounds to restore on resume are changed instead.
+func (c *Client) SetHPAReplicas(namespace, name string, minReplicas, maxReplicas int32) error {
+	if minReplicas < 1 {
+		return fmt.Errorf("minimum replicas must be at least 1, got %d", minReplicas)
+	}
+	if maxReplicas < minReplicas {
+		return fmt.Errorf("maximum replicas (%d) must not be below the minimum (%d)", maxReplicas, minReplicas)
+	}
+	return c.updateHPA(namespace, name, fmt.Sprintf("set horizontalpodautoscalers/%s replicas to %d-%d", name, minReplicas, maxReplicas), func(hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+		if _, paused := pausedBounds(hpa); paused {
+			return setPausedBounds(hpa, hpaBounds{Min: minReplicas, Max: maxReplicas})
+		}
+		hpa.Spec.MinReplicas = ptr.To(minReplicas)
+		hpa.Spec.MaxReplicas = maxReplicas
+		return nil
+	})
+}
+
+// PauseHPA holds a workload at its current replica count by pinning the
+// autoscaler's min and max to it, keeping the original bounds in an
+// annotation. Resuming restores them. Autoscalers have no pause switch of
+// their own, and deleting one would lose its configuration.
+func (c *Client) PauseHPA(namespace, name string, paused bool) error {
+	description := "resume horizontalpodautoscalers/" + name
+	if paused {
+		description = "pause horizontalpodautoscalers/" + name
+	}
+	return c.updateHPA(namespace, name, description, func(hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+		bounds, isPaused := pausedBounds(hpa)
+		switch {
+		case paused && isPaused, !paused && !isPaused:
+			return nil
+		case paused:
+			if err := setPausedBounds(hpa, hpaBounds{Min: ptr.Deref(hpa.Spec.MinReplicas, 1), Max: hpa.Spec.MaxReplicas}); err != nil {
+				return err
+			}
+			replicas := max(hpa.Status.CurrentReplicas, 1)
+			hpa.Spec.MinReplicas = ptr.To(replicas)
+			hpa.Spec.MaxReplicas = replicas
+		default:
+			delete(hpa.Annotations, hpaPausedAnnotation)
+			hpa.Spec.MinReplicas = ptr.To(bounds.Min)
+			hpa.Spec.MaxReplicas = bounds.Max
+		}
+		return nil
+	})
+}
+
+func setPausedBounds(hpa *autoscalingv2.HorizontalPodAutoscaler, bounds hpaBounds) error {
+	data, err := json.Marshal(bounds)
+	if err != nil {
+		return err
+	}
+	if hpa.Annotations == nil {
+		hpa.Annotations = make(map[string]string)
+	}
+	hpa.Annotations[hpaPausedAnnotation] = string(data)
+	return nil
+}
+
+// updateHPA applies change to the live autoscaler and updates it,
+// retrying on conflicts with the controller's status writes.
+func (c *Client) updateHPA(namespace, name, description string, change func(*autoscalingv2.HorizontalPodAutoscaler) error) error {
+	ref := ResourceRef{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler", Plural: "horizontalpodautoscalers", Namespace: namespace, Name: name}
+	err := c.mutate(ActionUpdate, ref, description, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				hpa, err := c.Clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				if err := change(hpa); err != nil {
+					return err
+				}
+				_, err = c.Clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Update(ctx, hpa, metav1.UpdateOptions{})
+				return err
+			})
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update autoscaler %s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package k8s
+
+import (
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+)
+
+func TestScalesTarget(t *testing.T) {
+	deployment := ResourceRef{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "shop", Name: "web"}
+	custom := ResourceRef{Group: "example.com", Version: "v1", Kind: "Deployment", Namespace: "shop", Name: "web"}
+	tests := []struct {
+		name   string
+		ref    autoscalingv2.CrossVersionObjectReference
+		target ResourceRef
+		want   bool
+	}{
+		{name: "same target", ref: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}, target: deployment, want: true},
+		{name: "other version", ref: autoscalingv2.CrossVersionObjectReference{APIVersion: "example.com/v1beta1", Kind: "Deployment", Name: "web"}, target: custom, want: true},
+		{name: "other group", ref: autoscalingv2.CrossVersionObjectReference{APIVersion: "example.com/v1", Kind: "Deployment", Name: "web"}, target: deployment},
+		{name: "built-in for custom", ref: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}, target: custom},
+		{name: "other name", ref: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "api"}, target: deployment},
+		{name: "other kind", ref: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "web"}, target: deployment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scalesTarget(tt.ref, tt.target); got != tt.want {
+				t.Errorf("scalesTarget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RunningImage is one digest a container runs as, with the pods running
+// it.
+type RunningImage struct {
+	Image  string   `json:"image"`
+	Digest string   `json:"digest"`
+	Pods   []string `json:"pods"`
+}
+
+type ContainerImageDrift struct {
+	Kind      string `json:"kind"`
+	Workload  string `json:"workload"`
+	Container string `json:"container"`
+	SpecImage string `json:"spec_image"`
+	// Running groups the workload's pods by the digest the container
+	// actually runs.
+	Running []RunningImage `json:"running"`
+	// StalePods are pods still created from an older pod template image,
+	// typically left over from a stuck or failed rollout.
+	StalePods []string `json:"stale_pods"`
+	// RegistryDigest is what the spec tag currently resolves to, when the
+	// registry was queried.
+	RegistryDigest string   `json:"registry_digest,omitempty"`
+	RegistryError  string   `json:"registry_error,omitempty"`
+	Drifted        bool     `json:"drifted"`
+	Issues         []string `json:"issues"`
+}
+
+type ImageDriftReport struct {
+	Namespace  string                `json:"namespace"`
+	Containers []ContainerImageDrift `json:"containers"`
+}
+
+// GetImageDrift compares, for every Deployment, StatefulSet and DaemonSet
+// of a namespace, the image in the pod template with what its pods really
+// run: pods on an older template image, a tag resolving to different
+// digests across pods, and a digest-pinned image running as something
+// else. With checkRegistry the spec tags are also resolved against their
+// registries (anonymously) to find tags that moved since the pods pulled
+// them.
+func (c *Client) GetImageDrift(namespace string, checkRegistry bool) (*ImageDriftReport, error) {
+	type workload struct {
+		kind, name string
+		selector   *metav1.LabelSelector
+		template   corev1.PodSpec
+	}
+	var workloads []workload
+	var pods *corev1.PodList
+	err := c.do(OpList, func(ctx context.Context) error {
+		apps := c.Clientset.AppsV1()
+		deployments, err := apps.Deployments(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		statefulSets, err := apps.StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		daemonSets, err := apps.DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		pods, err = c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		workloads = workloads[:0]
+		for _, d := range deployments.Items {
+			workloads = append(workloads, workload{"Deployment", d.Name, d.Spec.Selector, d.Spec.Template.Spec})
+		}
+		for _, s := range statefulSets.Items {
+			workloads = append(workloads, workload{"StatefulSet", s.Name, s.Spec.Selector, s.Spec.Template.Spec})
+		}
+		for _, d := range daemonSets.Items {
+			workloads = append(workloads, workload{"DaemonSet", d.Name, d.Spec.Selector, d.Spec.Template.Spec})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	registryDigests := make(map[string]string)
+	registryErrors := make(map[string]string)
+	report := &ImageDriftReport{Namespace: namespace, Containers: []ContainerImageDrift{}}
+	for _, w := range workloads {
+		selector, err := metav1.LabelSelectorAsSelector(w.selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		var owned []*corev1.Pod
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.DeletionTimestamp == nil && selector.Matches(labels.Set(pod.Labels)) {
+				owned = append(owned, pod)
+			}
+		}
+
+		containers := append(append([]corev1.Container{}, w.template.InitContainers...), w.template.Containers...)
+		for _, ctr := range containers {
+			drift := containerDrift(w.kind, w.name, ctr, owned)
+			spec := parseImageRef(ctr.Image)
+			if checkRegistry && spec.Digest == "" {
+				name := spec.Name()
+				if _, done := registryDigests[name]; !done && registryErrors[name] == "" {
+					ctx, cancel := c.opContext(OpGet)
+					digest, err := fetchManifestDigest(ctx, spec, registryAuth{})
+					cancel()
+					if err != nil {
+						registryErrors[name] = err.Error()
+					} else {
+						registryDigests[name] = digest
+					}
+				}
+				drift.RegistryDigest, drift.RegistryError = registryDigests[name], registryErrors[name]
+				if drift.RegistryDigest != "" && len(drift.Running) > 0 {
+					current := false
+					for _, r := range drift.Running {
+						if r.Digest == drift.RegistryDigest {
+							current = true
+						}
+					}
+					if !current {
+						drift.Issues = append(drift.Issues, fmt.Sprintf("tag %s now points to %s, which no pod runs; pods pull it on their next restart", spec.Tag, shortDigest(drift.RegistryDigest)))
+					}
+				}
+			}
+			drift.Drifted = len(drift.Issues) > 0
+			report.Containers = append(report.Containers, drift)
+		}
+	}
+
+	sort.Slice(report.Containers, func(i, j int) bool {
+		a, b := report.Containers[i], report.Containers[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		return a.Container < b.Container
+	})
+	return report, nil
+}
+
+// containerDrift compares one template container with its pods.
+func containerDrift(kind, name string, ctr corev1.Container, pods []*corev1.Pod) ContainerImageDrift {
+	drift := ContainerImageDrift{
+		Kind:      kind,
+		Workload:  name,
+		Container: ctr.Name,
+		SpecImage: ctr.Image,
+		Running:   []RunningImage{},
+		StalePods: []string{},
+		Issues:    []string{},
+	}
+	spec := parseImageRef(ctr.Image)
+	byDigest := make(map[string]*RunningImage)
+	for _, pod := range pods {
+		podImage := ""
+		for _, pc := range append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
+			if pc.Name == ctr.Name {
+				podImage = pc.Image
+			}
+		}
+		if podImage != "" && parseImageRef(podImage) != spec {
+			drift.StalePods = append(drift.StalePods, pod.Name)
+		}
+
+		for _, s := range append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			digest := imageIDDigest(s.ImageID)
+			if s.Name != ctr.Name || digest == "" {
+				continue
+			}
+			running, ok := byDigest[digest]
+			if !ok {
+				running = &RunningImage{Image: s.Image, Digest: digest}
+				byDigest[digest] = running
+			}
+			running.Pods = append(running.Pods, pod.Name)
+		}
+	}
+
+	for _, running := range byDigest {
+		sort.Strings(running.Pods)
+		drift.Running = append(drift.Running, *running)
+	}
+	sort.Slice(drift.Running, func(i, j int) bool { return drift.Running[i].Digest < drift.Running[j].Digest })
+	sort.Strings(drift.StalePods)
+
+	if len(drift.StalePods) > 0 {
+		drift.Issues = append(drift.Issues, fmt.Sprintf("%d pod(s) still run an image from an older revision; the rollout may be stuck", len(drift.StalePods)))
+	}
+	if spec.Digest != "" {
+		for _, r := range drift.Running {
+			if r.Digest != spec.Digest {
+				drift.Issues = append(drift.Issues, fmt.Sprintf("%d pod(s) run %s instead of the pinned %s", len(r.Pods), shortDigest(r.Digest), shortDigest(spec.Digest)))
+			}
+		}
+	} else if len(drift.Running) > 1 && len(drift.StalePods) == 0 {
+		drift.Issues = append(drift.Issues, fmt.Sprintf("tag %s runs as %d different digests across pods; it was re-pushed and pods pulled it at different times", spec.Tag, len(drift.Running)))
+	}
+	return drift
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
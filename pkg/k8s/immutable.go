@@ -0,0 +1,219 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const recreateDeleteTimeout = 2 * time.Minute
+
+type ImmutableFieldChange struct {
+	Path    string      `json:"path"`
+	Current interface{} `json:"current"`
+	Edited  interface{} `json:"edited"`
+	Message string      `json:"message"`
+}
+
+// immutableFields lists, per kind, fields the API server rejects changes
+// to once the object exists. Identity fields are checked for every kind.
+var immutableFields = map[string][]string{
+	"Service":               {"spec.clusterIP", "spec.clusterIPs"},
+	"PersistentVolumeClaim": {"spec.storageClassName", "spec.accessModes", "spec.volumeMode", "spec.selector", "spec.dataSource", "spec.dataSourceRef"},
+	"PersistentVolume":      {"spec.csi", "spec.hostPath", "spec.nfs", "spec.local"},
+	"Deployment":            {"spec.selector"},
+	"ReplicaSet":            {"spec.selector"},
+	"DaemonSet":             {"spec.selector"},
+	"StatefulSet":           {"spec.selector", "spec.serviceName", "spec.volumeClaimTemplates", "spec.podManagementPolicy"},
+	"Job":                   {"spec.selector", "spec.template", "spec.completions", "spec.completionMode"},
+	"StorageClass":          {"provisioner", "parameters", "reclaimPolicy", "volumeBindingMode"},
+	"RoleBinding":           {"roleRef"},
+	"ClusterRoleBinding":    {"roleRef"},
+	"Secret":                {"type"},
+}
+
+var identityFields = []string{"metadata.name", "metadata.namespace"}
+
+// podMutableSpecFields are the only pod spec fields that may change after
+// creation.
+var podMutableSpecFields = map[string]bool{
+	"activeDeadlineSeconds":         true,
+	"tolerations":                   true,
+	"terminationGracePeriodSeconds": true,
+	"schedulingGates":               true,
+}
+
+// FindImmutableFieldChanges compares a live object with an edited version
+// and reports every change the API server would reject as immutable.
+func FindImmutableFieldChanges(current, edited map[string]interface{}) []ImmutableFieldChange {
+	var changes []ImmutableFieldChange
+	kind, _, _ := unstructured.NestedString(current, "kind")
+
+	check := func(path, message string) {
+		fields := strings.Split(path, ".")
+		cur, curFound, _ := unstructured.NestedFieldNoCopy(current, fields...)
+		next, nextFound, _ := unstructured.NestedFieldNoCopy(edited, fields...)
+		// Leaving a server-defaulted field out of the edit is not a change.
+		if !nextFound {
+			return
+		}
+		if !curFound || !reflect.DeepEqual(cur, next) {
+			changes = append(changes, ImmutableFieldChange{Path: path, Current: cur, Edited: next, Message: message})
+		}
+	}
+
+	for _, path := range identityFields {
+		check(path, "renaming or moving an object creates a new object; the original is left untouched")
+	}
+	for _, path := range immutableFields[kind] {
+		check(path, fmt.Sprintf("%s is immutable on %s", path, kind))
+	}
+
+	switch kind {
+	case "Pod":
+		changes = append(changes, podSpecChanges(current, edited)...)
+	case "ConfigMap", "Secret":
+		if immutable, _, _ := unstructured.NestedBool(current, "immutable"); immutable {
+			for _, path := range []string{"data", "binaryData", "stringData"} {
+				check(path, fmt.Sprintf("%s is marked immutable", kind))
+			}
+		}
+	}
+
+	return changes
+}
+
+func podSpecChanges(current, edited map[string]interface{}) []ImmutableFieldChange {
+	curSpec, _, _ := unstructured.NestedMap(current, "spec")
+	nextSpec, found, _ := unstructured.NestedMap(edited, "spec")
+	if !found {
+		return nil
+	}
+
+	var changes []ImmutableFieldChange
+	for key, next := range nextSpec {
+		if podMutableSpecFields[key] {
+			continue
+		}
+		cur := curSpec[key]
+		if key == "containers" || key == "initContainers" {
+			// Only images may change; compare everything else.
+			cur, next = stripContainerImages(cur), stripContainerImages(next)
+		}
+		if !reflect.DeepEqual(cur, next) {
+			changes = append(changes, ImmutableFieldChange{
+				Path:    "spec." + key,
+				Current: curSpec[key],
+				Edited:  nextSpec[key],
+				Message: "pod spec fields other than images, tolerations and deadlines are immutable",
+			})
+		}
+	}
+	return changes
+}
+
+func stripContainerImages(v interface{}) interface{} {
+	list, ok := v.([]interface{})
+	if !ok {
+		return v
+	}
+	out := make([]interface{}, len(list))
+	for i, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			out[i] = item
+			continue
+		}
+		c := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			if k != "image" {
+				c[k] = val
+			}
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// CheckImmutableFields fetches the live object and reports the immutable
+// fields the edited version would change.
+func (c *Client) CheckImmutableFields(ref ResourceRef, edited map[string]interface{}) ([]ImmutableFieldChange, error) {
+	current, err := c.GetResource(ref.Group, ref.Version, ref.Kind, ref.Plural, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	obj, _ := current.(map[string]interface{})
+	return FindImmutableFieldChanges(obj, edited), nil
+}
+
+// RecreateResource deletes an object, waits for it to be gone and creates
+// the edited version. It's the escape hatch for changes to immutable
+// fields. Secret values blanked by the editor are taken from the live
+// object.
+func (c *Client) RecreateResource(ref ResourceRef, edited map[string]interface{}) (map[string]interface{}, error) {
+	obj := &unstructured.Unstructured{Object: edited}
+	live, err := c.getObject(ref)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if live != nil {
+		restoreRedactedSecret(obj.Object, live.Object)
+	}
+	obj.SetResourceVersion("")
+	obj.SetUID("")
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetManagedFields(nil)
+	obj.SetGeneration(0)
+	unstructured.RemoveNestedField(obj.Object, "status")
+	if ref.Namespace != "" {
+		obj.SetNamespace(ref.Namespace)
+	}
+
+	if err := c.DeleteResourceWithPropagation(ref, string(metav1.DeletePropagationForeground)); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	resource := c.DynamicClient.Resource(ref.GVR())
+	ctx, cancel := context.WithTimeout(context.Background(), recreateDeleteTimeout)
+	defer cancel()
+	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		var err error
+		if ref.Namespace != "" {
+			_, err = resource.Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		} else {
+			_, err = resource.Get(ctx, ref.Name, metav1.GetOptions{})
+		}
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("timed out waiting for %s/%s to be deleted", ref.Plural, ref.Name)
+	}
+
+	var created *unstructured.Unstructured
+	err = c.mutate(ActionCreate, ref, fmt.Sprintf("recreate %s/%s", ref.Plural, ref.Name), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			var err error
+			if ref.Namespace != "" {
+				created, err = resource.Namespace(ref.Namespace).Create(ctx, obj, metav1.CreateOptions{})
+			} else {
+				created, err = resource.Create(ctx, obj, metav1.CreateOptions{})
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("deleted %s/%s but failed to recreate it: %v", ref.Plural, ref.Name, err)
+	}
+	RedactSecret(created.Object)
+	return created.Object, nil
+}
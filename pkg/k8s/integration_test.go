@@ -0,0 +1,327 @@
+//go:build integration
+
+// Integration tests against a real kube-apiserver and etcd started by
+// envtest. Run them with
+//
+//	KUBEBUILDER_ASSETS=$(setup-envtest use -p path 1.35.x) go test -tags integration ./pkg/k8s
+//
+// Without KUBEBUILDER_ASSETS they are skipped.
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const integrationContext = "envtest"
+
+// integrationConfig is the REST config of the envtest cluster, nil when
+// KUBEBUILDER_ASSETS isn't set.
+var integrationConfig *rest.Config
+
+func TestMain(m *testing.M) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		os.Exit(m.Run())
+	}
+	env := &envtest.Environment{}
+	config, err := env.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start envtest: %v\n", err)
+		os.Exit(1)
+	}
+	integrationConfig = config
+	code := m.Run()
+	if err := env.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to stop envtest: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// integrationClient connects a Client to the envtest cluster the way the
+// app does, through a kubeconfig file, and creates a namespace for the
+// test.
+func integrationClient(t *testing.T) (*Client, string) {
+	t.Helper()
+	if integrationConfig == nil {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[integrationContext] = &clientcmdapi.Cluster{
+		Server:                   integrationConfig.Host,
+		CertificateAuthorityData: integrationConfig.CAData,
+	}
+	kubeconfig.AuthInfos[integrationContext] = &clientcmdapi.AuthInfo{
+		ClientCertificateData: integrationConfig.CertData,
+		ClientKeyData:         integrationConfig.KeyData,
+		Token:                 integrationConfig.BearerToken,
+	}
+	kubeconfig.Contexts[integrationContext] = &clientcmdapi.Context{Cluster: integrationContext, AuthInfo: integrationContext}
+	kubeconfig.CurrentContext = integrationContext
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*kubeconfig, path); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewK8sClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetKubeconfigPaths([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.subs.stopAll() })
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "teleskope-"}}
+	ns, err = c.Clientset.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ns.Name
+}
+
+func deploymentManifest(namespace, name string, replicas int, image string) string {
+	return fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[2]s
+  namespace: %[1]s
+spec:
+  replicas: %[3]d
+  selector:
+    matchLabels:
+      app: %[2]s
+  template:
+    metadata:
+      labels:
+        app: %[2]s
+    spec:
+      containers:
+      - name: %[2]s
+        image: %[4]s
+`, namespace, name, replicas, image)
+}
+
+func configMapManifest(namespace, name, value string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+  namespace: %s
+data:
+  key: %s
+`, name, namespace, value)
+}
+
+func TestIntegrationApply(t *testing.T) {
+	c, ns := integrationClient(t)
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{
+			name:     "create",
+			manifest: deploymentManifest(ns, "web", 1, "nginx:1.27") + "---\n" + configMapManifest(ns, "web", "a"),
+			want:     []string{ApplyCreated, ApplyCreated},
+		},
+		{
+			name:     "reapply",
+			manifest: deploymentManifest(ns, "web", 1, "nginx:1.27") + "---\n" + configMapManifest(ns, "web", "a"),
+			want:     []string{ApplyUnchanged, ApplyUnchanged},
+		},
+		{
+			name:     "change",
+			manifest: deploymentManifest(ns, "web", 1, "nginx:1.28") + "---\n" + configMapManifest(ns, "web", "a"),
+			want:     []string{ApplyConfigured, ApplyUnchanged},
+		},
+		{
+			name:     "invalid",
+			manifest: deploymentManifest(ns, "web", -1, "nginx:1.28"),
+			want:     []string{ApplyError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := c.ApplyManifest(tt.manifest, "", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(tt.want) {
+				t.Fatalf("got %d results, want %d: %+v", len(results), len(tt.want), results)
+			}
+			for i, r := range results {
+				if r.Status != tt.want[i] {
+					t.Errorf("document %d: status %q, want %q (error %q)", i, r.Status, tt.want[i], r.Error)
+				}
+			}
+		})
+	}
+
+	dryRun, err := c.ApplyManifest(configMapManifest(ns, "dry", "a"), "", true)
+	if err != nil || len(dryRun) != 1 || dryRun[0].Status != ApplyCreated {
+		t.Fatalf("dry run = %+v, %v", dryRun, err)
+	}
+	if _, err := c.GetResource("", "v1", "ConfigMap", "configmaps", ns, "dry"); !apierrors.IsNotFound(err) {
+		t.Errorf("dry-run apply created the ConfigMap: err = %v", err)
+	}
+}
+
+func TestIntegrationListAndGet(t *testing.T) {
+	c, ns := integrationClient(t)
+	manifest := deploymentManifest(ns, "web", 1, "nginx:1.27") + "---\n" + deploymentManifest(ns, "api", 2, "nginx:1.27")
+	if _, err := c.ApplyManifest(manifest, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		want     []string
+	}{
+		{name: "all", want: []string{"api", "web"}},
+		{name: "field selector", selector: NameFieldSelector("", "web"), want: []string{"web"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := c.ListResources("apps", "v1", "Deployment", "deployments", ns, "", tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := itemNames(items)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	obj, err := c.GetResource("apps", "v1", "Deployment", "deployments", ns, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(obj.(map[string]interface{}), "spec", "replicas"); replicas != 2 {
+		t.Errorf("api has %d replicas, want 2", replicas)
+	}
+	if _, err := c.GetResource("apps", "v1", "Deployment", "deployments", ns, "missing"); !apierrors.IsNotFound(err) {
+		t.Errorf("getting a missing deployment: err = %v, want NotFound", err)
+	}
+}
+
+func TestIntegrationScale(t *testing.T) {
+	c, ns := integrationClient(t)
+	if _, err := c.ApplyManifest(deploymentManifest(ns, "web", 1, "nginx:1.27"), "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ScaleResource("apps", "v1", "deployments", ns, "web", 4); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := c.GetResource("apps", "v1", "Deployment", "deployments", ns, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(obj.(map[string]interface{}), "spec", "replicas"); replicas != 4 {
+		t.Errorf("web has %d replicas after scaling, want 4", replicas)
+	}
+	if err := c.ScaleResource("apps", "v1", "deployments", ns, "missing", 1); err == nil {
+		t.Error("scaling a missing deployment succeeded")
+	}
+}
+
+func TestIntegrationDelete(t *testing.T) {
+	c, ns := integrationClient(t)
+	if _, err := c.ApplyManifest(configMapManifest(ns, "settings", "a"), "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.DeleteResource("", "v1", "ConfigMap", "configmaps", ns, "settings"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetResource("", "v1", "ConfigMap", "configmaps", ns, "settings"); !apierrors.IsNotFound(err) {
+		t.Errorf("deleted ConfigMap still readable: err = %v", err)
+	}
+	if err := c.DeleteResource("", "v1", "ConfigMap", "configmaps", ns, "settings"); !apierrors.IsNotFound(err) {
+		t.Errorf("deleting a missing ConfigMap: err = %v, want NotFound", err)
+	}
+}
+
+func TestIntegrationWatch(t *testing.T) {
+	c, ns := integrationClient(t)
+	if _, err := c.ApplyManifest(configMapManifest(ns, "first", "a"), "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan interface{}, 64)
+	c.Emit = func(name string, data interface{}) {
+		if name == EventResourcesSync || name == EventResourcesChange {
+			events <- data
+		}
+	}
+	id, err := c.WatchResources(WatchParams{Version: "v1", Plural: "configmaps", Namespace: ns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.StopSubscription(id)
+
+	sync, ok := (<-events).(ResourceSync)
+	if !ok || sync.SubscriptionID != id {
+		t.Fatalf("first event = %+v, want the sync of %s", sync, id)
+	}
+	// The namespace's kube-root-ca.crt ConfigMap isn't published without
+	// a controller manager, so only ours is listed.
+	if got := itemNames(sync.Items); fmt.Sprint(got) != "[first]" {
+		t.Fatalf("sync listed %v, want [first]", got)
+	}
+
+	if _, err := c.ApplyManifest(configMapManifest(ns, "second", "b"), "", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ApplyManifest(configMapManifest(ns, "first", "changed"), "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteResource("", "v1", "ConfigMap", "configmaps", ns, "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ADDED second", "MODIFIED first", "DELETED second"}
+	var got []string
+	timeout := time.After(10 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case ev := <-events:
+			if batch, ok := ev.(ResourceChanges); ok && batch.SubscriptionID == id {
+				for _, change := range batch.Changes {
+					name := (&unstructured.Unstructured{Object: change.Object.(map[string]interface{})}).GetName()
+					got = append(got, change.Type+" "+name)
+				}
+			}
+		case <-timeout:
+			t.Fatalf("got changes %v, want %v", got, want)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+
+	snapshot, err := c.GetWatchSnapshot(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := itemNames(snapshot.Items); fmt.Sprint(names) != "[first]" {
+		t.Errorf("snapshot lists %v, want [first]", names)
+	}
+}
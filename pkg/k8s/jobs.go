@@ -0,0 +1,247 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/ptr"
+)
+
+// jobControllerLabels are set by the Job controller on the pod template
+// and selector of every Job; a clone must not carry the old Job's values.
+var jobControllerLabels = []string{
+	"controller-uid",
+	"job-name",
+	batchv1.ControllerUidLabel,
+	batchv1.JobNameLabel,
+}
+
+// RetryJob re-runs a Job by creating a copy of it under a new name, with
+// server-populated metadata, status and the controller-generated selector
+// removed. Owner references are kept so a retried CronJob run still
+// belongs to its CronJob. It returns the name of the new Job.
+func (c *Client) RetryJob(namespace, name string) (string, error) {
+	var job *batchv1.Job
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		job, err = c.Clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	prefix := name
+	if len(prefix) > 50 {
+		prefix = prefix[:50]
+	}
+	clone := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            prefix + "-retry-" + utilrand.String(5),
+			Namespace:       namespace,
+			Labels:          job.Labels,
+			Annotations:     job.Annotations,
+			OwnerReferences: job.OwnerReferences,
+		},
+		Spec: *job.Spec.DeepCopy(),
+	}
+	for _, key := range jobControllerLabels {
+		delete(clone.Labels, key)
+		delete(clone.Spec.Template.Labels, key)
+	}
+	delete(clone.Annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	// A manual selector is the user's own and stays; the generated one
+	// points at the old Job's pods.
+	if !ptr.Deref(job.Spec.ManualSelector, false) {
+		clone.Spec.Selector = nil
+	}
+	// A suspended original would otherwise produce a suspended retry.
+	clone.Spec.Suspend = nil
+
+	ref := ResourceRef{Group: "batch", Version: "v1", Kind: "Job", Plural: "jobs", Namespace: namespace, Name: clone.Name}
+	err = c.mutate(ActionCreate, ref, "retry jobs/"+name, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.BatchV1().Jobs(namespace).Create(ctx, clone, metav1.CreateOptions{})
+			return err
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to retry job %s: %v", name, err)
+	}
+	return clone.Name, nil
+}
+
+type JobPodLogs struct {
+	Pod       string    `json:"pod"`
+	Container string    `json:"container"`
+	Phase     string    `json:"phase"`
+	Created   time.Time `json:"created"`
+	Logs      string    `json:"logs"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type JobLogs struct {
+	Namespace string       `json:"namespace"`
+	Job       string       `json:"job"`
+	Pods      []JobPodLogs `json:"pods"`
+}
+
+// GetJobLogs collects the logs of every pod a Job created, oldest pod
+// first, so the attempts of a retried Job read in the order they ran.
+// container defaults to the first container of each pod; tailLines limits
+// each pod's logs when positive. Pods whose logs can't be read are listed
+// with an error.
+func (c *Client) GetJobLogs(namespace, name, container string, tailLines int64) (*JobLogs, error) {
+	var job *batchv1.Job
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		job, err = c.Clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job.Spec.Selector == nil {
+		return nil, fmt.Errorf("job %s has no selector", name)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on job %s: %v", name, err)
+	}
+
+	var pods *corev1.PodList
+	err = c.do(OpList, func(ctx context.Context) error {
+		var err error
+		pods, err = c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		a, b := pods.Items[i].CreationTimestamp, pods.Items[j].CreationTimestamp
+		if !a.Equal(&b) {
+			return a.Before(&b)
+		}
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+
+	result := &JobLogs{Namespace: namespace, Job: name, Pods: []JobPodLogs{}}
+	for _, pod := range pods.Items {
+		entry := JobPodLogs{
+			Pod:       pod.Name,
+			Container: container,
+			Phase:     string(pod.Status.Phase),
+			Created:   pod.CreationTimestamp.Time,
+		}
+		if entry.Container == "" && len(pod.Spec.Containers) > 0 {
+			entry.Container = pod.Spec.Containers[0].Name
+		}
+		logOpts := &corev1.PodLogOptions{Container: entry.Container}
+		if tailLines > 0 {
+			logOpts.TailLines = &tailLines
+		}
+		err := c.do(OpGet, func(ctx context.Context) error {
+			data, err := c.Clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, logOpts).DoRaw(ctx)
+			entry.Logs = string(data)
+			return err
+		})
+		if err != nil {
+			entry.Logs = ""
+			entry.Error = err.Error()
+		}
+		result.Pods = append(result.Pods, entry)
+	}
+	return result, nil
+}
+
+type JobCleanupOptions struct {
+	// OlderThanSeconds is how long ago a Job must have finished.
+	OlderThanSeconds int64 `json:"older_than_seconds"`
+	// IncludeFailed also deletes failed Jobs; by default only succeeded
+	// ones are.
+	IncludeFailed bool `json:"include_failed"`
+	// DryRun only reports what would be deleted.
+	DryRun bool `json:"dry_run"`
+}
+
+type JobCleanupResult struct {
+	Deleted []string          `json:"deleted"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// CleanupJobs deletes the finished Jobs of a namespace that completed more
+// than opts.OlderThanSeconds ago, together with their pods. Jobs still
+// running are never touched.
+func (c *Client) CleanupJobs(namespace string, opts JobCleanupOptions) (*JobCleanupResult, error) {
+	if opts.OlderThanSeconds <= 0 {
+		return nil, fmt.Errorf("older-than duration must be positive")
+	}
+	var jobs *batchv1.JobList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		jobs, err = c.Clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-time.Duration(opts.OlderThanSeconds) * time.Second)
+	result := &JobCleanupResult{Deleted: []string{}}
+	for _, job := range jobs.Items {
+		finished, failed := jobFinished(&job)
+		if finished.IsZero() || finished.After(cutoff) || (failed && !opts.IncludeFailed) {
+			continue
+		}
+		key := job.Namespace + "/" + job.Name
+		if opts.DryRun {
+			result.Deleted = append(result.Deleted, key)
+			continue
+		}
+		ref := ResourceRef{Group: "batch", Version: "v1", Kind: "Job", Plural: "jobs", Namespace: job.Namespace, Name: job.Name}
+		err := c.mutate(ActionDelete, ref, "delete jobs/"+job.Name, func() error {
+			return c.do(OpMutate, func(ctx context.Context) error {
+				return c.Clientset.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, metav1.DeleteOptions{
+					PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+				})
+			})
+		})
+		if err != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]string)
+			}
+			result.Errors[key] = err.Error()
+			continue
+		}
+		result.Deleted = append(result.Deleted, key)
+	}
+	sort.Strings(result.Deleted)
+	return result, nil
+}
+
+// jobFinished returns when a Job completed or failed, or the zero time if
+// it hasn't finished.
+func jobFinished(job *batchv1.Job) (time.Time, bool) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			if job.Status.CompletionTime != nil {
+				return job.Status.CompletionTime.Time, false
+			}
+			return cond.LastTransitionTime.Time, false
+		case batchv1.JobFailed:
+			return cond.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
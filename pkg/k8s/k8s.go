@@ -3,34 +3,84 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"os"
+	"net/http"
 	"os/exec"
-	"path/filepath"
 	"sort"
-	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 type Client struct {
 	Config          clientcmd.ClientConfig
-	Clientset       *kubernetes.Clientset
+	RestConfig      *rest.Config
+	Clientset       kubernetes.Interface
 	DynamicClient   dynamic.Interface
-	DiscoveryClient *discovery.DiscoveryClient
+	DiscoveryClient discovery.DiscoveryInterface
+
+	// Emit forwards backend events to the frontend. It is wired to the
+	// Wails runtime by the App and may be nil.
+	Emit func(name string, data interface{})
+
+	subs      subscriptions
+	removed   removedResources
+	policies  policyStore
+	forwards  *PortForwardManager
+	terminals terminalSessions
+	access    accessCache
+	badges    badgeBoard
+	ops       operationQueue
+	scopes    resourceScopes
+	limiters  requestLimiters
+	search    searchCache
+	// snapshots holds the current list of every resource watch.
+	snapshots watchSnapshots
+	// discovered caches API discovery for groups that become unavailable.
+	discovered discoveredGroups
+	// execCreds caches kubeconfig exec plugin credentials per context.
+	execCreds execCredentials
+	reauth    reauthNotices
+	tunnels   sshTunnels
+	// tokenCerts configures client certificates held on PKCS#11 tokens.
+	tokenCerts tokenCertificates
+	// tunnel is the SSH connection of the active context, if it uses one.
+	tunnel *sshDialer
+
+	// demo is set while the built-in demo context is active.
+	demo bool
+	// contextName is the context selected with SetContext, overriding the
+	// kubeconfig's current-context.
+	contextName string
+	// kubeconfigPaths are the kubeconfig files chosen in the UI; empty
+	// means KUBECONFIG or ~/.kube/config.
+	kubeconfigPaths []string
+}
+
+func (c *Client) emit(name string, data interface{}) {
+	if c.Emit != nil {
+		c.Emit(name, data)
+	}
 }
 
 type KubeContext struct {
-	Name      string `json:"name"`
-	Cluster   string `json:"cluster"`
-	User      string `json:"user"`
-	Namespace string `json:"namespace"`
-	IsCurrent bool   `json:"is_current"`
+	Name      string            `json:"name"`
+	Cluster   string            `json:"cluster"`
+	User      string            `json:"user"`
+	Namespace string            `json:"namespace"`
+	IsCurrent bool              `json:"is_current"`
+	Tags      map[string]string `json:"tags"`
+
+	ClientCertificate *CertificateInfo `json:"client_certificate,omitempty"`
+	// Teleport is set for contexts whose credentials come from tsh.
+	Teleport *TeleportInfo `json:"teleport,omitempty"`
 }
 
 type ApiResourceInfo struct {
@@ -42,28 +92,93 @@ type ApiResourceInfo struct {
 	Verbs      []string `json:"verbs"`
 	ShortNames []string `json:"short_names"`
 	Category   string   `json:"category"`
+	// Stale is set when the group is unavailable and the resource comes
+	// from an earlier discovery, done at DiscoveredAt.
+	Stale        bool      `json:"stale,omitempty"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+}
+
+// ResourceRef identifies a single object by GVR and name.
+type ResourceRef struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Plural    string `json:"plural"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
 }
 
+func (r ResourceRef) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Plural}
+}
+
+// NewK8sClient reads the kubeconfig files from KUBECONFIG (a path list)
+// or ~/.kube/config. Without any, inside a pod, it uses the pod's service
+// account.
 func NewK8sClient() (*Client, error) {
-	kubeconfig := os.Getenv("KUBECONFIG")
-	if kubeconfig == "" {
-		home, _ := os.UserHomeDir()
-		kubeconfig = filepath.Join(home, ".kube", "config")
-	}
+	c := &Client{}
+	c.Config = c.clientConfig("")
+	if !c.hasKubeconfig() && inClusterAvailable() {
+		c.contextName = InClusterContext
+		c.Config = c.clientConfig(InClusterContext)
+	}
+	c.forwards = newPortForwardManager(c)
+	return c, nil
+}
 
-	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
-		&clientcmd.ConfigOverrides{},
-	)
+// restConfigFor builds the REST config of a context: exec plugin
+// credentials from the cache, its SSH tunnel and token certificate, and
+// the request limiter. The caller owns the returned tunnel, if any.
+func (c *Client) restConfigFor(config clientcmd.ClientConfig, contextName string) (*rest.Config, *sshDialer, error) {
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if restConfig.ExecProvider != nil {
+		if err := c.useCachedExecProvider(restConfig, contextName); err != nil {
+			return nil, nil, err
+		}
+	}
 
-	return &Client{Config: config}, nil
+	var dialer *sshDialer
+	if tunnel, ok := c.sshTunnelFor(contextName); ok {
+		dialer, err = newSSHDialer(tunnel)
+		if err != nil {
+			return nil, nil, err
+		}
+		restConfig.Proxy = http.ProxyURL(dialer.proxy.url)
+	}
+	if cert, pin, ok := c.tokenCertificateFor(contextName); ok {
+		if err := c.useTokenCertificate(restConfig, contextName, cert, pin); err != nil {
+			if dialer != nil {
+				dialer.Close()
+			}
+			return nil, nil, err
+		}
+	}
+	limiter := c.limiters.forContext(contextName)
+	restConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		rt = &unauthorizedRoundTripper{client: c, contextName: contextName, next: rt}
+		return &limitedRoundTripper{limiter: limiter, next: rt}
+	})
+	return restConfig, dialer, nil
 }
 
 func (c *Client) Init() error {
-	restConfig, err := c.Config.ClientConfig()
+	if c.demo {
+		return nil
+	}
+
+	current, err := c.GetCurrentContext()
+	if err != nil {
+		return err
+	}
+	c.closeTunnel()
+	restConfig, tunnel, err := c.restConfigFor(c.Config, current)
 	if err != nil {
 		return err
 	}
+	c.tunnel = tunnel
 
 	clientset, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
@@ -80,53 +195,87 @@ func (c *Client) Init() error {
 		return err
 	}
 
+	c.RestConfig = restConfig
 	c.Clientset = clientset
 	c.DynamicClient = dynamicClient
 	c.DiscoveryClient = discoveryClient
+	c.scopes.reset()
+	c.search.reset()
+	c.discovered.reset()
 
 	return nil
 }
 
 func (c *Client) GetContexts() ([]KubeContext, error) {
-	rawConfig, err := c.Config.RawConfig()
+	rawConfig, err := c.clientConfig("").RawConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	current := rawConfig.CurrentContext
+	if c.contextName != "" {
+		current = c.contextName
+	}
+
+	now := time.Now()
 	var contexts []KubeContext
 	for name, ctx := range rawConfig.Contexts {
-		contexts = append(contexts, KubeContext{
-			Name:      name,
-			Cluster:   ctx.Cluster,
-			User:      ctx.AuthInfo,
-			Namespace: ctx.Namespace,
-			IsCurrent: name == rawConfig.CurrentContext,
-		})
+		kc := KubeContext{
+			Name:              name,
+			Cluster:           ctx.Cluster,
+			User:              ctx.AuthInfo,
+			Namespace:         ctx.Namespace,
+			IsCurrent:         !c.demo && name == current,
+			ClientCertificate: clientCertificateInfo(rawConfig.AuthInfos[ctx.AuthInfo], now),
+		}
+		if auth := rawConfig.AuthInfos[ctx.AuthInfo]; auth != nil {
+			kc.Teleport = teleportExecInfo(auth.Exec)
+		}
+		contexts = append(contexts, kc)
 	}
 
 	sort.Slice(contexts, func(i, j int) bool {
 		return contexts[i].Name < contexts[j].Name
 	})
 
+	if inClusterAvailable() {
+		ns, _, _ := inClusterConfig{}.Namespace()
+		contexts = append(contexts, KubeContext{
+			Name:      InClusterContext,
+			Cluster:   InClusterContext,
+			User:      "serviceaccount",
+			Namespace: ns,
+			IsCurrent: !c.demo && current == InClusterContext,
+		})
+	}
+	contexts = append(contexts, demoKubeContext(c.demo))
 	return contexts, nil
 }
 
 func (c *Client) SetContext(name string) error {
-	kubeconfig := os.Getenv("KUBECONFIG")
-	if kubeconfig == "" {
-		home, _ := os.UserHomeDir()
-		kubeconfig = filepath.Join(home, ".kube", "config")
-	}
+	// Streams bound to the previous cluster are meaningless now.
+	c.subs.stopAll()
+	c.forwards.StopAll()
+	c.access.clear()
 
-	c.Config = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
-		&clientcmd.ConfigOverrides{CurrentContext: name},
-	)
+	if name == DemoContext {
+		c.useDemo()
+		return nil
+	}
+	c.demo = false
+	c.contextName = name
+	c.Config = c.clientConfig(name)
 
 	return c.Init()
 }
 
 func (c *Client) GetCurrentContext() (string, error) {
+	if c.demo {
+		return DemoContext, nil
+	}
+	if c.contextName != "" {
+		return c.contextName, nil
+	}
 	rawConfig, err := c.Config.RawConfig()
 	if err != nil {
 		return "", err
@@ -134,26 +283,33 @@ func (c *Client) GetCurrentContext() (string, error) {
 	return rawConfig.CurrentContext, nil
 }
 
+// GetApiResources lists the listable resource types of the cluster. Groups
+// that fail discovery don't fail the call: their resources are served from
+// the last discovery that reached them, marked Stale, or left out, and
+// GetDiscoveryStatus tells which.
 func (c *Client) GetApiResources() ([]ApiResourceInfo, error) {
-	_, resources, err := c.DiscoveryClient.ServerGroupsAndResources()
+	resources, err := c.discoverResources()
 	if err != nil {
 		return nil, err
 	}
 
 	var infos []ApiResourceInfo
-	for _, resList := range resources {
+	for _, discovered := range resources {
+		resList := discovered.list
 		gv, _ := schema.ParseGroupVersion(resList.GroupVersion)
 		for _, res := range resList.APIResources {
 			if contains(res.Verbs, "list") {
 				infos = append(infos, ApiResourceInfo{
-					Group:      gv.Group,
-					Version:    gv.Version,
-					Kind:       res.Kind,
-					Name:       res.Name,
-					Namespaced: res.Namespaced,
-					Verbs:      res.Verbs,
-					ShortNames: res.ShortNames,
-					Category:   CategorizeResource(gv.Group, res.Kind),
+					Group:        gv.Group,
+					Version:      gv.Version,
+					Kind:         res.Kind,
+					Name:         res.Name,
+					Namespaced:   res.Namespaced,
+					Verbs:        res.Verbs,
+					ShortNames:   res.ShortNames,
+					Category:     CategorizeResource(gv.Group, res.Kind),
+					Stale:        discovered.stale,
+					DiscoveredAt: discovered.at,
 				})
 			}
 		}
@@ -169,6 +325,21 @@ func (c *Client) GetApiResources() ([]ApiResourceInfo, error) {
 	return infos, nil
 }
 
+// RefreshDiscovery drops any cached discovery information and re-reads
+// the API resources, returning how many listable types the server has.
+// New CRDs show up after this without reconnecting.
+func (c *Client) RefreshDiscovery() (int, error) {
+	if cached, ok := c.DiscoveryClient.(discovery.CachedDiscoveryInterface); ok {
+		cached.Invalidate()
+	}
+	c.scopes.reset()
+	resources, err := c.GetApiResources()
+	if err != nil {
+		return 0, err
+	}
+	return len(resources), nil
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -201,29 +372,42 @@ func CategorizeResource(group, kind string) string {
 	return "Other"
 }
 
-func (c *Client) ListResources(group, version, kind, plural, namespace, labelSelector string) ([]interface{}, error) {
+func (c *Client) ListResources(group, version, kind, plural, namespace, labelSelector, fieldSelector string) ([]interface{}, error) {
 	gv := schema.GroupVersionResource{
 		Group:    group,
 		Version:  version,
 		Resource: plural,
 	}
 
+	if err := c.checkScope(gv, namespace); err != nil {
+		return nil, err
+	}
+
 	var list *unstructured.UnstructuredList
-	var err error
 
 	opts := metav1.ListOptions{
 		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
 	}
 
-	if namespace != "" {
-		list, err = c.DynamicClient.Resource(gv).Namespace(namespace).List(context.TODO(), opts)
-	} else {
-		list, err = c.DynamicClient.Resource(gv).List(context.TODO(), opts)
-	}
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if namespace != "" {
+			list, err = c.DynamicClient.Resource(gv).Namespace(namespace).List(ctx, opts)
+		} else {
+			list, err = c.DynamicClient.Resource(gv).List(ctx, opts)
+		}
+		return err
+	})
 
 	if err != nil {
+		if isResourceGone(err) {
+			go c.resourceGone(gv)
+			return nil, fmt.Errorf("resource type %s is no longer served by the cluster", gv.String())
+		}
 		return nil, err
 	}
+	c.removed.clear(gv)
 
 	var result []interface{}
 	for _, item := range list.Items {
@@ -240,14 +424,21 @@ func (c *Client) GetResource(group, version, kind, plural, namespace, name strin
 		Resource: plural,
 	}
 
+	if err := c.checkScope(gv, namespace); err != nil {
+		return nil, err
+	}
+
 	var res *unstructured.Unstructured
-	var err error
 
-	if namespace != "" {
-		res, err = c.DynamicClient.Resource(gv).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
-	} else {
-		res, err = c.DynamicClient.Resource(gv).Get(context.TODO(), name, metav1.GetOptions{})
-	}
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		if namespace != "" {
+			res, err = c.DynamicClient.Resource(gv).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		} else {
+			res, err = c.DynamicClient.Resource(gv).Get(ctx, name, metav1.GetOptions{})
+		}
+		return err
+	})
 
 	if err != nil {
 		return nil, err
@@ -257,7 +448,12 @@ func (c *Client) GetResource(group, version, kind, plural, namespace, name strin
 }
 
 func (c *Client) GetNamespaces() ([]string, error) {
-	list, err := c.Clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	var list *corev1.NamespaceList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -271,162 +467,78 @@ func (c *Client) GetNamespaces() ([]string, error) {
 }
 
 func (c *Client) ExecPod(namespace, podName, containerName string) error {
-	term, args := findTerminal()
-	if term == "" {
-		return fmt.Errorf("no terminal emulator found")
-	}
-
-	currentContext, _ := c.GetCurrentContext()
-	kCmd := fmt.Sprintf("kubectl exec -it %s -n %s", podName, namespace)
-	if currentContext != "" {
-		kCmd = fmt.Sprintf("kubectl --context %s exec -it %s -n %s", currentContext, podName, namespace)
-	}
-
+	args := []string{"exec", "-it", podName, "--namespace", namespace}
 	if containerName != "" {
-		kCmd = fmt.Sprintf("%s -c %s", kCmd, containerName)
+		args = append(args, "--container", containerName)
 	}
-	kCmd = kCmd + " -- sh -c 'command -v bash >/dev/null && exec bash || exec sh'"
-
-	fmt.Printf("Executing Pod: %s in terminal %s\n", kCmd, term)
+	args = append(args, "--")
+	args = append(args, c.shellFor(namespace, podName)...)
 
-	// Open in a shell within the terminal to handle the command better
-	shCmd := fmt.Sprintf("%s", kCmd)
-	fullArgs := append(args, shCmd)
-	cmd := exec.Command(term, fullArgs...)
-	return cmd.Start()
+	argv, err := c.kubectlCommand(args...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Executing Pod: %q\n", argv)
+	return runInTerminal(argv)
 }
 
 func (c *Client) EditResource(group, version, kind, plural, namespace, name string) error {
-	term, args := findTerminal()
-	if term == "" {
-		return fmt.Errorf("no terminal emulator found")
-	}
-
-	currentContext, _ := c.GetCurrentContext()
-	kCmd := fmt.Sprintf("kubectl edit %s/%s -n %s", plural, name, namespace)
-	if namespace == "" {
-		kCmd = fmt.Sprintf("kubectl edit %s/%s", plural, name)
+	target := plural + "/" + name
+	if group != "" {
+		target = plural + "." + version + "." + group + "/" + name
 	}
-	if currentContext != "" {
-		if namespace != "" {
-			kCmd = fmt.Sprintf("kubectl --context %s edit %s/%s -n %s", currentContext, plural, name, namespace)
-		} else {
-			kCmd = fmt.Sprintf("kubectl --context %s edit %s/%s", currentContext, plural, name)
-		}
+	args := []string{"edit", target}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
 	}
 
-	fmt.Printf("Editing resource: %s in terminal %s\n", kCmd, term)
-
-	fullArgs := append(args, kCmd)
-	cmd := exec.Command(term, fullArgs...)
-	return cmd.Start()
-}
-
-func (c *Client) GetRelatedResources(group, version, kind, namespace, name string) ([]interface{}, error) {
-	// Simple implementation:
-	// Deployment -> Pods (via selector)
-	// CronJob -> Jobs
-	// Job -> Pods
-
-	// We'll use a switch on Kind instead
-
-	switch kind {
-	case "Deployment", "ReplicaSet", "StatefulSet", "DaemonSet":
-		plural := strings.ToLower(kind) + "s"
-		if kind == "Ingress" { // This case is not hit by the current switch, but kept as per instruction
-			plural = "ingresses"
-		}
-		// For dynamic client we need the plural name.
-		// Since we don't have a full mapping here, we'll try a common one
-		// but a better way is to pass it from the frontend or have a lookup map.
-
-		res, err := c.GetResource(group, version, kind, plural, namespace, name)
-		if err != nil {
-			fmt.Printf("Error getting parent resource: %v\n", err)
-			return nil, err
-		}
-		obj := res.(map[string]interface{})
-		spec, ok := obj["spec"].(map[string]interface{})
-		if !ok {
-			return nil, nil
-		}
-		selector, ok := spec["selector"].(map[string]interface{})
-		if !ok {
-			return nil, nil
-		}
-		matchLabels, ok := selector["matchLabels"].(map[string]interface{})
-		if !ok {
-			return nil, nil
-		}
-
-		var labelSelectors []string
-		for k, v := range matchLabels {
-			labelSelectors = append(labelSelectors, fmt.Sprintf("%s=%v", k, v))
-		}
-
-		var selectorStr string
-		for i, s := range labelSelectors {
-			if i > 0 {
-				selectorStr += ","
-			}
-			selectorStr += s
-		}
-
-		return c.ListResources("", "v1", "Pod", "pods", namespace, selectorStr)
+	argv, err := c.kubectlCommand(args...)
+	if err != nil {
+		return err
 	}
-
-	return nil, nil
+	fmt.Printf("Editing resource: %q\n", argv)
+	return runInTerminal(argv)
 }
 
 func (c *Client) DeleteResource(group, version, kind, plural, namespace, name string) error {
-	gv := schema.GroupVersionResource{
-		Group:    group,
-		Version:  version,
-		Resource: plural,
-	}
-
+	ref := ResourceRef{Group: group, Version: version, Kind: kind, Plural: plural, Namespace: namespace, Name: name}
 	opts := metav1.DeleteOptions{}
 
-	if namespace != "" {
-		return c.DynamicClient.Resource(gv).Namespace(namespace).Delete(context.TODO(), name, opts)
-	}
-	return c.DynamicClient.Resource(gv).Delete(context.TODO(), name, opts)
+	return c.mutate(ActionDelete, ref, "delete "+plural+"/"+name, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			if namespace != "" {
+				return c.DynamicClient.Resource(ref.GVR()).Namespace(namespace).Delete(ctx, name, opts)
+			}
+			return c.DynamicClient.Resource(ref.GVR()).Delete(ctx, name, opts)
+		})
+	})
 }
 
 func (c *Client) GetPodLogs(namespace, podName, containerName string, follow bool, tailLines int64) (string, error) {
-	currentContext, _ := c.GetCurrentContext()
-
-	kCmd := fmt.Sprintf("kubectl logs %s -n %s", podName, namespace)
+	args := []string{"logs", podName, "--namespace", namespace}
 	if containerName != "" {
-		kCmd = fmt.Sprintf("%s -c %s", kCmd, containerName)
+		args = append(args, "--container", containerName)
 	}
 	if follow {
-		kCmd = fmt.Sprintf("%s -f", kCmd)
+		args = append(args, "--follow")
 	}
 	if tailLines > 0 {
-		kCmd = fmt.Sprintf("%s --tail=%d", kCmd, tailLines)
+		args = append(args, fmt.Sprintf("--tail=%d", tailLines))
 	}
-	if currentContext != "" {
-		kCmd = fmt.Sprintf("kubectl --context %s %s", currentContext, strings.TrimPrefix(kCmd, "kubectl "))
+
+	argv, err := c.kubectlCommand(args...)
+	if err != nil {
+		return "", err
 	}
 
 	if follow {
 		// For follow mode, open in terminal
-		term, args := findTerminal()
-		if term == "" {
-			return "", fmt.Errorf("no terminal emulator found")
-		}
-
-		fmt.Printf("Following logs: %s in terminal %s\n", kCmd, term)
-
-		shCmd := fmt.Sprintf("%s", kCmd)
-		fullArgs := append(args, shCmd)
-		cmd := exec.Command(term, fullArgs...)
-		return "", cmd.Start()
+		fmt.Printf("Following logs: %q\n", argv)
+		return "", runInTerminal(argv)
 	}
 
 	// For non-follow mode, get logs directly
-	cmd := exec.Command("sh", "-c", kCmd)
+	cmd := exec.Command(argv[0], argv[1:]...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get logs: %v", err)
@@ -434,24 +546,3 @@ func (c *Client) GetPodLogs(namespace, podName, containerName string, follow boo
 
 	return string(output), nil
 }
-
-func findTerminal() (string, []string) {
-	terminals := []struct {
-		name string
-		args []string
-	}{
-		{"alacritty", []string{"-e", "sh", "-c"}},
-		{"kitty", []string{"sh", "-c"}},
-		{"konsole", []string{"-e", "sh", "-c"}},
-		{"gnome-terminal", []string{"--", "sh", "-c"}},
-		{"xfce4-terminal", []string{"-e", "sh", "-c"}},
-		{"xterm", []string{"-e", "sh", "-c"}},
-	}
-	for _, t := range terminals {
-		path, err := exec.LookPath(t.name)
-		if err == nil {
-			return path, t.args
-		}
-	}
-	return "", nil
-}
@@ -0,0 +1,158 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// InClusterContext is the pseudo-context for the service account the app
+// runs as when it is started inside a pod.
+const InClusterContext = "in-cluster"
+
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// loadingRules returns where kubeconfigs are read from: the paths set with
+// SetKubeconfigPaths, else the KUBECONFIG list, else ~/.kube/config.
+// Several files are merged the way kubectl merges them.
+func (c *Client) loadingRules() *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if len(c.kubeconfigPaths) > 0 {
+		rules.Precedence = c.kubeconfigPaths
+	}
+	return rules
+}
+
+// clientConfig builds the client config for a context; an empty name
+// uses the kubeconfig's current-context.
+func (c *Client) clientConfig(name string) clientcmd.ClientConfig {
+	if name == InClusterContext {
+		return inClusterConfig{}
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(c.loadingRules(), &clientcmd.ConfigOverrides{CurrentContext: name})
+}
+
+// KubeconfigPaths returns the kubeconfig files currently merged.
+func (c *Client) KubeconfigPaths() []string {
+	return c.loadingRules().Precedence
+}
+
+// UseKubeconfigPaths replaces the merged kubeconfig files, e.g. with
+// files picked in the UI; nil goes back to KUBECONFIG or ~/.kube/config.
+// It doesn't connect; call Init or SetContext afterwards.
+func (c *Client) UseKubeconfigPaths(paths []string) error {
+	var cleaned []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "~/") {
+			home, _ := os.UserHomeDir()
+			p = filepath.Join(home, p[2:])
+		}
+		if _, err := os.Stat(p); err != nil {
+			return fmt.Errorf("kubeconfig %s: %v", p, err)
+		}
+		cleaned = append(cleaned, p)
+	}
+
+	previous := c.kubeconfigPaths
+	c.kubeconfigPaths = cleaned
+	if _, err := c.clientConfig("").RawConfig(); err != nil {
+		c.kubeconfigPaths = previous
+		return fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	if c.contextName == InClusterContext && c.hasKubeconfig() {
+		c.contextName = ""
+	}
+	if c.contextName != InClusterContext {
+		c.Config = c.clientConfig(c.contextName)
+	}
+	return nil
+}
+
+// SetKubeconfigPaths switches to other kubeconfig files and reconnects.
+// The active context is kept if the new files still define it, otherwise
+// their current-context is used.
+func (c *Client) SetKubeconfigPaths(paths []string) error {
+	current, _ := c.GetCurrentContext()
+	if err := c.UseKubeconfigPaths(paths); err != nil {
+		return err
+	}
+	if current == DemoContext || c.contextName == InClusterContext {
+		return nil
+	}
+	raw, err := c.clientConfig("").RawConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := raw.Contexts[current]; !ok {
+		current = raw.CurrentContext
+	}
+	return c.SetContext(current)
+}
+
+// inClusterAvailable reports whether the app runs in a pod with a
+// mounted service account.
+func inClusterAvailable() bool {
+	_, err := rest.InClusterConfig()
+	return err == nil
+}
+
+// hasKubeconfig reports whether any of the kubeconfig files exists.
+func (c *Client) hasKubeconfig() bool {
+	for _, p := range c.loadingRules().Precedence {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// inClusterConfig is a clientcmd.ClientConfig for the pod's service
+// account, presented as a single InClusterContext.
+type inClusterConfig struct{}
+
+func (inClusterConfig) RawConfig() (clientcmdapi.Config, error) {
+	config := clientcmdapi.NewConfig()
+	config.CurrentContext = InClusterContext
+	rc, err := rest.InClusterConfig()
+	if err != nil {
+		return *config, err
+	}
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = rc.Host
+	cluster.CertificateAuthority = rc.TLSClientConfig.CAFile
+	config.Clusters[InClusterContext] = cluster
+	auth := clientcmdapi.NewAuthInfo()
+	auth.TokenFile = rc.BearerTokenFile
+	config.AuthInfos[InClusterContext] = auth
+	ctx := clientcmdapi.NewContext()
+	ctx.Cluster = InClusterContext
+	ctx.AuthInfo = InClusterContext
+	ctx.Namespace, _, _ = inClusterConfig{}.Namespace()
+	config.Contexts[InClusterContext] = ctx
+	return *config, nil
+}
+
+func (inClusterConfig) ClientConfig() (*rest.Config, error) {
+	return rest.InClusterConfig()
+}
+
+func (inClusterConfig) Namespace() (string, bool, error) {
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "default", false, nil
+	}
+	return strings.TrimSpace(string(data)), false, nil
+}
+
+func (inClusterConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return clientcmd.NewDefaultClientConfigLoadingRules()
+}
@@ -0,0 +1,296 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubeconfigBackupSuffix names the copy kept of a kubeconfig file as it
+// was before the app first edited it.
+const kubeconfigBackupSuffix = ".teleskope.bak"
+
+// kubeconfigEdits serializes edits within the process; they toggle
+// clientcmd.UseModifyConfigLock while holding the file locks themselves.
+var kubeconfigEdits sync.Mutex
+
+// editKubeconfig applies fn to the merged kubeconfig and writes the
+// changes back to the files they belong to, new entries going to the
+// first file. The files are locked the way kubectl locks them for the
+// whole read-modify-write, so a concurrent kubectl edit is neither lost
+// nor overwritten. Each file is backed up before its first edit.
+func (c *Client) editKubeconfig(fn func(config *clientcmdapi.Config) error) error {
+	kubeconfigEdits.Lock()
+	defer kubeconfigEdits.Unlock()
+
+	rules := c.loadingRules()
+	files := rules.GetLoadingPrecedence()
+	unlock, err := lockKubeconfigs(files)
+	if err != nil {
+		return fmt.Errorf("failed to lock kubeconfig: %v", err)
+	}
+	defer unlock()
+
+	config, err := rules.GetStartingConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	if err := fn(config); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := backupFile(file); err != nil {
+			return fmt.Errorf("failed to back up %s: %v", file, err)
+		}
+	}
+	// The files are already locked; ModifyConfig would fail taking the
+	// same locks again.
+	clientcmd.UseModifyConfigLock = false
+	err = clientcmd.ModifyConfig(rules, *config, false)
+	clientcmd.UseModifyConfigLock = true
+	if err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %v", err)
+	}
+	// The deferred config caches what it loaded.
+	if c.contextName != InClusterContext && !c.demo {
+		c.Config = c.clientConfig(c.contextName)
+	}
+	return nil
+}
+
+// lockKubeconfigs takes kubectl's lock files (<file>.lock) of the given
+// kubeconfig files, in sorted order like kubectl to avoid deadlocks, and
+// returns the function releasing them.
+func lockKubeconfigs(files []string) (func(), error) {
+	sorted := append([]string{}, files...)
+	sort.Strings(sorted)
+	var locked []string
+	unlock := func() {
+		for _, file := range locked {
+			_ = os.Remove(file + ".lock")
+		}
+	}
+	for _, file := range sorted {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			unlock()
+			return nil, err
+		}
+		f, err := os.OpenFile(file+".lock", os.O_CREATE|os.O_EXCL, 0)
+		if err != nil {
+			unlock()
+			if os.IsExist(err) {
+				return nil, fmt.Errorf("%s is being edited by another program (remove %s.lock if it isn't)", file, file)
+			}
+			return nil, err
+		}
+		f.Close()
+		locked = append(locked, file)
+	}
+	return unlock, nil
+}
+
+// backupFile copies a kubeconfig file next to itself unless a backup
+// already exists, so the backup keeps the state from before the first
+// edit rather than the one before the last.
+func backupFile(file string) error {
+	backup := file + kubeconfigBackupSuffix
+	if _, err := os.Stat(backup); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(backup, data, 0o600)
+}
+
+// checkEditableContext rejects the pseudo-contexts that aren't in any
+// kubeconfig file.
+func checkEditableContext(name string) error {
+	if name == DemoContext || name == InClusterContext {
+		return fmt.Errorf("context %s isn't stored in a kubeconfig", name)
+	}
+	return nil
+}
+
+// AddKubeconfig merges a pasted kubeconfig into the default kubeconfig
+// file and returns the names of the contexts it added. Clusters, users or
+// contexts that already exist under the same name are only replaced with
+// overwrite.
+func (c *Client) AddKubeconfig(snippet string, overwrite bool) ([]string, error) {
+	incoming, err := clientcmd.Load([]byte(snippet))
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+	if len(incoming.Contexts) == 0 {
+		return nil, fmt.Errorf("the kubeconfig defines no contexts")
+	}
+	var added []string
+	err = c.editKubeconfig(func(config *clientcmdapi.Config) error {
+		var conflicts []string
+		for name := range incoming.Clusters {
+			if _, ok := config.Clusters[name]; ok {
+				conflicts = append(conflicts, "cluster "+name)
+			}
+		}
+		for name := range incoming.AuthInfos {
+			if _, ok := config.AuthInfos[name]; ok {
+				conflicts = append(conflicts, "user "+name)
+			}
+		}
+		for name := range incoming.Contexts {
+			if err := checkEditableContext(name); err != nil {
+				return err
+			}
+			if _, ok := config.Contexts[name]; ok {
+				conflicts = append(conflicts, "context "+name)
+			}
+		}
+		if len(conflicts) > 0 && !overwrite {
+			sort.Strings(conflicts)
+			return fmt.Errorf("already defined: %s", strings.Join(conflicts, ", "))
+		}
+
+		// Existing entries are rewritten in their own file; new ones, with
+		// no origin, go to the default file.
+		for name, cluster := range incoming.Clusters {
+			if existing, ok := config.Clusters[name]; ok {
+				cluster.LocationOfOrigin = existing.LocationOfOrigin
+			}
+			config.Clusters[name] = cluster
+		}
+		for name, auth := range incoming.AuthInfos {
+			if existing, ok := config.AuthInfos[name]; ok {
+				auth.LocationOfOrigin = existing.LocationOfOrigin
+			}
+			config.AuthInfos[name] = auth
+		}
+		for name, ctx := range incoming.Contexts {
+			if existing, ok := config.Contexts[name]; ok {
+				ctx.LocationOfOrigin = existing.LocationOfOrigin
+			}
+			config.Contexts[name] = ctx
+			added = append(added, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(added)
+	return added, nil
+}
+
+// RenameContext renames a context in the file that defines it, following
+// the rename in current-context and in the app's active context.
+func (c *Client) RenameContext(oldName, newName string) error {
+	if err := checkEditableContext(oldName); err != nil {
+		return err
+	}
+	if err := checkEditableContext(newName); err != nil {
+		return err
+	}
+	if newName == "" {
+		return fmt.Errorf("the new context name is empty")
+	}
+	err := c.editKubeconfig(func(config *clientcmdapi.Config) error {
+		ctx, ok := config.Contexts[oldName]
+		if !ok {
+			return fmt.Errorf("context %s not found", oldName)
+		}
+		if _, exists := config.Contexts[newName]; exists {
+			return fmt.Errorf("context %s already exists", newName)
+		}
+		config.Contexts[newName] = ctx
+		delete(config.Contexts, oldName)
+		if config.CurrentContext == oldName {
+			config.CurrentContext = newName
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if c.contextName == oldName {
+		c.contextName = newName
+		c.Config = c.clientConfig(newName)
+	}
+	return nil
+}
+
+// DeleteContext removes a context. With pruneUnused its cluster and user
+// are removed too when no other context uses them. The active context
+// can't be deleted.
+func (c *Client) DeleteContext(name string, pruneUnused bool) error {
+	if err := checkEditableContext(name); err != nil {
+		return err
+	}
+	if current, _ := c.GetCurrentContext(); current == name {
+		return fmt.Errorf("context %s is active; switch to another context first", name)
+	}
+	return c.editKubeconfig(func(config *clientcmdapi.Config) error {
+		ctx, ok := config.Contexts[name]
+		if !ok {
+			return fmt.Errorf("context %s not found", name)
+		}
+		delete(config.Contexts, name)
+		if config.CurrentContext == name {
+			config.CurrentContext = ""
+		}
+		if !pruneUnused {
+			return nil
+		}
+		clusterUsed, userUsed := false, false
+		for _, other := range config.Contexts {
+			clusterUsed = clusterUsed || other.Cluster == ctx.Cluster
+			userUsed = userUsed || other.AuthInfo == ctx.AuthInfo
+		}
+		if !clusterUsed {
+			delete(config.Clusters, ctx.Cluster)
+		}
+		if !userUsed {
+			delete(config.AuthInfos, ctx.AuthInfo)
+		}
+		return nil
+	})
+}
+
+// SetContextNamespace changes a context's default namespace in its
+// kubeconfig; an empty namespace clears it.
+func (c *Client) SetContextNamespace(name, namespace string) error {
+	if err := checkEditableContext(name); err != nil {
+		return err
+	}
+	return c.editKubeconfig(func(config *clientcmdapi.Config) error {
+		ctx, ok := config.Contexts[name]
+		if !ok {
+			return fmt.Errorf("context %s not found", name)
+		}
+		ctx.Namespace = namespace
+		return nil
+	})
+}
+
+// SaveCurrentContext writes name as the kubeconfig's current-context, so
+// kubectl and the next start of the app use it too.
+func (c *Client) SaveCurrentContext(name string) error {
+	if err := checkEditableContext(name); err != nil {
+		return err
+	}
+	return c.editKubeconfig(func(config *clientcmdapi.Config) error {
+		if _, ok := config.Contexts[name]; !ok {
+			return fmt.Errorf("context %s not found", name)
+		}
+		config.CurrentContext = name
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+users:
+- name: dev
+  user:
+    token: secret
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+current-context: dev
+`
+
+func TestEditKubeconfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(file, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{kubeconfigPaths: []string{file}}
+	setNamespace := func(namespace string) error {
+		return c.editKubeconfig(func(config *clientcmdapi.Config) error {
+			config.Contexts["dev"].Namespace = namespace
+			return nil
+		})
+	}
+
+	for _, namespace := range []string{"first", "second"} {
+		if err := setNamespace(namespace); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "namespace: second") {
+		t.Errorf("kubeconfig wasn't updated:\n%s", data)
+	}
+	backup, err := os.ReadFile(file + kubeconfigBackupSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(backup) != testKubeconfig {
+		t.Errorf("backup doesn't hold the kubeconfig from before the first edit:\n%s", backup)
+	}
+	if _, err := os.Stat(file + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+
+	// A lock held by kubectl blocks the edit and is left alone.
+	if err := os.WriteFile(file+".lock", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := setNamespace("third"); err == nil {
+		t.Error("edit succeeded while the kubeconfig was locked")
+	}
+	if _, err := os.Stat(file + ".lock"); err != nil {
+		t.Errorf("foreign lock file was removed: %v", err)
+	}
+}
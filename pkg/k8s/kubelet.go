@@ -0,0 +1,148 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type KubeletConfigDrift struct {
+	Path   string                 `json:"path"`
+	Values map[string]interface{} `json:"values"`
+}
+
+type KubeletConfigReport struct {
+	Nodes   []string                          `json:"nodes"`
+	Configs map[string]map[string]interface{} `json:"configs"`
+	Errors  map[string]string                 `json:"errors"`
+	Drift   []KubeletConfigDrift              `json:"drift"`
+}
+
+// GetKubeletConfig returns the effective kubelet configuration of a node
+// via the apiserver's node proxy.
+func (c *Client) GetKubeletConfig(nodeName string) (map[string]interface{}, error) {
+	var raw []byte
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		raw, err = c.Clientset.CoreV1().RESTClient().Get().
+			AbsPath("/api/v1/nodes", nodeName, "proxy", "configz").
+			DoRaw(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var wrapper struct {
+		KubeletConfig map[string]interface{} `json:"kubeletconfig"`
+	}
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to decode configz for node %s: %v", nodeName, err)
+	}
+	return wrapper.KubeletConfig, nil
+}
+
+// CompareKubeletConfigs fetches the kubelet config of the given nodes (all
+// nodes if none are given) and reports every setting whose value differs
+// between them.
+func (c *Client) CompareKubeletConfigs(nodeNames []string) (*KubeletConfigReport, error) {
+	if len(nodeNames) == 0 {
+		ctx, cancel := c.opContext(OpList)
+		nodes, err := c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes.Items {
+			nodeNames = append(nodeNames, n.Name)
+		}
+	}
+	sort.Strings(nodeNames)
+
+	report := &KubeletConfigReport{
+		Nodes:   nodeNames,
+		Configs: make(map[string]map[string]interface{}),
+		Errors:  make(map[string]string),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for _, name := range nodeNames {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cfg, err := c.GetKubeletConfig(name)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Errors[name] = err.Error()
+				return
+			}
+			report.Configs[name] = cfg
+		}(name)
+	}
+	wg.Wait()
+
+	flat := make(map[string]map[string]interface{}, len(report.Configs))
+	paths := make(map[string]bool)
+	for node, cfg := range report.Configs {
+		flat[node] = make(map[string]interface{})
+		flattenConfig("", cfg, flat[node])
+		for p := range flat[node] {
+			paths[p] = true
+		}
+	}
+
+	for p := range paths {
+		values := make(map[string]interface{}, len(flat))
+		var first interface{}
+		differs := false
+		i := 0
+		for node := range flat {
+			v, ok := flat[node][p]
+			if !ok {
+				v = nil
+			}
+			values[node] = v
+			if i == 0 {
+				first = v
+			} else if !reflect.DeepEqual(first, v) {
+				differs = true
+			}
+			i++
+		}
+		if differs {
+			report.Drift = append(report.Drift, KubeletConfigDrift{Path: p, Values: values})
+		}
+	}
+	sort.Slice(report.Drift, func(i, j int) bool {
+		return report.Drift[i].Path < report.Drift[j].Path
+	})
+
+	return report, nil
+}
+
+// flattenConfig turns nested maps into dotted paths. Lists are compared as
+// whole values.
+func flattenConfig(prefix string, in map[string]interface{}, out map[string]interface{}) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenConfig(key, nested, out)
+			continue
+		}
+		out[key] = v
+	}
+}
@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// recommendedLabels are the Kubernetes recommended labels. Only name and
+// instance count towards compliance; the others are reported as hints.
+var (
+	recommendedLabels = []string{
+		"app.kubernetes.io/name",
+		"app.kubernetes.io/instance",
+		"app.kubernetes.io/version",
+		"app.kubernetes.io/component",
+		"app.kubernetes.io/part-of",
+		"app.kubernetes.io/managed-by",
+	}
+	coreRecommendedLabels = map[string]bool{
+		"app.kubernetes.io/name":     true,
+		"app.kubernetes.io/instance": true,
+	}
+)
+
+var taxonomyWorkloads = []struct {
+	gvr  schema.GroupVersionResource
+	kind string
+}{
+	{schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, "Deployment"},
+	{schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}, "StatefulSet"},
+	{schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}, "DaemonSet"},
+	{schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}, "CronJob"},
+	{schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}, "Job"},
+}
+
+type LabelViolation struct {
+	Kind               string   `json:"kind"`
+	Name               string   `json:"name"`
+	MissingRequired    []string `json:"missing_required"`
+	MissingRecommended []string `json:"missing_recommended"`
+	Compliant          bool     `json:"compliant"`
+}
+
+type NamespaceLabelReport struct {
+	Namespace string           `json:"namespace"`
+	Total     int              `json:"total"`
+	Compliant int              `json:"compliant"`
+	Resources []LabelViolation `json:"resources"`
+}
+
+type LabelReport struct {
+	RequiredLabels []string               `json:"required_labels"`
+	Namespaces     []NamespaceLabelReport `json:"namespaces"`
+	Total          int                    `json:"total"`
+	Compliant      int                    `json:"compliant"`
+}
+
+// GetLabelTaxonomyReport checks workloads in a namespace (all when empty)
+// for the app.kubernetes.io/* recommended labels and the given required
+// labels. Jobs created by CronJobs are skipped; their parent is checked.
+// Only resources with missing labels are listed.
+func (c *Client) GetLabelTaxonomyReport(namespace string, required []string) (*LabelReport, error) {
+	report := &LabelReport{RequiredLabels: required}
+	byNamespace := make(map[string]*NamespaceLabelReport)
+
+	for _, w := range taxonomyWorkloads {
+		var list *unstructured.UnstructuredList
+		err := c.do(OpList, func(ctx context.Context) error {
+			var err error
+			list, err = c.DynamicClient.Resource(w.gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range list.Items {
+			if metav1.GetControllerOfNoCopy(&item) != nil {
+				continue
+			}
+			ns, ok := byNamespace[item.GetNamespace()]
+			if !ok {
+				ns = &NamespaceLabelReport{Namespace: item.GetNamespace(), Resources: []LabelViolation{}}
+				byNamespace[item.GetNamespace()] = ns
+			}
+
+			labels := item.GetLabels()
+			v := LabelViolation{Kind: w.kind, Name: item.GetName(), Compliant: true}
+			for _, key := range required {
+				if labels[key] == "" {
+					v.MissingRequired = append(v.MissingRequired, key)
+					v.Compliant = false
+				}
+			}
+			for _, key := range recommendedLabels {
+				if labels[key] == "" {
+					v.MissingRecommended = append(v.MissingRecommended, key)
+					if coreRecommendedLabels[key] {
+						v.Compliant = false
+					}
+				}
+			}
+
+			ns.Total++
+			report.Total++
+			if v.Compliant {
+				ns.Compliant++
+				report.Compliant++
+			}
+			if len(v.MissingRequired) > 0 || len(v.MissingRecommended) > 0 {
+				ns.Resources = append(ns.Resources, v)
+			}
+		}
+	}
+
+	for _, ns := range byNamespace {
+		sort.Slice(ns.Resources, func(i, j int) bool {
+			if ns.Resources[i].Compliant != ns.Resources[j].Compliant {
+				return !ns.Resources[i].Compliant
+			}
+			if ns.Resources[i].Kind != ns.Resources[j].Kind {
+				return ns.Resources[i].Kind < ns.Resources[j].Kind
+			}
+			return ns.Resources[i].Name < ns.Resources[j].Name
+		})
+		report.Namespaces = append(report.Namespaces, *ns)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+	return report, nil
+}
+
+// LabelCount is a label key or value and how many objects carry it.
+type LabelCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// GetLabelKeys returns the label keys used by objects of a type in a
+// namespace (all when empty), most used first, for selector autocomplete.
+// Objects come from the search cache, so typing doesn't list each time.
+func (c *Client) GetLabelKeys(group, version, plural, namespace string) ([]LabelCount, error) {
+	return c.labelCounts(group, version, plural, namespace, func(labels map[string]string, add func(string)) {
+		for k := range labels {
+			add(k)
+		}
+	})
+}
+
+// GetLabelValues returns the values of one label key on objects of a type
+// in a namespace (all when empty), most used first.
+func (c *Client) GetLabelValues(group, version, plural, namespace, key string) ([]LabelCount, error) {
+	return c.labelCounts(group, version, plural, namespace, func(labels map[string]string, add func(string)) {
+		if v, ok := labels[key]; ok {
+			add(v)
+		}
+	})
+}
+
+func (c *Client) labelCounts(group, version, plural, namespace string, collect func(map[string]string, func(string))) ([]LabelCount, error) {
+	entries, err := c.searchEntries(ApiResourceInfo{Group: group, Version: version, Name: plural}, namespace)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, e := range entries {
+		collect(e.labels, func(s string) { counts[s]++ })
+	}
+	result := make([]LabelCount, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, LabelCount{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type ResourceLink struct {
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Annotation string `json:"annotation"`
+}
+
+// linkPrefixes maps annotation prefixes to how their suffix is turned into
+// a title. An exact-match key (no trailing slash) uses the given title.
+var linkPrefixes = []struct {
+	prefix string
+	title  string
+}{
+	{"teleskope.io/runbook", "Runbook"},
+	{"teleskope.io/dashboard", "Dashboard"},
+	{"teleskope.io/docs", "Documentation"},
+	{"teleskope.io/link.", ""},
+	{"link.argocd.argoproj.io/", ""},
+	{"a8r.io/runbook", "Runbook"},
+	{"a8r.io/documentation", "Documentation"},
+	{"a8r.io/repository", "Repository"},
+	{"a8r.io/logs", "Logs"},
+	{"a8r.io/incidents", "Incidents"},
+	{"a8r.io/uptime", "Uptime"},
+	{"a8r.io/performance", "Performance"},
+	{"a8r.io/dependencies", "Dependencies"},
+	{"a8r.io/bugs", "Bugs"},
+	{"a8r.io/chat", "Chat"},
+}
+
+func (c *Client) GetResourceLinks(group, version, kind, plural, namespace, name string) ([]ResourceLink, error) {
+	res, err := c.GetResource(group, version, kind, plural, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	obj, _ := res.(map[string]interface{})
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	raw, _ := metadata["annotations"].(map[string]interface{})
+
+	annotations := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if s, ok := v.(string); ok {
+			annotations[k] = s
+		}
+	}
+	return ExtractLinks(annotations), nil
+}
+
+// ExtractLinks returns the http(s) links declared by well-known annotation
+// conventions. Annotations with non-URL values are ignored.
+func ExtractLinks(annotations map[string]string) []ResourceLink {
+	var links []ResourceLink
+	for key, value := range annotations {
+		title, ok := linkTitle(key)
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(value))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			continue
+		}
+		links = append(links, ResourceLink{Title: title, URL: u.String(), Annotation: key})
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Title != links[j].Title {
+			return links[i].Title < links[j].Title
+		}
+		return links[i].Annotation < links[j].Annotation
+	})
+	return links
+}
+
+func linkTitle(key string) (string, bool) {
+	for _, p := range linkPrefixes {
+		if p.title != "" {
+			if key == p.prefix {
+				return p.title, true
+			}
+			continue
+		}
+		if suffix := strings.TrimPrefix(key, p.prefix); suffix != key && suffix != "" {
+			return humanizeLinkName(suffix), true
+		}
+	}
+	return "", false
+}
+
+// humanizeLinkName turns "grafana-dashboard" into "Grafana Dashboard".
+func humanizeLinkName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxRecordLines caps how many lines are grouped into one record, so a
+// log of indented output doesn't turn into one endless record.
+const maxRecordLines = 500
+
+// ansiPattern matches CSI sequences (colours, cursor moves) and OSC
+// sequences (titles, hyperlinks).
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// exceptionLinePattern matches the last line of a Python traceback, e.g.
+// "ValueError: bad input" or "requests.exceptions.Timeout".
+var exceptionLinePattern = regexp.MustCompile(`^[A-Za-z_][\w.]*(Error|Exception|Exit|Interrupt|Warning|Timeout)\b`)
+
+// logGrouper joins continuation lines, such as the frames of Java and
+// Python stack traces, to the line that started them.
+type logGrouper struct {
+	pending *LogLine
+	// plain is the pending record's first line without ANSI codes.
+	plain string
+	// traceback is set while inside a Python traceback, which ends with a
+	// line that isn't indented.
+	traceback bool
+	updated   time.Time
+}
+
+// add takes the next line and returns the record it completed, if any.
+func (g *logGrouper) add(line LogLine, now time.Time) *LogLine {
+	plain := stripANSI(line.Text)
+	if g.pending != nil && g.pending.Lines < maxRecordLines && g.continues(plain) {
+		g.pending.Text += "\n" + line.Text
+		g.pending.Lines++
+		g.updated = now
+		return nil
+	}
+	done := g.pending
+	g.pending = &line
+	g.pending.Lines = 1
+	g.plain = plain
+	g.traceback = strings.HasPrefix(plain, "Traceback (most recent call last)")
+	g.updated = now
+	return done
+}
+
+// continues reports whether plain belongs to the pending record.
+func (g *logGrouper) continues(plain string) bool {
+	if plain == "" {
+		return false
+	}
+	switch {
+	case plain[0] == ' ' || plain[0] == '\t':
+		return true
+	case strings.HasPrefix(plain, "Caused by: "), strings.HasPrefix(plain, "Suppressed: "):
+		return true
+	}
+	if g.traceback && exceptionLinePattern.MatchString(plain) {
+		g.traceback = false
+		return true
+	}
+	return false
+}
+
+// flush returns the pending record if no line arrived for idle, so the
+// last record of a quiet stream isn't held back.
+func (g *logGrouper) flush(now time.Time, idle time.Duration) *LogLine {
+	if g.pending == nil || now.Sub(g.updated) < idle {
+		return nil
+	}
+	done := g.pending
+	g.pending = nil
+	g.traceback = false
+	return done
+}
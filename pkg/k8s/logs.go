@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	EventPodLogs    = "pod:logs"
+	EventPodLogsEnd = "pod:logs:end"
+
+	logBatchInterval = 100 * time.Millisecond
+	logBatchSize     = 500
+	maxLogLineBytes  = 1 << 20
+)
+
+type LogStreamOptions struct {
+	Namespace     string `json:"namespace"`
+	PodName       string `json:"podName"`
+	ContainerName string `json:"containerName"`
+	Follow        bool   `json:"follow"`
+	TailLines     int64  `json:"tailLines"`
+	Timestamps    bool   `json:"timestamps"`
+	Previous      bool   `json:"previous"`
+	SinceSeconds  int64  `json:"sinceSeconds"`
+	// StripANSI removes colour and cursor escape codes from the text.
+	StripANSI bool `json:"stripAnsi"`
+	// GroupMultiline joins continuation lines, like stack trace frames,
+	// to the line before them into a single record.
+	GroupMultiline bool `json:"groupMultiline"`
+}
+
+// LogLine is one log record. With GroupMultiline it may span several
+// lines joined by "\n"; Timestamp is that of the first.
+type LogLine struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Text      string `json:"text"`
+	Lines     int    `json:"lines,omitempty"`
+}
+
+type LogBatch struct {
+	SubscriptionID string    `json:"subscription_id"`
+	Lines          []LogLine `json:"lines"`
+}
+
+type LogStreamEnd struct {
+	SubscriptionID string `json:"subscription_id"`
+	Error          string `json:"error,omitempty"`
+}
+
+// StreamPodLogs streams container logs as batched EventPodLogs events,
+// followed by a single EventPodLogsEnd when the stream finishes. It returns
+// a subscription ID for StopSubscription.
+func (c *Client) StreamPodLogs(opts LogStreamOptions) (string, error) {
+	logOpts := &corev1.PodLogOptions{
+		Container:  opts.ContainerName,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+		Previous:   opts.Previous,
+	}
+	if opts.TailLines > 0 {
+		logOpts.TailLines = &opts.TailLines
+	}
+	if opts.SinceSeconds > 0 {
+		logOpts.SinceSeconds = &opts.SinceSeconds
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.Clientset.CoreV1().Pods(opts.Namespace).GetLogs(opts.PodName, logOpts).Stream(ctx)
+	if err != nil {
+		cancel()
+		return "", err
+	}
+
+	id := c.subs.add("logs", cancel)
+	go func() {
+		defer stream.Close()
+		defer c.subs.remove(id)
+		defer cancel()
+
+		lines := make(chan LogLine)
+		scanErr := make(chan error, 1)
+		go func() {
+			scanner := bufio.NewScanner(stream)
+			scanner.Buffer(make([]byte, 64*1024), maxLogLineBytes)
+			for scanner.Scan() {
+				line := parseLogLine(scanner.Text(), opts.Timestamps)
+				if opts.StripANSI {
+					line.Text = stripANSI(line.Text)
+				}
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			scanErr <- scanner.Err()
+			close(lines)
+		}()
+
+		ticker := time.NewTicker(logBatchInterval)
+		defer ticker.Stop()
+
+		var batch []LogLine
+		var grouper *logGrouper
+		if opts.GroupMultiline {
+			grouper = &logGrouper{}
+		}
+		// flush emits the batch. A grouped record still growing is held
+		// back until idle passes without new lines.
+		flush := func(idle time.Duration) {
+			if grouper != nil {
+				if record := grouper.flush(time.Now(), idle); record != nil {
+					batch = append(batch, *record)
+				}
+			}
+			if len(batch) > 0 {
+				c.emit(EventPodLogs, LogBatch{SubscriptionID: id, Lines: batch})
+				batch = nil
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				flush(0)
+				c.emit(EventPodLogsEnd, LogStreamEnd{SubscriptionID: id})
+				return
+			case line, ok := <-lines:
+				if !ok {
+					flush(0)
+					end := LogStreamEnd{SubscriptionID: id}
+					if err := <-scanErr; err != nil && ctx.Err() == nil {
+						end.Error = err.Error()
+					}
+					c.emit(EventPodLogsEnd, end)
+					return
+				}
+				if grouper != nil {
+					record := grouper.add(line, time.Now())
+					if record == nil {
+						continue
+					}
+					line = *record
+				}
+				batch = append(batch, line)
+				if len(batch) >= logBatchSize {
+					flush(logBatchInterval)
+				}
+			case <-ticker.C:
+				flush(logBatchInterval)
+			}
+		}
+	}()
+
+	return id, nil
+}
+
+// parseLogLine splits off the RFC3339 timestamp the API server prepends
+// when timestamps are requested.
+func parseLogLine(raw string, timestamps bool) LogLine {
+	if !timestamps {
+		return LogLine{Text: raw}
+	}
+	ts, text, ok := strings.Cut(raw, " ")
+	if !ok {
+		return LogLine{Text: raw}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+		return LogLine{Text: raw}
+	}
+	return LogLine{Timestamp: ts, Text: text}
+}
@@ -0,0 +1,207 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The metrics API is read through the dynamic client so the app doesn't
+// depend on k8s.io/metrics.
+var (
+	podMetricsGVR  = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+	nodeMetricsGVR = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"}
+)
+
+type ContainerMetrics struct {
+	Name          string `json:"name"`
+	CPUMillicores int64  `json:"cpu_millicores"`
+	MemoryBytes   int64  `json:"memory_bytes"`
+}
+
+type PodMetrics struct {
+	Namespace     string             `json:"namespace"`
+	Name          string             `json:"name"`
+	CPUMillicores int64              `json:"cpu_millicores"`
+	MemoryBytes   int64              `json:"memory_bytes"`
+	Containers    []ContainerMetrics `json:"containers"`
+}
+
+type NodeMetrics struct {
+	Name                     string  `json:"name"`
+	CPUMillicores            int64   `json:"cpu_millicores"`
+	MemoryBytes              int64   `json:"memory_bytes"`
+	AllocatableCPUMillicores int64   `json:"allocatable_cpu_millicores"`
+	AllocatableMemoryBytes   int64   `json:"allocatable_memory_bytes"`
+	CPUPercent               float64 `json:"cpu_percent"`
+	MemoryPercent            float64 `json:"memory_percent"`
+}
+
+// PodUtilization joins a pod's usage with its requests and limits.
+// Percentages are 0 when the corresponding request or limit isn't set.
+type PodUtilization struct {
+	PodMetrics
+	CPURequestMillicores int64   `json:"cpu_request_millicores"`
+	CPULimitMillicores   int64   `json:"cpu_limit_millicores"`
+	MemoryRequestBytes   int64   `json:"memory_request_bytes"`
+	MemoryLimitBytes     int64   `json:"memory_limit_bytes"`
+	CPURequestPercent    float64 `json:"cpu_request_percent"`
+	CPULimitPercent      float64 `json:"cpu_limit_percent"`
+	MemoryRequestPercent float64 `json:"memory_request_percent"`
+	MemoryLimitPercent   float64 `json:"memory_limit_percent"`
+}
+
+func metricsError(err error) error {
+	if apierrors.IsNotFound(err) || apierrors.IsServiceUnavailable(err) {
+		return fmt.Errorf("metrics API is not available (is metrics-server installed?): %v", err)
+	}
+	return err
+}
+
+// GetPodMetrics returns current CPU and memory usage of the pods in a
+// namespace (all namespaces when empty), like `kubectl top pod`.
+func (c *Client) GetPodMetrics(namespace string) ([]PodMetrics, error) {
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(podMetricsGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, metricsError(err)
+	}
+
+	result := make([]PodMetrics, 0, len(list.Items))
+	for _, item := range list.Items {
+		pm := PodMetrics{Namespace: item.GetNamespace(), Name: item.GetName()}
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		for _, raw := range containers {
+			m, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := m["name"].(string)
+			usage, _ := m["usage"].(map[string]interface{})
+			cm := ContainerMetrics{
+				Name:          name,
+				CPUMillicores: usageQuantity(usage, "cpu").MilliValue(),
+				MemoryBytes:   usageQuantity(usage, "memory").Value(),
+			}
+			pm.CPUMillicores += cm.CPUMillicores
+			pm.MemoryBytes += cm.MemoryBytes
+			pm.Containers = append(pm.Containers, cm)
+		}
+		result = append(result, pm)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// GetNodeMetrics returns node usage together with allocatable capacity,
+// like `kubectl top node`.
+func (c *Client) GetNodeMetrics() ([]NodeMetrics, error) {
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(nodeMetricsGVR).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, metricsError(err)
+	}
+
+	allocatable := make(map[string]corev1.ResourceList)
+	ctx, cancel := c.opContext(OpList)
+	defer cancel()
+	if nodes, err := c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, n := range nodes.Items {
+			allocatable[n.Name] = n.Status.Allocatable
+		}
+	}
+
+	result := make([]NodeMetrics, 0, len(list.Items))
+	for _, item := range list.Items {
+		usage, _, _ := unstructured.NestedMap(item.Object, "usage")
+		nm := NodeMetrics{
+			Name:          item.GetName(),
+			CPUMillicores: usageQuantity(usage, "cpu").MilliValue(),
+			MemoryBytes:   usageQuantity(usage, "memory").Value(),
+		}
+		if alloc, ok := allocatable[nm.Name]; ok {
+			nm.AllocatableCPUMillicores = alloc.Cpu().MilliValue()
+			nm.AllocatableMemoryBytes = alloc.Memory().Value()
+			nm.CPUPercent = percentOf(nm.CPUMillicores, nm.AllocatableCPUMillicores)
+			nm.MemoryPercent = percentOf(nm.MemoryBytes, nm.AllocatableMemoryBytes)
+		}
+		result = append(result, nm)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// GetPodUtilization joins pod usage with the pods' requests and limits so
+// the UI can show utilization percentages.
+func (c *Client) GetPodUtilization(namespace string) ([]PodUtilization, error) {
+	metrics, err := c.GetPodMetrics(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.opContext(OpList)
+	defer cancel()
+	pods, err := c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	specs := make(map[string]*corev1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		specs[pods.Items[i].Namespace+"/"+pods.Items[i].Name] = &pods.Items[i]
+	}
+
+	result := make([]PodUtilization, 0, len(metrics))
+	for _, m := range metrics {
+		u := PodUtilization{PodMetrics: m}
+		if pod, ok := specs[m.Namespace+"/"+m.Name]; ok {
+			for _, container := range pod.Spec.Containers {
+				u.CPURequestMillicores += container.Resources.Requests.Cpu().MilliValue()
+				u.CPULimitMillicores += container.Resources.Limits.Cpu().MilliValue()
+				u.MemoryRequestBytes += container.Resources.Requests.Memory().Value()
+				u.MemoryLimitBytes += container.Resources.Limits.Memory().Value()
+			}
+		}
+		u.CPURequestPercent = percentOf(m.CPUMillicores, u.CPURequestMillicores)
+		u.CPULimitPercent = percentOf(m.CPUMillicores, u.CPULimitMillicores)
+		u.MemoryRequestPercent = percentOf(m.MemoryBytes, u.MemoryRequestBytes)
+		u.MemoryLimitPercent = percentOf(m.MemoryBytes, u.MemoryLimitBytes)
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+func usageQuantity(usage map[string]interface{}, name string) *resource.Quantity {
+	s, _ := usage[name].(string)
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return &resource.Quantity{}
+	}
+	return &q
+}
+
+func percentOf(used, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
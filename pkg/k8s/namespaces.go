@@ -0,0 +1,230 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// namespaceStuckAfter is how long a namespace may be Terminating before
+// it is reported as stuck; emptying a busy namespace takes a while.
+const namespaceStuckAfter = 5 * time.Minute
+
+// systemNamespaces can't be deleted from the app.
+var systemNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+var namespacesRef = ResourceRef{Version: "v1", Kind: "Namespace", Plural: "namespaces"}
+
+type NamespaceCondition struct {
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// BlockingResource is an object left in a Terminating namespace. Objects
+// with finalizers wait for their controller to release them.
+type BlockingResource struct {
+	Resource   string   `json:"resource"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`
+	Finalizers []string `json:"finalizers"`
+}
+
+// TerminatingNamespace is a namespace being deleted. Finalizers are the
+// namespace's own spec.finalizers, released once it is empty.
+type TerminatingNamespace struct {
+	Name            string               `json:"name"`
+	DeletionStarted time.Time            `json:"deletion_started"`
+	Stuck           bool                 `json:"stuck"`
+	Finalizers      []string             `json:"finalizers"`
+	Conditions      []NamespaceCondition `json:"conditions"`
+	Remaining       []BlockingResource   `json:"remaining"`
+	// Errors lists resource types that couldn't be listed.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// CreateNamespace creates a namespace with the given labels.
+func (c *Client) CreateNamespace(name string, labels map[string]string) error {
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace name %q: %s", name, errs[0])
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	ref := namespacesRef
+	ref.Name = name
+	err := c.mutate(ActionCreate, ref, "create namespaces/"+name, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create namespace %s: %v", name, err)
+	}
+	return nil
+}
+
+// DeleteNamespace deletes a namespace and everything in it. The system
+// namespaces are refused.
+func (c *Client) DeleteNamespace(name string) error {
+	if systemNamespaces[name] {
+		return fmt.Errorf("namespace %s is a system namespace and can't be deleted", name)
+	}
+	ref := namespacesRef
+	ref.Name = name
+	err := c.mutate(ActionDelete, ref, "delete namespaces/"+name, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			return c.Clientset.CoreV1().Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete namespace %s: %v", name, err)
+	}
+	return nil
+}
+
+// GetTerminatingNamespaces returns the namespaces being deleted, with the
+// conditions the namespace controller reports and the objects still in
+// them. Those terminating for longer than namespaceStuckAfter are stuck.
+func (c *Client) GetTerminatingNamespaces() ([]TerminatingNamespace, error) {
+	var list *corev1.NamespaceList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []TerminatingNamespace{}
+	for i := range list.Items {
+		ns := &list.Items[i]
+		if ns.DeletionTimestamp == nil {
+			continue
+		}
+		result = append(result, c.terminatingNamespace(ns))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (c *Client) terminatingNamespace(ns *corev1.Namespace) TerminatingNamespace {
+	t := TerminatingNamespace{
+		Name:            ns.Name,
+		DeletionStarted: ns.DeletionTimestamp.Time,
+		Stuck:           time.Since(ns.DeletionTimestamp.Time) > namespaceStuckAfter,
+		Finalizers:      []string{},
+		Conditions:      []NamespaceCondition{},
+		Remaining:       []BlockingResource{},
+		Errors:          make(map[string]string),
+	}
+	for _, f := range ns.Spec.Finalizers {
+		t.Finalizers = append(t.Finalizers, string(f))
+	}
+	for _, cond := range ns.Status.Conditions {
+		// The controller keeps every condition and sets the ones that
+		// don't apply to False.
+		if cond.Status == corev1.ConditionTrue {
+			t.Conditions = append(t.Conditions, NamespaceCondition{Type: string(cond.Type), Reason: cond.Reason, Message: cond.Message})
+		}
+	}
+
+	resources, err := c.GetApiResources()
+	if err != nil {
+		t.Errors["discovery"] = err.Error()
+		return t
+	}
+	listed := make(map[schema.GroupKind]bool)
+	for _, res := range resources {
+		gk := schema.GroupKind{Group: res.Group, Kind: res.Kind}
+		if !res.Namespaced || !contains(res.Verbs, "list") || listed[gk] {
+			continue
+		}
+		listed[gk] = true
+		resource := res.Name
+		if res.Group != "" {
+			resource += "." + res.Group
+		}
+		gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}
+		var items *unstructured.UnstructuredList
+		err := c.do(OpList, func(ctx context.Context) error {
+			var err error
+			items, err = c.DynamicClient.Resource(gvr).Namespace(ns.Name).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			t.Errors[resource] = err.Error()
+			continue
+		}
+		for _, item := range items.Items {
+			finalizers := item.GetFinalizers()
+			if finalizers == nil {
+				finalizers = []string{}
+			}
+			t.Remaining = append(t.Remaining, BlockingResource{Resource: resource, Kind: res.Kind, Name: item.GetName(), Finalizers: finalizers})
+		}
+	}
+	sort.Slice(t.Remaining, func(i, j int) bool {
+		if t.Remaining[i].Resource != t.Remaining[j].Resource {
+			return t.Remaining[i].Resource < t.Remaining[j].Resource
+		}
+		return t.Remaining[i].Name < t.Remaining[j].Name
+	})
+	if len(t.Errors) == 0 {
+		t.Errors = nil
+	}
+	return t
+}
+
+// RemoveNamespaceFinalizers clears the spec.finalizers of a Terminating
+// namespace through its finalize subresource, so it is deleted even
+// though objects remain. Those objects are left behind in etcd and
+// reappear if a namespace of the same name is created, so confirm must
+// repeat the namespace name.
+func (c *Client) RemoveNamespaceFinalizers(name, confirm string) error {
+	if confirm != name {
+		return errors.New("confirmation doesn't match the namespace name")
+	}
+	var ns *corev1.Namespace
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		ns, err = c.Clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if ns.DeletionTimestamp == nil {
+		return fmt.Errorf("namespace %s is not being deleted", name)
+	}
+	if len(ns.Spec.Finalizers) == 0 {
+		return fmt.Errorf("namespace %s has no finalizers left", name)
+	}
+
+	ns.Spec.Finalizers = nil
+	ref := namespacesRef
+	ref.Name = name
+	err = c.mutate(ActionFinalize, ref, "remove finalizers of namespaces/"+name, func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.CoreV1().Namespaces().Finalize(ctx, ns, metav1.UpdateOptions{})
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove finalizers of namespace %s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Directions of a NetworkPolicyIntent.
+const (
+	PolicyIngress = "ingress"
+	PolicyEgress  = "egress"
+)
+
+// PolicyPeer is one allowed source (for ingress) or destination (for
+// egress). Namespace selects a namespace by name; with neither it nor
+// NamespaceLabels set, PodLabels select pods in the policy's namespace.
+// CIDR excludes the other fields.
+type PolicyPeer struct {
+	Namespace       string            `json:"namespace"`
+	NamespaceLabels map[string]string `json:"namespace_labels"`
+	PodLabels       map[string]string `json:"pod_labels"`
+	CIDR            string            `json:"cidr"`
+}
+
+// PolicyPort is a port number or name. Protocol defaults to TCP.
+type PolicyPort struct {
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// NetworkPolicyIntent is a policy in the terms of "allow traffic to the
+// pods labelled X from namespace Y on port Z". An empty PodSelector
+// applies to every pod of the namespace; no peers allows nothing, which
+// isolates the pods.
+type NetworkPolicyIntent struct {
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	PodSelector map[string]string `json:"pod_selector"`
+	Direction   string            `json:"direction"`
+	Peers       []PolicyPeer      `json:"peers"`
+	Ports       []PolicyPort      `json:"ports"`
+}
+
+// BuildNetworkPolicy renders an intent as a NetworkPolicy manifest for the
+// validate and apply flow.
+func BuildNetworkPolicy(intent NetworkPolicyIntent) (string, error) {
+	if errs := validation.IsDNS1123Subdomain(intent.Name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid policy name %q: %s", intent.Name, errs[0])
+	}
+	if intent.Namespace == "" {
+		return "", errors.New("the policy needs a namespace")
+	}
+
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, p := range intent.Peers {
+		peer, err := policyPeer(p)
+		if err != nil {
+			return "", err
+		}
+		peers = append(peers, peer)
+	}
+	var ports []networkingv1.NetworkPolicyPort
+	for _, p := range intent.Ports {
+		port, err := policyPort(p)
+		if err != nil {
+			return "", err
+		}
+		ports = append(ports, port)
+	}
+
+	policy := &networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: intent.Name, Namespace: intent.Namespace},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: intent.PodSelector},
+		},
+	}
+	switch intent.Direction {
+	case PolicyIngress, "":
+		policy.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+		if len(peers) > 0 {
+			policy.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{{From: peers, Ports: ports}}
+		}
+	case PolicyEgress:
+		policy.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+		if len(peers) > 0 {
+			policy.Spec.Egress = []networkingv1.NetworkPolicyEgressRule{{To: peers, Ports: ports}}
+		}
+	default:
+		return "", fmt.Errorf("unknown direction %q", intent.Direction)
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(policy)
+	if err != nil {
+		return "", err
+	}
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func policyPeer(p PolicyPeer) (networkingv1.NetworkPolicyPeer, error) {
+	var peer networkingv1.NetworkPolicyPeer
+	if p.CIDR != "" {
+		if p.Namespace != "" || len(p.NamespaceLabels) > 0 || len(p.PodLabels) > 0 {
+			return peer, fmt.Errorf("peer %s can't also select namespaces or pods", p.CIDR)
+		}
+		if _, _, err := net.ParseCIDR(p.CIDR); err != nil {
+			return peer, fmt.Errorf("invalid CIDR %q", p.CIDR)
+		}
+		peer.IPBlock = &networkingv1.IPBlock{CIDR: p.CIDR}
+		return peer, nil
+	}
+	switch {
+	case p.Namespace != "" && len(p.NamespaceLabels) > 0:
+		return peer, fmt.Errorf("peer namespace %s can't also be selected by labels", p.Namespace)
+	case p.Namespace != "":
+		// Set on every namespace since 1.21.
+		peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: p.Namespace}}
+	case len(p.NamespaceLabels) > 0:
+		peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: p.NamespaceLabels}
+	}
+	if len(p.PodLabels) > 0 || peer.NamespaceSelector == nil {
+		peer.PodSelector = &metav1.LabelSelector{MatchLabels: p.PodLabels}
+	}
+	return peer, nil
+}
+
+func policyPort(p PolicyPort) (networkingv1.NetworkPolicyPort, error) {
+	var port networkingv1.NetworkPolicyPort
+	protocol := corev1.Protocol(strings.ToUpper(p.Protocol))
+	switch protocol {
+	case "":
+		protocol = corev1.ProtocolTCP
+	case corev1.ProtocolTCP, corev1.ProtocolUDP, corev1.ProtocolSCTP:
+	default:
+		return port, fmt.Errorf("unknown protocol %q", p.Protocol)
+	}
+	port.Protocol = &protocol
+	if p.Port != "" {
+		value := intstr.Parse(p.Port)
+		if value.Type == intstr.Int && (value.IntVal < 1 || value.IntVal > 65535) {
+			return port, fmt.Errorf("port %s is out of range", p.Port)
+		}
+		port.Port = &value
+	}
+	return port, nil
+}
+
+// PodRef names a pod.
+type PodRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// PolicyVerdict is the outcome for one side of a connection. A pod that no
+// policy selects for the direction isn't isolated and allows everything.
+type PolicyVerdict struct {
+	Isolated bool `json:"isolated"`
+	Allowed  bool `json:"allowed"`
+	// Policies select the pod for the direction; AllowedBy are those with
+	// a rule matching the connection.
+	Policies  []string `json:"policies"`
+	AllowedBy []string `json:"allowed_by"`
+}
+
+// TrafficSimulation tells whether a connection from one pod to another
+// would be allowed: the source's egress and the destination's ingress
+// must both allow it.
+type TrafficSimulation struct {
+	From     PodRef        `json:"from"`
+	To       PodRef        `json:"to"`
+	Port     int32         `json:"port"`
+	Protocol string        `json:"protocol"`
+	Allowed  bool          `json:"allowed"`
+	Egress   PolicyVerdict `json:"egress"`
+	Ingress  PolicyVerdict `json:"ingress"`
+	Notes    []string      `json:"notes"`
+}
+
+// SimulateTraffic evaluates the NetworkPolicies of both pods' namespaces
+// for a connection from one pod to the other's port, as a CNI enforcing
+// them would. Protocol defaults to TCP.
+func (c *Client) SimulateTraffic(from, to PodRef, port int32, protocol string) (*TrafficSimulation, error) {
+	if protocol == "" {
+		protocol = string(corev1.ProtocolTCP)
+	}
+	protocol = strings.ToUpper(protocol)
+
+	var src, dst *corev1.Pod
+	nsLabels := make(map[string]map[string]string)
+	policies := make(map[string][]networkingv1.NetworkPolicy)
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		if src, err = c.Clientset.CoreV1().Pods(from.Namespace).Get(ctx, from.Name, metav1.GetOptions{}); err != nil {
+			return err
+		}
+		if dst, err = c.Clientset.CoreV1().Pods(to.Namespace).Get(ctx, to.Name, metav1.GetOptions{}); err != nil {
+			return err
+		}
+		for _, ns := range []string{from.Namespace, to.Namespace} {
+			if _, ok := policies[ns]; ok {
+				continue
+			}
+			namespace, err := c.Clientset.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			nsLabels[ns] = namespace.Labels
+			list, err := c.Clientset.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			policies[ns] = list.Items
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	source := policyEndpoint{pod: src, nsLabels: nsLabels[from.Namespace]}
+	dest := policyEndpoint{pod: dst, nsLabels: nsLabels[to.Namespace]}
+	sim := &TrafficSimulation{From: from, To: to, Port: port, Protocol: protocol, Notes: []string{}}
+	sim.Egress = evaluatePolicies(policies[from.Namespace], source, dest, networkingv1.PolicyTypeEgress, port, protocol)
+	sim.Ingress = evaluatePolicies(policies[to.Namespace], dest, source, networkingv1.PolicyTypeIngress, port, protocol)
+	sim.Allowed = sim.Egress.Allowed && sim.Ingress.Allowed
+
+	if src.Spec.HostNetwork || dst.Spec.HostNetwork {
+		sim.Notes = append(sim.Notes, "host-network pods aren't subject to NetworkPolicies on most CNIs")
+	}
+	if dst.Status.PodIP == "" {
+		sim.Notes = append(sim.Notes, "the destination pod has no IP yet; ipBlock rules can't match it")
+	}
+	if from.Namespace == to.Namespace && from.Name == to.Name {
+		sim.Notes = append(sim.Notes, "traffic from a pod to itself is always allowed")
+		sim.Allowed = true
+	}
+	return sim, nil
+}
+
+// policyEndpoint is a pod with the labels of its namespace.
+type policyEndpoint struct {
+	pod      *corev1.Pod
+	nsLabels map[string]string
+}
+
+// evaluatePolicies checks the policies of self's namespace for traffic
+// between self and peer in direction, as seen from self. The port is
+// always the destination's: self for ingress, peer for egress.
+func evaluatePolicies(policies []networkingv1.NetworkPolicy, self, peer policyEndpoint, direction networkingv1.PolicyType, port int32, protocol string) PolicyVerdict {
+	verdict := PolicyVerdict{Policies: []string{}, AllowedBy: []string{}}
+	dest := self
+	if direction == networkingv1.PolicyTypeEgress {
+		dest = peer
+	}
+	for i := range policies {
+		np := &policies[i]
+		selector, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+		if err != nil || !selector.Matches(labels.Set(self.pod.Labels)) || !hasPolicyType(np, direction) {
+			continue
+		}
+		verdict.Isolated = true
+		verdict.Policies = append(verdict.Policies, np.Name)
+
+		if direction == networkingv1.PolicyTypeIngress {
+			for _, rule := range np.Spec.Ingress {
+				if peersMatch(rule.From, np.Namespace, peer) && portsMatch(rule.Ports, dest.pod, port, protocol) {
+					verdict.AllowedBy = append(verdict.AllowedBy, np.Name)
+					break
+				}
+			}
+		} else {
+			for _, rule := range np.Spec.Egress {
+				if peersMatch(rule.To, np.Namespace, peer) && portsMatch(rule.Ports, dest.pod, port, protocol) {
+					verdict.AllowedBy = append(verdict.AllowedBy, np.Name)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(verdict.Policies)
+	sort.Strings(verdict.AllowedBy)
+	verdict.Allowed = !verdict.Isolated || len(verdict.AllowedBy) > 0
+	return verdict
+}
+
+// hasPolicyType applies the API defaulting: Ingress always, Egress when
+// the policy has egress rules.
+func hasPolicyType(np *networkingv1.NetworkPolicy, direction networkingv1.PolicyType) bool {
+	if len(np.Spec.PolicyTypes) == 0 {
+		return direction == networkingv1.PolicyTypeIngress || len(np.Spec.Egress) > 0
+	}
+	for _, t := range np.Spec.PolicyTypes {
+		if t == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// peersMatch reports whether a rule's peers include ep. No peers match
+// everything.
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, policyNamespace string, ep policyEndpoint) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, p := range peers {
+		if p.IPBlock != nil {
+			if ipBlockContains(p.IPBlock, ep.pod.Status.PodIP) {
+				return true
+			}
+			continue
+		}
+		if p.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(p.NamespaceSelector)
+			if err != nil || !selector.Matches(labels.Set(ep.nsLabels)) {
+				continue
+			}
+		} else if ep.pod.Namespace != policyNamespace {
+			continue
+		}
+		if p.PodSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(p.PodSelector)
+			if err != nil || !selector.Matches(labels.Set(ep.pod.Labels)) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func ipBlockContains(block *networkingv1.IPBlock, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	if _, cidr, err := net.ParseCIDR(block.CIDR); err != nil || !cidr.Contains(addr) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, cidr, err := net.ParseCIDR(except); err == nil && cidr.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// portsMatch reports whether a rule's ports include port. Named ports are
+// resolved against the destination pod's containers. No ports match
+// every port.
+func portsMatch(ports []networkingv1.NetworkPolicyPort, dest *corev1.Pod, port int32, protocol string) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		ruleProtocol := string(corev1.ProtocolTCP)
+		if p.Protocol != nil {
+			ruleProtocol = string(*p.Protocol)
+		}
+		if ruleProtocol != protocol {
+			continue
+		}
+		switch {
+		case p.Port == nil:
+			return true
+		case p.Port.Type == intstr.String:
+			for _, container := range dest.Spec.Containers {
+				for _, cp := range container.Ports {
+					if cp.Name == p.Port.StrVal && cp.ContainerPort == port {
+						return true
+					}
+				}
+			}
+		case p.EndPort != nil:
+			if port >= p.Port.IntVal && port <= *p.EndPort {
+				return true
+			}
+		case p.Port.IntVal == port:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Kubelet defaults used when a node's configuration can't be read.
+var (
+	defaultEvictionHard = map[string]string{
+		"nodefs.available":   "10%",
+		"nodefs.inodesFree":  "5%",
+		"imagefs.available":  "15%",
+		"imagefs.inodesFree": "5%",
+	}
+	defaultImageGCHighPercent = 85
+	defaultImageGCLowPercent  = 80
+)
+
+// Filesystems reported by the kubelet stats summary.
+const (
+	FilesystemNode      = "nodefs"
+	FilesystemImage     = "imagefs"
+	FilesystemContainer = "containerfs"
+)
+
+type FilesystemUsage struct {
+	Name           string  `json:"name"`
+	CapacityBytes  int64   `json:"capacity_bytes"`
+	UsedBytes      int64   `json:"used_bytes"`
+	AvailableBytes int64   `json:"available_bytes"`
+	UsedPercent    float64 `json:"used_percent"`
+	Inodes         int64   `json:"inodes"`
+	InodesFree     int64   `json:"inodes_free"`
+	// Shared is set when the filesystem is the same one as nodefs, which
+	// is how most nodes are set up.
+	Shared bool `json:"shared"`
+	// EvictionBytes is the available space below which the kubelet evicts
+	// pods (evictionHard), and SoftEvictionBytes the evictionSoft one.
+	EvictionBytes     int64 `json:"eviction_bytes"`
+	SoftEvictionBytes int64 `json:"soft_eviction_bytes,omitempty"`
+	EvictionInodes    int64 `json:"eviction_inodes"`
+	// HeadroomBytes is how much more can be written before eviction.
+	HeadroomBytes int64 `json:"headroom_bytes"`
+	// ImageGCHighPercent and ImageGCLowPercent are set on the filesystem
+	// holding images: above the high mark the kubelet deletes unused
+	// images until usage is back at the low mark.
+	ImageGCHighPercent int      `json:"image_gc_high_percent,omitempty"`
+	ImageGCLowPercent  int      `json:"image_gc_low_percent,omitempty"`
+	Status             string   `json:"status"`
+	Messages           []string `json:"messages"`
+}
+
+type NodeDiskUsage struct {
+	Node         string            `json:"node"`
+	DiskPressure bool              `json:"disk_pressure"`
+	Filesystems  []FilesystemUsage `json:"filesystems"`
+	// DefaultThresholds is set when the kubelet configuration couldn't be
+	// read and the kubelet defaults were assumed.
+	DefaultThresholds bool   `json:"default_thresholds"`
+	Status            string `json:"status"`
+}
+
+type NodeDiskReport struct {
+	Nodes  []NodeDiskUsage   `json:"nodes"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type kubeletFsStats struct {
+	AvailableBytes *uint64 `json:"availableBytes"`
+	CapacityBytes  *uint64 `json:"capacityBytes"`
+	UsedBytes      *uint64 `json:"usedBytes"`
+	InodesFree     *uint64 `json:"inodesFree"`
+	Inodes         *uint64 `json:"inodes"`
+}
+
+type kubeletStatsSummary struct {
+	Node struct {
+		Fs      *kubeletFsStats `json:"fs"`
+		Runtime *struct {
+			ImageFs     *kubeletFsStats `json:"imageFs"`
+			ContainerFs *kubeletFsStats `json:"containerFs"`
+		} `json:"runtime"`
+	} `json:"node"`
+}
+
+// GetNodeDiskUsage reports the node, image and container filesystems of
+// the given nodes (all when empty) from the kubelet stats summary, with
+// each one's distance to the kubelet's eviction thresholds and image GC
+// marks, so DiskPressure can be seen coming. A filesystem is critical at
+// or below its eviction threshold and a warning within twice of it, past
+// a soft threshold or above the image GC high mark.
+func (c *Client) GetNodeDiskUsage(nodeNames []string) (*NodeDiskReport, error) {
+	var nodes *corev1.NodeList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		nodes, err = c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	pressure := make(map[string]bool)
+	for _, n := range nodes.Items {
+		pressure[n.Name] = false
+		for _, cond := range n.Status.Conditions {
+			if cond.Type == corev1.NodeDiskPressure {
+				pressure[n.Name] = cond.Status == corev1.ConditionTrue
+			}
+		}
+	}
+	if len(nodeNames) == 0 {
+		for name := range pressure {
+			nodeNames = append(nodeNames, name)
+		}
+	}
+	sort.Strings(nodeNames)
+
+	report := &NodeDiskReport{Nodes: []NodeDiskUsage{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 8)
+	for _, name := range nodeNames {
+		if _, ok := pressure[name]; !ok {
+			if report.Errors == nil {
+				report.Errors = make(map[string]string)
+			}
+			report.Errors[name] = "node not found"
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			usage, err := c.nodeDiskUsage(name, pressure[name])
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if report.Errors == nil {
+					report.Errors = make(map[string]string)
+				}
+				report.Errors[name] = err.Error()
+				return
+			}
+			report.Nodes = append(report.Nodes, *usage)
+		}(name)
+	}
+	wg.Wait()
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Node < report.Nodes[j].Node })
+	return report, nil
+}
+
+func (c *Client) nodeDiskUsage(name string, diskPressure bool) (*NodeDiskUsage, error) {
+	var raw []byte
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		raw, err = c.Clientset.CoreV1().RESTClient().Get().
+			AbsPath("/api/v1/nodes", name, "proxy", "stats", "summary").
+			Param("only_cpu_and_memory", "false").
+			DoRaw(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var summary kubeletStatsSummary
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return nil, fmt.Errorf("failed to decode stats summary for node %s: %v", name, err)
+	}
+	if summary.Node.Fs == nil {
+		return nil, fmt.Errorf("node %s reports no filesystem stats", name)
+	}
+
+	usage := &NodeDiskUsage{Node: name, DiskPressure: diskPressure, Filesystems: []FilesystemUsage{}, Status: "ok"}
+	hard, soft := defaultEvictionHard, map[string]string{}
+	gcHigh, gcLow := defaultImageGCHighPercent, defaultImageGCLowPercent
+	if cfg, err := c.GetKubeletConfig(name); err == nil {
+		// configz has the kubelet defaults filled in already.
+		hard, soft = stringMap(cfg["evictionHard"]), stringMap(cfg["evictionSoft"])
+		if v, ok := cfg["imageGCHighThresholdPercent"].(float64); ok {
+			gcHigh = int(v)
+		}
+		if v, ok := cfg["imageGCLowThresholdPercent"].(float64); ok {
+			gcLow = int(v)
+		}
+	} else {
+		usage.DefaultThresholds = true
+	}
+
+	nodeFs := filesystemUsage(FilesystemNode, summary.Node.Fs)
+	imageFs, containerFs := nodeFs, (*FilesystemUsage)(nil)
+	imageFs.Name = FilesystemImage
+	imageFs.Shared = true
+	if rt := summary.Node.Runtime; rt != nil {
+		if rt.ImageFs != nil {
+			imageFs = filesystemUsage(FilesystemImage, rt.ImageFs)
+			imageFs.Shared = sameFilesystem(nodeFs, imageFs)
+		}
+		if rt.ContainerFs != nil {
+			fs := filesystemUsage(FilesystemContainer, rt.ContainerFs)
+			fs.Shared = sameFilesystem(nodeFs, fs)
+			containerFs = &fs
+		}
+	}
+	applyEviction(&nodeFs, "nodefs", hard, soft)
+	applyEviction(&imageFs, "imagefs", hard, soft)
+	imageFs.ImageGCHighPercent, imageFs.ImageGCLowPercent = gcHigh, gcLow
+	if imageFs.UsedPercent >= float64(gcHigh) {
+		imageFs.Messages = append(imageFs.Messages, fmt.Sprintf("above the image GC high mark of %d%%; unused images are being deleted down to %d%%", gcHigh, gcLow))
+		raiseStatus(&imageFs.Status, "warning")
+	}
+	usage.Filesystems = append(usage.Filesystems, nodeFs, imageFs)
+	if containerFs != nil {
+		// Split image filesystems (KEP-4191) evict on containerfs signals,
+		// falling back to the nodefs ones.
+		signal := "containerfs"
+		if _, ok := hard["containerfs.available"]; !ok {
+			signal = "nodefs"
+		}
+		applyEviction(containerFs, signal, hard, soft)
+		usage.Filesystems = append(usage.Filesystems, *containerFs)
+	}
+	for _, fs := range usage.Filesystems {
+		raiseStatus(&usage.Status, fs.Status)
+	}
+	if diskPressure {
+		usage.Status = "critical"
+	}
+	return usage, nil
+}
+
+func filesystemUsage(name string, s *kubeletFsStats) FilesystemUsage {
+	value := func(v *uint64) int64 {
+		if v == nil {
+			return 0
+		}
+		return int64(*v)
+	}
+	fs := FilesystemUsage{
+		Name:           name,
+		CapacityBytes:  value(s.CapacityBytes),
+		UsedBytes:      value(s.UsedBytes),
+		AvailableBytes: value(s.AvailableBytes),
+		Inodes:         value(s.Inodes),
+		InodesFree:     value(s.InodesFree),
+		Status:         "ok",
+		Messages:       []string{},
+	}
+	if fs.CapacityBytes > 0 {
+		fs.UsedPercent = float64(fs.CapacityBytes-fs.AvailableBytes) * 100 / float64(fs.CapacityBytes)
+	}
+	return fs
+}
+
+func sameFilesystem(a, b FilesystemUsage) bool {
+	return a.CapacityBytes == b.CapacityBytes && a.Inodes == b.Inodes
+}
+
+// applyEviction sets the eviction thresholds of a filesystem from the
+// kubelet's "<signal>.available" and "<signal>.inodesFree" settings and
+// rates how close it is to them.
+func applyEviction(fs *FilesystemUsage, signal string, hard, soft map[string]string) {
+	fs.EvictionBytes = evictionThreshold(hard[signal+".available"], fs.CapacityBytes)
+	fs.SoftEvictionBytes = evictionThreshold(soft[signal+".available"], fs.CapacityBytes)
+	fs.EvictionInodes = evictionThreshold(hard[signal+".inodesFree"], fs.Inodes)
+	fs.HeadroomBytes = fs.AvailableBytes - fs.EvictionBytes
+
+	switch {
+	case fs.EvictionBytes > 0 && fs.AvailableBytes <= fs.EvictionBytes:
+		fs.Messages = append(fs.Messages, fmt.Sprintf("available space is below the %s eviction threshold", signal))
+		raiseStatus(&fs.Status, "critical")
+	case fs.SoftEvictionBytes > 0 && fs.AvailableBytes <= fs.SoftEvictionBytes:
+		fs.Messages = append(fs.Messages, fmt.Sprintf("available space is below the %s soft eviction threshold", signal))
+		raiseStatus(&fs.Status, "warning")
+	case fs.EvictionBytes > 0 && fs.AvailableBytes <= 2*fs.EvictionBytes:
+		fs.Messages = append(fs.Messages, fmt.Sprintf("%s of headroom before %s eviction", resource.NewQuantity(fs.HeadroomBytes, resource.BinarySI), signal))
+		raiseStatus(&fs.Status, "warning")
+	}
+	if fs.EvictionInodes > 0 && fs.Inodes > 0 && fs.InodesFree <= fs.EvictionInodes {
+		fs.Messages = append(fs.Messages, fmt.Sprintf("free inodes are below the %s eviction threshold", signal))
+		raiseStatus(&fs.Status, "critical")
+	}
+}
+
+// evictionThreshold resolves "10%" or a quantity like "1Gi" against
+// total; 0 means no threshold.
+func evictionThreshold(threshold string, total int64) int64 {
+	if threshold == "" {
+		return 0
+	}
+	q, ok := evictionQuantity(threshold, *resource.NewQuantity(total, resource.BinarySI))
+	if !ok {
+		return 0
+	}
+	return q.Value()
+}
+
+func raiseStatus(status *string, to string) {
+	rank := map[string]int{"ok": 0, "warning": 1, "critical": 2}
+	if rank[to] > rank[*status] {
+		*status = to
+	}
+}
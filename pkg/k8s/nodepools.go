@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodePoolLabels are the labels provisioners put on nodes to name their
+// group, by provider, in the order they are tried.
+var nodePoolLabels = []struct {
+	provider string
+	label    string
+}{
+	{"karpenter", "karpenter.sh/nodepool"},
+	{"karpenter", "karpenter.sh/provisioner-name"},
+	{"eks", "eks.amazonaws.com/nodegroup"},
+	{"eksctl", "alpha.eksctl.io/nodegroup-name"},
+	{"gke", "cloud.google.com/gke-nodepool"},
+	{"aks", "kubernetes.azure.com/agentpool"},
+	{"aks", "agentpool"},
+	{"doks", "doks.digitalocean.com/node-pool"},
+	{"oke", "oci.oraclecloud.com/node-pool-id"},
+}
+
+// NodePool is a group of nodes provisioned together. Nodes matching no
+// known label form a pool with an empty Provider and Name.
+type NodePool struct {
+	Provider      string   `json:"provider"`
+	Name          string   `json:"name"`
+	Nodes         []string `json:"nodes"`
+	Ready         int      `json:"ready"`
+	Unschedulable int      `json:"unschedulable"`
+	// Pressure counts nodes reporting memory, disk or PID pressure.
+	Pressure int `json:"pressure"`
+	// KubeletVersions, InstanceTypes and Zones count nodes by value; more
+	// than one kubelet version means an upgrade is in progress or stuck.
+	KubeletVersions map[string]int `json:"kubelet_versions"`
+	InstanceTypes   map[string]int `json:"instance_types"`
+	Zones           map[string]int `json:"zones"`
+	Resources       []NodeResource `json:"resources"`
+	// Status is "ok", "warning" when some nodes aren't Ready or are under
+	// pressure, or "critical" when none is Ready.
+	Status string `json:"status"`
+}
+
+// GetNodePools groups nodes by their cloud node group or Karpenter
+// NodePool and reports each group's health, kubelet versions and
+// allocatable resources against what its pods request.
+func (c *Client) GetNodePools() ([]NodePool, error) {
+	var nodes *corev1.NodeList
+	var pods *corev1.PodList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if nodes, err = c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err != nil {
+			return err
+		}
+		pods, err = c.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	podRequests := make(map[string]corev1.ResourceList)
+	podLimits := make(map[string]corev1.ResourceList)
+	podCounts := make(map[string]int64)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		node := pod.Spec.NodeName
+		if node == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if podRequests[node] == nil {
+			podRequests[node], podLimits[node] = corev1.ResourceList{}, corev1.ResourceList{}
+		}
+		req, lim := podResources(pod)
+		addResources(podRequests[node], req)
+		addResources(podLimits[node], lim)
+		podCounts[node]++
+	}
+
+	type totals struct {
+		capacity, allocatable, requested, limits corev1.ResourceList
+	}
+	pools := make(map[[2]string]*NodePool)
+	sums := make(map[[2]string]*totals)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		provider, name := nodePool(node)
+		key := [2]string{provider, name}
+		pool, ok := pools[key]
+		if !ok {
+			pool = &NodePool{
+				Provider:        provider,
+				Name:            name,
+				Nodes:           []string{},
+				KubeletVersions: make(map[string]int),
+				InstanceTypes:   make(map[string]int),
+				Zones:           make(map[string]int),
+				Resources:       []NodeResource{},
+			}
+			pools[key] = pool
+			sums[key] = &totals{corev1.ResourceList{}, corev1.ResourceList{}, corev1.ResourceList{}, corev1.ResourceList{}}
+		}
+
+		pool.Nodes = append(pool.Nodes, node.Name)
+		if node.Spec.Unschedulable {
+			pool.Unschedulable++
+		}
+		pressure := false
+		for _, cond := range node.Status.Conditions {
+			switch cond.Type {
+			case corev1.NodeReady:
+				if cond.Status == corev1.ConditionTrue {
+					pool.Ready++
+				}
+			case corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure:
+				pressure = pressure || cond.Status == corev1.ConditionTrue
+			}
+		}
+		if pressure {
+			pool.Pressure++
+		}
+		pool.KubeletVersions[node.Status.NodeInfo.KubeletVersion]++
+		if t := node.Labels[corev1.LabelInstanceTypeStable]; t != "" {
+			pool.InstanceTypes[t]++
+		}
+		if z := node.Labels[corev1.LabelTopologyZone]; z != "" {
+			pool.Zones[z]++
+		}
+
+		sum := sums[key]
+		addResources(sum.capacity, node.Status.Capacity)
+		addResources(sum.allocatable, node.Status.Allocatable)
+		addResources(sum.requested, podRequests[node.Name])
+		addResources(sum.limits, podLimits[node.Name])
+		sum.requested[corev1.ResourcePods] = *resource.NewQuantity(sum.requested.Pods().Value()+podCounts[node.Name], resource.DecimalSI)
+	}
+
+	result := make([]NodePool, 0, len(pools))
+	for key, pool := range pools {
+		sum := sums[key]
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourcePods} {
+			allocatable := sum.allocatable[name]
+			req := sum.requested[name]
+			lim := sum.limits[name]
+			entry := NodeResource{
+				Resource:    string(name),
+				Capacity:    quantityString(sum.capacity, name),
+				Allocatable: allocatable.String(),
+				Requested:   req.String(),
+				Limits:      lim.String(),
+			}
+			if allocatable.MilliValue() > 0 {
+				entry.RequestedPercent = float64(req.MilliValue()) / float64(allocatable.MilliValue()) * 100
+				entry.LimitsPercent = float64(lim.MilliValue()) / float64(allocatable.MilliValue()) * 100
+			}
+			pool.Resources = append(pool.Resources, entry)
+		}
+
+		sort.Strings(pool.Nodes)
+		pool.Status = "ok"
+		switch {
+		case pool.Ready == 0:
+			pool.Status = "critical"
+		case pool.Ready < len(pool.Nodes) || pool.Pressure > 0:
+			pool.Status = "warning"
+		}
+		result = append(result, *pool)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Provider != result[j].Provider {
+			return result[i].Provider < result[j].Provider
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func nodePool(node *corev1.Node) (string, string) {
+	for _, l := range nodePoolLabels {
+		if name := node.Labels[l.label]; name != "" {
+			return l.provider, name
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,290 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+const nodeRoleLabelPrefix = "node-role.kubernetes.io/"
+
+type NodeCondition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"last_transition_time,omitempty"`
+	// Problem is set for a Ready condition that isn't True and for
+	// pressure conditions that are True.
+	Problem bool `json:"problem"`
+}
+
+// NodeResource compares what a node has with what its pods asked for.
+// Requested and Limits are summed over the non-terminated pods on the
+// node, the way the scheduler accounts for them.
+type NodeResource struct {
+	Resource         string  `json:"resource"`
+	Capacity         string  `json:"capacity"`
+	Allocatable      string  `json:"allocatable"`
+	Requested        string  `json:"requested"`
+	Limits           string  `json:"limits"`
+	RequestedPercent float64 `json:"requested_percent"`
+	LimitsPercent    float64 `json:"limits_percent"`
+}
+
+type NodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+type NodeImage struct {
+	Names     []string `json:"names"`
+	SizeBytes int64    `json:"size_bytes"`
+}
+
+type NodePod struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Phase       string `json:"phase"`
+	CPURequest  string `json:"cpu_request"`
+	MemRequest  string `json:"memory_request"`
+	Restarts    int32  `json:"restarts"`
+	CreatedAt   string `json:"created_at"`
+	OwnerKind   string `json:"owner_kind,omitempty"`
+	OwnerName   string `json:"owner_name,omitempty"`
+	Terminating bool   `json:"terminating"`
+}
+
+type NodeDetail struct {
+	Name             string            `json:"name"`
+	Roles            []string          `json:"roles"`
+	Unschedulable    bool              `json:"unschedulable"`
+	OS               string            `json:"os"`
+	KubeletVersion   string            `json:"kubelet_version"`
+	OSImage          string            `json:"os_image"`
+	KernelVersion    string            `json:"kernel_version"`
+	ContainerRuntime string            `json:"container_runtime"`
+	Architecture     string            `json:"architecture"`
+	Addresses        map[string]string `json:"addresses"`
+	Conditions       []NodeCondition   `json:"conditions"`
+	// Pressure lists the pressure conditions currently reported, e.g.
+	// MemoryPressure.
+	Pressure    []string          `json:"pressure"`
+	Resources   []NodeResource    `json:"resources"`
+	Taints      []NodeTaint       `json:"taints"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	Images      []NodeImage       `json:"images"`
+	Pods        []NodePod         `json:"pods"`
+}
+
+// GetNodeDetail aggregates everything the node view shows: conditions,
+// capacity against allocatable against what the scheduled pods request,
+// taints, labels, cached images and the pods on the node.
+func (c *Client) GetNodeDetail(name string) (*NodeDetail, error) {
+	var node *corev1.Node
+	var pods *corev1.PodList
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		node, err = c.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = c.do(OpList, func(ctx context.Context) error {
+		var err error
+		pods, err = c.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := node.Status.NodeInfo
+	detail := &NodeDetail{
+		Name:             node.Name,
+		Roles:            nodeRoles(node.Labels),
+		Unschedulable:    node.Spec.Unschedulable,
+		OS:               nodeOS(node),
+		KubeletVersion:   info.KubeletVersion,
+		OSImage:          info.OSImage,
+		KernelVersion:    info.KernelVersion,
+		ContainerRuntime: info.ContainerRuntimeVersion,
+		Architecture:     info.Architecture,
+		Addresses:        make(map[string]string),
+		Conditions:       []NodeCondition{},
+		Pressure:         []string{},
+		Taints:           []NodeTaint{},
+		Labels:           node.Labels,
+		Annotations:      node.Annotations,
+		Images:           []NodeImage{},
+		Pods:             []NodePod{},
+	}
+	for _, addr := range node.Status.Addresses {
+		detail.Addresses[string(addr.Type)] = addr.Address
+	}
+
+	for _, cond := range node.Status.Conditions {
+		problem := cond.Status == corev1.ConditionTrue
+		if cond.Type == corev1.NodeReady {
+			problem = cond.Status != corev1.ConditionTrue
+		} else if problem {
+			detail.Pressure = append(detail.Pressure, string(cond.Type))
+		}
+		entry := NodeCondition{
+			Type:    string(cond.Type),
+			Status:  string(cond.Status),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+			Problem: problem,
+		}
+		if !cond.LastTransitionTime.IsZero() {
+			entry.LastTransitionTime = cond.LastTransitionTime.Format(time.RFC3339)
+		}
+		detail.Conditions = append(detail.Conditions, entry)
+	}
+
+	for _, t := range node.Spec.Taints {
+		detail.Taints = append(detail.Taints, NodeTaint{Key: t.Key, Value: t.Value, Effect: string(t.Effect)})
+	}
+	for _, img := range node.Status.Images {
+		detail.Images = append(detail.Images, NodeImage{Names: img.Names, SizeBytes: img.SizeBytes})
+	}
+	sort.Slice(detail.Images, func(i, j int) bool { return detail.Images[i].SizeBytes > detail.Images[j].SizeBytes })
+
+	requested := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+	podCount := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		podCount++
+		req, lim := podResources(pod)
+		addResources(requested, req)
+		addResources(limits, lim)
+
+		entry := NodePod{
+			Namespace:   pod.Namespace,
+			Name:        pod.Name,
+			Phase:       string(pod.Status.Phase),
+			CPURequest:  quantityString(req, corev1.ResourceCPU),
+			MemRequest:  quantityString(req, corev1.ResourceMemory),
+			CreatedAt:   pod.CreationTimestamp.Format(time.RFC3339),
+			Terminating: pod.DeletionTimestamp != nil,
+		}
+		for _, s := range pod.Status.ContainerStatuses {
+			entry.Restarts += s.RestartCount
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			entry.OwnerKind = owner.Kind
+			entry.OwnerName = owner.Name
+		}
+		detail.Pods = append(detail.Pods, entry)
+	}
+	sort.Slice(detail.Pods, func(i, j int) bool {
+		if detail.Pods[i].Namespace != detail.Pods[j].Namespace {
+			return detail.Pods[i].Namespace < detail.Pods[j].Namespace
+		}
+		return detail.Pods[i].Name < detail.Pods[j].Name
+	})
+	requested[corev1.ResourcePods] = *resource.NewQuantity(int64(podCount), resource.DecimalSI)
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourcePods} {
+		allocatable := node.Status.Allocatable[name]
+		req := requested[name]
+		lim := limits[name]
+		entry := NodeResource{
+			Resource:    string(name),
+			Capacity:    quantityString(node.Status.Capacity, name),
+			Allocatable: allocatable.String(),
+			Requested:   req.String(),
+			Limits:      lim.String(),
+		}
+		if allocatable.MilliValue() > 0 {
+			entry.RequestedPercent = float64(req.MilliValue()) / float64(allocatable.MilliValue()) * 100
+			entry.LimitsPercent = float64(lim.MilliValue()) / float64(allocatable.MilliValue()) * 100
+		}
+		detail.Resources = append(detail.Resources, entry)
+	}
+	return detail, nil
+}
+
+// podResources returns the effective requests and limits of a pod as the
+// scheduler computes them: app containers and sidecars add up, a regular
+// init container only counts if it needs more than that on its own, and
+// pod overhead comes on top.
+func podResources(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	requests := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+	for _, ctr := range pod.Spec.Containers {
+		addResources(requests, ctr.Resources.Requests)
+		addResources(limits, ctr.Resources.Limits)
+	}
+
+	sidecarRequests := corev1.ResourceList{}
+	sidecarLimits := corev1.ResourceList{}
+	initRequests := corev1.ResourceList{}
+	initLimits := corev1.ResourceList{}
+	for _, ctr := range pod.Spec.InitContainers {
+		if ctr.RestartPolicy != nil && *ctr.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			addResources(sidecarRequests, ctr.Resources.Requests)
+			addResources(sidecarLimits, ctr.Resources.Limits)
+			continue
+		}
+		// An init container runs next to the sidecars started before it.
+		maxResources(initRequests, sumResources(sidecarRequests, ctr.Resources.Requests))
+		maxResources(initLimits, sumResources(sidecarLimits, ctr.Resources.Limits))
+	}
+	addResources(requests, sidecarRequests)
+	addResources(limits, sidecarLimits)
+	maxResources(requests, initRequests)
+	maxResources(limits, initLimits)
+
+	addResources(requests, pod.Spec.Overhead)
+	if len(limits) > 0 {
+		addResources(limits, pod.Spec.Overhead)
+	}
+	return requests, limits
+}
+
+func addResources(total, add corev1.ResourceList) {
+	for name, q := range add {
+		sum := total[name]
+		sum.Add(q)
+		total[name] = sum
+	}
+}
+
+func sumResources(a, b corev1.ResourceList) corev1.ResourceList {
+	sum := corev1.ResourceList{}
+	addResources(sum, a)
+	addResources(sum, b)
+	return sum
+}
+
+func maxResources(total, other corev1.ResourceList) {
+	for name, q := range other {
+		if current, ok := total[name]; !ok || q.Cmp(current) > 0 {
+			total[name] = q.DeepCopy()
+		}
+	}
+}
+
+func quantityString(list corev1.ResourceList, name corev1.ResourceName) string {
+	q, ok := list[name]
+	if !ok {
+		return "0"
+	}
+	return q.String()
+}
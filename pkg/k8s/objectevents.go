@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+type ObjectEvent struct {
+	Type           string    `json:"type"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Count          int32     `json:"count"`
+	Source         string    `json:"source"`
+	FirstTimestamp time.Time `json:"first_timestamp"`
+	LastTimestamp  time.Time `json:"last_timestamp"`
+}
+
+// GetEventsForObject returns the events about one object from both the
+// core and events.k8s.io APIs. Events repeating the same reason and
+// message are merged into a single series, and the result is sorted by
+// last occurrence, newest last. uid is optional and narrows the match to
+// one incarnation of a recreated object.
+func (c *Client) GetEventsForObject(namespace, kind, name, uid string) ([]ObjectEvent, error) {
+	ctx, cancel := c.opContext(OpList)
+	defer cancel()
+
+	coreSelector := fields.Set{
+		"involvedObject.kind": kind,
+		"involvedObject.name": name,
+	}
+	newSelector := fields.Set{
+		"regarding.kind": kind,
+		"regarding.name": name,
+	}
+	if uid != "" {
+		coreSelector["involvedObject.uid"] = uid
+		newSelector["regarding.uid"] = uid
+	}
+
+	seen := make(map[string]bool)
+	series := make(map[string]*ObjectEvent)
+	add := func(uid string, ev ObjectEvent) {
+		if seen[uid] {
+			return
+		}
+		seen[uid] = true
+		key := ev.Type + "\x00" + ev.Reason + "\x00" + ev.Message
+		existing, ok := series[key]
+		if !ok {
+			series[key] = &ev
+			return
+		}
+		existing.Count += ev.Count
+		if ev.FirstTimestamp.Before(existing.FirstTimestamp) {
+			existing.FirstTimestamp = ev.FirstTimestamp
+		}
+		if ev.LastTimestamp.After(existing.LastTimestamp) {
+			existing.LastTimestamp = ev.LastTimestamp
+		}
+	}
+
+	core, coreErr := c.Clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: coreSelector.AsSelector().String(),
+	})
+	if coreErr == nil {
+		for i := range core.Items {
+			e := &core.Items[i]
+			add(string(e.UID), coreObjectEvent(e))
+		}
+	}
+
+	// events.k8s.io carries the same objects on newer clusters; it's read
+	// as well because some components only populate its fields.
+	newer, newErr := c.Clientset.EventsV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: newSelector.AsSelector().String(),
+	})
+	if newErr == nil {
+		for i := range newer.Items {
+			e := &newer.Items[i]
+			add(string(e.UID), eventsV1ObjectEvent(e))
+		}
+	}
+
+	if coreErr != nil && newErr != nil {
+		return nil, coreErr
+	}
+
+	result := make([]ObjectEvent, 0, len(series))
+	for _, ev := range series {
+		result = append(result, *ev)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LastTimestamp.Before(result[j].LastTimestamp)
+	})
+	return result, nil
+}
+
+func coreObjectEvent(e *corev1.Event) ObjectEvent {
+	ev := ObjectEvent{
+		Type:           e.Type,
+		Reason:         e.Reason,
+		Message:        e.Message,
+		Count:          e.Count,
+		Source:         e.Source.Component,
+		FirstTimestamp: e.FirstTimestamp.Time,
+		LastTimestamp:  eventTimestamp(e),
+	}
+	if e.Series != nil {
+		ev.Count = e.Series.Count
+	}
+	if ev.Count == 0 {
+		ev.Count = 1
+	}
+	if ev.Source == "" {
+		ev.Source = e.ReportingController
+	}
+	if ev.FirstTimestamp.IsZero() {
+		ev.FirstTimestamp = ev.LastTimestamp
+	}
+	return ev
+}
+
+func eventsV1ObjectEvent(e *eventsv1.Event) ObjectEvent {
+	ev := ObjectEvent{
+		Type:           e.Type,
+		Reason:         e.Reason,
+		Message:        e.Note,
+		Count:          1,
+		Source:         e.ReportingController,
+		FirstTimestamp: e.EventTime.Time,
+		LastTimestamp:  e.EventTime.Time,
+	}
+	if e.Series != nil {
+		ev.Count = e.Series.Count
+		ev.LastTimestamp = e.Series.LastObservedTime.Time
+	}
+	// Events created through the core API only fill the deprecated fields.
+	if e.DeprecatedCount > ev.Count {
+		ev.Count = e.DeprecatedCount
+	}
+	if ev.FirstTimestamp.IsZero() {
+		ev.FirstTimestamp = e.DeprecatedFirstTimestamp.Time
+	}
+	if !e.DeprecatedLastTimestamp.IsZero() && e.DeprecatedLastTimestamp.After(ev.LastTimestamp) {
+		ev.LastTimestamp = e.DeprecatedLastTimestamp.Time
+	}
+	if ev.LastTimestamp.IsZero() {
+		ev.LastTimestamp = e.CreationTimestamp.Time
+	}
+	if ev.FirstTimestamp.IsZero() {
+		ev.FirstTimestamp = ev.LastTimestamp
+	}
+	if ev.Source == "" {
+		ev.Source = e.DeprecatedSource.Component
+	}
+	return ev
+}
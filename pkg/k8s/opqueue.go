@@ -0,0 +1,306 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// EventOperationDone is emitted with an Operation after every queued
+	// mutation.
+	EventOperationDone = "operation:done"
+
+	operationHistoryLimit = 50
+	// operationQueueWait bounds how long a mutation waits for the queue of
+	// its context to take it before giving up.
+	operationQueueWait = time.Minute
+)
+
+// Mutating actions recorded by the operation queue.
+const (
+	ActionScale   = "scale"
+	ActionLabel   = "label"
+	ActionPatch   = "patch"
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestart = "restart"
+	// ActionRollback rolls a workload back to an earlier revision;
+	// ActionUndo reverts a recorded operation.
+	ActionRollback = "rollback"
+	ActionUndo     = "undo"
+	ActionApply    = "apply"
+	ActionExpand   = "expand"
+	ActionEvict    = "evict"
+	ActionCordon   = "cordon"
+	// ActionFinalize removes the finalizers of a terminating namespace,
+	// which can't be given back.
+	ActionFinalize = "finalize"
+)
+
+// undoableActions can be reverted by re-applying the prior object state.
+var undoableActions = map[string]bool{
+	ActionScale: true,
+	ActionLabel: true,
+	ActionPatch: true,
+}
+
+type Operation struct {
+	ID          string      `json:"id"`
+	Action      string      `json:"action"`
+	Target      ResourceRef `json:"target"`
+	Description string      `json:"description"`
+	Context     string      `json:"context"`
+	Started     time.Time   `json:"started"`
+	Finished    time.Time   `json:"finished"`
+	Error       string      `json:"error,omitempty"`
+	Undoable    bool        `json:"undoable"`
+	Undone      bool        `json:"undone"`
+}
+
+type operationRecord struct {
+	Operation
+	// previous is the object as it was before the operation, kept for
+	// undoable actions only.
+	previous map[string]interface{}
+}
+
+type queuedOperation struct {
+	op   *operationRecord
+	fn   func() error
+	done chan error
+}
+
+// operationQueue runs the mutations of each context one at a time, in the
+// order they were submitted, and keeps a bounded history of them. Contexts
+// have their own worker, so a slow cluster doesn't hold up the others.
+type operationQueue struct {
+	mu      sync.Mutex
+	workers map[string]chan queuedOperation
+	nextID  int
+	history []*operationRecord
+}
+
+// worker returns the job channel of a context, starting its worker on
+// first use.
+func (c *Client) worker(contextName string) chan queuedOperation {
+	q := &c.ops
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if jobs, ok := q.workers[contextName]; ok {
+		return jobs
+	}
+	if q.workers == nil {
+		q.workers = make(map[string]chan queuedOperation)
+	}
+	jobs := make(chan queuedOperation)
+	q.workers[contextName] = jobs
+	go func() {
+		for job := range jobs {
+			job.done <- c.runOperation(job.op, job.fn)
+		}
+	}()
+	return jobs
+}
+
+// mutate submits fn to the operation queue and waits for it. For undoable
+// actions the target is read first so the operation can be reverted with
+// UndoLastOperation. It fails when the queue of the context stays busy for
+// operationQueueWait. fn must not submit to the queue itself.
+func (c *Client) mutate(action string, target ResourceRef, description string, fn func() error) error {
+	q := &c.ops
+	q.mu.Lock()
+	q.nextID++
+	id := strconv.Itoa(q.nextID)
+	q.mu.Unlock()
+
+	currentContext, _ := c.GetCurrentContext()
+	op := &operationRecord{Operation: Operation{
+		ID:          id,
+		Action:      action,
+		Target:      target,
+		Description: description,
+		Context:     currentContext,
+	}}
+	done := make(chan error, 1)
+	timer := time.NewTimer(operationQueueWait)
+	defer timer.Stop()
+	select {
+	case c.worker(currentContext) <- queuedOperation{op: op, fn: fn, done: done}:
+	case <-timer.C:
+		return fmt.Errorf("failed to %s: the operation queue is still busy after %s", description, operationQueueWait)
+	}
+	return <-done
+}
+
+func (c *Client) runOperation(op *operationRecord, fn func() error) error {
+	op.Started = time.Now()
+	if undoableActions[op.Action] {
+		// Best effort: without the prior state the operation still runs,
+		// it just can't be undone.
+		var subresources []string
+		if op.Action == ActionScale {
+			// The scale subresource maps the replica count to wherever the
+			// resource keeps it, e.g. a CRD's specReplicasPath.
+			subresources = []string{"scale"}
+		}
+		if prev, err := c.getObject(op.Target, subresources...); err == nil {
+			op.previous = prev.Object
+		}
+	}
+	err := fn()
+	op.Finished = time.Now()
+	if err != nil {
+		op.Error = err.Error()
+	}
+	op.Undoable = err == nil && op.previous != nil
+
+	q := &c.ops
+	q.mu.Lock()
+	q.history = append(q.history, op)
+	if len(q.history) > operationHistoryLimit {
+		q.history = q.history[len(q.history)-operationHistoryLimit:]
+	}
+	q.mu.Unlock()
+
+	c.emit(EventOperationDone, op.Operation)
+	return err
+}
+
+// GetOperations returns the recorded mutations, newest first.
+func (c *Client) GetOperations() []Operation {
+	q := &c.ops
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ops := make([]Operation, len(q.history))
+	for i, op := range q.history {
+		ops[len(q.history)-1-i] = op.Operation
+	}
+	return ops
+}
+
+// UndoLastOperation reverts the most recent scale, label or patch
+// operation of the active context that hasn't been undone yet. A scale is
+// reverted through the scale subresource to the recorded replica count;
+// labels and patches re-apply the recorded prior state onto the live
+// object, see restoreFields. Changes made since by others to those fields
+// are overwritten.
+func (c *Client) UndoLastOperation() (*Operation, error) {
+	currentContext, _ := c.GetCurrentContext()
+	q := &c.ops
+	q.mu.Lock()
+	var last *operationRecord
+	for i := len(q.history) - 1; i >= 0; i-- {
+		op := q.history[i]
+		if op.Undoable && !op.Undone && op.Context == currentContext {
+			last = op
+			break
+		}
+	}
+	q.mu.Unlock()
+	if last == nil {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+
+	description := fmt.Sprintf("undo %s of %s/%s", last.Action, last.Target.Plural, last.Target.Name)
+	err := c.mutate(ActionUndo, last.Target, description, func() error {
+		if last.Action == ActionScale {
+			return c.restoreScale(last.Target, last.previous)
+		}
+		return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := c.getObject(last.Target)
+			if err != nil {
+				return err
+			}
+			restoreFields(current.Object, last.previous, last.Action)
+			_, err = c.updateObject(last.Target, current, false)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to undo %s: %v", last.Description, err)
+	}
+
+	q.mu.Lock()
+	last.Undone = true
+	undone := last.Operation
+	q.mu.Unlock()
+	return &undone, nil
+}
+
+// restoreScale sets the replica count of ref back to that of the recorded
+// scale subresource.
+func (c *Client) restoreScale(ref ResourceRef, previous map[string]interface{}) error {
+	// Scale omits a replica count of 0.
+	replicas, _, _ := unstructured.NestedInt64(previous, "spec", "replicas")
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	return c.do(OpMutate, func(ctx context.Context) error {
+		var err error
+		if ref.Namespace != "" {
+			_, err = c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Patch(ctx, ref.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "scale")
+		} else {
+			_, err = c.DynamicClient.Resource(ref.GVR()).Patch(ctx, ref.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "scale")
+		}
+		return err
+	})
+}
+
+// restoreFields copies what an action may have changed from previous into
+// obj: the labels for label, and for patch every top-level field but
+// metadata and status, plus the labels and annotations. Fields missing
+// from previous are removed.
+func restoreFields(obj, previous map[string]interface{}, action string) {
+	var paths [][]string
+	switch action {
+	case ActionLabel:
+		paths = [][]string{{"metadata", "labels"}}
+	case ActionPatch:
+		for key := range obj {
+			if _, ok := previous[key]; !ok && !serverOwnedFields[key] {
+				delete(obj, key)
+			}
+		}
+		for key, value := range previous {
+			if !serverOwnedFields[key] {
+				obj[key] = runtime.DeepCopyJSONValue(value)
+			}
+		}
+		paths = [][]string{{"metadata", "labels"}, {"metadata", "annotations"}}
+	}
+	for _, path := range paths {
+		value, found, _ := unstructured.NestedFieldCopy(previous, path...)
+		if found {
+			_ = unstructured.SetNestedField(obj, value, path...)
+		} else {
+			unstructured.RemoveNestedField(obj, path...)
+		}
+	}
+}
+
+// serverOwnedFields are the top-level fields an undone patch leaves alone:
+// metadata carries the resourceVersion of the live object and status isn't
+// written through the main resource.
+var serverOwnedFields = map[string]bool{"apiVersion": true, "kind": true, "metadata": true, "status": true}
+
+func (c *Client) getObject(ref ResourceRef, subresources ...string) (*unstructured.Unstructured, error) {
+	var obj *unstructured.Unstructured
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		if ref.Namespace != "" {
+			obj, err = c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{}, subresources...)
+		} else {
+			obj, err = c.DynamicClient.Resource(ref.GVR()).Get(ctx, ref.Name, metav1.GetOptions{}, subresources...)
+		}
+		return err
+	})
+	return obj, err
+}
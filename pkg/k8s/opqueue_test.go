@@ -0,0 +1,189 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRestoreFields(t *testing.T) {
+	previous := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":   "web",
+			"labels": map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{"replicas": int64(2), "paused": false},
+		"data": map[string]interface{}{"mode": "old"},
+	}
+	current := func() map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":            "web",
+				"resourceVersion": "7",
+				"labels":          map[string]interface{}{"app": "web", "tier": "front"},
+				"annotations":     map[string]interface{}{"note": "added"},
+			},
+			"spec":       map[string]interface{}{"replicas": int64(5), "paused": true},
+			"data":       map[string]interface{}{"mode": "new", "extra": "x"},
+			"binaryData": map[string]interface{}{"blob": "AAE="},
+			"status":     map[string]interface{}{"readyReplicas": int64(5)},
+		}
+	}
+
+	tests := []struct {
+		action string
+		want   map[string]interface{}
+	}{
+		{
+			action: ActionLabel,
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":            "web",
+					"resourceVersion": "7",
+					"labels":          map[string]interface{}{"app": "web"},
+					"annotations":     map[string]interface{}{"note": "added"},
+				},
+				"spec":       map[string]interface{}{"replicas": int64(5), "paused": true},
+				"data":       map[string]interface{}{"mode": "new", "extra": "x"},
+				"binaryData": map[string]interface{}{"blob": "AAE="},
+				"status":     map[string]interface{}{"readyReplicas": int64(5)},
+			},
+		},
+		{
+			action: ActionPatch,
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":            "web",
+					"resourceVersion": "7",
+					"labels":          map[string]interface{}{"app": "web"},
+				},
+				"spec":   map[string]interface{}{"replicas": int64(2), "paused": false},
+				"data":   map[string]interface{}{"mode": "old"},
+				"status": map[string]interface{}{"readyReplicas": int64(5)},
+			},
+		},
+		{
+			// Not undoable: nothing is restored.
+			action: ActionCordon,
+			want:   current(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			obj := current()
+			restoreFields(obj, previous, tt.action)
+			if !reflect.DeepEqual(obj, tt.want) {
+				t.Errorf("restoreFields(%s) =\n%v\nwant\n%v", tt.action, obj, tt.want)
+			}
+		})
+	}
+}
+
+func TestUndoDataPatch(t *testing.T) {
+	c := NewDemoClient()
+	ref := ResourceRef{Version: "v1", Kind: "ConfigMap", Plural: "configmaps", Namespace: "shop", Name: "flags"}
+	cm := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "flags", "namespace": "shop"},
+		"data":       map[string]interface{}{"mode": "old"},
+	}}
+	if _, err := c.DynamicClient.Resource(ref.GVR()).Namespace("shop").Create(context.Background(), cm, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.PatchResource(ref, "merge", `{"data":{"mode":"new","extra":"x"},"binaryData":{"blob":"AAE="}}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UndoLastOperation(); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := c.getObject(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := obj.Object["data"]; !reflect.DeepEqual(data, map[string]interface{}{"mode": "old"}) {
+		t.Errorf("data after undo = %v, want mode=old only", data)
+	}
+	if _, found := obj.Object["binaryData"]; found {
+		t.Errorf("binaryData added by the patch survived the undo: %v", obj.Object["binaryData"])
+	}
+}
+
+// A custom resource keeping its replica count at spec.size, its
+// specReplicasPath, and exposing it through the scale subresource.
+func TestUndoCustomResourceScale(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "w", "namespace": "shop"},
+		"spec":       map[string]interface{}{"size": int64(2)},
+	}}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "WidgetList"}, widget)
+	scaleOf := func(obj runtime.Object) *unstructured.Unstructured {
+		size, _, _ := unstructured.NestedInt64(obj.(*unstructured.Unstructured).Object, "spec", "size")
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "autoscaling/v1",
+			"kind":       "Scale",
+			"metadata":   map[string]interface{}{"name": "w", "namespace": "shop"},
+			"spec":       map[string]interface{}{"replicas": size},
+		}}
+	}
+	dyn.PrependReactor("get", "widgets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		obj, err := dyn.Tracker().Get(gvr, "shop", "w")
+		if err != nil {
+			return true, nil, err
+		}
+		return true, scaleOf(obj), nil
+	})
+	dyn.PrependReactor("patch", "widgets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		var patch struct {
+			Spec struct {
+				Replicas int64 `json:"replicas"`
+			} `json:"spec"`
+		}
+		if err := json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &patch); err != nil {
+			return true, nil, err
+		}
+		obj, err := dyn.Tracker().Get(gvr, "shop", "w")
+		if err != nil {
+			return true, nil, err
+		}
+		u := obj.(*unstructured.Unstructured).DeepCopy()
+		_ = unstructured.SetNestedField(u.Object, patch.Spec.Replicas, "spec", "size")
+		return true, scaleOf(u), dyn.Tracker().Update(gvr, u, "shop")
+	})
+	c := &Client{DynamicClient: dyn, contextName: "test"}
+
+	if err := c.ScaleResource("example.com", "v1", "widgets", "shop", "w", 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UndoLastOperation(); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := dyn.Tracker().Get(gvr, "shop", "w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := obj.(*unstructured.Unstructured).Object["spec"]
+	if !reflect.DeepEqual(spec, map[string]interface{}{"size": int64(2)}) {
+		t.Errorf("spec after undo = %v, want size 2 and no replicas field", spec)
+	}
+}
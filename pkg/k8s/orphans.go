@@ -0,0 +1,81 @@
+package k8s
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// OrphanedObject has ownerReferences to objects that no longer exist.
+// Normally the garbage collector deletes such objects once all owners are
+// gone; when it doesn't (orphan propagation, a stuck collector, owners in
+// another namespace) they linger forever.
+type OrphanedObject struct {
+	ObjectNode
+	MissingOwners []string `json:"missing_owners"`
+	// AllOwnersGone is set when none of the owners exist anymore.
+	AllOwnersGone bool `json:"all_owners_gone"`
+}
+
+type OrphanReport struct {
+	Namespace string           `json:"namespace"`
+	Orphans   []OrphanedObject `json:"orphans"`
+	// Errors lists resource types that couldn't be scanned. Owners of
+	// those types are reported as missing, so the report is only reliable
+	// when this is empty.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// FindOrphans scans a namespace, or the whole cluster when namespace is
+// empty, for objects whose owners are missing. Cluster-scoped types are
+// always scanned since namespaced objects may be owned by them.
+func (c *Client) FindOrphans(namespace string) (*OrphanReport, error) {
+	idx, err := c.buildOwnerIndex(namespace, true)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[types.UID]int)
+	orphans := make(map[types.UID]*OrphanedObject)
+	for owner, children := range idx.children {
+		for _, child := range children {
+			uid := types.UID(child.UID)
+			owners[uid]++
+			if idx.uids[owner] {
+				continue
+			}
+			o, ok := orphans[uid]
+			if !ok {
+				o = &OrphanedObject{ObjectNode: child}
+				orphans[uid] = o
+			}
+			o.MissingOwners = append(o.MissingOwners, string(owner))
+		}
+	}
+
+	report := &OrphanReport{Namespace: namespace, Orphans: []OrphanedObject{}}
+	if len(idx.errors) > 0 {
+		report.Errors = idx.errors
+	}
+	for uid, o := range orphans {
+		// With a namespace, cluster-scoped objects owned by something in
+		// another namespace can't be judged.
+		if namespace != "" && o.Namespace != namespace {
+			continue
+		}
+		sort.Strings(o.MissingOwners)
+		o.AllOwnersGone = len(o.MissingOwners) == owners[uid]
+		report.Orphans = append(report.Orphans, *o)
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool {
+		a, b := report.Orphans[i], report.Orphans[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
@@ -0,0 +1,78 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const defaultPageSize = 500
+
+// ResourcePage is one chunk of a paginated list. Continue is empty on the
+// last page. RemainingItemCount is the server's estimate of how many items
+// follow this page; it is nil when the server can't tell (e.g. with a
+// label or field selector).
+type ResourcePage struct {
+	Items              []interface{} `json:"items"`
+	Continue           string        `json:"continue"`
+	RemainingItemCount *int64        `json:"remaining_item_count,omitempty"`
+	ResourceVersion    string        `json:"resource_version"`
+}
+
+// ListResourcesPage lists at most limit objects, starting after the
+// continue token of the previous page. All pages of one traversal are
+// served from the same snapshot; if the token has expired the caller
+// must start over from the first page.
+func (c *Client) ListResourcesPage(group, version, plural, namespace, labelSelector, fieldSelector string, limit int64, continueToken string) (*ResourcePage, error) {
+	gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: plural}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	opts := metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
+		Limit:         limit,
+		Continue:      continueToken,
+	}
+
+	if err := c.checkScope(gvr, namespace); err != nil {
+		return nil, err
+	}
+
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if namespace != "" {
+			list, err = c.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, opts)
+		} else {
+			list, err = c.DynamicClient.Resource(gvr).List(ctx, opts)
+		}
+		return err
+	})
+	if err != nil {
+		switch {
+		case isResourceGone(err):
+			go c.resourceGone(gvr)
+			return nil, fmt.Errorf("resource type %s is no longer served by the cluster", gvr.String())
+		case continueToken != "" && apierrors.IsResourceExpired(err):
+			return nil, fmt.Errorf("list snapshot expired, reload from the first page: %v", err)
+		}
+		return nil, err
+	}
+	c.removed.clear(gvr)
+
+	page := &ResourcePage{
+		Items:              make([]interface{}, 0, len(list.Items)),
+		Continue:           list.GetContinue(),
+		RemainingItemCount: list.GetRemainingItemCount(),
+		ResourceVersion:    list.GetResourceVersion(),
+	}
+	for _, item := range list.Items {
+		page.Items = append(page.Items, item.Object)
+	}
+	return page, nil
+}
@@ -0,0 +1,88 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// patchTypes maps the short names accepted by PatchResource to their
+// content types. The content types themselves are accepted as well.
+var patchTypes = map[string]types.PatchType{
+	"json":                                types.JSONPatchType,
+	"merge":                               types.MergePatchType,
+	"strategic":                           types.StrategicMergePatchType,
+	string(types.JSONPatchType):           types.JSONPatchType,
+	string(types.MergePatchType):          types.MergePatchType,
+	string(types.StrategicMergePatchType): types.StrategicMergePatchType,
+}
+
+// PatchResource applies a JSON Patch ("json"), JSON merge patch ("merge")
+// or strategic merge patch ("strategic") to an object and returns the
+// patched object, with Secret values blanked. Strategic merge only works
+// for built-in kinds; custom resources must use one of the other two.
+func (c *Client) PatchResource(ref ResourceRef, patchType, patch string) (interface{}, error) {
+	pt, ok := patchTypes[patchType]
+	if !ok {
+		return nil, fmt.Errorf("unknown patch type %q, expected json, merge or strategic", patchType)
+	}
+	if !json.Valid([]byte(patch)) {
+		return nil, fmt.Errorf("patch is not valid JSON")
+	}
+
+	var res *unstructured.Unstructured
+	err := c.mutate(ActionPatch, ref, fmt.Sprintf("%s patch of %s/%s", patchType, ref.Plural, ref.Name), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			var err error
+			if ref.Namespace != "" {
+				res, err = c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Patch(ctx, ref.Name, pt, []byte(patch), metav1.PatchOptions{})
+			} else {
+				res, err = c.DynamicClient.Resource(ref.GVR()).Patch(ctx, ref.Name, pt, []byte(patch), metav1.PatchOptions{})
+			}
+			return err
+		})
+	})
+	if err != nil {
+		if pt == types.StrategicMergePatchType && apierrors.IsUnsupportedMediaType(err) {
+			return nil, fmt.Errorf("%s does not support strategic merge patches, use a merge or JSON patch", ref.Kind)
+		}
+		return nil, fmt.Errorf("failed to patch %s/%s: %v", ref.Kind, ref.Name, err)
+	}
+	RedactSecret(res.Object)
+	return res.Object, nil
+}
+
+// SetLabels adds or changes the given labels and removes the ones listed
+// in remove, as a single merge patch.
+func (c *Client) SetLabels(ref ResourceRef, labels map[string]string, remove []string) error {
+	changes := make(map[string]interface{}, len(labels)+len(remove))
+	for _, k := range remove {
+		changes[k] = nil
+	}
+	for k, v := range labels {
+		changes[k] = v
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": changes},
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.mutate(ActionLabel, ref, fmt.Sprintf("label %s/%s", ref.Plural, ref.Name), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			var err error
+			if ref.Namespace != "" {
+				_, err = c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Patch(ctx, ref.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			} else {
+				_, err = c.DynamicClient.Resource(ref.GVR()).Patch(ctx, ref.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			}
+			return err
+		})
+	})
+}
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// EventPluginOutput carries one line of plugin output.
+	EventPluginOutput = "plugin:output"
+	EventPluginExit   = "plugin:exit"
+
+	kubectlPluginPrefix = "kubectl-"
+)
+
+// KubectlPlugin is an executable named kubectl-* found on PATH. Command is
+// how kubectl would invoke it, e.g. "kubectl view-secret" for
+// kubectl-view_secret.
+type KubectlPlugin struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Path    string `json:"path"`
+}
+
+type PluginRun struct {
+	Plugin string   `json:"plugin"`
+	Args   []string `json:"args"`
+	// Target, when set, is appended as "<plural>/<name>" with its
+	// namespace, which is what most resource-oriented plugins expect.
+	Target *ResourceRef `json:"target,omitempty"`
+}
+
+type PluginOutput struct {
+	RunID  string `json:"run_id"`
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+type PluginExit struct {
+	RunID    string `json:"run_id"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ListKubectlPlugins scans PATH like `kubectl plugin list`. When the same
+// plugin exists in several directories, the first one wins, as it does
+// for kubectl.
+func ListKubectlPlugins() []KubectlPlugin {
+	seen := make(map[string]bool)
+	var plugins []KubectlPlugin
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			file := entry.Name()
+			if !strings.HasPrefix(file, kubectlPluginPrefix) || entry.IsDir() {
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(file, kubectlPluginPrefix), ".exe")
+			if name == "" || seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, file)
+			if info, err := os.Stat(path); err != nil || info.Mode()&0o111 == 0 {
+				continue
+			}
+			seen[name] = true
+			// kubectl maps "-" to subcommands and "_" to dashes.
+			command := "kubectl " + strings.ReplaceAll(strings.ReplaceAll(name, "-", " "), "_", "-")
+			plugins = append(plugins, KubectlPlugin{Name: name, Command: command, Path: path})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// RunKubectlPlugin starts a discovered plugin against the active context
+// and streams its stdout and stderr line by line as EventPluginOutput,
+// followed by EventPluginExit. The returned ID doubles as a subscription
+// ID, so StopSubscription kills the plugin.
+func (c *Client) RunKubectlPlugin(run PluginRun) (string, error) {
+	if c.demo {
+		return "", errors.New("kubectl plugins are not available for the demo context")
+	}
+	if c.contextName == InClusterContext {
+		return "", errors.New("kubectl plugins are not available for the in-cluster context")
+	}
+	var plugin *KubectlPlugin
+	for _, p := range ListKubectlPlugins() {
+		if p.Name == run.Plugin {
+			plugin = &p
+			break
+		}
+	}
+	if plugin == nil {
+		return "", fmt.Errorf("kubectl plugin %s not found on PATH", run.Plugin)
+	}
+
+	args := append([]string{}, run.Args...)
+	if t := run.Target; t != nil {
+		args = append(args, t.Plural+"/"+t.Name)
+		if t.Namespace != "" {
+			args = append(args, "--namespace", t.Namespace)
+		}
+	}
+	if currentContext, _ := c.GetCurrentContext(); currentContext != "" {
+		args = append(args, "--context", currentContext)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, plugin.Path, args...)
+	if kubeconfig := c.kubeconfigEnv(); kubeconfig != "" {
+		cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfig)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return "", err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return "", fmt.Errorf("failed to start %s: %v", plugin.Command, err)
+	}
+	id := c.subs.add("plugin", cancel)
+
+	var wg sync.WaitGroup
+	stream := func(name string, r io.Reader) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			c.emit(EventPluginOutput, PluginOutput{RunID: id, Stream: name, Line: scanner.Text()})
+		}
+	}
+	wg.Add(2)
+	go stream("stdout", stdout)
+	go stream("stderr", stderr)
+
+	go func() {
+		defer c.subs.remove(id)
+		defer cancel()
+		wg.Wait()
+		exit := PluginExit{RunID: id}
+		if err := cmd.Wait(); err != nil {
+			exit.Error = err.Error()
+			exit.ExitCode = -1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exit.ExitCode = exitErr.ExitCode()
+			}
+		}
+		c.emit(EventPluginExit, exit)
+	}()
+	return id, nil
+}
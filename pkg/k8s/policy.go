@@ -0,0 +1,156 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+type OperationType string
+
+const (
+	OpList   OperationType = "list"
+	OpGet    OperationType = "get"
+	OpWatch  OperationType = "watch"
+	OpExec   OperationType = "exec"
+	OpMutate OperationType = "mutate"
+)
+
+// OperationPolicy bounds how long a single attempt of an operation may
+// take and how many times a transient failure is retried.
+type OperationPolicy struct {
+	// Timeout applies to each attempt. For watches it is the lifetime of a
+	// single watch request, after which it is transparently re-established.
+	Timeout time.Duration `json:"timeout"`
+	// Retries is the number of extra attempts after a transient failure.
+	// For watches it is the number of consecutive failed reconnects
+	// tolerated, with 0 meaning reconnect forever. Mutations are never
+	// retried: a request that timed out may still have been applied.
+	Retries int `json:"retries"`
+	// Backoff is the delay before the first retry; it doubles with each
+	// further retry, up to maxBackoff.
+	Backoff time.Duration `json:"backoff"`
+}
+
+// maxBackoff caps the delay between retries, so a watch that failed for a
+// while reconnects within a minute of the cluster coming back.
+const maxBackoff = time.Minute
+
+// delay returns the backoff before the given retry, counting from 1.
+func (p OperationPolicy) delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+func DefaultOperationPolicies() map[OperationType]OperationPolicy {
+	return map[OperationType]OperationPolicy{
+		OpList:   {Timeout: 30 * time.Second, Retries: 2, Backoff: 500 * time.Millisecond},
+		OpGet:    {Timeout: 15 * time.Second, Retries: 2, Backoff: 500 * time.Millisecond},
+		OpWatch:  {Timeout: 5 * time.Minute, Retries: 0, Backoff: 2 * time.Second},
+		OpExec:   {Timeout: 30 * time.Second, Retries: 0, Backoff: time.Second},
+		OpMutate: {Timeout: 30 * time.Second, Retries: 0, Backoff: time.Second},
+	}
+}
+
+type policyStore struct {
+	mu       sync.RWMutex
+	policies map[OperationType]OperationPolicy
+}
+
+func (s *policyStore) get(op OperationType) OperationPolicy {
+	s.mu.RLock()
+	p, ok := s.policies[op]
+	s.mu.RUnlock()
+	if !ok {
+		p = DefaultOperationPolicies()[op]
+	}
+	return p
+}
+
+// SetOperationPolicies overrides the policies for the given operation
+// types; other types keep their current policy. Retries of OpMutate are
+// ignored, see OperationPolicy.
+func (c *Client) SetOperationPolicies(policies map[OperationType]OperationPolicy) {
+	c.policies.mu.Lock()
+	defer c.policies.mu.Unlock()
+	if c.policies.policies == nil {
+		c.policies.policies = DefaultOperationPolicies()
+	}
+	for op, p := range policies {
+		if op == OpMutate {
+			p.Retries = 0
+		}
+		c.policies.policies[op] = p
+	}
+}
+
+func (c *Client) GetOperationPolicies() map[OperationType]OperationPolicy {
+	result := DefaultOperationPolicies()
+	for op := range result {
+		result[op] = c.policies.get(op)
+	}
+	return result
+}
+
+// opContext returns a context bounded by the timeout of op.
+func (c *Client) opContext(op OperationType) (context.Context, context.CancelFunc) {
+	p := c.policies.get(op)
+	if p.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), p.Timeout)
+}
+
+// do runs fn under the timeout and retry budget of op. Only transient
+// failures (timeouts, throttling, dropped connections) are retried.
+func (c *Client) do(op OperationType, fn func(ctx context.Context) error) error {
+	return c.doWithPriority(op, PriorityInteractive, fn)
+}
+
+// doWithPriority is do for requests of a given priority; background ones
+// yield to what the user is waiting on.
+func (c *Client) doWithPriority(op OperationType, priority RequestPriority, fn func(ctx context.Context) error) error {
+	p := c.policies.get(op)
+
+	var err error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(p.delay(attempt))
+		}
+
+		ctx, cancel := c.opContext(op)
+		err = fn(withPriority(ctx, priority))
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		if err == nil || !(timedOut || isTransient(err)) {
+			return err
+		}
+	}
+	return err
+}
+
+func isTransient(err error) bool {
+	if apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsUnexpectedServerError(err) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,39 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOperationPolicyDelay(t *testing.T) {
+	p := OperationPolicy{Backoff: 500 * time.Millisecond}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 500 * time.Millisecond},
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{8, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := p.delay(tt.retry); got != tt.want {
+			t.Errorf("delay(%d) = %s, want %s", tt.retry, got, tt.want)
+		}
+	}
+}
+
+func TestSetOperationPoliciesNeverRetriesMutations(t *testing.T) {
+	c := &Client{}
+	c.SetOperationPolicies(map[OperationType]OperationPolicy{
+		OpMutate: {Timeout: time.Minute, Retries: 3},
+		OpList:   {Timeout: time.Minute, Retries: 3},
+	})
+	if got := c.policies.get(OpMutate); got.Retries != 0 || got.Timeout != time.Minute {
+		t.Errorf("mutate policy = %+v, want 0 retries and a 1m timeout", got)
+	}
+	if got := c.policies.get(OpList).Retries; got != 3 {
+		t.Errorf("list retries = %d, want 3", got)
+	}
+}
@@ -0,0 +1,324 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	EventPortForward = "portforward:status"
+
+	portForwardReadyTimeout = 15 * time.Second
+)
+
+const (
+	PortForwardStarting = "starting"
+	PortForwardActive   = "active"
+	PortForwardFailed   = "failed"
+	PortForwardStopped  = "stopped"
+)
+
+type PortForwardRequest struct {
+	Namespace string `json:"namespace"`
+	// Kind is "pod" or "service".
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// RemotePort is the container port, or the service port for services.
+	RemotePort int `json:"remote_port"`
+	// LocalPort is allocated automatically when zero.
+	LocalPort int `json:"local_port"`
+}
+
+type PortForwardInfo struct {
+	ID         string    `json:"id"`
+	Namespace  string    `json:"namespace"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	Pod        string    `json:"pod"`
+	LocalPort  int       `json:"local_port"`
+	RemotePort int       `json:"remote_port"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+type portForwardEntry struct {
+	info PortForwardInfo
+	stop chan struct{}
+}
+
+// PortForwardManager runs port-forwards to pods and services in-process
+// using client-go's portforward package.
+type PortForwardManager struct {
+	client *Client
+
+	mu       sync.Mutex
+	next     int
+	forwards map[string]*portForwardEntry
+}
+
+func newPortForwardManager(c *Client) *PortForwardManager {
+	return &PortForwardManager{client: c, forwards: make(map[string]*portForwardEntry)}
+}
+
+// Start resolves the target pod, opens the forward and waits until it is
+// listening locally.
+func (m *PortForwardManager) Start(req PortForwardRequest) (*PortForwardInfo, error) {
+	c := m.client
+	if c.RestConfig == nil {
+		return nil, errors.New("port-forwarding is not available for this context")
+	}
+	if req.RemotePort <= 0 {
+		return nil, fmt.Errorf("invalid remote port %d", req.RemotePort)
+	}
+
+	podName, targetPort, err := m.resolveTarget(req)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, upgrader, err := spdyRoundTripperFor(c.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+	url := c.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(req.Namespace).
+		Name(podName).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", url)
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	ports := []string{fmt.Sprintf("%d:%d", req.LocalPort, targetPort)}
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, ports, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.next++
+	entry := &portForwardEntry{
+		info: PortForwardInfo{
+			ID:         fmt.Sprintf("portforward-%d", m.next),
+			Namespace:  req.Namespace,
+			Kind:       strings.ToLower(req.Kind),
+			Name:       req.Name,
+			Pod:        podName,
+			LocalPort:  req.LocalPort,
+			RemotePort: req.RemotePort,
+			Status:     PortForwardStarting,
+			StartedAt:  time.Now(),
+		},
+		stop: stopCh,
+	}
+	m.forwards[entry.info.ID] = entry
+	m.mu.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		m.finish(entry, err)
+		if err == nil {
+			err = errors.New("port-forward closed before it became ready")
+		}
+		return nil, err
+	case <-time.After(portForwardReadyTimeout):
+		m.Stop(entry.info.ID)
+		return nil, fmt.Errorf("timed out waiting for port-forward to %s/%s", req.Namespace, podName)
+	}
+
+	if forwarded, err := fw.GetPorts(); err == nil && len(forwarded) > 0 {
+		m.mu.Lock()
+		entry.info.LocalPort = int(forwarded[0].Local)
+		m.mu.Unlock()
+	}
+	info := m.setStatus(entry, PortForwardActive, "")
+
+	// ForwardPorts returns once stopped or when the connection to the pod
+	// breaks; the latter is reported as a failure.
+	go func() {
+		m.finish(entry, <-errCh)
+	}()
+
+	return &info, nil
+}
+
+func (m *PortForwardManager) finish(entry *portForwardEntry, err error) {
+	m.mu.Lock()
+	_, tracked := m.forwards[entry.info.ID]
+	delete(m.forwards, entry.info.ID)
+	m.mu.Unlock()
+
+	// Forwards removed by Stop already reported themselves as stopped.
+	if !tracked {
+		return
+	}
+	if err != nil {
+		m.setStatus(entry, PortForwardFailed, err.Error())
+	} else {
+		m.setStatus(entry, PortForwardFailed, "connection to the pod was lost")
+	}
+}
+
+func (m *PortForwardManager) setStatus(entry *portForwardEntry, status, errMsg string) PortForwardInfo {
+	m.mu.Lock()
+	entry.info.Status = status
+	entry.info.Error = errMsg
+	info := entry.info
+	m.mu.Unlock()
+	m.client.emit(EventPortForward, info)
+	return info
+}
+
+// Stop closes a running forward.
+func (m *PortForwardManager) Stop(id string) error {
+	m.mu.Lock()
+	entry, ok := m.forwards[id]
+	delete(m.forwards, id)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("port-forward %s not found", id)
+	}
+	close(entry.stop)
+	m.setStatus(entry, PortForwardStopped, "")
+	return nil
+}
+
+// StopAll closes every running forward.
+func (m *PortForwardManager) StopAll() {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.forwards))
+	for id := range m.forwards {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+	for _, id := range ids {
+		m.Stop(id)
+	}
+}
+
+func (m *PortForwardManager) List() []PortForwardInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make([]PortForwardInfo, 0, len(m.forwards))
+	for _, entry := range m.forwards {
+		result = append(result, entry.info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.Before(result[j].StartedAt)
+	})
+	return result
+}
+
+// resolveTarget returns the pod and container port to forward to. Services
+// are resolved to a ready backing pod, translating the service port to its
+// targetPort.
+func (m *PortForwardManager) resolveTarget(req PortForwardRequest) (string, int, error) {
+	c := m.client
+	ctx, cancel := c.opContext(OpGet)
+	defer cancel()
+
+	switch strings.ToLower(req.Kind) {
+	case "", "pod", "pods":
+		return req.Name, req.RemotePort, nil
+	case "service", "services", "svc":
+	default:
+		return "", 0, fmt.Errorf("cannot port-forward to kind %q", req.Kind)
+	}
+
+	svc, err := c.Clientset.CoreV1().Services(req.Namespace).Get(ctx, req.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", 0, err
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return "", 0, fmt.Errorf("service %s has no selector", req.Name)
+	}
+
+	var svcPort *corev1.ServicePort
+	for i := range svc.Spec.Ports {
+		if int(svc.Spec.Ports[i].Port) == req.RemotePort {
+			svcPort = &svc.Spec.Ports[i]
+			break
+		}
+	}
+	if svcPort == nil {
+		return "", 0, fmt.Errorf("service %s has no port %d", req.Name, req.RemotePort)
+	}
+
+	pods, err := c.Clientset.CoreV1().Pods(req.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			continue
+		}
+		port, err := containerPortFor(pod, svcPort.TargetPort, int(svcPort.Port))
+		if err != nil {
+			return "", 0, err
+		}
+		return pod.Name, port, nil
+	}
+	return "", 0, fmt.Errorf("service %s has no ready pods", req.Name)
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func containerPortFor(pod *corev1.Pod, target intstr.IntOrString, servicePort int) (int, error) {
+	if target.Type == intstr.Int {
+		if target.IntVal == 0 {
+			return servicePort, nil
+		}
+		return int(target.IntVal), nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, p := range container.Ports {
+			if p.Name == target.StrVal {
+				return int(p.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no port named %q", pod.Name, target.StrVal)
+}
+
+func (c *Client) StartPortForward(req PortForwardRequest) (*PortForwardInfo, error) {
+	return c.forwards.Start(req)
+}
+
+func (c *Client) StopPortForward(id string) error {
+	return c.forwards.Stop(id)
+}
+
+func (c *Client) ListPortForwards() []PortForwardInfo {
+	return c.forwards.List()
+}
@@ -0,0 +1,259 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// probeTimeout bounds each step of a connection probe, so an unreachable
+// server fails fast instead of after the client-go default.
+const probeTimeout = 10 * time.Second
+
+// Outcomes of a probe check.
+const (
+	ProbeOK      = "ok"
+	ProbeWarning = "warning"
+	ProbeFailed  = "failed"
+	ProbeSkipped = "skipped"
+)
+
+// Capabilities a probe reports; the frontend enables features by them.
+const (
+	CapabilityListNamespaces = "list-namespaces"
+	CapabilityListNodes      = "list-nodes"
+	CapabilityListPods       = "list-pods"
+	CapabilityPodLogs        = "pod-logs"
+	CapabilityExec           = "exec"
+	CapabilityPortForward    = "port-forward"
+	CapabilityEvents         = "events"
+	CapabilitySecrets        = "secrets"
+	CapabilityMetrics        = "metrics"
+	CapabilityHelm           = "helm"
+	CapabilityArgoCD         = "argocd"
+	CapabilityFlux           = "flux"
+)
+
+type ProbeCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// ProbeReport is what a context can do, for first-run onboarding.
+type ProbeReport struct {
+	Context       string `json:"context"`
+	Namespace     string `json:"namespace"`
+	Reachable     bool   `json:"reachable"`
+	Authenticated bool   `json:"authenticated"`
+	// User is who the server says we are, when it supports
+	// SelfSubjectReview.
+	User          string          `json:"user,omitempty"`
+	ServerVersion string          `json:"server_version,omitempty"`
+	Checks        []ProbeCheck    `json:"checks"`
+	Capabilities  map[string]bool `json:"capabilities"`
+}
+
+// rbacProbes are the permissions checked, by the capability they grant.
+// Namespaced ones are checked in the context's default namespace.
+var rbacProbes = []struct {
+	capability  string
+	verb        string
+	group       string
+	resource    string
+	subresource string
+	namespaced  bool
+}{
+	{CapabilityListNamespaces, "list", "", "namespaces", "", false},
+	{CapabilityListNodes, "list", "", "nodes", "", false},
+	{CapabilityListPods, "list", "", "pods", "", true},
+	{CapabilityPodLogs, "get", "", "pods", "log", true},
+	{CapabilityExec, "create", "", "pods", "exec", true},
+	{CapabilityPortForward, "create", "", "pods", "portforward", true},
+	{CapabilityEvents, "list", "", "events", "", true},
+	{CapabilitySecrets, "list", "", "secrets", "", true},
+}
+
+// ProbeContext checks a context without switching to it: whether its API
+// server is reachable, whether it accepts the credentials, the basic
+// permissions the UI relies on, and whether metrics-server, Helm, Argo CD
+// and Flux are there. Steps that can't run after a failure are skipped.
+func (c *Client) ProbeContext(contextName string) (*ProbeReport, error) {
+	if contextName == DemoContext {
+		return nil, errors.New("the demo context can't be probed")
+	}
+	config := c.clientConfig(contextName)
+	report := &ProbeReport{Context: contextName, Checks: []ProbeCheck{}, Capabilities: make(map[string]bool)}
+	report.Namespace, _, _ = config.Namespace()
+	if report.Namespace == "" {
+		report.Namespace = "default"
+	}
+
+	restConfig, tunnel, err := c.restConfigFor(config, contextName)
+	if err != nil {
+		report.Checks = append(report.Checks, ProbeCheck{Name: "config", Status: ProbeFailed, Message: err.Error()})
+		return report, nil
+	}
+	if tunnel != nil {
+		defer tunnel.Close()
+	}
+	restConfig.Timeout = probeTimeout
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	step := func(name string, fn func(ctx context.Context) (string, string)) string {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		start := time.Now()
+		status, message := fn(ctx)
+		report.Checks = append(report.Checks, ProbeCheck{Name: name, Status: status, Message: message, Duration: time.Since(start).Milliseconds()})
+		return status
+	}
+	skip := func(names ...string) {
+		for _, name := range names {
+			report.Checks = append(report.Checks, ProbeCheck{Name: name, Status: ProbeSkipped})
+		}
+	}
+
+	reachable := step("reachability", func(ctx context.Context) (string, string) {
+		v, err := clientset.Discovery().ServerVersion()
+		switch {
+		case err == nil:
+			report.Reachable = true
+			report.ServerVersion = v.GitVersion
+			return ProbeOK, "API server " + v.GitVersion
+		case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+			// Anonymous access to /version is disabled; the server answered.
+			report.Reachable = true
+			return ProbeOK, "API server answered"
+		}
+		return ProbeFailed, err.Error()
+	})
+	if reachable != ProbeOK {
+		skip("authentication", "rbac", "metrics", "helm", "gitops")
+		return report, nil
+	}
+
+	authenticated := step("authentication", func(ctx context.Context) (string, string) {
+		review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+		if err == nil {
+			report.Authenticated = true
+			report.User = review.Status.UserInfo.Username
+			return ProbeOK, "authenticated as " + report.User
+		}
+		if apierrors.IsUnauthorized(err) {
+			return ProbeFailed, "the API server rejected the credentials"
+		}
+		// Before 1.28, or with the API turned off: any answer but 401
+		// means the credentials were accepted.
+		_, err = clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &authorizationv1.ResourceAttributes{Verb: "get", Resource: "namespaces"}},
+		}, metav1.CreateOptions{})
+		if apierrors.IsUnauthorized(err) {
+			return ProbeFailed, "the API server rejected the credentials"
+		}
+		if err != nil {
+			return ProbeWarning, err.Error()
+		}
+		report.Authenticated = true
+		return ProbeOK, "authenticated"
+	})
+	if authenticated == ProbeFailed {
+		skip("rbac", "metrics", "helm", "gitops")
+		return report, nil
+	}
+
+	step("rbac", func(ctx context.Context) (string, string) {
+		var denied []string
+		for _, p := range rbacProbes {
+			attrs := &authorizationv1.ResourceAttributes{Verb: p.verb, Group: p.group, Resource: p.resource, Subresource: p.subresource}
+			if p.namespaced {
+				attrs.Namespace = report.Namespace
+			}
+			review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return ProbeWarning, fmt.Sprintf("access reviews failed: %v", err)
+			}
+			report.Capabilities[p.capability] = review.Status.Allowed
+			if !review.Status.Allowed {
+				denied = append(denied, p.capability)
+			}
+		}
+		switch {
+		case len(denied) == 0:
+			return ProbeOK, "all basic permissions granted"
+		case !report.Capabilities[CapabilityListPods]:
+			return ProbeFailed, "can't list pods in " + report.Namespace + "; denied: " + strings.Join(denied, ", ")
+		}
+		return ProbeWarning, "denied: " + strings.Join(denied, ", ")
+	})
+
+	groups := make(map[string]bool)
+	if list, err := clientset.Discovery().ServerGroups(); err == nil {
+		for _, g := range list.Groups {
+			groups[g.Name] = true
+		}
+	}
+
+	step("metrics", func(ctx context.Context) (string, string) {
+		if !groups["metrics.k8s.io"] {
+			return ProbeWarning, "metrics-server isn't installed; CPU and memory usage won't show"
+		}
+		err := clientset.CoreV1().RESTClient().Get().AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", report.Namespace, "pods").Param("limit", "1").Do(ctx).Error()
+		if err != nil {
+			return ProbeWarning, metricsError(err).Error()
+		}
+		report.Capabilities[CapabilityMetrics] = true
+		return ProbeOK, "metrics API available"
+	})
+
+	step("helm", func(ctx context.Context) (string, string) {
+		if !report.Capabilities[CapabilitySecrets] {
+			return ProbeSkipped, "Helm releases are stored in Secrets, which can't be listed"
+		}
+		list, err := clientset.CoreV1().Secrets("").List(ctx, metav1.ListOptions{LabelSelector: "owner=helm", Limit: 1})
+		if err != nil {
+			list, err = clientset.CoreV1().Secrets(report.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "owner=helm", Limit: 1})
+		}
+		if err != nil {
+			return ProbeWarning, err.Error()
+		}
+		if len(list.Items) == 0 {
+			return ProbeOK, "no Helm releases found"
+		}
+		report.Capabilities[CapabilityHelm] = true
+		return ProbeOK, "Helm releases found"
+	})
+
+	step("gitops", func(ctx context.Context) (string, string) {
+		var found []string
+		if groups["argoproj.io"] {
+			report.Capabilities[CapabilityArgoCD] = true
+			found = append(found, "Argo CD")
+		}
+		for g := range groups {
+			if strings.HasSuffix(g, ".toolkit.fluxcd.io") {
+				report.Capabilities[CapabilityFlux] = true
+				found = append(found, "Flux")
+				break
+			}
+		}
+		if len(found) == 0 {
+			return ProbeOK, "no GitOps controllers found"
+		}
+		return ProbeOK, strings.Join(found, " and ") + " found"
+	})
+	return report, nil
+}
@@ -0,0 +1,146 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	rancherProjectAnnotation   = "field.cattle.io/projectId"
+	openshiftDisplayAnnotation = "openshift.io/display-name"
+	openshiftRequesterAnno     = "openshift.io/requester"
+)
+
+var (
+	rancherProjectGVR   = schema.GroupVersionResource{Group: "management.cattle.io", Version: "v3", Resource: "projects"}
+	openshiftProjectGVR = schema.GroupVersionResource{Group: "project.openshift.io", Version: "v1", Resource: "projects"}
+)
+
+// NamespaceGroup is a set of namespaces belonging to one Rancher Project
+// or OpenShift Project. Namespaces outside any project are grouped under
+// an empty Project.
+type NamespaceGroup struct {
+	Project     string   `json:"project"`
+	DisplayName string   `json:"display_name"`
+	Source      string   `json:"source"`
+	Requester   string   `json:"requester,omitempty"`
+	Namespaces  []string `json:"namespaces"`
+}
+
+// GetNamespaceGroups groups namespaces by Rancher Project (from the
+// field.cattle.io/projectId annotation) or OpenShift Project. Project
+// display names are looked up when the cluster serves the project APIs.
+func (c *Client) GetNamespaceGroups() ([]NamespaceGroup, error) {
+	var list *corev1.NamespaceList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rancherNames := c.projectDisplayNames(rancherProjectGVR, "spec", "displayName")
+	openshift := c.openshiftProjects()
+
+	groups := make(map[string]*NamespaceGroup)
+	group := func(key string, init NamespaceGroup) *NamespaceGroup {
+		g, ok := groups[key]
+		if !ok {
+			g = &init
+			groups[key] = g
+		}
+		return g
+	}
+
+	for _, ns := range list.Items {
+		switch {
+		case ns.Annotations[rancherProjectAnnotation] != "":
+			// The annotation is "<cluster>:<project>".
+			id := ns.Annotations[rancherProjectAnnotation]
+			project := id[strings.LastIndex(id, ":")+1:]
+			display := rancherNames[project]
+			if display == "" {
+				display = project
+			}
+			g := group("rancher/"+project, NamespaceGroup{Project: project, DisplayName: display, Source: "rancher"})
+			g.Namespaces = append(g.Namespaces, ns.Name)
+		case openshift[ns.Name]:
+			display := ns.Annotations[openshiftDisplayAnnotation]
+			if display == "" {
+				display = ns.Name
+			}
+			g := group("openshift/"+ns.Name, NamespaceGroup{
+				Project:     ns.Name,
+				DisplayName: display,
+				Source:      "openshift",
+				Requester:   ns.Annotations[openshiftRequesterAnno],
+			})
+			g.Namespaces = append(g.Namespaces, ns.Name)
+		default:
+			g := group("", NamespaceGroup{})
+			g.Namespaces = append(g.Namespaces, ns.Name)
+		}
+	}
+
+	result := make([]NamespaceGroup, 0, len(groups))
+	for _, g := range groups {
+		sort.Strings(g.Namespaces)
+		result = append(result, *g)
+	}
+	// Projects first by display name; unassigned namespaces last.
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].Project == "") != (result[j].Project == "") {
+			return result[j].Project == ""
+		}
+		return result[i].DisplayName < result[j].DisplayName
+	})
+	return result, nil
+}
+
+// projectDisplayNames maps project names to display names. It returns an
+// empty map when the API isn't served, e.g. on Rancher downstream
+// clusters, where projects only exist on the management cluster.
+func (c *Client) projectDisplayNames(gvr schema.GroupVersionResource, path ...string) map[string]string {
+	names := make(map[string]string)
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return names
+	}
+	for _, item := range list.Items {
+		if display, _, _ := unstructured.NestedString(item.Object, path...); display != "" {
+			names[item.GetName()] = display
+		}
+	}
+	return names
+}
+
+// openshiftProjects returns the namespaces that are OpenShift Projects.
+func (c *Client) openshiftProjects() map[string]bool {
+	projects := make(map[string]bool)
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(openshiftProjectGVR).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return projects
+	}
+	for _, item := range list.Items {
+		projects[item.GetName()] = true
+	}
+	return projects
+}
@@ -0,0 +1,164 @@
+package k8s
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// RegistryCheck is the outcome of testing one registry entry of a pull
+// secret.
+type RegistryCheck struct {
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+	// Image is set when the check pulled an image manifest rather than
+	// only authenticating.
+	Image string `json:"image,omitempty"`
+	Valid bool   `json:"valid"`
+	// Status is the HTTP status the registry answered with, 0 when it
+	// couldn't be reached.
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message"`
+}
+
+type PullSecretTest struct {
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+	Checks    []RegistryCheck `json:"checks"`
+}
+
+// TestPullSecret checks an imagePullSecret against its registries. With
+// an image, only the entry for the image's registry is used and the
+// image's manifest is requested, which also proves the account may pull
+// that repository; without one each entry is authenticated against its
+// registry.
+func (c *Client) TestPullSecret(namespace, name, image string) (*PullSecretTest, error) {
+	secret, err := c.getSecret(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	auths, err := pullSecretAuths(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PullSecretTest{Namespace: namespace, Name: name, Checks: []RegistryCheck{}}
+	var ref imageRef
+	if image != "" {
+		ref = parseImageRef(image)
+		if _, ok := auths[ref.Registry]; !ok {
+			return nil, fmt.Errorf("secret %s has no credentials for %s", name, ref.Registry)
+		}
+	}
+
+	for registry, auth := range auths {
+		if image != "" && registry != ref.Registry {
+			continue
+		}
+		check := RegistryCheck{Registry: registry, Username: auth.Username}
+		ctx, cancel := c.opContext(OpGet)
+		if image != "" {
+			check.Image = ref.Name()
+			var digest string
+			digest, err = fetchManifestDigest(ctx, ref, auth)
+			if err == nil {
+				check.Message = "image can be pulled (" + shortDigest(digest) + ")"
+			}
+		} else {
+			err = checkRegistryAuth(ctx, registry, auth)
+			if err == nil {
+				check.Message = "credentials accepted"
+			}
+		}
+		cancel()
+
+		var regErr *registryError
+		switch {
+		case err == nil:
+			check.Valid = true
+		case errors.As(err, &regErr):
+			check.Status = regErr.Status
+			check.Message = registryFailure(regErr.Status, err)
+		default:
+			check.Message = "registry unreachable: " + err.Error()
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	sort.Slice(result.Checks, func(i, j int) bool { return result.Checks[i].Registry < result.Checks[j].Registry })
+	return result, nil
+}
+
+func registryFailure(status int, err error) string {
+	switch status {
+	case 401:
+		return "credentials rejected; the password or token may have expired"
+	case 403:
+		return "credentials accepted but not allowed to pull this repository"
+	case 404:
+		return "image or tag not found"
+	case 429:
+		return "rate limited by the registry"
+	}
+	return err.Error()
+}
+
+// pullSecretAuths reads the credentials of a dockerconfigjson or legacy
+// dockercfg Secret, keyed by registry host.
+func pullSecretAuths(secret *corev1.Secret) (map[string]registryAuth, error) {
+	type entry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	var entries map[string]entry
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var config struct {
+			Auths map[string]entry `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", corev1.DockerConfigJsonKey, err)
+		}
+		entries = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &entries); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", corev1.DockerConfigKey, err)
+		}
+	default:
+		return nil, fmt.Errorf("secret %s is of type %s, not an image pull secret", secret.Name, secret.Type)
+	}
+
+	auths := make(map[string]registryAuth, len(entries))
+	for server, e := range entries {
+		auth := registryAuth{Username: e.Username, Password: e.Password}
+		if e.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(e.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth for %s: %v", server, err)
+			}
+			auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		auths[registryHost(server)] = auth
+	}
+	if len(auths) == 0 {
+		return nil, fmt.Errorf("secret %s has no registry credentials", secret.Name)
+	}
+	return auths, nil
+}
+
+// registryHost normalises a docker config server key such as
+// "https://index.docker.io/v1/" to the host images refer to.
+func registryHost(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", dockerHubRegistry:
+		return dockerHub
+	}
+	return host
+}
@@ -0,0 +1,173 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	EventPVCResize = "pvc:resize"
+
+	resizeTrackTimeout = 15 * time.Minute
+)
+
+type PVCResizeStatus struct {
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	Requested  string            `json:"requested"`
+	Capacity   string            `json:"capacity"`
+	Conditions []ResizeCondition `json:"conditions"`
+	Done       bool              `json:"done"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type ResizeCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// ExpandPVC grows a claim to newSize after checking that its StorageClass
+// allows expansion. Progress is reported through EventPVCResize events
+// until the new capacity is reported or tracking times out.
+func (c *Client) ExpandPVC(namespace, name, newSize string) error {
+	ctx, cancel := c.opContext(OpGet)
+	defer cancel()
+
+	size, err := resource.ParseQuantity(newSize)
+	if err != nil {
+		return fmt.Errorf("invalid size %q: %v", newSize, err)
+	}
+
+	pvc, err := c.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	className := ""
+	if pvc.Spec.StorageClassName != nil {
+		className = *pvc.Spec.StorageClassName
+	}
+	if className == "" {
+		return fmt.Errorf("PVC %s/%s has no StorageClass, expansion is not supported", namespace, name)
+	}
+
+	sc, err := c.Clientset.StorageV1().StorageClasses().Get(ctx, className, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get StorageClass %s: %v", className, err)
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return fmt.Errorf("StorageClass %s does not allow volume expansion", className)
+	}
+
+	current := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if size.Cmp(current) <= 0 {
+		return fmt.Errorf("new size %s must be larger than the current request %s", size.String(), current.String())
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"resources":{"requests":{"storage":%q}}}}`, size.String())
+	ref := ResourceRef{Version: "v1", Kind: "PersistentVolumeClaim", Plural: "persistentvolumeclaims", Namespace: namespace, Name: name}
+	err = c.mutate(ActionExpand, ref, fmt.Sprintf("expand persistentvolumeclaims/%s to %s", name, size.String()), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.Clientset.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	go c.trackPVCResize(namespace, name, size)
+	return nil
+}
+
+func (c *Client) GetPVCResizeStatus(namespace, name string) (*PVCResizeStatus, error) {
+	ctx, cancel := c.opContext(OpGet)
+	defer cancel()
+	pvc, err := c.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	return resizeStatus(pvc, requested), nil
+}
+
+func (c *Client) trackPVCResize(namespace, name string, target resource.Quantity) {
+	ctx, cancel := context.WithTimeout(context.Background(), resizeTrackTimeout)
+	defer cancel()
+
+	w, err := c.Clientset.CoreV1().PersistentVolumeClaims(namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	})
+	if err != nil {
+		c.emit(EventPVCResize, PVCResizeStatus{Namespace: namespace, Name: name, Error: err.Error()})
+		return
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.emit(EventPVCResize, PVCResizeStatus{
+				Namespace: namespace,
+				Name:      name,
+				Requested: target.String(),
+				Error:     "timed out waiting for resize to complete",
+			})
+			return
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			if ev.Type == watch.Deleted {
+				c.emit(EventPVCResize, PVCResizeStatus{Namespace: namespace, Name: name, Error: "PVC was deleted"})
+				return
+			}
+			pvc, ok := ev.Object.(*corev1.PersistentVolumeClaim)
+			if !ok {
+				continue
+			}
+			status := resizeStatus(pvc, target)
+			c.emit(EventPVCResize, status)
+			if status.Done {
+				return
+			}
+		}
+	}
+}
+
+func resizeStatus(pvc *corev1.PersistentVolumeClaim, target resource.Quantity) *PVCResizeStatus {
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	status := &PVCResizeStatus{
+		Namespace: pvc.Namespace,
+		Name:      pvc.Name,
+		Requested: target.String(),
+		Capacity:  capacity.String(),
+	}
+	pending := false
+	for _, cond := range pvc.Status.Conditions {
+		if (cond.Type == corev1.PersistentVolumeClaimResizing || cond.Type == corev1.PersistentVolumeClaimFileSystemResizePending) &&
+			cond.Status == corev1.ConditionTrue {
+			pending = true
+		}
+		status.Conditions = append(status.Conditions, ResizeCondition{
+			Type:    string(cond.Type),
+			Status:  string(cond.Status),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		})
+	}
+	// The resize is finished once the reported capacity reaches the
+	// request and no resize condition is still pending.
+	status.Done = capacity.Cmp(target) >= 0 && !pending
+	return status
+}
@@ -0,0 +1,243 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// quotaWarningPercent is the share of a quota above which it is reported
+// as nearly exhausted.
+const quotaWarningPercent = 80
+
+// QuotaItem is one constrained resource of a ResourceQuota. Computed is
+// the same figure summed from the namespace's pods, set for unscoped
+// quotas on compute resources; a gap to Used means the quota controller
+// hasn't caught up yet.
+type QuotaItem struct {
+	Resource string  `json:"resource"`
+	Hard     string  `json:"hard"`
+	Used     string  `json:"used"`
+	Computed string  `json:"computed,omitempty"`
+	Percent  float64 `json:"percent"`
+	// Status is "ok", "warning" from quotaWarningPercent, or "critical"
+	// when the quota is exhausted and new objects will be rejected.
+	Status string `json:"status"`
+}
+
+type NamespaceQuota struct {
+	Name   string      `json:"name"`
+	Scopes []string    `json:"scopes"`
+	Items  []QuotaItem `json:"items"`
+}
+
+// LimitRangeItem is a LimitRange constraint on one resource of one type
+// (Container, Pod or PersistentVolumeClaim).
+type LimitRangeItem struct {
+	LimitRange     string `json:"limit_range"`
+	Type           string `json:"type"`
+	Resource       string `json:"resource"`
+	Min            string `json:"min,omitempty"`
+	Max            string `json:"max,omitempty"`
+	Default        string `json:"default,omitempty"`
+	DefaultRequest string `json:"default_request,omitempty"`
+	MaxRatio       string `json:"max_limit_request_ratio,omitempty"`
+}
+
+// PodResourceTotal is what the non-terminated pods of a namespace request
+// and are limited to for one resource. Unset counts the containers
+// without a request.
+type PodResourceTotal struct {
+	Resource string `json:"resource"`
+	Requests string `json:"requests"`
+	Limits   string `json:"limits"`
+	Unset    int    `json:"unset"`
+}
+
+type NamespaceResourceUsage struct {
+	Namespace   string             `json:"namespace"`
+	Pods        int                `json:"pods"`
+	Quotas      []NamespaceQuota   `json:"quotas"`
+	LimitRanges []LimitRangeItem   `json:"limit_ranges"`
+	Totals      []PodResourceTotal `json:"totals"`
+	Issues      []string           `json:"issues"`
+}
+
+// GetNamespaceResourceUsage returns the ResourceQuotas of a namespace with
+// hard and used figures for quota bars, its LimitRange constraints, and
+// the requests and limits of its pods summed as the scheduler does. Issues
+// point out quotas on requests or limits that pods without them would be
+// rejected by, because no LimitRange fills in a default.
+func (c *Client) GetNamespaceResourceUsage(namespace string) (*NamespaceResourceUsage, error) {
+	var quotas *corev1.ResourceQuotaList
+	var limitRanges *corev1.LimitRangeList
+	var pods *corev1.PodList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if quotas, err = c.Clientset.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			return err
+		}
+		if limitRanges, err = c.Clientset.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			return err
+		}
+		pods, err = c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &NamespaceResourceUsage{
+		Namespace:   namespace,
+		Quotas:      []NamespaceQuota{},
+		LimitRanges: []LimitRangeItem{},
+		Totals:      []PodResourceTotal{},
+		Issues:      []string{},
+	}
+
+	requests := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+	unset := make(map[corev1.ResourceName]int)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		usage.Pods++
+		req, lim := podResources(pod)
+		addResources(requests, req)
+		addResources(limits, lim)
+		for _, ctr := range pod.Spec.Containers {
+			for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				if _, ok := ctr.Resources.Requests[name]; !ok {
+					unset[name]++
+				}
+			}
+		}
+	}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage} {
+		usage.Totals = append(usage.Totals, PodResourceTotal{
+			Resource: string(name),
+			Requests: quantityString(requests, name),
+			Limits:   quantityString(limits, name),
+			Unset:    unset[name],
+		})
+	}
+	computed := map[corev1.ResourceName]string{
+		corev1.ResourcePods:                     fmt.Sprint(usage.Pods),
+		corev1.ResourceCPU:                      quantityString(requests, corev1.ResourceCPU),
+		corev1.ResourceMemory:                   quantityString(requests, corev1.ResourceMemory),
+		corev1.ResourceEphemeralStorage:         quantityString(requests, corev1.ResourceEphemeralStorage),
+		corev1.ResourceRequestsCPU:              quantityString(requests, corev1.ResourceCPU),
+		corev1.ResourceRequestsMemory:           quantityString(requests, corev1.ResourceMemory),
+		corev1.ResourceRequestsEphemeralStorage: quantityString(requests, corev1.ResourceEphemeralStorage),
+		corev1.ResourceLimitsCPU:                quantityString(limits, corev1.ResourceCPU),
+		corev1.ResourceLimitsMemory:             quantityString(limits, corev1.ResourceMemory),
+		corev1.ResourceLimitsEphemeralStorage:   quantityString(limits, corev1.ResourceEphemeralStorage),
+	}
+
+	// Container defaults from LimitRanges, which the admission plugin
+	// fills into pods that don't set them.
+	defaulted := make(map[corev1.ResourceName]bool)
+	for _, lr := range limitRanges.Items {
+		for _, limit := range lr.Spec.Limits {
+			names := make(map[corev1.ResourceName]bool)
+			for _, list := range []corev1.ResourceList{limit.Min, limit.Max, limit.Default, limit.DefaultRequest, limit.MaxLimitRequestRatio} {
+				for name := range list {
+					names[name] = true
+				}
+			}
+			for name := range names {
+				usage.LimitRanges = append(usage.LimitRanges, LimitRangeItem{
+					LimitRange:     lr.Name,
+					Type:           string(limit.Type),
+					Resource:       string(name),
+					Min:            optionalQuantity(limit.Min, name),
+					Max:            optionalQuantity(limit.Max, name),
+					Default:        optionalQuantity(limit.Default, name),
+					DefaultRequest: optionalQuantity(limit.DefaultRequest, name),
+					MaxRatio:       optionalQuantity(limit.MaxLimitRequestRatio, name),
+				})
+			}
+			if limit.Type != corev1.LimitTypeContainer {
+				continue
+			}
+			// A default limit doubles as the default request.
+			for name := range limit.Default {
+				defaulted["requests."+name] = true
+				defaulted["limits."+name] = true
+			}
+			for name := range limit.DefaultRequest {
+				defaulted["requests."+name] = true
+			}
+		}
+	}
+	sort.Slice(usage.LimitRanges, func(i, j int) bool {
+		a, b := usage.LimitRanges[i], usage.LimitRanges[j]
+		if a.LimitRange != b.LimitRange {
+			return a.LimitRange < b.LimitRange
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Resource < b.Resource
+	})
+
+	for _, q := range quotas.Items {
+		nq := NamespaceQuota{Name: q.Name, Scopes: []string{}, Items: []QuotaItem{}}
+		for _, scope := range q.Spec.Scopes {
+			nq.Scopes = append(nq.Scopes, string(scope))
+		}
+		scoped := len(q.Spec.Scopes) > 0 || q.Spec.ScopeSelector != nil
+		for name, hard := range q.Status.Hard {
+			used := q.Status.Used[name]
+			item := QuotaItem{Resource: string(name), Hard: hard.String(), Used: used.String(), Status: "ok"}
+			if !scoped {
+				item.Computed = computed[name]
+			}
+			if hard.MilliValue() > 0 {
+				item.Percent = float64(used.MilliValue()) / float64(hard.MilliValue()) * 100
+			}
+			switch {
+			case used.Cmp(hard) >= 0:
+				item.Status = "critical"
+			case item.Percent >= quotaWarningPercent:
+				item.Status = "warning"
+			}
+			nq.Items = append(nq.Items, item)
+
+			if key, ok := quotaRequirement(name); ok && !defaulted[key] {
+				usage.Issues = append(usage.Issues, fmt.Sprintf("quota %s constrains %s but no LimitRange sets a default; pods without %s are rejected", q.Name, name, key))
+			}
+		}
+		sort.Slice(nq.Items, func(i, j int) bool { return nq.Items[i].Resource < nq.Items[j].Resource })
+		usage.Quotas = append(usage.Quotas, nq)
+	}
+	sort.Slice(usage.Quotas, func(i, j int) bool { return usage.Quotas[i].Name < usage.Quotas[j].Name })
+	sort.Strings(usage.Issues)
+	return usage, nil
+}
+
+// quotaRequirement returns the container field a quota on name forces
+// every pod to set, as "requests.<resource>" or "limits.<resource>".
+func quotaRequirement(name corev1.ResourceName) (corev1.ResourceName, bool) {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		return "requests." + name, true
+	case corev1.ResourceRequestsCPU, corev1.ResourceRequestsMemory, corev1.ResourceRequestsEphemeralStorage,
+		corev1.ResourceLimitsCPU, corev1.ResourceLimitsMemory, corev1.ResourceLimitsEphemeralStorage:
+		return name, true
+	}
+	return "", false
+}
+
+func optionalQuantity(list corev1.ResourceList, name corev1.ResourceName) string {
+	q, ok := list[name]
+	if !ok {
+		return ""
+	}
+	return q.String()
+}
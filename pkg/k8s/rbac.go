@@ -0,0 +1,249 @@
+package k8s
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AllNamespaces marks permissions granted cluster-wide by a
+// ClusterRoleBinding.
+const AllNamespaces = "*"
+
+// RBACSubject identifies whose permissions to resolve. Kind is User,
+// Group or ServiceAccount; Namespace is only used for ServiceAccounts.
+// Groups lists further groups a user belongs to, which RBAC can't know.
+type RBACSubject struct {
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+}
+
+// PermissionEntry is one row of the permission matrix: the verbs allowed
+// on a resource in a namespace, merged over every binding that grants
+// them.
+type PermissionEntry struct {
+	Namespace     string   `json:"namespace"`
+	APIGroup      string   `json:"api_group"`
+	Resource      string   `json:"resource"`
+	ResourceNames []string `json:"resource_names,omitempty"`
+	Verbs         []string `json:"verbs"`
+	// Sources are the bindings and roles behind the row, as
+	// "RoleBinding ns/name -> ClusterRole name".
+	Sources []string `json:"sources"`
+}
+
+type EffectivePermissions struct {
+	Subject RBACSubject `json:"subject"`
+	// Groups are the groups the subject was matched as, including the
+	// implicit ones of ServiceAccounts.
+	Groups          []string          `json:"groups"`
+	Permissions     []PermissionEntry `json:"permissions"`
+	NonResourceURLs []PermissionEntry `json:"non_resource_urls"`
+	// Errors lists bindings whose role couldn't be found and objects that
+	// couldn't be listed.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// GetEffectivePermissions resolves every RoleBinding and
+// ClusterRoleBinding that applies to a subject and merges the rules of the
+// bound roles into a resource × verb × namespace matrix. Only RBAC is
+// considered; other authorizers may grant more.
+func (c *Client) GetEffectivePermissions(subject RBACSubject) (*EffectivePermissions, error) {
+	rbac := c.Clientset.RbacV1()
+	var clusterRoles *rbacv1.ClusterRoleList
+	var clusterBindings *rbacv1.ClusterRoleBindingList
+	var roles *rbacv1.RoleList
+	var bindings *rbacv1.RoleBindingList
+	for _, list := range []func(ctx context.Context) error{
+		func(ctx context.Context) (err error) {
+			clusterRoles, err = rbac.ClusterRoles().List(ctx, metav1.ListOptions{})
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			clusterBindings, err = rbac.ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			roles, err = rbac.Roles("").List(ctx, metav1.ListOptions{})
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			bindings, err = rbac.RoleBindings("").List(ctx, metav1.ListOptions{})
+			return err
+		},
+	} {
+		if err := c.do(OpList, list); err != nil {
+			return nil, err
+		}
+	}
+
+	groups := append([]string{}, subject.Groups...)
+	if subject.Kind == rbacv1.ServiceAccountKind {
+		groups = append(groups, "system:serviceaccounts", "system:serviceaccounts:"+subject.Namespace, "system:authenticated")
+	}
+	sort.Strings(groups)
+
+	result := &EffectivePermissions{
+		Subject:         subject,
+		Groups:          groups,
+		Permissions:     []PermissionEntry{},
+		NonResourceURLs: []PermissionEntry{},
+	}
+	clusterRoleRules := make(map[string][]rbacv1.PolicyRule, len(clusterRoles.Items))
+	for _, r := range clusterRoles.Items {
+		clusterRoleRules[r.Name] = r.Rules
+	}
+	roleRules := make(map[string][]rbacv1.PolicyRule, len(roles.Items))
+	for _, r := range roles.Items {
+		roleRules[r.Namespace+"/"+r.Name] = r.Rules
+	}
+
+	m := newPermissionMatrix()
+	for _, b := range clusterBindings.Items {
+		if !bindsSubject(b.Subjects, subject, groups) {
+			continue
+		}
+		source := "ClusterRoleBinding " + b.Name + " -> ClusterRole " + b.RoleRef.Name
+		rules, ok := clusterRoleRules[b.RoleRef.Name]
+		if !ok {
+			result.Errors = append(result.Errors, source+": role not found")
+			continue
+		}
+		m.add(AllNamespaces, rules, source, true)
+	}
+	for _, b := range bindings.Items {
+		if !bindsSubject(b.Subjects, subject, groups) {
+			continue
+		}
+		source := "RoleBinding " + b.Namespace + "/" + b.Name + " -> " + b.RoleRef.Kind + " " + b.RoleRef.Name
+		var rules []rbacv1.PolicyRule
+		var ok bool
+		if b.RoleRef.Kind == "ClusterRole" {
+			rules, ok = clusterRoleRules[b.RoleRef.Name]
+		} else {
+			rules, ok = roleRules[b.Namespace+"/"+b.RoleRef.Name]
+		}
+		if !ok {
+			result.Errors = append(result.Errors, source+": role not found")
+			continue
+		}
+		// Non-resource URLs are cluster-scoped and ignored in RoleBindings.
+		m.add(b.Namespace, rules, source, false)
+	}
+
+	result.Permissions, result.NonResourceURLs = m.entries()
+	return result, nil
+}
+
+// bindsSubject reports whether a binding's subjects include the subject,
+// directly or through one of its groups.
+func bindsSubject(subjects []rbacv1.Subject, subject RBACSubject, groups []string) bool {
+	for _, s := range subjects {
+		switch s.Kind {
+		case rbacv1.GroupKind:
+			if subject.Kind == rbacv1.GroupKind && s.Name == subject.Name {
+				return true
+			}
+			for _, g := range groups {
+				if s.Name == g {
+					return true
+				}
+			}
+		case rbacv1.ServiceAccountKind:
+			if subject.Kind == rbacv1.ServiceAccountKind && s.Name == subject.Name && s.Namespace == subject.Namespace {
+				return true
+			}
+		case rbacv1.UserKind:
+			if subject.Kind == rbacv1.UserKind && s.Name == subject.Name {
+				return true
+			}
+			// A ServiceAccount authenticates as this user name.
+			if subject.Kind == rbacv1.ServiceAccountKind && s.Name == "system:serviceaccount:"+subject.Namespace+":"+subject.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type permissionMatrix struct {
+	rows map[string]*PermissionEntry
+	urls map[string]*PermissionEntry
+}
+
+func newPermissionMatrix() *permissionMatrix {
+	return &permissionMatrix{rows: make(map[string]*PermissionEntry), urls: make(map[string]*PermissionEntry)}
+}
+
+// add expands rules into one row per namespace, group, resource and set
+// of resource names, merging verbs and sources into existing rows.
+func (m *permissionMatrix) add(namespace string, rules []rbacv1.PolicyRule, source string, clusterWide bool) {
+	for _, rule := range rules {
+		names := append([]string{}, rule.ResourceNames...)
+		sort.Strings(names)
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				key := strings.Join([]string{namespace, group, resource, strings.Join(names, ",")}, "|")
+				row, ok := m.rows[key]
+				if !ok {
+					row = &PermissionEntry{Namespace: namespace, APIGroup: group, Resource: resource, ResourceNames: names}
+					m.rows[key] = row
+				}
+				row.merge(rule.Verbs, source)
+			}
+		}
+		if !clusterWide {
+			continue
+		}
+		for _, url := range rule.NonResourceURLs {
+			row, ok := m.urls[url]
+			if !ok {
+				row = &PermissionEntry{Namespace: AllNamespaces, Resource: url}
+				m.urls[url] = row
+			}
+			row.merge(rule.Verbs, source)
+		}
+	}
+}
+
+func (e *PermissionEntry) merge(verbs []string, source string) {
+	for _, v := range verbs {
+		if !contains(e.Verbs, v) {
+			e.Verbs = append(e.Verbs, v)
+		}
+	}
+	sort.Strings(e.Verbs)
+	if !contains(e.Sources, source) {
+		e.Sources = append(e.Sources, source)
+	}
+}
+
+func (m *permissionMatrix) entries() ([]PermissionEntry, []PermissionEntry) {
+	collect := func(rows map[string]*PermissionEntry) []PermissionEntry {
+		result := make([]PermissionEntry, 0, len(rows))
+		for _, row := range rows {
+			sort.Strings(row.Sources)
+			result = append(result, *row)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			a, b := result[i], result[j]
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.APIGroup != b.APIGroup {
+				return a.APIGroup < b.APIGroup
+			}
+			if a.Resource != b.Resource {
+				return a.Resource < b.Resource
+			}
+			return strings.Join(a.ResourceNames, ",") < strings.Join(b.ResourceNames, ",")
+		})
+		return result
+	}
+	return collect(m.rows), collect(m.urls)
+}
@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WatchWorkloadReadiness reports the availability of a workload every time
+// the watch observes it, starting with its current state. A deleted
+// workload counts as unavailable. It returns a subscription ID for
+// StopSubscription.
+func (c *Client) WatchWorkloadReadiness(ref ResourceRef, observe func(ready bool, at time.Time)) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	id := c.subs.add("readiness", cancel, ref.GVR())
+	nameSelector := fields.OneTermEqualSelector("metadata.name", ref.Name).String()
+
+	go c.watchLoop(ctx, id, func(ctx context.Context, rv string) (watch.Interface, error) {
+		return c.DynamicClient.Resource(ref.GVR()).Namespace(ref.Namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   nameSelector,
+			ResourceVersion: rv,
+		})
+	}, func(ev watch.Event) {
+		u, ok := asUnstructured(ev.Object)
+		if !ok {
+			return
+		}
+		observe(ev.Type != watch.Deleted && workloadAvailable(u), time.Now())
+	}, func() { c.resourceGone(ref.GVR()) })
+
+	return id, nil
+}
+
+// workloadAvailable decides whether a workload is serving: the Available
+// condition for Deployments, all desired replicas ready for the other
+// built-in controllers, and a Ready condition for anything else.
+func workloadAvailable(u *unstructured.Unstructured) bool {
+	switch u.GetKind() {
+	case "Deployment":
+		return conditionStatus(u, "Available") == "True"
+	case "StatefulSet", "ReplicaSet":
+		desired, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+		if !found {
+			desired = 1
+		}
+		ready, _, _ := unstructured.NestedInt64(u.Object, "status", "readyReplicas")
+		return ready >= desired
+	case "DaemonSet":
+		desired, _, _ := unstructured.NestedInt64(u.Object, "status", "desiredNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(u.Object, "status", "numberReady")
+		return ready >= desired
+	}
+	return conditionStatus(u, "Ready") == "True"
+}
+
+func conditionStatus(u *unstructured.Unstructured, condType string) string {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, raw := range conditions {
+		cond, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t, _ := cond["type"].(string); t == condType {
+			status, _ := cond["status"].(string)
+			return status
+		}
+	}
+	return ""
+}
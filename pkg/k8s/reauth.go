@@ -0,0 +1,206 @@
+package k8s
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// EventReauthRequired is emitted when a context's credentials are
+	// rejected or can't be refreshed, instead of leaving the user with
+	// opaque 401 errors.
+	EventReauthRequired = "kubeconfig:reauth-required"
+
+	// reauthNoticeInterval keeps a burst of rejected requests from
+	// emitting one event each.
+	reauthNoticeInterval = 30 * time.Second
+)
+
+// Ways a kubeconfig user authenticates.
+const (
+	AuthMethodExec              = "exec"
+	AuthMethodAuthProvider      = "auth-provider"
+	AuthMethodToken             = "token"
+	AuthMethodClientCertificate = "client-certificate"
+	AuthMethodBasic             = "basic"
+	AuthMethodNone              = "none"
+)
+
+type ReauthRequired struct {
+	Context string `json:"context"`
+	Method  string `json:"method"`
+	// LoginCommand is what Reauthenticate runs in a terminal, when the
+	// credentials come from a CLI session such as gcloud or aws sso.
+	LoginCommand []string `json:"login_command,omitempty"`
+	// CanReauthenticate is false when only editing the kubeconfig helps,
+	// e.g. for a static token.
+	CanReauthenticate bool   `json:"can_reauthenticate"`
+	Message           string `json:"message"`
+}
+
+// reauthNotices remembers when each context last reported
+// EventReauthRequired.
+type reauthNotices struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func (n *reauthNotices) due(contextName string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if now.Sub(n.last[contextName]) < reauthNoticeInterval {
+		return false
+	}
+	if n.last == nil {
+		n.last = make(map[string]time.Time)
+	}
+	n.last[contextName] = now
+	return true
+}
+
+func authMethod(auth *clientcmdapi.AuthInfo) string {
+	switch {
+	case auth == nil:
+		return AuthMethodNone
+	case auth.Exec != nil:
+		return AuthMethodExec
+	case auth.AuthProvider != nil:
+		return AuthMethodAuthProvider
+	case auth.Token != "" || auth.TokenFile != "":
+		return AuthMethodToken
+	case len(auth.ClientCertificateData) > 0 || auth.ClientCertificate != "":
+		return AuthMethodClientCertificate
+	case auth.Username != "":
+		return AuthMethodBasic
+	}
+	return AuthMethodNone
+}
+
+// execLoginCommand returns the CLI login that renews the session behind
+// well-known cloud plugins. Other plugins, like oidc-login, log in
+// themselves when run interactively, and get nil.
+func execLoginCommand(cfg *clientcmdapi.ExecConfig) []string {
+	switch strings.TrimSuffix(filepath.Base(cfg.Command), ".exe") {
+	case "gke-gcloud-auth-plugin", "gcloud":
+		return []string{"gcloud", "auth", "login"}
+	case "aws", "aws-iam-authenticator":
+		argv := []string{"aws", "sso", "login"}
+		for i, arg := range cfg.Args {
+			if arg == "--profile" && i+1 < len(cfg.Args) {
+				return append(argv, "--profile", cfg.Args[i+1])
+			}
+		}
+		for _, env := range cfg.Env {
+			if env.Name == "AWS_PROFILE" {
+				return append(argv, "--profile", env.Value)
+			}
+		}
+		return argv
+	case "kubelogin":
+		if strings.Contains(strings.Join(cfg.Args, " "), "azurecli") {
+			return []string{"az", "login"}
+		}
+	}
+	return nil
+}
+
+// authInfoFor returns the kubeconfig user of a context.
+func (c *Client) authInfoFor(contextName string) (*clientcmdapi.AuthInfo, error) {
+	if err := checkEditableContext(contextName); err != nil {
+		return nil, err
+	}
+	raw, err := c.clientConfig("").RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	ctx, ok := raw.Contexts[contextName]
+	if !ok {
+		return nil, fmt.Errorf("context %s not found", contextName)
+	}
+	return raw.AuthInfos[ctx.AuthInfo], nil
+}
+
+// reportUnauthorized emits EventReauthRequired for a context, at most once
+// per reauthNoticeInterval.
+func (c *Client) reportUnauthorized(contextName, message string) {
+	if !c.reauth.due(contextName, time.Now()) {
+		return
+	}
+	notice := ReauthRequired{Context: contextName, Method: AuthMethodNone, Message: message}
+	auth, err := c.authInfoFor(contextName)
+	if err == nil {
+		notice.Method = authMethod(auth)
+	}
+	switch notice.Method {
+	case AuthMethodExec:
+		if info := teleportExecInfo(auth.Exec); info != nil {
+			notice.LoginCommand = tshLoginCommand(info)
+		} else {
+			notice.LoginCommand = execLoginCommand(auth.Exec)
+		}
+		notice.CanReauthenticate = true
+	case AuthMethodAuthProvider:
+		notice.Message += "; the provider's refresh token may have expired, log in again with the tool that wrote the kubeconfig"
+	default:
+		notice.Message += "; update the credentials in the kubeconfig"
+	}
+	c.emit(EventReauthRequired, notice)
+}
+
+// unauthorizedRoundTripper reports 401 responses as EventReauthRequired.
+type unauthorizedRoundTripper struct {
+	client      *Client
+	contextName string
+	next        http.RoundTripper
+}
+
+func (rt *unauthorizedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.next.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		rt.client.reportUnauthorized(rt.contextName, "the API server rejected the credentials of "+rt.contextName)
+	}
+	return resp, err
+}
+
+// Reauthenticate renews the credentials of an exec plugin context. Cloud
+// CLI sessions (gcloud, aws sso, az, tsh) are renewed in a terminal
+// window; other plugins run interactively so they can open a browser,
+// and their new credentials are used right away.
+func (c *Client) Reauthenticate(contextName string) error {
+	auth, err := c.authInfoFor(contextName)
+	if err != nil {
+		return err
+	}
+	if authMethod(auth) != AuthMethodExec {
+		return fmt.Errorf("context %s doesn't use a credential plugin; update its credentials in the kubeconfig", contextName)
+	}
+	c.execCreds.invalidate(contextName)
+	if info := teleportExecInfo(auth.Exec); info != nil {
+		return runInTerminal(tshLoginCommand(info))
+	}
+	if argv := execLoginCommand(auth.Exec); argv != nil {
+		return runInTerminal(argv)
+	}
+
+	lock := c.execCreds.lock(contextName)
+	lock.Lock()
+	cred, err := c.runExecPlugin(contextName, auth.Exec, true)
+	if err == nil {
+		c.execCreds.set(contextName, cred)
+	}
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+	// Client certificates are fixed at Init.
+	if current, _ := c.GetCurrentContext(); current == contextName && len(cred.certData) > 0 {
+		return c.Init()
+	}
+	return nil
+}
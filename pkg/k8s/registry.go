@@ -0,0 +1,308 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	dockerHub         = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+	registryTimeout   = 15 * time.Second
+)
+
+// manifestMediaTypes are accepted when resolving a tag, so multi-arch
+// images resolve to the index digest the kubelet records as imageID.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// imageRef is a parsed image reference with Docker Hub defaults applied.
+type imageRef struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// parseImageRef parses "registry:5000/org/app:1.2@sha256:..." the way the
+// container runtime does: the first path component is a registry only if
+// it looks like a host, and a bare name is a Docker Hub library image.
+func parseImageRef(image string) imageRef {
+	var ref imageRef
+	if i := strings.Index(image, "@"); i >= 0 {
+		ref.Digest = image[i+1:]
+		image = image[:i]
+	}
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		ref.Tag = image[colon+1:]
+		image = image[:colon]
+	}
+	if first, rest, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, ref.Repository = first, rest
+	} else {
+		ref.Registry, ref.Repository = dockerHub, image
+	}
+	if ref.Registry == dockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref
+}
+
+// Name is the fully qualified reference without the digest.
+func (r imageRef) Name() string {
+	name := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		name += ":" + r.Tag
+	}
+	return name
+}
+
+// imageIDDigest extracts the digest from a container status imageID such
+// as "docker-pullable://nginx@sha256:...".
+func imageIDDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	if strings.HasPrefix(imageID, "sha256:") {
+		return imageID
+	}
+	return ""
+}
+
+// registryAuth are credentials for a registry; empty means anonymous.
+type registryAuth struct {
+	Username string
+	Password string
+}
+
+// registryError is a registry answer other than success, kept apart from
+// network errors so callers can tell "denied" from "unreachable".
+type registryError struct {
+	Status int
+	Msg    string
+}
+
+func (e *registryError) Error() string {
+	return fmt.Sprintf("registry answered %d: %s", e.Status, e.Msg)
+}
+
+// fetchManifestDigest resolves a reference to its manifest digest with a
+// HEAD request, following the registry's token auth challenge.
+func fetchManifestDigest(ctx context.Context, ref imageRef, auth registryAuth) (string, error) {
+	host := ref.Registry
+	if host == dockerHub {
+		host = dockerHubRegistry
+	}
+	reference := ref.Tag
+	if ref.Digest != "" {
+		reference = ref.Digest
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, ref.Repository, reference)
+	client := &http.Client{Timeout: registryTimeout}
+
+	head := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return resp, nil
+	}
+
+	resp, err := head("")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		scheme, params := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+		var authorization string
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			scope := params["scope"]
+			if scope == "" {
+				scope = "repository:" + ref.Repository + ":pull"
+			}
+			token, err := fetchRegistryToken(ctx, client, params, scope, auth)
+			if err != nil {
+				return "", err
+			}
+			authorization = "Bearer " + token
+		case "basic":
+			if auth.Username == "" {
+				return "", &registryError{Status: resp.StatusCode, Msg: "credentials required"}
+			}
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.SetBasicAuth(auth.Username, auth.Password)
+			authorization = req.Header.Get("Authorization")
+		default:
+			return "", &registryError{Status: resp.StatusCode, Msg: "unsupported auth challenge " + scheme}
+		}
+		if resp, err = head(authorization); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &registryError{Status: resp.StatusCode, Msg: http.StatusText(resp.StatusCode)}
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for %s", ref.Name())
+	}
+	return digest, nil
+}
+
+// fetchRegistryToken gets a token for scope (none when empty) from a
+// Bearer challenge's realm, sending the credentials, if any, as basic
+// auth. Realms other than https are refused, as the credentials would
+// travel in the clear.
+func fetchRegistryToken(ctx context.Context, client *http.Client, challenge map[string]string, scope string, auth registryAuth) (string, error) {
+	realm := challenge["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry auth challenge has no realm")
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %v", realm, err)
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("refusing token realm %q: only https realms are supported", realm)
+	}
+	q := u.Query()
+	if service := challenge["service"]; service != "" {
+		q.Set("service", service)
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth.Username != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg := "token request rejected"
+		if auth.Username != "" {
+			msg = "credentials rejected"
+		}
+		return "", &registryError{Status: resp.StatusCode, Msg: msg}
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %v", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseAuthChallenge splits `Bearer realm="...",service="..."` into the
+// scheme and its parameters.
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
+
+// checkRegistryAuth verifies credentials against a registry's /v2/
+// endpoint, exchanging them for a token when the registry asks for one.
+func checkRegistryAuth(ctx context.Context, registry string, auth registryAuth) error {
+	host := registry
+	if host == dockerHub {
+		host = dockerHubRegistry
+	}
+	client := &http.Client{Timeout: registryTimeout}
+	get := func(configure func(*http.Request)) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/v2/", nil)
+		if err != nil {
+			return nil, err
+		}
+		configure(req)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+		return resp, nil
+	}
+
+	resp, err := get(func(*http.Request) {})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusOK {
+		// The registry doesn't authenticate /v2/; credentials can only be
+		// checked against an image.
+		return nil
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return &registryError{Status: resp.StatusCode, Msg: http.StatusText(resp.StatusCode)}
+	}
+
+	scheme, params := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		_, err := fetchRegistryToken(ctx, client, params, "", auth)
+		return err
+	case "basic":
+		resp, err := get(func(req *http.Request) { req.SetBasicAuth(auth.Username, auth.Password) })
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return &registryError{Status: resp.StatusCode, Msg: "credentials rejected"}
+		}
+		return nil
+	}
+	return &registryError{Status: resp.StatusCode, Msg: "unsupported auth challenge " + scheme}
+}
@@ -0,0 +1,41 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchRegistryToken(t *testing.T) {
+	var gotQuery, gotUser string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		gotUser, _, _ = r.BasicAuth()
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "t0k3n"})
+	}))
+	defer server.Close()
+
+	auth := registryAuth{Username: "robot", Password: "pw"}
+	challenge := map[string]string{"realm": server.URL + "/token", "service": "registry.example.com"}
+	token, err := fetchRegistryToken(context.Background(), server.Client(), challenge, "repository:app:pull", auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "t0k3n" {
+		t.Errorf("token = %q, want %q", token, "t0k3n")
+	}
+	if want := "scope=repository%3Aapp%3Apull&service=registry.example.com"; gotQuery != want {
+		t.Errorf("query = %q, want %q", gotQuery, want)
+	}
+	if gotUser != "robot" {
+		t.Errorf("basic auth user = %q, want %q", gotUser, "robot")
+	}
+
+	for _, realm := range []string{"http://registry.example.com/token", "", "ftp://registry.example.com/token"} {
+		if _, err := fetchRegistryToken(context.Background(), server.Client(), map[string]string{"realm": realm}, "", auth); err == nil {
+			t.Errorf("realm %q was accepted", realm)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package k8s
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ResourceReservation struct {
+	Resource       string `json:"resource"`
+	Capacity       string `json:"capacity"`
+	Allocatable    string `json:"allocatable"`
+	KubeReserved   string `json:"kube_reserved"`
+	SystemReserved string `json:"system_reserved"`
+	EvictionHard   string `json:"eviction_hard"`
+	// Unexplained is the part of capacity minus allocatable not covered by
+	// the reservations above (e.g. reservations set by a cloud provider
+	// outside the kubelet config).
+	Unexplained string `json:"unexplained"`
+}
+
+type NodeReservations struct {
+	Node               string                `json:"node"`
+	Resources          []ResourceReservation `json:"resources"`
+	EvictionHard       map[string]string     `json:"eviction_hard"`
+	EvictionSoft       map[string]string     `json:"eviction_soft"`
+	EnforceAllocatable []string              `json:"enforce_node_allocatable"`
+	ConfigError        string                `json:"config_error,omitempty"`
+}
+
+// evictionSignals maps eviction signals to the node resource they reduce.
+var evictionSignals = map[string]corev1.ResourceName{
+	"memory.available": corev1.ResourceMemory,
+	"nodefs.available": corev1.ResourceEphemeralStorage,
+}
+
+// GetNodeReservations explains the gap between a node's capacity and its
+// allocatable resources using the kubelet's reservation settings.
+func (c *Client) GetNodeReservations(nodeName string) (*NodeReservations, error) {
+	ctx, cancel := c.opContext(OpGet)
+	node, err := c.Clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NodeReservations{Node: nodeName}
+
+	var kubeReserved, systemReserved map[string]string
+	cfg, err := c.GetKubeletConfig(nodeName)
+	if err != nil {
+		// Capacity and allocatable are still useful without configz access.
+		result.ConfigError = err.Error()
+	} else {
+		kubeReserved = stringMap(cfg["kubeReserved"])
+		systemReserved = stringMap(cfg["systemReserved"])
+		result.EvictionHard = stringMap(cfg["evictionHard"])
+		result.EvictionSoft = stringMap(cfg["evictionSoft"])
+		if list, ok := cfg["enforceNodeAllocatable"].([]interface{}); ok {
+			for _, v := range list {
+				if s, ok := v.(string); ok {
+					result.EnforceAllocatable = append(result.EnforceAllocatable, s)
+				}
+			}
+		}
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourcePods} {
+		capacity, ok := node.Status.Capacity[name]
+		if !ok {
+			continue
+		}
+		allocatable := node.Status.Allocatable[name]
+
+		r := ResourceReservation{
+			Resource:       string(name),
+			Capacity:       capacity.String(),
+			Allocatable:    allocatable.String(),
+			KubeReserved:   kubeReserved[string(name)],
+			SystemReserved: systemReserved[string(name)],
+		}
+
+		explained := resource.Quantity{}
+		addQuantity(&explained, r.KubeReserved)
+		addQuantity(&explained, r.SystemReserved)
+		for signal, res := range evictionSignals {
+			if res != name {
+				continue
+			}
+			if threshold, ok := result.EvictionHard[signal]; ok {
+				r.EvictionHard = threshold
+				if q, ok := evictionQuantity(threshold, capacity); ok {
+					explained.Add(q)
+				}
+			}
+		}
+
+		gap := capacity.DeepCopy()
+		gap.Sub(allocatable)
+		gap.Sub(explained)
+		if gap.Sign() > 0 {
+			r.Unexplained = gap.String()
+		}
+		result.Resources = append(result.Resources, r)
+	}
+
+	return result, nil
+}
+
+// evictionQuantity resolves a threshold like "100Mi" or "10%" against the
+// resource capacity.
+func evictionQuantity(threshold string, capacity resource.Quantity) (resource.Quantity, bool) {
+	if strings.HasSuffix(threshold, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil {
+			return resource.Quantity{}, false
+		}
+		return *resource.NewQuantity(int64(float64(capacity.Value())*pct/100), resource.BinarySI), true
+	}
+	q, err := resource.ParseQuantity(threshold)
+	if err != nil {
+		return resource.Quantity{}, false
+	}
+	return q, true
+}
+
+func addQuantity(total *resource.Quantity, s string) {
+	if s == "" {
+		return
+	}
+	if q, err := resource.ParseQuantity(s); err == nil {
+		total.Add(q)
+	}
+}
+
+func stringMap(v interface{}) map[string]string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		if s, ok := val.(string); ok {
+			out[k] = s
+		}
+	}
+	return out
+}
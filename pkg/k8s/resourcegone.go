@@ -0,0 +1,71 @@
+package k8s
+
+import (
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const EventResourceRemoved = "api:resource-removed"
+
+type ResourceRemoved struct {
+	Group                string            `json:"group"`
+	Version              string            `json:"version"`
+	Resource             string            `json:"resource"`
+	StoppedSubscriptions []string          `json:"stopped_subscriptions"`
+	Resources            []ApiResourceInfo `json:"resources"`
+}
+
+// removedResources remembers which resource types were already reported
+// as gone so repeated failures don't flood the frontend.
+type removedResources struct {
+	mu       sync.Mutex
+	reported map[schema.GroupVersionResource]bool
+}
+
+func (r *removedResources) markReported(gvr schema.GroupVersionResource) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reported[gvr] {
+		return false
+	}
+	if r.reported == nil {
+		r.reported = make(map[schema.GroupVersionResource]bool)
+	}
+	r.reported[gvr] = true
+	return true
+}
+
+func (r *removedResources) clear(gvr schema.GroupVersionResource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reported, gvr)
+}
+
+// isResourceGone reports whether err means the resource type itself is no
+// longer served, as happens when a CRD is uninstalled. Only meaningful for
+// collection requests (list/watch), where NotFound can't refer to an object.
+func isResourceGone(err error) bool {
+	return apierrors.IsNotFound(err)
+}
+
+// resourceGone stops the streams depending on gvr, refreshes the API
+// resource list and tells the frontend, once per disappearance.
+func (c *Client) resourceGone(gvr schema.GroupVersionResource) {
+	if !c.removed.markReported(gvr) {
+		return
+	}
+	c.scopes.reset()
+
+	event := ResourceRemoved{
+		Group:                gvr.Group,
+		Version:              gvr.Version,
+		Resource:             gvr.Resource,
+		StoppedSubscriptions: c.subs.stopGVR(gvr),
+	}
+	if resources, err := c.GetApiResources(); err == nil {
+		event.Resources = resources
+	}
+	c.emit(EventResourceRemoved, event)
+}
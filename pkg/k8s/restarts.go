@@ -0,0 +1,410 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Probable causes attributed to a restart.
+const (
+	CauseOOMKilled     = "oom-killed"
+	CauseLivenessProbe = "liveness-probe"
+	CauseStartupProbe  = "startup-probe"
+	CauseError         = "error"
+	CauseCompleted     = "completed"
+	CauseKilled        = "killed"
+	CauseEviction      = "eviction"
+	CausePreemption    = "preemption"
+	CauseNodeDrain     = "node-drain"
+	CauseNodeFailure   = "node-failure"
+	CauseRollout       = "rollout"
+	CauseUnknown       = "unknown"
+)
+
+const (
+	// probeWindow is how long before a container terminated a failed
+	// probe event still counts as its cause.
+	probeWindow = 10 * time.Minute
+	// rolloutWindow is how long after a new StatefulSet or DaemonSet
+	// revision a replaced pod is attributed to the rollout.
+	rolloutWindow = 30 * time.Minute
+)
+
+var (
+	containerFieldPath = regexp.MustCompile(`spec\.(?:initContainers|containers|ephemeralContainers)\{(.+)\}`)
+	scheduledToNode    = regexp.MustCompile(` to (\S+)$`)
+)
+
+type RestartRecord struct {
+	Pod string `json:"pod"`
+	// Container is empty when the whole pod was replaced rather than a
+	// container restarted in place.
+	Container         string    `json:"container,omitempty"`
+	Node              string    `json:"node,omitempty"`
+	Time              time.Time `json:"time"`
+	RestartCount      int32     `json:"restart_count,omitempty"`
+	ExitCode          int32     `json:"exit_code,omitempty"`
+	TerminationReason string    `json:"termination_reason,omitempty"`
+	Cause             string    `json:"cause"`
+	// Evidence are the facts the cause was inferred from.
+	Evidence []string `json:"evidence"`
+}
+
+type RestartHistory struct {
+	Kind      string          `json:"kind"`
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+	Restarts  []RestartRecord `json:"restarts"`
+}
+
+// podEvent is a pod event with the container it is about, if any.
+type podEvent struct {
+	ObjectEvent
+	container string
+}
+
+// GetRestartHistory lists the container restarts of a workload's pods and
+// the pods it lost and replaced, oldest first, each attributed to a
+// probable cause: OOM kills, failed liveness or startup probes, evictions,
+// node drains or rollouts. Kubernetes only keeps the last termination of
+// each container and events for about an hour, so older restarts are
+// only counted. kind is Deployment, StatefulSet, DaemonSet or Pod.
+func (c *Client) GetRestartHistory(kind, namespace, name string) (*RestartHistory, error) {
+	ctx, cancel := c.opContext(OpList)
+	defer cancel()
+
+	owner, err := c.restartOwner(ctx, kind, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []corev1.Pod
+	if kind == "Pod" {
+		pod, err := c.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		pods = []corev1.Pod{*pod}
+	} else {
+		list, err := c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: owner.selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		pods = list.Items
+	}
+
+	events, err := c.Clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.kind", "Pod").String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod events: %v", err)
+	}
+	byPod := make(map[string][]podEvent)
+	for i := range events.Items {
+		e := &events.Items[i]
+		ev := podEvent{ObjectEvent: coreObjectEvent(e)}
+		if m := containerFieldPath.FindStringSubmatch(e.InvolvedObject.FieldPath); m != nil {
+			ev.container = m[1]
+		}
+		byPod[e.InvolvedObject.Name] = append(byPod[e.InvolvedObject.Name], ev)
+	}
+
+	// Listing nodes may be forbidden; drains are then only recognised by
+	// their events.
+	nodes := make(map[string]*corev1.Node)
+	if list, err := c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err == nil {
+		for i := range list.Items {
+			nodes[list.Items[i].Name] = &list.Items[i]
+		}
+	}
+
+	history := &RestartHistory{Kind: kind, Namespace: namespace, Name: name, Restarts: []RestartRecord{}}
+	current := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		current[pod.Name] = true
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, s := range statuses {
+			if s.RestartCount == 0 || s.LastTerminationState.Terminated == nil {
+				continue
+			}
+			history.Restarts = append(history.Restarts, containerRestart(pod, s, byPod[pod.Name]))
+		}
+	}
+
+	if kind != "Pod" {
+		for podName, evs := range byPod {
+			if current[podName] || !owner.ownsPod(podName) {
+				continue
+			}
+			if record, ok := owner.replacedPod(podName, evs, nodes); ok {
+				history.Restarts = append(history.Restarts, record)
+			}
+		}
+	}
+
+	sort.Slice(history.Restarts, func(i, j int) bool {
+		a, b := history.Restarts[i], history.Restarts[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		return a.Pod+"/"+a.Container < b.Pod+"/"+b.Container
+	})
+	return history, nil
+}
+
+// containerRestart attributes the last termination of a restarted
+// container.
+func containerRestart(pod corev1.Pod, s corev1.ContainerStatus, events []podEvent) RestartRecord {
+	term := s.LastTerminationState.Terminated
+	record := RestartRecord{
+		Pod:               pod.Name,
+		Container:         s.Name,
+		Node:              pod.Spec.NodeName,
+		Time:              term.FinishedAt.Time,
+		RestartCount:      s.RestartCount,
+		ExitCode:          term.ExitCode,
+		TerminationReason: term.Reason,
+		Evidence:          []string{fmt.Sprintf("last terminated with exit code %d (%s)", term.ExitCode, term.Reason)},
+	}
+	if term.Message != "" {
+		record.Evidence = append(record.Evidence, term.Message)
+	}
+	if term.Reason == "OOMKilled" {
+		record.Cause = CauseOOMKilled
+		return record
+	}
+
+	for _, ev := range events {
+		if ev.container != s.Name && !strings.Contains(ev.Message, "Container "+s.Name+" ") {
+			continue
+		}
+		if ev.LastTimestamp.Before(record.Time.Add(-probeWindow)) || ev.LastTimestamp.After(record.Time.Add(time.Minute)) {
+			continue
+		}
+		msg := strings.ToLower(ev.Message)
+		switch {
+		case strings.Contains(msg, "liveness probe"):
+			record.Cause = CauseLivenessProbe
+		case strings.Contains(msg, "startup probe"):
+			record.Cause = CauseStartupProbe
+		default:
+			continue
+		}
+		record.Evidence = append(record.Evidence, ev.Reason+": "+ev.Message)
+		return record
+	}
+
+	switch term.ExitCode {
+	case 0:
+		record.Cause = CauseCompleted
+	case 137, 143:
+		// SIGKILL or SIGTERM without an OOM or probe event: something
+		// outside the container stopped it.
+		record.Cause = CauseKilled
+	default:
+		record.Cause = CauseError
+	}
+	return record
+}
+
+// restartOwner knows which pods belong to a workload and which of its
+// revisions they ran.
+type restartOwner struct {
+	kind     string
+	name     string
+	selector labels.Selector
+	// replicaSets maps a Deployment's ReplicaSet names to their revision.
+	replicaSets     map[string]int64
+	currentRevision int64
+	changeCause     string
+	// revisionCreated is when the current revision of a StatefulSet or
+	// DaemonSet was created.
+	revisionCreated time.Time
+}
+
+func (c *Client) restartOwner(ctx context.Context, kind, namespace, name string) (*restartOwner, error) {
+	owner := &restartOwner{kind: kind, name: name}
+	switch kind {
+	case "Pod":
+		return owner, nil
+	case "Deployment":
+		d, err := c.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if owner.selector, err = metav1.LabelSelectorAsSelector(d.Spec.Selector); err != nil {
+			return nil, err
+		}
+		sets, err := c.ownedReplicaSets(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+		owner.replicaSets = make(map[string]int64, len(sets))
+		owner.currentRevision, _ = strconv.ParseInt(d.Annotations[revisionAnnotation], 10, 64)
+		for _, rs := range sets {
+			rev, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+			owner.replicaSets[rs.Name] = rev
+			if rev == owner.currentRevision {
+				owner.changeCause = rs.Annotations[changeCauseAnnotation]
+			}
+		}
+	case "StatefulSet", "DaemonSet":
+		revisions, current, err := c.controllerRevisions(ctx, kind, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		var selector *metav1.LabelSelector
+		if kind == "StatefulSet" {
+			s, err := c.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			selector = s.Spec.Selector
+		} else {
+			d, err := c.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			selector = d.Spec.Selector
+		}
+		if owner.selector, err = metav1.LabelSelectorAsSelector(selector); err != nil {
+			return nil, err
+		}
+		for _, cr := range revisions {
+			if cr.Name == current {
+				owner.currentRevision = cr.Revision
+				owner.changeCause = cr.Annotations[changeCauseAnnotation]
+				owner.revisionCreated = cr.CreationTimestamp.Time
+			}
+		}
+	default:
+		return nil, fmt.Errorf("restart history is not supported for %s", kind)
+	}
+	return owner, nil
+}
+
+// ownsPod tells from its name whether a pod that no longer exists
+// belonged to the workload.
+func (o *restartOwner) ownsPod(pod string) bool {
+	switch o.kind {
+	case "Deployment":
+		_, ok := o.replicaSets[o.replicaSetOf(pod)]
+		return ok
+	case "StatefulSet":
+		ordinal, ok := strings.CutPrefix(pod, o.name+"-")
+		if !ok {
+			return false
+		}
+		_, err := strconv.Atoi(ordinal)
+		return err == nil
+	case "DaemonSet":
+		suffix, ok := strings.CutPrefix(pod, o.name+"-")
+		return ok && len(suffix) == 5 && !strings.Contains(suffix, "-")
+	}
+	return false
+}
+
+func (o *restartOwner) replicaSetOf(pod string) string {
+	i := strings.LastIndex(pod, "-")
+	if i < 0 {
+		return ""
+	}
+	return pod[:i]
+}
+
+// replacedPod attributes the loss of a pod that no longer exists from the
+// events left behind. ok is false when the events don't show it being
+// stopped, e.g. for a pod that never started.
+func (o *restartOwner) replacedPod(pod string, events []podEvent, nodes map[string]*corev1.Node) (RestartRecord, bool) {
+	record := RestartRecord{Pod: pod, Evidence: []string{"the pod no longer exists"}}
+	var stopped *podEvent
+	for i := range events {
+		ev := &events[i]
+		if ev.Reason == "Scheduled" {
+			if m := scheduledToNode.FindStringSubmatch(ev.Message); m != nil {
+				record.Node = m[1]
+			}
+		}
+		switch ev.Reason {
+		case "Killing", "Evicted", "Preempted", "TaintManagerEviction":
+			// Evictions and preemptions explain the Killing that
+			// follows them.
+			switch {
+			case stopped == nil:
+				stopped = ev
+			case (stopped.Reason == "Killing") != (ev.Reason == "Killing"):
+				if stopped.Reason == "Killing" {
+					stopped = ev
+				}
+			case ev.LastTimestamp.After(stopped.LastTimestamp):
+				stopped = ev
+			}
+		}
+	}
+	if stopped == nil {
+		return record, false
+	}
+	record.Time = stopped.LastTimestamp
+	record.Evidence = append(record.Evidence, stopped.Reason+": "+stopped.Message)
+
+	switch stopped.Reason {
+	case "Evicted":
+		record.Cause = CauseEviction
+		return record, true
+	case "Preempted":
+		record.Cause = CausePreemption
+		return record, true
+	case "TaintManagerEviction":
+		record.Cause = CauseNodeFailure
+		return record, true
+	}
+
+	if record.Node != "" {
+		node, exists := nodes[record.Node]
+		switch {
+		case len(nodes) > 0 && !exists:
+			record.Cause = CauseNodeDrain
+			record.Evidence = append(record.Evidence, "node "+record.Node+" has since been removed")
+			return record, true
+		case exists && node.Spec.Unschedulable:
+			record.Cause = CauseNodeDrain
+			record.Evidence = append(record.Evidence, "node "+record.Node+" is cordoned")
+			return record, true
+		}
+	}
+
+	switch o.kind {
+	case "Deployment":
+		if rev := o.replicaSets[o.replicaSetOf(pod)]; rev != 0 && rev != o.currentRevision {
+			record.Cause = CauseRollout
+			record.Evidence = append(record.Evidence, o.rolloutEvidence(fmt.Sprintf("ran revision %d", rev)))
+			return record, true
+		}
+	case "StatefulSet", "DaemonSet":
+		if !o.revisionCreated.IsZero() && !record.Time.Before(o.revisionCreated) && record.Time.Sub(o.revisionCreated) < rolloutWindow {
+			record.Cause = CauseRollout
+			record.Evidence = append(record.Evidence, o.rolloutEvidence("stopped shortly after revision "+strconv.FormatInt(o.currentRevision, 10)+" was created"))
+			return record, true
+		}
+	}
+	record.Cause = CauseUnknown
+	return record, true
+}
+
+func (o *restartOwner) rolloutEvidence(what string) string {
+	msg := fmt.Sprintf("%s; the current revision is %d", what, o.currentRevision)
+	if o.changeCause != "" {
+		msg += " (" + o.changeCause + ")"
+	}
+	return msg
+}
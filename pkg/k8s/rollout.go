@@ -0,0 +1,404 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+type RolloutStatus struct {
+	Kind               string `json:"kind"`
+	Namespace          string `json:"namespace"`
+	Name               string `json:"name"`
+	Done               bool   `json:"done"`
+	Message            string `json:"message"`
+	Generation         int64  `json:"generation"`
+	ObservedGeneration int64  `json:"observed_generation"`
+	Desired            int32  `json:"desired"`
+	Updated            int32  `json:"updated"`
+	Ready              int32  `json:"ready"`
+	Available          int32  `json:"available"`
+}
+
+type RolloutRevision struct {
+	Revision    int64     `json:"revision"`
+	ChangeCause string    `json:"change_cause"`
+	Created     time.Time `json:"created"`
+	Images      []string  `json:"images"`
+	Current     bool      `json:"current"`
+	// Source is the ReplicaSet or ControllerRevision holding the revision.
+	Source string `json:"source"`
+}
+
+// RestartRollout triggers a rolling restart the way `kubectl rollout
+// restart` does, by stamping the pod template with the current time.
+func (c *Client) RestartRollout(kind, namespace, name string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	ref := ResourceRef{Group: "apps", Version: "v1", Kind: kind, Plural: strings.ToLower(kind) + "s", Namespace: namespace, Name: name}
+	return c.mutate(ActionRestart, ref, "restart "+ref.Plural+"/"+name, func() error {
+		return c.patchWorkload(kind, namespace, name, types.StrategicMergePatchType, patch)
+	})
+}
+
+func (c *Client) patchWorkload(kind, namespace, name string, pt types.PatchType, patch []byte) error {
+	return c.do(OpMutate, func(ctx context.Context) error {
+		var err error
+		switch kind {
+		case "Deployment":
+			_, err = c.Clientset.AppsV1().Deployments(namespace).Patch(ctx, name, pt, patch, metav1.PatchOptions{})
+		case "StatefulSet":
+			_, err = c.Clientset.AppsV1().StatefulSets(namespace).Patch(ctx, name, pt, patch, metav1.PatchOptions{})
+		case "DaemonSet":
+			_, err = c.Clientset.AppsV1().DaemonSets(namespace).Patch(ctx, name, pt, patch, metav1.PatchOptions{})
+		default:
+			return fmt.Errorf("rollouts are not supported for %s", kind)
+		}
+		return err
+	})
+}
+
+// GetRolloutStatus reports rollout progress with the same rules as
+// `kubectl rollout status`.
+func (c *Client) GetRolloutStatus(kind, namespace, name string) (*RolloutStatus, error) {
+	ctx, cancel := c.opContext(OpGet)
+	defer cancel()
+	status := &RolloutStatus{Kind: kind, Namespace: namespace, Name: name}
+
+	switch kind {
+	case "Deployment":
+		d, err := c.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		status.Generation, status.ObservedGeneration = d.Generation, d.Status.ObservedGeneration
+		status.Desired = replicasOrOne(d.Spec.Replicas)
+		status.Updated, status.Ready, status.Available = d.Status.UpdatedReplicas, d.Status.ReadyReplicas, d.Status.AvailableReplicas
+		for _, cond := range d.Status.Conditions {
+			if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+				status.Message = fmt.Sprintf("deployment %q exceeded its progress deadline", name)
+				return status, nil
+			}
+		}
+		switch {
+		case d.Generation > d.Status.ObservedGeneration:
+			status.Message = "waiting for deployment spec update to be observed"
+		case d.Status.UpdatedReplicas < status.Desired:
+			status.Message = fmt.Sprintf("%d of %d updated replicas are available", d.Status.UpdatedReplicas, status.Desired)
+		case d.Status.Replicas > d.Status.UpdatedReplicas:
+			status.Message = fmt.Sprintf("%d old replicas are pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)
+		case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+			status.Message = fmt.Sprintf("%d of %d updated replicas are available", d.Status.AvailableReplicas, d.Status.UpdatedReplicas)
+		default:
+			status.Done = true
+			status.Message = fmt.Sprintf("deployment %q successfully rolled out", name)
+		}
+
+	case "StatefulSet":
+		s, err := c.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		status.Generation, status.ObservedGeneration = s.Generation, s.Status.ObservedGeneration
+		status.Desired = replicasOrOne(s.Spec.Replicas)
+		status.Updated, status.Ready, status.Available = s.Status.UpdatedReplicas, s.Status.ReadyReplicas, s.Status.AvailableReplicas
+		switch {
+		case s.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType:
+			status.Done = true
+			status.Message = "rollout status is not available with the OnDelete update strategy"
+		case s.Status.ObservedGeneration == 0 || s.Generation > s.Status.ObservedGeneration:
+			status.Message = "waiting for statefulset spec update to be observed"
+		case s.Status.ReadyReplicas < status.Desired:
+			status.Message = fmt.Sprintf("%d of %d pods are ready", s.Status.ReadyReplicas, status.Desired)
+		case s.Spec.UpdateStrategy.RollingUpdate != nil && s.Spec.UpdateStrategy.RollingUpdate.Partition != nil &&
+			*s.Spec.UpdateStrategy.RollingUpdate.Partition > 0:
+			partition := *s.Spec.UpdateStrategy.RollingUpdate.Partition
+			if s.Status.UpdatedReplicas < status.Desired-partition {
+				status.Message = fmt.Sprintf("%d of %d partitioned pods updated", s.Status.UpdatedReplicas, status.Desired-partition)
+			} else {
+				status.Done = true
+				status.Message = fmt.Sprintf("partitioned rollout complete: %d new pods", s.Status.UpdatedReplicas)
+			}
+		case s.Status.UpdateRevision != s.Status.CurrentRevision:
+			status.Message = fmt.Sprintf("%d of %d pods updated to revision %s", s.Status.UpdatedReplicas, status.Desired, s.Status.UpdateRevision)
+		default:
+			status.Done = true
+			status.Message = fmt.Sprintf("statefulset rolling update complete %d pods at revision %s", s.Status.CurrentReplicas, s.Status.CurrentRevision)
+		}
+
+	case "DaemonSet":
+		d, err := c.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		status.Generation, status.ObservedGeneration = d.Generation, d.Status.ObservedGeneration
+		status.Desired = d.Status.DesiredNumberScheduled
+		status.Updated, status.Ready, status.Available = d.Status.UpdatedNumberScheduled, d.Status.NumberReady, d.Status.NumberAvailable
+		switch {
+		case d.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType:
+			status.Done = true
+			status.Message = "rollout status is only available for the RollingUpdate strategy"
+		case d.Generation > d.Status.ObservedGeneration:
+			status.Message = "waiting for daemon set spec update to be observed"
+		case d.Status.UpdatedNumberScheduled < d.Status.DesiredNumberScheduled:
+			status.Message = fmt.Sprintf("%d of %d updated pods are available", d.Status.UpdatedNumberScheduled, d.Status.DesiredNumberScheduled)
+		case d.Status.NumberAvailable < d.Status.DesiredNumberScheduled:
+			status.Message = fmt.Sprintf("%d of %d updated pods are available", d.Status.NumberAvailable, d.Status.DesiredNumberScheduled)
+		default:
+			status.Done = true
+			status.Message = fmt.Sprintf("daemon set %q successfully rolled out", name)
+		}
+
+	default:
+		return nil, fmt.Errorf("rollouts are not supported for %s", kind)
+	}
+	return status, nil
+}
+
+func replicasOrOne(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// GetRolloutHistory lists the revisions kept for a workload, oldest first:
+// ReplicaSets for Deployments and ControllerRevisions for StatefulSets and
+// DaemonSets.
+func (c *Client) GetRolloutHistory(kind, namespace, name string) ([]RolloutRevision, error) {
+	ctx, cancel := c.opContext(OpList)
+	defer cancel()
+
+	var history []RolloutRevision
+	switch kind {
+	case "Deployment":
+		d, err := c.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		sets, err := c.ownedReplicaSets(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+		current := d.Annotations[revisionAnnotation]
+		for _, rs := range sets {
+			rev, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+			if err != nil {
+				continue
+			}
+			history = append(history, RolloutRevision{
+				Revision:    rev,
+				ChangeCause: rs.Annotations[changeCauseAnnotation],
+				Created:     rs.CreationTimestamp.Time,
+				Images:      containerImages(rs.Spec.Template.Spec),
+				Current:     rs.Annotations[revisionAnnotation] == current,
+				Source:      rs.Name,
+			})
+		}
+
+	case "StatefulSet", "DaemonSet":
+		revisions, current, err := c.controllerRevisions(ctx, kind, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, cr := range revisions {
+			entry := RolloutRevision{
+				Revision:    cr.Revision,
+				ChangeCause: cr.Annotations[changeCauseAnnotation],
+				Created:     cr.CreationTimestamp.Time,
+				Current:     cr.Name == current,
+				Source:      cr.Name,
+			}
+			var data struct {
+				Spec struct {
+					Template struct {
+						Spec corev1.PodSpec `json:"spec"`
+					} `json:"template"`
+				} `json:"spec"`
+			}
+			if json.Unmarshal(cr.Data.Raw, &data) == nil {
+				entry.Images = containerImages(data.Spec.Template.Spec)
+			}
+			history = append(history, entry)
+		}
+
+	default:
+		return nil, fmt.Errorf("rollouts are not supported for %s", kind)
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].Revision < history[j].Revision })
+	return history, nil
+}
+
+func (c *Client) ownedReplicaSets(ctx context.Context, d *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.Clientset.AppsV1().ReplicaSets(d.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var owned []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == d.UID {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// controllerRevisions returns the revisions owned by a StatefulSet or
+// DaemonSet and the name of the one currently in use.
+func (c *Client) controllerRevisions(ctx context.Context, kind, namespace, name string) ([]appsv1.ControllerRevision, string, error) {
+	var uid types.UID
+	var selector *metav1.LabelSelector
+	var current string
+	switch kind {
+	case "StatefulSet":
+		s, err := c.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, "", err
+		}
+		uid, selector, current = s.UID, s.Spec.Selector, s.Status.UpdateRevision
+	case "DaemonSet":
+		d, err := c.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, "", err
+		}
+		uid, selector = d.UID, d.Spec.Selector
+	}
+
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, "", err
+	}
+	list, err := c.Clientset.AppsV1().ControllerRevisions(namespace).List(ctx, metav1.ListOptions{LabelSelector: sel.String()})
+	if err != nil {
+		return nil, "", err
+	}
+	var owned []appsv1.ControllerRevision
+	for _, cr := range list.Items {
+		if owner := metav1.GetControllerOf(&cr); owner != nil && owner.UID == uid {
+			owned = append(owned, cr)
+		}
+	}
+	// DaemonSets don't record their current revision; it's the newest.
+	if current == "" && len(owned) > 0 {
+		newest := owned[0]
+		for _, cr := range owned[1:] {
+			if cr.Revision > newest.Revision {
+				newest = cr
+			}
+		}
+		current = newest.Name
+	}
+	return owned, current, nil
+}
+
+// UndoRollout rolls a workload back to toRevision, or to the previous
+// revision when toRevision is 0, like `kubectl rollout undo`.
+func (c *Client) UndoRollout(kind, namespace, name string, toRevision int64) error {
+	history, err := c.GetRolloutHistory(kind, namespace, name)
+	if err != nil {
+		return err
+	}
+	target, err := pickRevision(history, toRevision)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.opContext(OpGet)
+	defer cancel()
+
+	var pt types.PatchType
+	var patch []byte
+	switch kind {
+	case "Deployment":
+		rs, err := c.Clientset.AppsV1().ReplicaSets(namespace).Get(ctx, target.Source, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		template := rs.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		patch, err = json.Marshal([]map[string]interface{}{
+			{"op": "replace", "path": "/spec/template", "value": template},
+		})
+		if err != nil {
+			return err
+		}
+		pt = types.JSONPatchType
+
+	default:
+		// ControllerRevision data is already a strategic merge patch of the
+		// pod template.
+		cr, err := c.Clientset.AppsV1().ControllerRevisions(namespace).Get(ctx, target.Source, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		patch = cr.Data.Raw
+		pt = types.StrategicMergePatchType
+	}
+
+	ref := ResourceRef{Group: "apps", Version: "v1", Kind: kind, Plural: strings.ToLower(kind) + "s", Namespace: namespace, Name: name}
+	return c.mutate(ActionRollback, ref, fmt.Sprintf("roll back %s/%s to revision %d", ref.Plural, name, target.Revision), func() error {
+		return c.patchWorkload(kind, namespace, name, pt, patch)
+	})
+}
+
+func pickRevision(history []RolloutRevision, toRevision int64) (*RolloutRevision, error) {
+	if toRevision > 0 {
+		for i := range history {
+			if history[i].Revision == toRevision {
+				if history[i].Current {
+					return nil, fmt.Errorf("revision %d is already the current revision", toRevision)
+				}
+				return &history[i], nil
+			}
+		}
+		return nil, fmt.Errorf("revision %d not found", toRevision)
+	}
+	// history is sorted oldest first; the previous revision is the newest
+	// one that isn't current.
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].Current {
+			return &history[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no previous revision to roll back to")
+}
+
+func containerImages(spec corev1.PodSpec) []string {
+	images := make([]string, 0, len(spec.Containers))
+	for _, c := range spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
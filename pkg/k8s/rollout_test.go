@@ -0,0 +1,36 @@
+package k8s
+
+import "testing"
+
+func TestPickRevision(t *testing.T) {
+	history := []RolloutRevision{
+		{Revision: 1, Source: "web-1"},
+		{Revision: 2, Source: "web-2"},
+		{Revision: 3, Source: "web-3", Current: true},
+	}
+	tests := []struct {
+		name       string
+		history    []RolloutRevision
+		toRevision int64
+		want       int64
+		wantErr    bool
+	}{
+		{name: "previous", history: history, want: 2},
+		{name: "explicit", history: history, toRevision: 1, want: 1},
+		{name: "current", history: history, toRevision: 3, wantErr: true},
+		{name: "missing", history: history, toRevision: 7, wantErr: true},
+		{name: "only current", history: history[2:], wantErr: true},
+		{name: "empty", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickRevision(tt.history, tt.toRevision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Revision != tt.want {
+				t.Errorf("picked revision %d, want %d", got.Revision, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package k8s
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	ingressGVR = schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	// OpenShift Routes play the role of Ingresses and are treated the same
+	// way when relating objects to Services.
+	routeGVR = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+)
+
+// ingressServices returns the Service names an Ingress routes to.
+func ingressServices(obj map[string]interface{}) []string {
+	var names []string
+	if name, _, _ := unstructured.NestedString(obj, "spec", "defaultBackend", "service", "name"); name != "" {
+		names = append(names, name)
+	}
+	rules, _, _ := unstructured.NestedSlice(obj, "spec", "rules")
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+		for _, p := range paths {
+			path, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if name, _, _ := unstructured.NestedString(path, "backend", "service", "name"); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// routeServices returns the Service names a Route sends traffic to.
+func routeServices(obj map[string]interface{}) []string {
+	var names []string
+	if kind, _, _ := unstructured.NestedString(obj, "spec", "to", "kind"); kind == "" || kind == "Service" {
+		if name, _, _ := unstructured.NestedString(obj, "spec", "to", "name"); name != "" {
+			names = append(names, name)
+		}
+	}
+	backends, _, _ := unstructured.NestedSlice(obj, "spec", "alternateBackends")
+	for _, b := range backends {
+		backend, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if kind, _ := backend["kind"].(string); kind != "" && kind != "Service" {
+			continue
+		}
+		if name, _ := backend["name"].(string); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// serviceFrontends lists the Ingresses and Routes pointing at a Service.
+// Routes are skipped silently on clusters without the OpenShift API.
+func (c *Client) serviceFrontends(namespace, service string) ([]interface{}, error) {
+	var related []interface{}
+	for _, source := range []struct {
+		gvr      schema.GroupVersionResource
+		backends func(map[string]interface{}) []string
+		optional bool
+	}{
+		{ingressGVR, ingressServices, false},
+		{routeGVR, routeServices, true},
+	} {
+		var list *unstructured.UnstructuredList
+		err := c.do(OpList, func(ctx context.Context) error {
+			var err error
+			list, err = c.DynamicClient.Resource(source.gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			if source.optional {
+				continue
+			}
+			return nil, err
+		}
+		for _, item := range list.Items {
+			for _, name := range source.backends(item.Object) {
+				if name == service {
+					related = append(related, item.Object)
+					break
+				}
+			}
+		}
+	}
+	return related, nil
+}
+
+// frontendServices returns the Services behind an Ingress or Route.
+func (c *Client) frontendServices(gvr schema.GroupVersionResource, namespace, name string) ([]interface{}, error) {
+	var obj *unstructured.Unstructured
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		obj, err = c.DynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	backends := ingressServices
+	if gvr == routeGVR {
+		backends = routeServices
+	}
+	var related []interface{}
+	seen := make(map[string]bool)
+	for _, svc := range backends(obj.Object) {
+		if seen[svc] {
+			continue
+		}
+		seen[svc] = true
+		res, err := c.GetResource("", "v1", "Service", "services", namespace, svc)
+		if err != nil {
+			continue
+		}
+		related = append(related, res)
+	}
+	return related, nil
+}
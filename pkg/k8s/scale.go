@@ -0,0 +1,32 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ScaleResource sets the replica count through the scale subresource, so
+// it works for Deployments, StatefulSets, ReplicaSets and any custom
+// resource that exposes /scale.
+func (c *Client) ScaleResource(group, version, plural, namespace, name string, replicas int) error {
+	if replicas < 0 {
+		return fmt.Errorf("replicas must not be negative, got %d", replicas)
+	}
+	ref := ResourceRef{Group: group, Version: version, Plural: plural, Namespace: namespace, Name: name}
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+
+	err := c.mutate(ActionScale, ref, fmt.Sprintf("scale %s/%s to %d", plural, name, replicas), func() error {
+		return c.do(OpMutate, func(ctx context.Context) error {
+			_, err := c.DynamicClient.Resource(ref.GVR()).Namespace(namespace).
+				Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}, "scale")
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scale %s/%s: %v", plural, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package k8s
+
+import (
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	slowScheduleThreshold = 10 * time.Second
+	slowReadyThreshold    = 2 * time.Minute
+	schedulingBucket      = time.Hour
+)
+
+type PodLatency struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	OwnerKind string    `json:"owner_kind"`
+	OwnerName string    `json:"owner_name"`
+	Node      string    `json:"node"`
+	Created   time.Time `json:"created"`
+	// ScheduleSeconds and ReadySeconds are measured from creation; -1
+	// means the pod hasn't reached that point yet.
+	ScheduleSeconds float64 `json:"schedule_seconds"`
+	ReadySeconds    float64 `json:"ready_seconds"`
+}
+
+type WorkloadLatency struct {
+	Namespace          string  `json:"namespace"`
+	OwnerKind          string  `json:"owner_kind"`
+	OwnerName          string  `json:"owner_name"`
+	Pods               int     `json:"pods"`
+	AvgScheduleSeconds float64 `json:"avg_schedule_seconds"`
+	MaxScheduleSeconds float64 `json:"max_schedule_seconds"`
+	AvgReadySeconds    float64 `json:"avg_ready_seconds"`
+	MaxReadySeconds    float64 `json:"max_ready_seconds"`
+	Slow               bool    `json:"slow"`
+	PendingOrNotReady  int     `json:"pending_or_not_ready"`
+}
+
+// workloadAccumulator sums latencies; averages only count pods that
+// reached the respective point.
+type workloadAccumulator struct {
+	WorkloadLatency
+	scheduled, ready int
+}
+
+type SchedulingPeriod struct {
+	Start              time.Time `json:"start"`
+	Pods               int       `json:"pods"`
+	AvgScheduleSeconds float64   `json:"avg_schedule_seconds"`
+	MaxScheduleSeconds float64   `json:"max_schedule_seconds"`
+	Slow               bool      `json:"slow"`
+}
+
+type SchedulingReport struct {
+	Namespace          string             `json:"namespace"`
+	Pods               []PodLatency       `json:"pods"`
+	Workloads          []WorkloadLatency  `json:"workloads"`
+	Periods            []SchedulingPeriod `json:"periods"`
+	P50ScheduleSeconds float64            `json:"p50_schedule_seconds"`
+	P95ScheduleSeconds float64            `json:"p95_schedule_seconds"`
+	P50ReadySeconds    float64            `json:"p50_ready_seconds"`
+	P95ReadySeconds    float64            `json:"p95_ready_seconds"`
+}
+
+// GetSchedulingLatency measures, for every pod in a namespace (all
+// namespaces when empty), the time from creation to PodScheduled and to
+// Ready, aggregated per owning workload and per hour of creation.
+func (c *Client) GetSchedulingLatency(namespace string) (*SchedulingReport, error) {
+	ctx, cancel := c.opContext(OpList)
+	defer cancel()
+
+	pods, err := c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// ReplicaSets are resolved to their Deployment so rollouts of the same
+	// workload aggregate together.
+	rsOwners := make(map[string]metav1.OwnerReference)
+	if rsList, err := c.Clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{}); err == nil {
+		for _, rs := range rsList.Items {
+			if owner := metav1.GetControllerOf(&rs); owner != nil {
+				rsOwners[rs.Namespace+"/"+rs.Name] = *owner
+			}
+		}
+	}
+
+	report := &SchedulingReport{Namespace: namespace, Pods: []PodLatency{}}
+	workloads := make(map[string]*workloadAccumulator)
+	periods := make(map[time.Time]*SchedulingPeriod)
+	var scheduleTimes, readyTimes []float64
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		entry := PodLatency{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			OwnerKind:       "Pod",
+			OwnerName:       pod.Name,
+			Node:            pod.Spec.NodeName,
+			Created:         pod.CreationTimestamp.Time,
+			ScheduleSeconds: -1,
+			ReadySeconds:    -1,
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil {
+			entry.OwnerKind, entry.OwnerName = owner.Kind, owner.Name
+			if owner.Kind == "ReplicaSet" {
+				if dep, ok := rsOwners[pod.Namespace+"/"+owner.Name]; ok {
+					entry.OwnerKind, entry.OwnerName = dep.Kind, dep.Name
+				}
+			}
+		}
+		if t := podConditionTime(pod, corev1.PodScheduled); !t.IsZero() {
+			entry.ScheduleSeconds = t.Sub(entry.Created).Seconds()
+			scheduleTimes = append(scheduleTimes, entry.ScheduleSeconds)
+		}
+		if t := podConditionTime(pod, corev1.PodReady); !t.IsZero() {
+			entry.ReadySeconds = t.Sub(entry.Created).Seconds()
+			readyTimes = append(readyTimes, entry.ReadySeconds)
+		}
+		report.Pods = append(report.Pods, entry)
+
+		key := entry.Namespace + "/" + entry.OwnerKind + "/" + entry.OwnerName
+		w, ok := workloads[key]
+		if !ok {
+			w = &workloadAccumulator{WorkloadLatency: WorkloadLatency{Namespace: entry.Namespace, OwnerKind: entry.OwnerKind, OwnerName: entry.OwnerName}}
+			workloads[key] = w
+		}
+		w.Pods++
+		if entry.ScheduleSeconds >= 0 {
+			w.scheduled++
+			w.AvgScheduleSeconds += entry.ScheduleSeconds
+			w.MaxScheduleSeconds = maxFloat(w.MaxScheduleSeconds, entry.ScheduleSeconds)
+		}
+		if entry.ReadySeconds >= 0 {
+			w.ready++
+			w.AvgReadySeconds += entry.ReadySeconds
+			w.MaxReadySeconds = maxFloat(w.MaxReadySeconds, entry.ReadySeconds)
+		} else {
+			w.PendingOrNotReady++
+		}
+
+		if entry.ScheduleSeconds >= 0 {
+			bucket := entry.Created.Truncate(schedulingBucket)
+			p, ok := periods[bucket]
+			if !ok {
+				p = &SchedulingPeriod{Start: bucket}
+				periods[bucket] = p
+			}
+			p.Pods++
+			p.AvgScheduleSeconds += entry.ScheduleSeconds
+			p.MaxScheduleSeconds = maxFloat(p.MaxScheduleSeconds, entry.ScheduleSeconds)
+		}
+	}
+
+	for _, w := range workloads {
+		if w.scheduled > 0 {
+			w.AvgScheduleSeconds /= float64(w.scheduled)
+		}
+		if w.ready > 0 {
+			w.AvgReadySeconds /= float64(w.ready)
+		}
+		w.Slow = w.AvgScheduleSeconds > slowScheduleThreshold.Seconds() ||
+			w.AvgReadySeconds > slowReadyThreshold.Seconds()
+		report.Workloads = append(report.Workloads, w.WorkloadLatency)
+	}
+	for _, p := range periods {
+		p.AvgScheduleSeconds /= float64(p.Pods)
+		p.Slow = p.AvgScheduleSeconds > slowScheduleThreshold.Seconds()
+		report.Periods = append(report.Periods, *p)
+	}
+
+	sort.Slice(report.Pods, func(i, j int) bool {
+		return report.Pods[i].Created.Before(report.Pods[j].Created)
+	})
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		return report.Workloads[i].AvgReadySeconds > report.Workloads[j].AvgReadySeconds
+	})
+	sort.Slice(report.Periods, func(i, j int) bool {
+		return report.Periods[i].Start.Before(report.Periods[j].Start)
+	})
+
+	report.P50ScheduleSeconds = percentile(scheduleTimes, 0.50)
+	report.P95ScheduleSeconds = percentile(scheduleTimes, 0.95)
+	report.P50ReadySeconds = percentile(readyTimes, 0.50)
+	report.P95ReadySeconds = percentile(readyTimes, 0.95)
+	return report, nil
+}
+
+// podConditionTime returns when a condition last became True, or the zero
+// time if it isn't True.
+func podConditionTime(pod *corev1.Pod, condType corev1.PodConditionType) time.Time {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == condType && cond.Status == corev1.ConditionTrue {
+			return cond.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
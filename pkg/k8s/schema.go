@@ -0,0 +1,273 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Where a ResourceSchema came from.
+const (
+	SchemaSourceOpenAPI = "openapi-v3"
+	SchemaSourceCRD     = "crd"
+)
+
+// SchemaNode is an OpenAPI v3 schema with its references resolved,
+// trimmed to what forms need to render, validate and document a field.
+type SchemaNode struct {
+	Type        string                 `json:"type,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Properties  map[string]*SchemaNode `json:"properties,omitempty"`
+	Items       *SchemaNode            `json:"items,omitempty"`
+	// AdditionalProperties is the schema of map values.
+	AdditionalProperties *SchemaNode   `json:"additional_properties,omitempty"`
+	Enum                 []interface{} `json:"enum,omitempty"`
+	Default              interface{}   `json:"default,omitempty"`
+	Nullable             bool          `json:"nullable,omitempty"`
+	Minimum              *float64      `json:"minimum,omitempty"`
+	Maximum              *float64      `json:"maximum,omitempty"`
+	MinLength            *int64        `json:"min_length,omitempty"`
+	MaxLength            *int64        `json:"max_length,omitempty"`
+	MinItems             *int64        `json:"min_items,omitempty"`
+	MaxItems             *int64        `json:"max_items,omitempty"`
+	Pattern              string        `json:"pattern,omitempty"`
+	IntOrString          bool          `json:"int_or_string,omitempty"`
+	// PreserveUnknownFields marks free-form objects, such as Helm values
+	// in a custom resource.
+	PreserveUnknownFields bool     `json:"preserve_unknown_fields,omitempty"`
+	EmbeddedResource      bool     `json:"embedded_resource,omitempty"`
+	ListType              string   `json:"list_type,omitempty"`
+	ListMapKeys           []string `json:"list_map_keys,omitempty"`
+	// Ref names the definition the node was resolved from, e.g.
+	// "io.k8s.api.core.v1.PodSpec".
+	Ref string `json:"ref,omitempty"`
+	// Recursive marks a reference to a definition already being expanded
+	// above it; it is left unexpanded.
+	Recursive bool `json:"recursive,omitempty"`
+}
+
+type ResourceSchema struct {
+	Group   string      `json:"group"`
+	Version string      `json:"version"`
+	Kind    string      `json:"kind"`
+	Source  string      `json:"source"`
+	Schema  *SchemaNode `json:"schema"`
+}
+
+// openAPISchema is the part of an OpenAPI v3 schema, or of a CRD's
+// openAPIV3Schema, that SchemaNode keeps.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref"`
+	AllOf                []*openAPISchema          `json:"allOf"`
+	Type                 string                    `json:"type"`
+	Format               string                    `json:"format"`
+	Description          string                    `json:"description"`
+	Required             []string                  `json:"required"`
+	Properties           map[string]*openAPISchema `json:"properties"`
+	Items                *openAPISchema            `json:"items"`
+	AdditionalProperties json.RawMessage           `json:"additionalProperties"`
+	Enum                 []interface{}             `json:"enum"`
+	Default              interface{}               `json:"default"`
+	Nullable             bool                      `json:"nullable"`
+	Minimum              *float64                  `json:"minimum"`
+	Maximum              *float64                  `json:"maximum"`
+	MinLength            *int64                    `json:"minLength"`
+	MaxLength            *int64                    `json:"maxLength"`
+	MinItems             *int64                    `json:"minItems"`
+	MaxItems             *int64                    `json:"maxItems"`
+	Pattern              string                    `json:"pattern"`
+	IntOrString          bool                      `json:"x-kubernetes-int-or-string"`
+	PreserveUnknown      bool                      `json:"x-kubernetes-preserve-unknown-fields"`
+	EmbeddedResource     bool                      `json:"x-kubernetes-embedded-resource"`
+	ListType             string                    `json:"x-kubernetes-list-type"`
+	ListMapKeys          []string                  `json:"x-kubernetes-list-map-keys"`
+	GroupVersionKinds    []struct {
+		Group   string `json:"group"`
+		Version string `json:"version"`
+		Kind    string `json:"kind"`
+	} `json:"x-kubernetes-group-version-kind"`
+}
+
+// GetResourceSchema returns the schema of a kind for form-based editing,
+// from the server's OpenAPI v3 document with references resolved. Custom
+// resources fall back to the structural schema in their CRD when the
+// server doesn't publish OpenAPI v3.
+func (c *Client) GetResourceSchema(group, version, kind string) (*ResourceSchema, error) {
+	if c.demo {
+		return nil, errors.New("schemas are not available for the demo context")
+	}
+	result := &ResourceSchema{Group: group, Version: version, Kind: kind}
+	node, openAPIErr := c.openAPISchema(group, version, kind)
+	if openAPIErr == nil {
+		result.Source, result.Schema = SchemaSourceOpenAPI, node
+		return result, nil
+	}
+	if group == "" {
+		return nil, openAPIErr
+	}
+	node, err := c.crdSchema(group, version, kind)
+	if err != nil {
+		return nil, fmt.Errorf("%v; %v", openAPIErr, err)
+	}
+	result.Source, result.Schema = SchemaSourceCRD, node
+	return result, nil
+}
+
+func (c *Client) openAPISchema(group, version, kind string) (*SchemaNode, error) {
+	path := "apis/" + group + "/" + version
+	if group == "" {
+		path = "api/" + version
+	}
+	var doc []byte
+	err := c.do(OpGet, func(ctx context.Context) error {
+		paths, err := c.DiscoveryClient.OpenAPIV3().Paths()
+		if err != nil {
+			return err
+		}
+		gv, ok := paths[path]
+		if !ok {
+			return fmt.Errorf("the server publishes no OpenAPI v3 schema for %s", strings.TrimPrefix(path, "apis/"))
+		}
+		doc, err = gv.Schema("application/json")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Components struct {
+			Schemas map[string]*openAPISchema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(doc, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document for %s: %v", path, err)
+	}
+	defs := parsed.Components.Schemas
+	for name, s := range defs {
+		for _, gvk := range s.GroupVersionKinds {
+			if gvk.Group == group && gvk.Version == version && gvk.Kind == kind {
+				return convertSchema(&openAPISchema{Ref: "#/components/schemas/" + name}, defs, map[string]bool{}), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("kind %s not found in the OpenAPI schema of %s", kind, strings.TrimPrefix(path, "apis/"))
+}
+
+// crdSchema reads the structural schema of a CRD version.
+func (c *Client) crdSchema(group, version, kind string) (*SchemaNode, error) {
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = c.DynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, crd := range list.Items {
+		crdGroup, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		crdKind, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "kind")
+		if crdGroup != group || crdKind != kind {
+			continue
+		}
+		versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+		for _, raw := range versions {
+			v, ok := raw.(map[string]interface{})
+			if !ok || v["name"] != version {
+				continue
+			}
+			props, found, _ := unstructured.NestedMap(v, "schema", "openAPIV3Schema")
+			if !found {
+				return nil, fmt.Errorf("CRD %s has no schema for version %s", crd.GetName(), version)
+			}
+			data, err := json.Marshal(props)
+			if err != nil {
+				return nil, err
+			}
+			var s openAPISchema
+			if err := json.Unmarshal(data, &s); err != nil {
+				return nil, fmt.Errorf("invalid schema in CRD %s: %v", crd.GetName(), err)
+			}
+			return convertSchema(&s, nil, map[string]bool{}), nil
+		}
+		return nil, fmt.Errorf("CRD %s doesn't serve version %s", crd.GetName(), version)
+	}
+	return nil, fmt.Errorf("no CRD defines %s.%s", kind, group)
+}
+
+// convertSchema resolves s against defs. expanding holds the definitions
+// being expanded on the current path, so self-referencing types such as
+// JSONSchemaProps end instead of recursing forever.
+func convertSchema(s *openAPISchema, defs map[string]*openAPISchema, expanding map[string]bool) *SchemaNode {
+	if s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		def, ok := defs[name]
+		if !ok || expanding[name] {
+			return &SchemaNode{Ref: name, Recursive: ok}
+		}
+		expanding[name] = true
+		node := convertSchema(def, defs, expanding)
+		delete(expanding, name)
+		node.Ref = name
+		return node
+	}
+	// Kubernetes wraps references in allOf to attach a description or
+	// default to them.
+	if len(s.AllOf) == 1 && s.Type == "" && len(s.Properties) == 0 {
+		node := convertSchema(s.AllOf[0], defs, expanding)
+		if s.Description != "" {
+			node.Description = s.Description
+		}
+		if s.Default != nil {
+			node.Default = s.Default
+		}
+		return node
+	}
+
+	node := &SchemaNode{
+		Type:                  s.Type,
+		Format:                s.Format,
+		Description:           s.Description,
+		Required:              s.Required,
+		Enum:                  s.Enum,
+		Default:               s.Default,
+		Nullable:              s.Nullable,
+		Minimum:               s.Minimum,
+		Maximum:               s.Maximum,
+		MinLength:             s.MinLength,
+		MaxLength:             s.MaxLength,
+		MinItems:              s.MinItems,
+		MaxItems:              s.MaxItems,
+		Pattern:               s.Pattern,
+		IntOrString:           s.IntOrString,
+		PreserveUnknownFields: s.PreserveUnknown,
+		EmbeddedResource:      s.EmbeddedResource,
+		ListType:              s.ListType,
+		ListMapKeys:           s.ListMapKeys,
+	}
+	if len(s.Properties) > 0 {
+		node.Properties = make(map[string]*SchemaNode, len(s.Properties))
+		for name, prop := range s.Properties {
+			node.Properties[name] = convertSchema(prop, defs, expanding)
+		}
+	}
+	if s.Items != nil {
+		node.Items = convertSchema(s.Items, defs, expanding)
+	}
+	// additionalProperties is either a schema or a boolean.
+	if len(s.AdditionalProperties) > 0 && s.AdditionalProperties[0] == '{' {
+		var additional openAPISchema
+		if err := json.Unmarshal(s.AdditionalProperties, &additional); err == nil {
+			node.AdditionalProperties = convertSchema(&additional, defs, expanding)
+		}
+	}
+	return node
+}
@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// resourceScopes caches whether resource types are namespaced, per group
+// version, as read from discovery.
+type resourceScopes struct {
+	mu         sync.Mutex
+	namespaced map[schema.GroupVersion]map[string]bool
+}
+
+func (s *resourceScopes) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.namespaced = nil
+}
+
+// isNamespaced reports whether gvr is namespaced. known is false when
+// discovery couldn't tell, in which case callers go ahead and let the API
+// server decide.
+func (c *Client) isNamespaced(gvr schema.GroupVersionResource) (namespaced, known bool) {
+	gv := gvr.GroupVersion()
+	s := &c.scopes
+	s.mu.Lock()
+	resources, ok := s.namespaced[gv]
+	s.mu.Unlock()
+
+	if !ok {
+		err := c.do(OpGet, func(ctx context.Context) error {
+			list, err := c.DiscoveryClient.ServerResourcesForGroupVersion(gv.String())
+			if err != nil {
+				return err
+			}
+			resources = make(map[string]bool, len(list.APIResources))
+			for _, res := range list.APIResources {
+				resources[res.Name] = res.Namespaced
+			}
+			return nil
+		})
+		if err != nil {
+			return false, false
+		}
+		s.mu.Lock()
+		if s.namespaced == nil {
+			s.namespaced = make(map[schema.GroupVersion]map[string]bool)
+		}
+		s.namespaced[gv] = resources
+		s.mu.Unlock()
+	}
+
+	namespaced, known = resources[gvr.Resource]
+	return namespaced, known
+}
+
+// checkScope rejects a namespace given for a cluster-scoped resource type
+// before the request is made, since the API server answers those with a
+// confusing 404. An empty namespace is valid for both scopes: it lists
+// namespaced types across all namespaces.
+func (c *Client) checkScope(gvr schema.GroupVersionResource, namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if namespaced, known := c.isNamespaced(gvr); known && !namespaced {
+		return fmt.Errorf("%s is cluster-scoped; use an empty namespace instead of %q", gvr.GroupResource().String(), namespace)
+	}
+	return nil
+}
+
+// ResourceScope tells whether a resource type is namespaced, as reported
+// by discovery.
+func (c *Client) ResourceScope(group, version, plural string) (bool, error) {
+	gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: plural}
+	namespaced, known := c.isNamespaced(gvr)
+	if !known {
+		return false, fmt.Errorf("resource type %s is not served by the cluster", gvr.String())
+	}
+	return namespaced, nil
+}
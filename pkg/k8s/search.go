@@ -0,0 +1,344 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	searchWorkers = 6
+	// searchCacheTTL is how long a listed resource type is searched from
+	// memory, so typing in the palette doesn't list on every keystroke.
+	searchCacheTTL   = 30 * time.Second
+	maxSearchMatches = 100
+)
+
+// defaultSearchKinds are searched when no kinds are given.
+var defaultSearchKinds = []string{
+	"pods", "deployments.apps", "statefulsets.apps", "daemonsets.apps",
+	"jobs.batch", "cronjobs.batch", "services", "ingresses.networking.k8s.io",
+	"configmaps", "secrets", "persistentvolumeclaims", "nodes", "namespaces",
+}
+
+// Fields a search term can match.
+const (
+	MatchName       = "name"
+	MatchLabel      = "label"
+	MatchAnnotation = "annotation"
+)
+
+type SearchMatch struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Plural    string `json:"plural"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Score     int    `json:"score"`
+	// MatchedOn is the best field matched; Match is the label or
+	// annotation ("key=value") when it wasn't the name.
+	MatchedOn string `json:"matched_on"`
+	Match     string `json:"match,omitempty"`
+}
+
+type SearchResult struct {
+	Query   string        `json:"query"`
+	Matches []SearchMatch `json:"matches"`
+	// Errors maps "<plural>[.<group>]", with "/<namespace>" for
+	// namespaced types, to why it couldn't be searched.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// searchEntry is the metadata of one object kept for searching.
+type searchEntry struct {
+	namespace, name string
+	labels          map[string]string
+	annotations     map[string]string
+}
+
+type searchList struct {
+	entries []searchEntry
+	listed  time.Time
+}
+
+// searchCache keeps recently listed resource types, keyed by GVR and
+// namespace.
+type searchCache struct {
+	mu    sync.Mutex
+	lists map[string]*searchList
+}
+
+func (s *searchCache) get(key string, now time.Time) []searchEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list, ok := s.lists[key]
+	if !ok || now.Sub(list.listed) > searchCacheTTL {
+		return nil
+	}
+	return list.entries
+}
+
+func (s *searchCache) set(key string, entries []searchEntry, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lists == nil {
+		s.lists = make(map[string]*searchList)
+	}
+	s.lists[key] = &searchList{entries: entries, listed: now}
+}
+
+func (s *searchCache) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists = nil
+}
+
+// SearchResources looks for objects whose name, labels or annotations
+// match every whitespace-separated term of query, across kinds (plural,
+// "plural.group", kind or short name; defaultSearchKinds when empty) in
+// the given namespaces (all when empty). Types are listed concurrently
+// and kept for searchCacheTTL. Matches are ranked, best first: exact
+// names, then name prefixes, substrings and fuzzy name matches, then
+// labels and annotations.
+func (c *Client) SearchResources(query string, namespaces, kinds []string) (*SearchResult, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	result := &SearchResult{Query: query, Matches: []SearchMatch{}}
+	if len(terms) == 0 {
+		return result, nil
+	}
+	if len(kinds) == 0 {
+		kinds = defaultSearchKinds
+	}
+	resources, err := c.GetApiResources()
+	if err != nil {
+		return nil, err
+	}
+	types, err := resolveSearchKinds(resources, kinds)
+	if err != nil {
+		return nil, err
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	type job struct {
+		res       ApiResourceInfo
+		namespace string
+	}
+	var jobs []job
+	for _, res := range types {
+		if !res.Namespaced {
+			jobs = append(jobs, job{res, ""})
+			continue
+		}
+		for _, ns := range namespaces {
+			jobs = append(jobs, job{res, ns})
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, searchWorkers)
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			entries, err := c.searchEntries(j.res, j.namespace)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if result.Errors == nil {
+					result.Errors = make(map[string]string)
+				}
+				key := j.res.Name
+				if j.res.Group != "" {
+					key += "." + j.res.Group
+				}
+				if j.namespace != "" {
+					key += "/" + j.namespace
+				}
+				result.Errors[key] = err.Error()
+				return
+			}
+			for _, e := range entries {
+				if m, ok := matchSearchEntry(e, terms); ok {
+					m.Group, m.Version, m.Kind, m.Plural = j.res.Group, j.res.Version, j.res.Kind, j.res.Name
+					result.Matches = append(result.Matches, m)
+				}
+			}
+		}(j)
+	}
+	wg.Wait()
+
+	sort.Slice(result.Matches, func(i, j int) bool {
+		a, b := result.Matches[i], result.Matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Name) != len(b.Name) {
+			return len(a.Name) < len(b.Name)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	if len(result.Matches) > maxSearchMatches {
+		result.Matches = result.Matches[:maxSearchMatches]
+	}
+	return result, nil
+}
+
+// resolveSearchKinds maps the kinds of a search to listable resource
+// types, one version per type.
+func resolveSearchKinds(resources []ApiResourceInfo, kinds []string) ([]ApiResourceInfo, error) {
+	seen := make(map[string]bool)
+	var types []ApiResourceInfo
+	for _, kind := range kinds {
+		want := strings.ToLower(kind)
+		found := false
+		for _, res := range resources {
+			if strings.Contains(res.Name, "/") {
+				continue
+			}
+			full := res.Name
+			if res.Group != "" {
+				full += "." + res.Group
+			}
+			if want != full && want != res.Name && want != strings.ToLower(res.Kind) && !contains(res.ShortNames, want) {
+				continue
+			}
+			found = true
+			key := res.Group + "/" + res.Name
+			if !seen[key] {
+				seen[key] = true
+				types = append(types, res)
+			}
+		}
+		// The defaults may name types a cluster doesn't serve.
+		if !found && !contains(defaultSearchKinds, kind) {
+			return nil, fmt.Errorf("unknown resource type %q", kind)
+		}
+	}
+	return types, nil
+}
+
+// searchEntries returns the metadata of every object of a type, from the
+// cache when it is fresh.
+func (c *Client) searchEntries(res ApiResourceInfo, namespace string) ([]searchEntry, error) {
+	gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}
+	key := gvr.String() + "/" + namespace
+	now := time.Now()
+	if entries := c.search.get(key, now); entries != nil {
+		return entries, nil
+	}
+
+	entries := []searchEntry{}
+	err := c.do(OpList, func(ctx context.Context) error {
+		list, err := c.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		entries = entries[:0]
+		for _, item := range list.Items {
+			entries = append(entries, searchEntry{
+				namespace:   item.GetNamespace(),
+				name:        item.GetName(),
+				labels:      item.GetLabels(),
+				annotations: item.GetAnnotations(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.search.set(key, entries, now)
+	return entries, nil
+}
+
+// matchSearchEntry scores an object against lowercase terms; every term
+// must match somewhere.
+func matchSearchEntry(e searchEntry, terms []string) (SearchMatch, bool) {
+	m := SearchMatch{Namespace: e.namespace, Name: e.name, MatchedOn: MatchName}
+	name := strings.ToLower(e.name)
+	bestField := 0
+	for _, term := range terms {
+		score, field, match := nameScore(name, term), MatchName, ""
+		if score < 40 {
+			for k, v := range e.labels {
+				if s := metadataScore(k, v, term, 40); s > score {
+					score, field, match = s, MatchLabel, k+"="+v
+				}
+			}
+		}
+		if score < 20 {
+			for k, v := range e.annotations {
+				if s := metadataScore(k, v, term, 20); s > score {
+					score, field, match = s, MatchAnnotation, k+"="+v
+				}
+			}
+		}
+		if score == 0 {
+			return m, false
+		}
+		m.Score += score
+		if score > bestField {
+			bestField = score
+			m.MatchedOn, m.Match = field, match
+		}
+	}
+	return m, true
+}
+
+func nameScore(name, term string) int {
+	switch {
+	case name == term:
+		return 100
+	case strings.HasPrefix(name, term):
+		return 80
+	case strings.Contains(name, term):
+		return 60
+	case len(term) >= 3 && fuzzyMatch(name, term):
+		return 30
+	}
+	return 0
+}
+
+// metadataScore scores a label or annotation: an exact value or
+// "key=value" match scores top, a substring of either half as much.
+func metadataScore(key, value, term string, top int) int {
+	key, value = strings.ToLower(key), strings.ToLower(value)
+	switch {
+	case value == term, key+"="+value == term:
+		return top
+	case strings.Contains(value, term), strings.Contains(key, term):
+		return top / 2
+	}
+	return 0
+}
+
+// fuzzyMatch reports whether the characters of term appear in s in order,
+// so "ngxdep" finds "nginx-deployment".
+func fuzzyMatch(s, term string) bool {
+	i := 0
+	for _, r := range s {
+		if i < len(term) && rune(term[i]) == r {
+			i++
+		}
+	}
+	return i == len(term)
+}
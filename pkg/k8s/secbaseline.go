@@ -0,0 +1,248 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SecurityBaseline is the container securityContext a team expects. Nil
+// and empty fields aren't checked.
+type SecurityBaseline struct {
+	RunAsNonRoot             *bool `json:"run_as_non_root"`
+	ReadOnlyRootFilesystem   *bool `json:"read_only_root_filesystem"`
+	AllowPrivilegeEscalation *bool `json:"allow_privilege_escalation"`
+	Privileged               *bool `json:"privileged"`
+	// DropCapabilities must all be dropped, e.g. ["ALL"].
+	DropCapabilities []string `json:"drop_capabilities"`
+	// AllowedCapabilities are the only capabilities that may be added.
+	// Adding any is a deviation when it's empty.
+	AllowedCapabilities []string `json:"allowed_capabilities"`
+	// SeccompProfile is the required profile type, "RuntimeDefault" or
+	// "Localhost".
+	SeccompProfile string `json:"seccomp_profile"`
+}
+
+// SecurityDeviation is a container field that doesn't match the baseline.
+// Actual is empty when the field is unset.
+type SecurityDeviation struct {
+	Container string `json:"container"`
+	Field     string `json:"field"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+}
+
+// WorkloadSecurityReport lists a workload's deviations. Patch is a
+// strategic merge patch for PatchResource that sets the baseline values
+// on the offending containers.
+type WorkloadSecurityReport struct {
+	Target     ResourceRef         `json:"target"`
+	Deviations []SecurityDeviation `json:"deviations"`
+	PatchType  string              `json:"patch_type,omitempty"`
+	Patch      string              `json:"patch,omitempty"`
+}
+
+// GetSecurityBaselineReport compares the containers of the workloads in a
+// namespace (all when empty) with the baseline. Pod-level settings count
+// where Kubernetes inherits them: runAsNonRoot and the seccomp profile.
+// Only workloads with deviations are returned; Jobs created by CronJobs
+// are covered by their CronJob.
+func (c *Client) GetSecurityBaselineReport(namespace string, baseline SecurityBaseline) ([]WorkloadSecurityReport, error) {
+	result := []WorkloadSecurityReport{}
+	for _, w := range taxonomyWorkloads {
+		var list *unstructured.UnstructuredList
+		err := c.do(OpList, func(ctx context.Context) error {
+			var err error
+			list, err = c.DynamicClient.Resource(w.gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		path := podTemplatePath(w.kind)
+		for _, item := range list.Items {
+			if metav1.GetControllerOfNoCopy(&item) != nil {
+				continue
+			}
+			raw, found, _ := unstructured.NestedMap(item.Object, path...)
+			if !found {
+				continue
+			}
+			var spec corev1.PodSpec
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &spec); err != nil {
+				continue
+			}
+
+			report := WorkloadSecurityReport{
+				Target: ResourceRef{
+					Group:     w.gvr.Group,
+					Version:   w.gvr.Version,
+					Kind:      w.kind,
+					Plural:    w.gvr.Resource,
+					Namespace: item.GetNamespace(),
+					Name:      item.GetName(),
+				},
+				Deviations: []SecurityDeviation{},
+			}
+			fixes := make(map[string][]interface{})
+			for field, containers := range map[string][]corev1.Container{"initContainers": spec.InitContainers, "containers": spec.Containers} {
+				for i := range containers {
+					deviations, fix := checkSecurityContext(&containers[i], spec.SecurityContext, baseline)
+					if len(deviations) == 0 {
+						continue
+					}
+					report.Deviations = append(report.Deviations, deviations...)
+					fixes[field] = append(fixes[field], map[string]interface{}{"name": containers[i].Name, "securityContext": fix})
+				}
+			}
+			if len(report.Deviations) == 0 {
+				continue
+			}
+			sort.SliceStable(report.Deviations, func(i, j int) bool {
+				if report.Deviations[i].Container != report.Deviations[j].Container {
+					return report.Deviations[i].Container < report.Deviations[j].Container
+				}
+				return report.Deviations[i].Field < report.Deviations[j].Field
+			})
+
+			patch := map[string]interface{}{}
+			for field, list := range fixes {
+				_ = unstructured.SetNestedField(patch, list, append(path, field)...)
+			}
+			if data, err := json.Marshal(patch); err == nil {
+				report.PatchType = string(types.StrategicMergePatchType)
+				report.Patch = string(data)
+			}
+			result = append(result, report)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Target, result[j].Target
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return result, nil
+}
+
+// checkSecurityContext returns the deviations of a container and the
+// securityContext fields that fix them.
+func checkSecurityContext(ctr *corev1.Container, pod *corev1.PodSecurityContext, baseline SecurityBaseline) ([]SecurityDeviation, map[string]interface{}) {
+	sc := ctr.SecurityContext
+	if sc == nil {
+		sc = &corev1.SecurityContext{}
+	}
+	if pod == nil {
+		pod = &corev1.PodSecurityContext{}
+	}
+	var deviations []SecurityDeviation
+	fix := map[string]interface{}{}
+	deviate := func(field, expected, actual string, value interface{}) {
+		deviations = append(deviations, SecurityDeviation{Container: ctr.Name, Field: "securityContext." + field, Expected: expected, Actual: actual})
+		fix[field] = value
+	}
+	checkBool := func(field string, want *bool, values ...*bool) {
+		if want == nil {
+			return
+		}
+		// The first set value wins: the container's, then the pod's.
+		for _, v := range values {
+			if v != nil {
+				if *v != *want {
+					deviate(field, fmt.Sprint(*want), fmt.Sprint(*v), *want)
+				}
+				return
+			}
+		}
+		deviate(field, fmt.Sprint(*want), "", *want)
+	}
+
+	checkBool("runAsNonRoot", baseline.RunAsNonRoot, sc.RunAsNonRoot, pod.RunAsNonRoot)
+	checkBool("readOnlyRootFilesystem", baseline.ReadOnlyRootFilesystem, sc.ReadOnlyRootFilesystem)
+	checkBool("allowPrivilegeEscalation", baseline.AllowPrivilegeEscalation, sc.AllowPrivilegeEscalation)
+	checkBool("privileged", baseline.Privileged, sc.Privileged)
+
+	if baseline.SeccompProfile != "" {
+		profile := sc.SeccompProfile
+		if profile == nil {
+			profile = pod.SeccompProfile
+		}
+		actual := ""
+		if profile != nil {
+			actual = string(profile.Type)
+		}
+		if actual != baseline.SeccompProfile {
+			deviate("seccompProfile", baseline.SeccompProfile, actual, map[string]interface{}{"type": baseline.SeccompProfile})
+		}
+	}
+
+	var dropped, added []string
+	if sc.Capabilities != nil {
+		for _, name := range sc.Capabilities.Drop {
+			dropped = append(dropped, string(name))
+		}
+		for _, name := range sc.Capabilities.Add {
+			added = append(added, string(name))
+		}
+	}
+	capabilities := map[string]interface{}{}
+	var missing []string
+	for _, name := range baseline.DropCapabilities {
+		if !containsFold(dropped, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		deviations = append(deviations, SecurityDeviation{Container: ctr.Name, Field: "securityContext.capabilities.drop", Expected: strings.Join(baseline.DropCapabilities, ","), Actual: strings.Join(dropped, ",")})
+		capabilities["drop"] = toInterfaces(append(dropped, missing...))
+	}
+	var allowed, forbidden []string
+	for _, name := range added {
+		if containsFold(baseline.AllowedCapabilities, name) {
+			allowed = append(allowed, name)
+		} else {
+			forbidden = append(forbidden, name)
+		}
+	}
+	if len(forbidden) > 0 {
+		deviations = append(deviations, SecurityDeviation{Container: ctr.Name, Field: "securityContext.capabilities.add", Expected: strings.Join(baseline.AllowedCapabilities, ","), Actual: strings.Join(added, ",")})
+		capabilities["add"] = toInterfaces(allowed)
+	}
+	if len(capabilities) > 0 {
+		fix["capabilities"] = capabilities
+	}
+	return deviations, fix
+}
+
+// containsFold is contains for capability names, which are matched
+// case-insensitively and with or without the CAP_ prefix.
+func containsFold(list []string, s string) bool {
+	s = strings.TrimPrefix(strings.ToUpper(s), "CAP_")
+	for _, v := range list {
+		if strings.TrimPrefix(strings.ToUpper(v), "CAP_") == s {
+			return true
+		}
+	}
+	return false
+}
+
+func toInterfaces(list []string) []interface{} {
+	out := make([]interface{}, 0, len(list))
+	for _, s := range list {
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SecretKey describes one entry of a Secret. Value is only set for keys
+// that were asked to be revealed; binary values are returned base64
+// encoded.
+type SecretKey struct {
+	Key      string `json:"key"`
+	Size     int    `json:"size"`
+	Binary   bool   `json:"binary"`
+	Revealed bool   `json:"revealed"`
+	Value    string `json:"value,omitempty"`
+}
+
+type SecretData struct {
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Keys      []SecretKey `json:"keys"`
+}
+
+// GetSecretData lists a Secret's keys with their decoded size and whether
+// they hold binary data. Only the keys named in reveal carry a value, so
+// the UI can show one value at a time.
+func (c *Client) GetSecretData(namespace, name string, reveal []string) (*SecretData, error) {
+	secret, err := c.getSecret(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	show := make(map[string]bool, len(reveal))
+	for _, k := range reveal {
+		show[k] = true
+	}
+
+	result := &SecretData{Namespace: namespace, Name: name, Type: string(secret.Type), Keys: []SecretKey{}}
+	for key, value := range secret.Data {
+		entry := SecretKey{Key: key, Size: len(value), Binary: isBinary(value)}
+		if show[key] {
+			entry.Revealed = true
+			entry.Value = secretValueString(value, entry.Binary)
+		}
+		result.Keys = append(result.Keys, entry)
+	}
+	sort.Slice(result.Keys, func(i, j int) bool { return result.Keys[i].Key < result.Keys[j].Key })
+	return result, nil
+}
+
+// GetSecretValue returns a single decoded value, e.g. for copying it to
+// the clipboard. Binary values are returned base64 encoded.
+func (c *Client) GetSecretValue(namespace, name, key string) (string, error) {
+	secret, err := c.getSecret(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %q", namespace, name, key)
+	}
+	return secretValueString(value, isBinary(value)), nil
+}
+
+func (c *Client) getSecret(namespace, name string) (*corev1.Secret, error) {
+	var secret *corev1.Secret
+	err := c.do(OpGet, func(ctx context.Context) error {
+		var err error
+		secret, err = c.Clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	return secret, err
+}
+
+func secretValueString(value []byte, binary bool) string {
+	if binary {
+		return base64.StdEncoding.EncodeToString(value)
+	}
+	return string(value)
+}
+
+// isBinary reports whether a value isn't printable text.
+func isBinary(value []byte) bool {
+	if !utf8.Valid(value) {
+		return true
+	}
+	for _, r := range string(value) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecret blanks the values of a core/v1 Secret in place, keeping its
+// keys, so generic resource responses never carry secret values. Other
+// objects are left alone.
+func RedactSecret(obj map[string]interface{}) {
+	if obj["kind"] != "Secret" || obj["apiVersion"] != "v1" {
+		return
+	}
+	for _, field := range []string{"data", "stringData"} {
+		if data, ok := obj[field].(map[string]interface{}); ok {
+			for key := range data {
+				data[key] = ""
+			}
+		}
+	}
+	// The last-applied annotation may hold the values as well.
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, corev1.LastAppliedConfigAnnotation)
+		}
+	}
+}
+
+// restoreRedactedSecret undoes RedactSecret on an edited copy of a Secret
+// before it is written back: blank data values of keys the live object has
+// are taken from it, as is the last-applied annotation. A key can't be
+// emptied through the editor this way, only removed.
+func restoreRedactedSecret(edited, live map[string]interface{}) {
+	if edited["kind"] != "Secret" || edited["apiVersion"] != "v1" {
+		return
+	}
+	liveData, _, _ := unstructured.NestedMap(live, "data")
+	if data, ok := edited["data"].(map[string]interface{}); ok {
+		for key, v := range data {
+			if v == "" {
+				if value, ok := liveData[key]; ok {
+					data[key] = value
+				}
+			}
+		}
+	}
+	if applied, found, _ := unstructured.NestedString(live, "metadata", "annotations", corev1.LastAppliedConfigAnnotation); found {
+		if _, found, _ := unstructured.NestedString(edited, "metadata", "annotations", corev1.LastAppliedConfigAnnotation); !found {
+			_ = unstructured.SetNestedField(edited, applied, "metadata", "annotations", corev1.LastAppliedConfigAnnotation)
+		}
+	}
+}
+
+// redactSecretDiffs drops the values from the diffs of a Secret's data,
+// keeping the paths, so a diff doesn't give away what RedactSecret hid.
+func redactSecretDiffs(ref ResourceRef, diffs []FieldDiff) []FieldDiff {
+	if ref.Kind != "Secret" || ref.Group != "" {
+		return diffs
+	}
+	for i, d := range diffs {
+		switch {
+		case d.Path == "data", strings.HasPrefix(d.Path, "data."),
+			d.Path == "stringData", strings.HasPrefix(d.Path, "stringData."),
+			d.Path == "metadata.annotations", d.Path == "metadata.annotations."+corev1.LastAppliedConfigAnnotation:
+			diffs[i].Old, diffs[i].New = nil, nil
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRestoreRedactedSecret(t *testing.T) {
+	live := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":        "creds",
+			"annotations": map[string]interface{}{corev1.LastAppliedConfigAnnotation: "{}"},
+		},
+		"data": map[string]interface{}{"user": "YWRtaW4=", "password": "aHVudGVyMg==", "old": "eA=="},
+	}
+	edited := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "creds"},
+		"data":       map[string]interface{}{"user": "", "password": "bmV3", "added": ""},
+	}
+	restoreRedactedSecret(edited, live)
+
+	want := map[string]interface{}{"user": "YWRtaW4=", "password": "bmV3", "added": ""}
+	if got := edited["data"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+	annotations := edited["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	if annotations[corev1.LastAppliedConfigAnnotation] != "{}" {
+		t.Errorf("last-applied annotation wasn't restored: %v", annotations)
+	}
+
+	diffs := redactSecretDiffs(ResourceRef{Version: "v1", Kind: "Secret"}, DiffObjects(live, edited))
+	for _, d := range diffs {
+		if d.Old != nil || d.New != nil {
+			t.Errorf("diff of %s carries values: %+v", d.Path, d)
+		}
+	}
+	if len(diffs) != 3 {
+		t.Errorf("got %d diffs, want 3 (password changed, old removed, added added): %+v", len(diffs), diffs)
+	}
+}
@@ -0,0 +1,283 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ServicePortMapping is a Service port and where it leads. EndpointPort
+// is the port published in the EndpointSlices for it, 0 while no slice
+// carries it.
+type ServicePortMapping struct {
+	Name         string `json:"name"`
+	Protocol     string `json:"protocol"`
+	Port         int32  `json:"port"`
+	TargetPort   string `json:"target_port"`
+	NodePort     int32  `json:"node_port,omitempty"`
+	EndpointPort int32  `json:"endpoint_port,omitempty"`
+}
+
+// ServiceEndpoint is an endpoint of an EndpointSlice. Ready and Serving
+// follow the EndpointSlice conditions, which count an unset condition as
+// true; Serving may stay true while a pod terminates.
+type ServiceEndpoint struct {
+	Addresses   []string `json:"addresses"`
+	Ready       bool     `json:"ready"`
+	Serving     bool     `json:"serving"`
+	Terminating bool     `json:"terminating"`
+	Pod         string   `json:"pod,omitempty"`
+	Node        string   `json:"node,omitempty"`
+	Zone        string   `json:"zone,omitempty"`
+}
+
+type EndpointSlicePort struct {
+	Name     string `json:"name"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+type ServiceEndpointSlice struct {
+	Name        string              `json:"name"`
+	AddressType string              `json:"address_type"`
+	Ports       []EndpointSlicePort `json:"ports"`
+	Endpoints   []ServiceEndpoint   `json:"endpoints"`
+}
+
+// ServiceBackendPod is a pod matching the Service selector. Reason says
+// why it isn't a ready endpoint, and is empty when it is one.
+type ServiceBackendPod struct {
+	Name        string `json:"name"`
+	Phase       string `json:"phase"`
+	IP          string `json:"ip"`
+	Node        string `json:"node"`
+	Ready       bool   `json:"ready"`
+	InEndpoints bool   `json:"in_endpoints"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type ServiceBackends struct {
+	Namespace      string                 `json:"namespace"`
+	Name           string                 `json:"name"`
+	Type           string                 `json:"type"`
+	Selector       map[string]string      `json:"selector"`
+	Ports          []ServicePortMapping   `json:"ports"`
+	Slices         []ServiceEndpointSlice `json:"slices"`
+	Pods           []ServiceBackendPod    `json:"pods"`
+	ReadyEndpoints int                    `json:"ready_endpoints"`
+	// Issues explain a Service without ready endpoints: no selected pods,
+	// pods failing readiness, or target ports the pods don't declare.
+	Issues []string `json:"issues"`
+}
+
+// GetServiceBackends resolves a Service to its EndpointSlices and the pods
+// its selector matches, with the readiness of every endpoint and how each
+// Service port maps to the pods.
+func (c *Client) GetServiceBackends(namespace, name string) (*ServiceBackends, error) {
+	var svc *corev1.Service
+	var slices *discoveryv1.EndpointSliceList
+	var pods *corev1.PodList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if svc, err = c.Clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			return err
+		}
+		if slices, err = c.Clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: name}).String(),
+		}); err != nil {
+			return err
+		}
+		if len(svc.Spec.Selector) == 0 {
+			pods = &corev1.PodList{}
+			return nil
+		}
+		pods, err = c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(svc.Spec.Selector).String(),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	backends := &ServiceBackends{
+		Namespace: namespace,
+		Name:      name,
+		Type:      string(svc.Spec.Type),
+		Selector:  svc.Spec.Selector,
+		Ports:     []ServicePortMapping{},
+		Slices:    []ServiceEndpointSlice{},
+		Pods:      []ServiceBackendPod{},
+		Issues:    []string{},
+	}
+	if backends.Selector == nil {
+		backends.Selector = map[string]string{}
+	}
+
+	endpointPorts := make(map[string]int32)
+	readyPods := make(map[string]bool)
+	inEndpoints := make(map[string]bool)
+	for _, slice := range slices.Items {
+		s := ServiceEndpointSlice{
+			Name:        slice.Name,
+			AddressType: string(slice.AddressType),
+			Ports:       []EndpointSlicePort{},
+			Endpoints:   []ServiceEndpoint{},
+		}
+		for _, p := range slice.Ports {
+			port := EndpointSlicePort{}
+			if p.Name != nil {
+				port.Name = *p.Name
+			}
+			if p.Port != nil {
+				port.Port = *p.Port
+				endpointPorts[port.Name] = *p.Port
+			}
+			if p.Protocol != nil {
+				port.Protocol = string(*p.Protocol)
+			}
+			s.Ports = append(s.Ports, port)
+		}
+		for _, ep := range slice.Endpoints {
+			e := ServiceEndpoint{
+				Addresses:   ep.Addresses,
+				Ready:       ep.Conditions.Ready == nil || *ep.Conditions.Ready,
+				Terminating: ep.Conditions.Terminating != nil && *ep.Conditions.Terminating,
+			}
+			e.Serving = e.Ready
+			if ep.Conditions.Serving != nil {
+				e.Serving = *ep.Conditions.Serving
+			}
+			if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+				e.Pod = ep.TargetRef.Name
+				inEndpoints[e.Pod] = true
+				if e.Ready {
+					readyPods[e.Pod] = true
+				}
+			}
+			if ep.NodeName != nil {
+				e.Node = *ep.NodeName
+			}
+			if ep.Zone != nil {
+				e.Zone = *ep.Zone
+			}
+			if e.Ready {
+				backends.ReadyEndpoints++
+			}
+			s.Endpoints = append(s.Endpoints, e)
+		}
+		backends.Slices = append(backends.Slices, s)
+	}
+	sort.Slice(backends.Slices, func(i, j int) bool { return backends.Slices[i].Name < backends.Slices[j].Name })
+
+	for _, p := range svc.Spec.Ports {
+		backends.Ports = append(backends.Ports, ServicePortMapping{
+			Name:         p.Name,
+			Protocol:     string(p.Protocol),
+			Port:         p.Port,
+			TargetPort:   p.TargetPort.String(),
+			NodePort:     p.NodePort,
+			EndpointPort: endpointPorts[p.Name],
+		})
+	}
+
+	// Pods lacking the named port a Service port targets, per port.
+	missingPorts := make(map[int]int)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		bp := ServiceBackendPod{
+			Name:        pod.Name,
+			Phase:       string(pod.Status.Phase),
+			IP:          pod.Status.PodIP,
+			Node:        pod.Spec.NodeName,
+			Ready:       isPodReady(pod),
+			InEndpoints: inEndpoints[pod.Name],
+		}
+		if !readyPods[pod.Name] {
+			bp.Reason = podNotReadyReason(pod)
+		}
+		for j, p := range svc.Spec.Ports {
+			if _, err := containerPortFor(pod, p.TargetPort, int(p.Port)); err != nil {
+				bp.Reason = strings.TrimPrefix(bp.Reason+"; "+err.Error(), "; ")
+				missingPorts[j]++
+			}
+		}
+		backends.Pods = append(backends.Pods, bp)
+	}
+	sort.Slice(backends.Pods, func(i, j int) bool { return backends.Pods[i].Name < backends.Pods[j].Name })
+	for j, p := range svc.Spec.Ports {
+		if n := missingPorts[j]; n > 0 {
+			backends.Issues = append(backends.Issues, fmt.Sprintf("service port %s targets port %q, which %d of %d pods don't declare", portLabel(p), p.TargetPort.String(), n, len(pods.Items)))
+		}
+	}
+
+	switch {
+	case svc.Spec.Type == corev1.ServiceTypeExternalName:
+		backends.Issues = append(backends.Issues, fmt.Sprintf("ExternalName service resolves to %s and has no endpoints", svc.Spec.ExternalName))
+	case len(svc.Spec.Selector) == 0 && len(slices.Items) == 0:
+		backends.Issues = append(backends.Issues, "service has no selector and no manually managed EndpointSlices")
+	case len(svc.Spec.Selector) > 0 && len(pods.Items) == 0:
+		backends.Issues = append(backends.Issues, fmt.Sprintf("selector %s matches no pods", labels.SelectorFromSet(svc.Spec.Selector)))
+	case backends.ReadyEndpoints == 0 && len(pods.Items) > 0:
+		notReady := 0
+		for _, p := range backends.Pods {
+			if !p.Ready {
+				notReady++
+			}
+		}
+		if notReady > 0 {
+			backends.Issues = append(backends.Issues, fmt.Sprintf("%d of %d selected pods aren't ready", notReady, len(pods.Items)))
+		} else {
+			backends.Issues = append(backends.Issues, "selected pods are ready but not published in any EndpointSlice yet")
+		}
+	}
+	return backends, nil
+}
+
+// podNotReadyReason explains why a pod isn't ready, from its phase, its
+// Ready condition or its containers' states.
+func podNotReadyReason(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "terminating"
+	}
+	if pod.Status.Phase != corev1.PodRunning {
+		if pod.Status.Reason != "" {
+			return string(pod.Status.Phase) + ": " + pod.Status.Reason
+		}
+		return string(pod.Status.Phase)
+	}
+	var waiting []string
+	for _, cs := range pod.Status.ContainerStatuses {
+		switch {
+		case cs.State.Waiting != nil:
+			waiting = append(waiting, fmt.Sprintf("%s %s", cs.Name, cs.State.Waiting.Reason))
+		case !cs.Ready:
+			waiting = append(waiting, cs.Name+" not ready")
+		}
+	}
+	if len(waiting) > 0 {
+		return strings.Join(waiting, ", ")
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status != corev1.ConditionTrue && cond.Message != "" {
+			return cond.Message
+		}
+	}
+	if !isPodReady(pod) {
+		return "not ready"
+	}
+	return "not in any EndpointSlice"
+}
+
+func portLabel(p corev1.ServicePort) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprint(p.Port)
+}
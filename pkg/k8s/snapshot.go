@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// snapshotSkipped are namespaced types that are pure runtime state and
+// not worth keeping in a snapshot.
+var snapshotSkipped = map[string]bool{
+	"events":                          true,
+	"events.events.k8s.io":            true,
+	"endpoints":                       true,
+	"endpointslices.discovery.k8s.io": true,
+	"leases.coordination.k8s.io":      true,
+}
+
+type NamespaceSnapshot struct {
+	Namespace string `json:"namespace"`
+	// Dir is the directory or zip archive written.
+	Dir     string `json:"dir"`
+	Objects int    `json:"objects"`
+	// Errors lists resource types that couldn't be listed or written.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// SnapshotNamespace writes every object of a namespace as a portable
+// manifest to dir, one file per object under a directory per resource
+// type. Objects with a controller are skipped since their owner recreates
+// them, and Secret values are redacted so no credentials end up on disk.
+func (c *Client) SnapshotNamespace(namespace, dir string) (*NamespaceSnapshot, error) {
+	return c.ExportNamespaceManifests(namespace, dir, true)
+}
+
+// ExportNamespaceManifests writes a namespace like SnapshotNamespace, to
+// seed a Git repository. A path ending in .zip is written as a zip archive
+// with the same layout instead of a directory. cleaned is as for
+// ExportResource.
+func (c *Client) ExportNamespaceManifests(namespace, path string, cleaned bool) (*NamespaceSnapshot, error) {
+	// Only the preferred version of a kind is written, since every
+	// version lists the same objects.
+	resources, err := c.preferredApiResources()
+	if err != nil {
+		return nil, err
+	}
+
+	var write func(name string, data []byte) error
+	var archive *zip.Writer
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		archive = zip.NewWriter(f)
+		write = func(name string, data []byte) error {
+			w, err := archive.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			return nil, err
+		}
+		write = func(name string, data []byte) error {
+			file := filepath.Join(path, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+				return err
+			}
+			return os.WriteFile(file, data, 0o600)
+		}
+	}
+
+	snapshot := &NamespaceSnapshot{Namespace: namespace, Dir: path, Errors: make(map[string]string)}
+	for _, res := range resources {
+		resource := res.Name
+		if res.Group != "" {
+			resource += "." + res.Group
+		}
+		if !res.Namespaced || snapshotSkipped[resource] || !contains(res.Verbs, "get") {
+			continue
+		}
+		gvr := schema.GroupVersionResource{Group: res.Group, Version: res.Version, Resource: res.Name}
+		var list *unstructured.UnstructuredList
+		err := c.do(OpList, func(ctx context.Context) error {
+			var err error
+			list, err = c.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			snapshot.Errors[resource] = err.Error()
+			continue
+		}
+
+		for _, item := range list.Items {
+			if metav1.GetControllerOf(&item) != nil {
+				continue
+			}
+			data, err := exportManifest(item.Object, cleaned)
+			if err == nil {
+				err = write(resource+"/"+item.GetName()+".yaml", data)
+			}
+			if err != nil {
+				snapshot.Errors[resource] = fmt.Sprintf("%s: %v", item.GetName(), err)
+				continue
+			}
+			snapshot.Objects++
+		}
+	}
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if len(snapshot.Errors) == 0 {
+		snapshot.Errors = nil
+	}
+	return snapshot, nil
+}
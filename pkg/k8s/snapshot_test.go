@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// twoVersionClient serves HorizontalPodAutoscalers as autoscaling/v1 and
+// autoscaling/v2, the preferred version, with one HPA visible in both like
+// on a real server. v1 is discovered first.
+func twoVersionClient() *Client {
+	hpa := func(version string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "web", "namespace": "shop"},
+			"spec": map[string]interface{}{
+				"scaleTargetRef": map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "web"},
+				"maxReplicas":    int64(5),
+			},
+		}}
+		u.SetAPIVersion("autoscaling/" + version)
+		u.SetKind("HorizontalPodAutoscaler")
+		if version == "v2" {
+			u.Object["spec"].(map[string]interface{})["metrics"] = []interface{}{map[string]interface{}{"type": "Resource"}}
+		}
+		return u
+	}
+
+	clientset := fake.NewClientset()
+	verbs := metav1.Verbs{"get", "list", "watch"}
+	resource := metav1.APIResource{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler", Namespaced: true, Verbs: verbs}
+	clientset.Fake.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "autoscaling/v1", APIResources: []metav1.APIResource{resource}},
+		{GroupVersion: "autoscaling/v2", APIResources: []metav1.APIResource{resource}},
+	}
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"}: "HorizontalPodAutoscalerList",
+		{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}: "HorizontalPodAutoscalerList",
+		crdGVR: "CustomResourceDefinitionList",
+	}
+	objects := []runtime.Object{hpa("v1"), hpa("v2")}
+	return &Client{
+		Clientset:       clientset,
+		DynamicClient:   dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...),
+		DiscoveryClient: preferringDiscovery{clientset.Discovery().(*fakediscovery.FakeDiscovery), "v2"},
+	}
+}
+
+// preferringDiscovery reports version as the preferred version of every
+// group; the fake discovery client prefers the first version listed.
+type preferringDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	version string
+}
+
+func (d preferringDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	groups, err := d.FakeDiscovery.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups.Groups {
+		g := &groups.Groups[i]
+		g.PreferredVersion = metav1.GroupVersionForDiscovery{GroupVersion: schema.GroupVersion{Group: g.Name, Version: d.version}.String(), Version: d.version}
+	}
+	return groups, nil
+}
+
+func TestExportNamespaceManifestsPreferredVersion(t *testing.T) {
+	c := twoVersionClient()
+	dir := t.TempDir()
+
+	snapshot, err := c.ExportNamespaceManifests("shop", dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Objects != 1 || len(snapshot.Errors) != 0 {
+		t.Fatalf("wrote %d objects with errors %v, want the HPA once", snapshot.Objects, snapshot.Errors)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "horizontalpodautoscalers.autoscaling", "web.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "apiVersion: autoscaling/v2") || !strings.Contains(string(data), "metrics:") {
+		t.Errorf("HPA wasn't written in the preferred version:\n%s", data)
+	}
+}
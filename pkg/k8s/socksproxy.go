@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// socksProxy is a SOCKS5 proxy on loopback opening connections with dial.
+// It requires a random password so other local users can't reach the
+// cluster network through it.
+type socksProxy struct {
+	listener net.Listener
+	dial     func(ctx context.Context, network, addr string) (net.Conn, error)
+	// url is socks5://<user>:<password>@127.0.0.1:<port>, for
+	// http.ProxyURL.
+	url *url.URL
+}
+
+func newSOCKSProxy(dial func(ctx context.Context, network, addr string) (net.Conn, error)) (*socksProxy, error) {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &socksProxy{
+		listener: l,
+		dial:     dial,
+		url: &url.URL{
+			Scheme: "socks5",
+			User:   url.UserPassword("teleskope", hex.EncodeToString(secret)),
+			Host:   l.Addr().String(),
+		},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p, nil
+}
+
+func (p *socksProxy) Close() {
+	p.listener.Close()
+}
+
+// SOCKS5 (RFC 1928) with username/password authentication (RFC 1929);
+// only CONNECT is supported.
+const (
+	socksHandshakeTimeout = 15 * time.Second
+
+	socksVersion      = 5
+	socksAuthPassword = 2
+	socksNoMethod     = 0xff
+	socksConnect      = 1
+	socksAddrIPv4     = 1
+	socksAddrDomain   = 3
+	socksAddrIPv6     = 4
+
+	socksSucceeded          = 0
+	socksGeneralFailure     = 1
+	socksCommandUnsupported = 7
+	socksAddrUnsupported    = 8
+)
+
+func (p *socksProxy) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(socksHandshakeTimeout))
+
+	addr, err := p.handshake(conn)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), socksHandshakeTimeout)
+	upstream, err := p.dial(ctx, "tcp", addr)
+	cancel()
+	if err != nil {
+		socksReply(conn, socksGeneralFailure)
+		return
+	}
+	defer upstream.Close()
+	if err := socksReply(conn, socksSucceeded); err != nil {
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// handshake authenticates a client and returns the address it asks to
+// connect to.
+func (p *socksProxy) handshake(conn net.Conn) (string, error) {
+	buf := make([]byte, 256)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	if buf[0] != socksVersion {
+		return "", errors.New("not a SOCKS5 client")
+	}
+	methods := buf[:buf[1]]
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+	offered := false
+	for _, m := range methods {
+		offered = offered || m == socksAuthPassword
+	}
+	if !offered {
+		conn.Write([]byte{socksVersion, socksNoMethod})
+		return "", errors.New("client didn't offer password authentication")
+	}
+	if _, err := conn.Write([]byte{socksVersion, socksAuthPassword}); err != nil {
+		return "", err
+	}
+
+	// Username/password subnegotiation: VER ULEN UNAME PLEN PASSWD.
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	user := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, user); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+		return "", err
+	}
+	password := make([]byte, buf[0])
+	if _, err := io.ReadFull(conn, password); err != nil {
+		return "", err
+	}
+	wantPassword, _ := p.url.User.Password()
+	if subtle.ConstantTimeCompare(user, []byte(p.url.User.Username())) != 1 ||
+		subtle.ConstantTimeCompare(password, []byte(wantPassword)) != 1 {
+		conn.Write([]byte{1, 1})
+		return "", errors.New("bad proxy credentials")
+	}
+	if _, err := conn.Write([]byte{1, 0}); err != nil {
+		return "", err
+	}
+
+	// Request: VER CMD RSV ATYP DST.ADDR DST.PORT.
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return "", err
+	}
+	if buf[1] != socksConnect {
+		socksReply(conn, socksCommandUnsupported)
+		return "", errors.New("unsupported SOCKS command")
+	}
+	var host string
+	switch buf[3] {
+	case socksAddrIPv4, socksAddrIPv6:
+		ip := make(net.IP, 4)
+		if buf[3] == socksAddrIPv6 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = ip.String()
+	case socksAddrDomain:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return "", err
+		}
+		name := make([]byte, buf[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		socksReply(conn, socksAddrUnsupported)
+		return "", errors.New("unsupported SOCKS address type")
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2])))), nil
+}
+
+// socksReply answers a request; the bound address isn't meaningful for a
+// tunnel and is sent as 0.0.0.0:0.
+func socksReply(conn net.Conn, code byte) error {
+	_, err := conn.Write([]byte{socksVersion, code, 0, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestSOCKSProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer backend.Close()
+
+	proxy, err := newSOCKSProxy((&net.Dialer{}).DialContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	get := func(proxyURL *url.URL) (string, error) {
+		client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+		resp, err := client.Get(backend.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	body, err := get(proxy.url)
+	if err != nil {
+		t.Fatalf("request through proxy failed: %v", err)
+	}
+	if body != "ok" {
+		t.Fatalf("got body %q, want %q", body, "ok")
+	}
+
+	wrong := *proxy.url
+	wrong.User = url.UserPassword(proxy.url.User.Username(), "wrong")
+	if _, err := get(&wrong); err == nil {
+		t.Fatal("request with a wrong proxy password succeeded")
+	}
+	noAuth := *proxy.url
+	noAuth.User = nil
+	if _, err := get(&noAuth); err == nil {
+		t.Fatal("request without proxy credentials succeeded")
+	}
+}
@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// List sort orders.
+const (
+	SortByName   = "name"
+	SortByAge    = "age"
+	SortByStatus = "status"
+)
+
+// NameFieldSelector adds a metadata.name match to a field selector. Every
+// resource type supports it, so an exact name is filtered by the API
+// server.
+func NameFieldSelector(fieldSelector, name string) string {
+	term := fields.OneTermEqualSelector("metadata.name", name).String()
+	if fieldSelector == "" {
+		return term
+	}
+	return fieldSelector + "," + term
+}
+
+// FilterByNamePrefix keeps the objects whose name starts with prefix. The
+// API server can't match prefixes, so this runs on the listed objects.
+func FilterByNamePrefix(items []interface{}, prefix string) []interface{} {
+	if prefix == "" {
+		return items
+	}
+	filtered := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _, _ := unstructured.NestedString(obj, "metadata", "name"); strings.HasPrefix(name, prefix) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// SortItems orders listed objects by name, age (youngest first) or
+// status, reversed with desc. Ties are broken by namespace and name so the
+// order is stable between refreshes.
+func SortItems(items []interface{}, by string, desc bool) error {
+	type sortKey struct {
+		namespace, name, status string
+		created                 int64
+	}
+	keys := make(map[int]sortKey, len(items))
+	for i, item := range items {
+		obj, _ := item.(map[string]interface{})
+		u := unstructured.Unstructured{Object: obj}
+		keys[i] = sortKey{
+			namespace: u.GetNamespace(),
+			name:      u.GetName(),
+			status:    objectStatus(&u),
+			created:   u.GetCreationTimestamp().Unix(),
+		}
+	}
+
+	var less func(a, b sortKey) bool
+	byName := func(a, b sortKey) bool {
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.namespace < b.namespace
+	}
+	switch by {
+	case "", SortByName:
+		less = byName
+	case SortByAge:
+		less = func(a, b sortKey) bool {
+			if a.created != b.created {
+				return a.created > b.created
+			}
+			return byName(a, b)
+		}
+	case SortByStatus:
+		less = func(a, b sortKey) bool {
+			if a.status != b.status {
+				return a.status < b.status
+			}
+			return byName(a, b)
+		}
+	default:
+		return fmt.Errorf("unknown sort order %q", by)
+	}
+
+	// Sort indexes so keys stay attached to their items.
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if desc {
+			return less(keys[order[j]], keys[order[i]])
+		}
+		return less(keys[order[i]], keys[order[j]])
+	})
+	sorted := make([]interface{}, len(items))
+	for i, idx := range order {
+		sorted[i] = items[idx]
+	}
+	copy(items, sorted)
+	return nil
+}
+
+// objectStatus is a short status for any object: Terminating, the phase
+// if it has one, or Ready/NotReady from its Ready condition.
+func objectStatus(u *unstructured.Unstructured) string {
+	if u.GetDeletionTimestamp() != nil {
+		return "Terminating"
+	}
+	if phase, _, _ := unstructured.NestedString(u.Object, "status", "phase"); phase != "" {
+		return phase
+	}
+	switch conditionStatus(u, "Ready") {
+	case "True":
+		return "Ready"
+	case "False", "Unknown":
+		return "NotReady"
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSortItems(t *testing.T) {
+	now := time.Now()
+	item := func(namespace, name string, age time.Duration, phase string) interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"namespace":         namespace,
+				"name":              name,
+				"creationTimestamp": metav1.NewTime(now.Add(-age)).UTC().Format(time.RFC3339),
+			},
+			"status": map[string]interface{}{"phase": phase},
+		}
+	}
+	items := func() []interface{} {
+		return []interface{}{
+			item("shop", "web", time.Hour, "Running"),
+			item("dev", "web", time.Hour, "Pending"),
+			item("shop", "api", time.Minute, "Running"),
+			item("shop", "db", 24*time.Hour, "Failed"),
+		}
+	}
+	names := func(items []interface{}) []string {
+		var out []string
+		for _, it := range items {
+			meta := it.(map[string]interface{})["metadata"].(map[string]interface{})
+			out = append(out, meta["namespace"].(string)+"/"+meta["name"].(string))
+		}
+		return out
+	}
+
+	tests := []struct {
+		by      string
+		desc    bool
+		want    []string
+		wantErr bool
+	}{
+		{by: "", want: []string{"shop/api", "shop/db", "dev/web", "shop/web"}},
+		{by: SortByName, desc: true, want: []string{"shop/web", "dev/web", "shop/db", "shop/api"}},
+		{by: SortByAge, want: []string{"shop/api", "dev/web", "shop/web", "shop/db"}},
+		{by: SortByAge, desc: true, want: []string{"shop/db", "shop/web", "dev/web", "shop/api"}},
+		{by: SortByStatus, want: []string{"shop/db", "dev/web", "shop/api", "shop/web"}},
+		{by: "size", wantErr: true},
+	}
+	for _, tt := range tests {
+		name := tt.by
+		if tt.desc {
+			name += " desc"
+		}
+		t.Run(name, func(t *testing.T) {
+			got := items()
+			err := SortItems(got, tt.by, tt.desc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(names(got), tt.want) {
+				t.Errorf("order = %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sshDialTimeout       = 15 * time.Second
+	sshKeepaliveInterval = 30 * time.Second
+)
+
+// SSHTunnel routes a context's API server connections through a bastion
+// host.
+type SSHTunnel struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	User string `json:"user"`
+	// KeyPath is a private key file; the ssh-agent is used when empty.
+	KeyPath string `json:"key_path"`
+	// KnownHostsPath defaults to ~/.ssh/known_hosts.
+	KnownHostsPath string `json:"known_hosts_path"`
+}
+
+func (t SSHTunnel) address() string {
+	port := t.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(t.Host, strconv.Itoa(port))
+}
+
+// sshTunnels holds the configured tunnels per context name.
+type sshTunnels struct {
+	mu      sync.Mutex
+	configs map[string]SSHTunnel
+}
+
+// SetSSHTunnels replaces the per-context tunnel configuration. It applies
+// the next time a context connects.
+func (c *Client) SetSSHTunnels(tunnels map[string]SSHTunnel) {
+	c.tunnels.mu.Lock()
+	defer c.tunnels.mu.Unlock()
+	c.tunnels.configs = tunnels
+}
+
+func (c *Client) closeTunnel() {
+	if c.tunnel != nil {
+		c.tunnel.Close()
+		c.tunnel = nil
+	}
+}
+
+func (c *Client) sshTunnelFor(contextName string) (SSHTunnel, bool) {
+	c.tunnels.mu.Lock()
+	defer c.tunnels.mu.Unlock()
+	t, ok := c.tunnels.configs[contextName]
+	return t, ok && t.Host != ""
+}
+
+// sshDialer keeps one SSH connection to the bastion and opens API server
+// connections through it, reconnecting when the connection drops.
+//
+// client-go only uses rest.Config.Dial for plain requests; the SPDY and
+// WebSocket transports behind exec, port-forward and terminals dial on
+// their own but all honour rest.Config.Proxy. So the dialer is exposed
+// through a loopback SOCKS5 proxy as well.
+type sshDialer struct {
+	cfg SSHTunnel
+
+	mu     sync.Mutex
+	client *ssh.Client
+	closed bool
+
+	proxy *socksProxy
+}
+
+func newSSHDialer(cfg SSHTunnel) (*sshDialer, error) {
+	d := &sshDialer{cfg: cfg}
+	// Connect eagerly so configuration errors surface from Init.
+	if _, err := d.connect(); err != nil {
+		return nil, err
+	}
+	proxy, err := newSOCKSProxy(d.DialContext)
+	if err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to start the ssh tunnel proxy: %v", err)
+	}
+	d.proxy = proxy
+	return d, nil
+}
+
+func (d *sshDialer) connect() (*ssh.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return nil, errors.New("ssh tunnel is closed")
+	}
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	config, closeAgent, err := sshClientConfig(d.cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := ssh.Dial("tcp", d.cfg.address(), config)
+	// The agent is only needed for the handshake.
+	closeAgent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh bastion %s: %v", d.cfg.address(), err)
+	}
+	d.client = client
+	go d.keepalive(client)
+	return client, nil
+}
+
+func (d *sshDialer) keepalive(client *ssh.Client) {
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+			d.drop(client)
+			return
+		}
+	}
+}
+
+// drop forgets a broken connection so the next dial reconnects.
+func (d *sshDialer) drop(client *ssh.Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client == client {
+		d.client = nil
+	}
+	client.Close()
+}
+
+func (d *sshDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil && ctx.Err() == nil {
+		// The SSH connection itself may be dead; retry once on a fresh one.
+		d.drop(client)
+		if client, err = d.connect(); err != nil {
+			return nil, err
+		}
+		conn, err = client.DialContext(ctx, network, addr)
+	}
+	return conn, err
+}
+
+func (d *sshDialer) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	if d.proxy != nil {
+		d.proxy.Close()
+	}
+	if d.client != nil {
+		d.client.Close()
+		d.client = nil
+	}
+}
+
+// sshClientConfig returns the client configuration of a tunnel and a
+// function closing its ssh-agent connection, if any, once the handshake
+// is done.
+func sshClientConfig(cfg SSHTunnel) (*ssh.ClientConfig, func(), error) {
+	home, _ := os.UserHomeDir()
+	knownHosts := cfg.KnownHostsPath
+	if knownHosts == "" {
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(expandHome(knownHosts, home))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load known hosts from %s: %v", knownHosts, err)
+	}
+
+	closeAgent := func() {}
+	var auth []ssh.AuthMethod
+	if cfg.KeyPath != "" {
+		key, err := os.ReadFile(expandHome(cfg.KeyPath, home))
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, nil, fmt.Errorf("ssh key %s is passphrase protected; add it to ssh-agent and leave the key path empty", cfg.KeyPath)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse ssh key %s: %v", cfg.KeyPath, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	} else if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to reach ssh-agent: %v", err)
+		}
+		closeAgent = func() { conn.Close() }
+		auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	} else {
+		return nil, nil, errors.New("no ssh key configured and no ssh-agent running")
+	}
+
+	user := cfg.User
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}, closeAgent, nil
+}
+
+func expandHome(path, home string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultStorageClassAnnotation     = "storageclass.kubernetes.io/is-default-class"
+	betaDefaultStorageClassAnnotation = "storageclass.beta.kubernetes.io/is-default-class"
+)
+
+type StorageClassInfo struct {
+	Name                 string            `json:"name"`
+	Provisioner          string            `json:"provisioner"`
+	ReclaimPolicy        string            `json:"reclaim_policy"`
+	VolumeBindingMode    string            `json:"volume_binding_mode"`
+	AllowVolumeExpansion bool              `json:"allow_volume_expansion"`
+	IsDefault            bool              `json:"is_default"`
+	Parameters           map[string]string `json:"parameters"`
+}
+
+type CSIDriverInfo struct {
+	Name           string   `json:"name"`
+	AttachRequired bool     `json:"attach_required"`
+	PodInfoOnMount bool     `json:"pod_info_on_mount"`
+	LifecycleModes []string `json:"lifecycle_modes"`
+}
+
+type CSINodeInfo struct {
+	Name    string   `json:"name"`
+	Drivers []string `json:"drivers"`
+}
+
+type PendingClaim struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+type StorageOverview struct {
+	StorageClasses []StorageClassInfo `json:"storage_classes"`
+	CSIDrivers     []CSIDriverInfo    `json:"csi_drivers"`
+	CSINodes       []CSINodeInfo      `json:"csi_nodes"`
+	DefaultClass   string             `json:"default_class"`
+	PendingClaims  []PendingClaim     `json:"pending_claims"`
+}
+
+func (c *Client) GetStorageOverview() (*StorageOverview, error) {
+	ctx, cancel := c.opContext(OpList)
+	defer cancel()
+	overview := &StorageOverview{}
+
+	classes, err := c.Clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, sc := range classes.Items {
+		info := StorageClassInfo{
+			Name:        sc.Name,
+			Provisioner: sc.Provisioner,
+			IsDefault:   isDefaultStorageClass(sc.Annotations),
+			Parameters:  sc.Parameters,
+		}
+		if sc.ReclaimPolicy != nil {
+			info.ReclaimPolicy = string(*sc.ReclaimPolicy)
+		}
+		if sc.VolumeBindingMode != nil {
+			info.VolumeBindingMode = string(*sc.VolumeBindingMode)
+		}
+		if sc.AllowVolumeExpansion != nil {
+			info.AllowVolumeExpansion = *sc.AllowVolumeExpansion
+		}
+		if info.IsDefault && overview.DefaultClass == "" {
+			overview.DefaultClass = sc.Name
+		}
+		known[sc.Name] = true
+		overview.StorageClasses = append(overview.StorageClasses, info)
+	}
+
+	// CSI objects are optional on older or minimal clusters, so failures
+	// here shouldn't hide the StorageClass data.
+	if drivers, err := c.Clientset.StorageV1().CSIDrivers().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, d := range drivers.Items {
+			info := CSIDriverInfo{Name: d.Name}
+			if d.Spec.AttachRequired != nil {
+				info.AttachRequired = *d.Spec.AttachRequired
+			}
+			if d.Spec.PodInfoOnMount != nil {
+				info.PodInfoOnMount = *d.Spec.PodInfoOnMount
+			}
+			for _, m := range d.Spec.VolumeLifecycleModes {
+				info.LifecycleModes = append(info.LifecycleModes, string(m))
+			}
+			overview.CSIDrivers = append(overview.CSIDrivers, info)
+		}
+	}
+
+	if nodes, err := c.Clientset.StorageV1().CSINodes().List(ctx, metav1.ListOptions{}); err == nil {
+		for _, n := range nodes.Items {
+			info := CSINodeInfo{Name: n.Name}
+			for _, d := range n.Spec.Drivers {
+				info.Drivers = append(info.Drivers, d.Name)
+			}
+			overview.CSINodes = append(overview.CSINodes, info)
+		}
+	}
+
+	pvcs, err := c.Clientset.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs.Items {
+		if pvc.Status.Phase != corev1.ClaimPending {
+			continue
+		}
+		if reason := pendingClaimReason(&pvc, overview.DefaultClass, known); reason != "" {
+			overview.PendingClaims = append(overview.PendingClaims, PendingClaim{
+				Namespace: pvc.Namespace,
+				Name:      pvc.Name,
+				Reason:    reason,
+			})
+		}
+	}
+
+	sort.Slice(overview.StorageClasses, func(i, j int) bool {
+		return overview.StorageClasses[i].Name < overview.StorageClasses[j].Name
+	})
+	sort.Slice(overview.PendingClaims, func(i, j int) bool {
+		if overview.PendingClaims[i].Namespace != overview.PendingClaims[j].Namespace {
+			return overview.PendingClaims[i].Namespace < overview.PendingClaims[j].Namespace
+		}
+		return overview.PendingClaims[i].Name < overview.PendingClaims[j].Name
+	})
+
+	return overview, nil
+}
+
+func isDefaultStorageClass(annotations map[string]string) bool {
+	return annotations[defaultStorageClassAnnotation] == "true" ||
+		annotations[betaDefaultStorageClassAnnotation] == "true"
+}
+
+// pendingClaimReason explains why a Pending claim is stuck on storage class
+// resolution. Claims bound to an explicit, existing class return "".
+func pendingClaimReason(pvc *corev1.PersistentVolumeClaim, defaultClass string, known map[string]bool) string {
+	if pvc.Spec.StorageClassName == nil {
+		if defaultClass == "" {
+			return "no storageClassName set and the cluster has no default StorageClass"
+		}
+		return ""
+	}
+	name := *pvc.Spec.StorageClassName
+	if name != "" && !known[name] {
+		return "StorageClass " + name + " does not exist"
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package k8s
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	spdyhttp "k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// spdyRoundTripperFor is spdy.RoundTripperFor for configs whose TLS
+// settings live in a custom transport (token certificates), which
+// client-go's upgrade transports don't look into.
+func spdyRoundTripperFor(config *rest.Config) (http.RoundTripper, spdy.Upgrader, error) {
+	if config.Transport == nil {
+		return spdy.RoundTripperFor(config)
+	}
+	tlsConfig, err := utilnet.TLSClientConfig(config.Transport)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tlsConfig != nil {
+		// The shared transport may have offered HTTP/2, which can't be
+		// upgraded.
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	}
+	proxy := http.ProxyFromEnvironment
+	if config.Proxy != nil {
+		proxy = config.Proxy
+	}
+	upgrader, err := spdyhttp.NewRoundTripperWithConfig(spdyhttp.RoundTripperConfig{
+		TLS:        tlsConfig,
+		Proxier:    proxy,
+		PingPeriod: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapper, err := rest.HTTPWrappersForConfig(config, upgrader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wrapper, upgrader, nil
+}
+
+func newSPDYExecutor(config *rest.Config, method string, url *url.URL) (remotecommand.Executor, error) {
+	transport, upgrader, err := spdyRoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return remotecommand.NewSPDYExecutorForTransports(transport, upgrader, method, url)
+}
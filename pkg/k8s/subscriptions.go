@@ -0,0 +1,188 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// EventSubscriptionFailed is emitted with a SubscriptionFailure when a
+// stream gives up, after which its subscription no longer exists.
+const EventSubscriptionFailed = "subscription:failed"
+
+type SubscriptionFailure struct {
+	SubscriptionID string `json:"subscription_id"`
+	Error          string `json:"error"`
+}
+
+// subscriptions tracks long-running background streams (watches, log
+// follows, ...) so they can be stopped by ID, by the resource they watch,
+// or all at once when the active context changes.
+type subscriptions struct {
+	mu      sync.Mutex
+	next    int
+	entries map[string]subscription
+}
+
+type subscription struct {
+	cancel context.CancelFunc
+	gvrs   []schema.GroupVersionResource
+}
+
+// add registers a stream. gvrs lists the resource types it depends on so
+// it can be stopped if one of them disappears from the cluster.
+func (s *subscriptions) add(prefix string, cancel context.CancelFunc, gvrs ...schema.GroupVersionResource) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]subscription)
+	}
+	s.next++
+	id := fmt.Sprintf("%s-%d", prefix, s.next)
+	s.entries[id] = subscription{cancel: cancel, gvrs: gvrs}
+	return id
+}
+
+func (s *subscriptions) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+}
+
+func (s *subscriptions) stop(id string) bool {
+	s.mu.Lock()
+	sub, ok := s.entries[id]
+	delete(s.entries, id)
+	s.mu.Unlock()
+	if ok {
+		sub.cancel()
+	}
+	return ok
+}
+
+// stopGVR stops every stream depending on gvr and returns their IDs.
+func (s *subscriptions) stopGVR(gvr schema.GroupVersionResource) []string {
+	s.mu.Lock()
+	var stopped []subscription
+	var ids []string
+	for id, sub := range s.entries {
+		for _, g := range sub.gvrs {
+			if g == gvr {
+				stopped = append(stopped, sub)
+				ids = append(ids, id)
+				delete(s.entries, id)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+	for _, sub := range stopped {
+		sub.cancel()
+	}
+	return ids
+}
+
+func (s *subscriptions) stopAll() {
+	s.mu.Lock()
+	entries := s.entries
+	s.entries = nil
+	s.mu.Unlock()
+	for _, sub := range entries {
+		sub.cancel()
+	}
+}
+
+// subscriptionFailed stops a subscription whose stream gave up and tells
+// the frontend, once even if several of its watches fail.
+func (c *Client) subscriptionFailed(id string, err error) {
+	if c.subs.stop(id) {
+		c.emit(EventSubscriptionFailed, SubscriptionFailure{SubscriptionID: id, Error: err.Error()})
+	}
+}
+
+// StopSubscription cancels a background stream started by one of the
+// Watch*/Stream* methods.
+func (c *Client) StopSubscription(id string) error {
+	if !c.subs.stop(id) {
+		return fmt.Errorf("subscription %s not found", id)
+	}
+	return nil
+}
+
+// watchLoop keeps a watch running until ctx is cancelled, resuming from the
+// last seen resourceVersion and starting over when the server reports the
+// version as expired. If the resource type itself is gone (e.g. its CRD was
+// uninstalled) gone is called, when set, and the loop ends. When the
+// OpWatch retries run out the subscription id fails, see
+// subscriptionFailed.
+//
+// Each watch request lives at most the OpWatch timeout, so a connection
+// that silently stopped delivering (e.g. behind a flaky VPN) is replaced.
+func (c *Client) watchLoop(ctx context.Context, id string, start func(ctx context.Context, resourceVersion string) (watch.Interface, error), handle func(watch.Event), gone func()) {
+	c.watchLoopFrom(ctx, id, "", start, handle, gone)
+}
+
+// watchGaveUp is the error of a watch that failed too often in a row.
+func watchGaveUp(failures int, err error) error {
+	return fmt.Errorf("watch failed %d times in a row: %v", failures, err)
+}
+
+// watchLoopFrom is watchLoop starting at resourceVersion, typically that of
+// a list the caller already processed, so the objects in it aren't
+// replayed.
+func (c *Client) watchLoopFrom(ctx context.Context, id, resourceVersion string, start func(ctx context.Context, resourceVersion string) (watch.Interface, error), handle func(watch.Event), gone func()) {
+	policy := c.policies.get(OpWatch)
+	failures := 0
+	for {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		if policy.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		}
+
+		w, err := start(attemptCtx, resourceVersion)
+		if err != nil {
+			cancel()
+			if isResourceGone(err) && gone != nil {
+				gone()
+				return
+			}
+			failures++
+			if policy.Retries > 0 && failures > policy.Retries {
+				c.subscriptionFailed(id, watchGaveUp(failures, err))
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(policy.delay(failures)):
+				continue
+			}
+		}
+		failures = 0
+
+		for ev := range w.ResultChan() {
+			if ev.Type == watch.Error {
+				// Typically 410 Gone: our resourceVersion is too old.
+				resourceVersion = ""
+				break
+			}
+			if obj, err := meta.Accessor(ev.Object); err == nil {
+				resourceVersion = obj.GetResourceVersion()
+			}
+			handle(ev)
+		}
+		w.Stop()
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
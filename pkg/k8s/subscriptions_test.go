@@ -0,0 +1,44 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestWatchLoopGivesUp(t *testing.T) {
+	c := &Client{}
+	c.SetOperationPolicies(map[OperationType]OperationPolicy{
+		OpWatch: {Timeout: time.Second, Retries: 2, Backoff: time.Millisecond},
+	})
+	var events []SubscriptionFailure
+	c.Emit = func(name string, data interface{}) {
+		if name == EventSubscriptionFailed {
+			events = append(events, data.(SubscriptionFailure))
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	id := c.subs.add("test", cancel)
+
+	attempts := 0
+	c.watchLoop(ctx, id, func(context.Context, string) (watch.Interface, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	}, func(watch.Event) {}, nil)
+
+	if attempts != 3 {
+		t.Errorf("watch was attempted %d times, want 3", attempts)
+	}
+	if len(events) != 1 || events[0].SubscriptionID != id {
+		t.Fatalf("failure events = %+v, want one for %s", events, id)
+	}
+	if ctx.Err() == nil {
+		t.Error("the failed subscription wasn't cancelled")
+	}
+	if err := c.StopSubscription(id); err == nil {
+		t.Error("the failed subscription is still registered")
+	}
+}
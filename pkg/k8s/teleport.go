@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// EventTeleportLoginRequired is emitted instead of an opaque exec
+	// plugin failure when a tsh session has expired.
+	EventTeleportLoginRequired = "teleport:login-required"
+
+	tshStatusTimeout = 10 * time.Second
+)
+
+// TeleportInfo describes a kubeconfig entry managed by Teleport's tsh.
+// Session fields are only filled by GetTeleportStatus.
+type TeleportInfo struct {
+	Proxy           string    `json:"proxy"`
+	TeleportCluster string    `json:"teleport_cluster"`
+	KubeCluster     string    `json:"kube_cluster"`
+	LoggedIn        bool      `json:"logged_in"`
+	Username        string    `json:"username,omitempty"`
+	Roles           []string  `json:"roles,omitempty"`
+	ValidUntil      time.Time `json:"valid_until,omitempty"`
+}
+
+type TeleportLoginRequired struct {
+	Context      string   `json:"context"`
+	Proxy        string   `json:"proxy"`
+	Cluster      string   `json:"cluster"`
+	LoginCommand []string `json:"login_command"`
+	Message      string   `json:"message"`
+}
+
+// teleportExecInfo recognises `tsh kube credentials` exec plugins.
+func teleportExecInfo(cfg *clientcmdapi.ExecConfig) *TeleportInfo {
+	if cfg == nil || strings.TrimSuffix(filepath.Base(cfg.Command), ".exe") != "tsh" {
+		return nil
+	}
+	info := &TeleportInfo{}
+	for _, arg := range cfg.Args {
+		key, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "proxy":
+			info.Proxy = value
+		case "teleport-cluster":
+			info.TeleportCluster = value
+		case "kube-cluster":
+			info.KubeCluster = value
+		}
+	}
+	return info
+}
+
+func (c *Client) teleportInfoFor(contextName string) (*TeleportInfo, error) {
+	if c.Config == nil {
+		return nil, fmt.Errorf("context %s is not a Teleport context", contextName)
+	}
+	rawConfig, err := c.Config.RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	ctx, ok := rawConfig.Contexts[contextName]
+	if !ok {
+		return nil, fmt.Errorf("context %s not found", contextName)
+	}
+	auth := rawConfig.AuthInfos[ctx.AuthInfo]
+	if auth == nil {
+		return nil, fmt.Errorf("context %s is not a Teleport context", contextName)
+	}
+	info := teleportExecInfo(auth.Exec)
+	if info == nil {
+		return nil, fmt.Errorf("context %s is not a Teleport context", contextName)
+	}
+	return info, nil
+}
+
+func tshLoginCommand(info *TeleportInfo) []string {
+	argv := []string{"tsh", "login"}
+	if info.Proxy != "" {
+		argv = append(argv, "--proxy="+info.Proxy)
+	}
+	if info.TeleportCluster != "" {
+		argv = append(argv, info.TeleportCluster)
+	}
+	return argv
+}
+
+// GetTeleportStatus reports the tsh session backing a context, via
+// `tsh status`.
+func (c *Client) GetTeleportStatus(contextName string) (*TeleportInfo, error) {
+	info, err := c.teleportInfoFor(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tshStatusTimeout)
+	defer cancel()
+	args := []string{"status", "--format=json"}
+	if info.Proxy != "" {
+		args = append(args, "--proxy="+info.Proxy)
+	}
+	out, err := exec.CommandContext(ctx, "tsh", args...).Output()
+	if err != nil {
+		// tsh exits non-zero when there is no active profile.
+		return info, nil
+	}
+
+	var status struct {
+		Active *struct {
+			Username   string    `json:"username"`
+			Cluster    string    `json:"cluster"`
+			Roles      []string  `json:"roles"`
+			ValidUntil time.Time `json:"valid_until"`
+		} `json:"active"`
+	}
+	if err := json.Unmarshal(out, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse tsh status: %v", err)
+	}
+	if status.Active != nil {
+		info.Username = status.Active.Username
+		info.Roles = status.Active.Roles
+		info.ValidUntil = status.Active.ValidUntil
+		info.LoggedIn = status.Active.ValidUntil.After(time.Now())
+		if info.TeleportCluster == "" {
+			info.TeleportCluster = status.Active.Cluster
+		}
+	}
+	return info, nil
+}
+
+// TeleportLogin runs `tsh login` for a context in a terminal window, since
+// it usually needs a browser or an interactive second factor.
+func (c *Client) TeleportLogin(contextName string) error {
+	info, err := c.teleportInfoFor(contextName)
+	if err != nil {
+		return err
+	}
+	return runInTerminal(tshLoginCommand(info))
+}
+
+// reportTeleportLogin turns a failed tsh credential run into a structured
+// login request. It reports whether cfg was a tsh plugin.
+func (c *Client) reportTeleportLogin(contextName string, cfg *clientcmdapi.ExecConfig, stderr string) bool {
+	info := teleportExecInfo(cfg)
+	if info == nil {
+		return false
+	}
+	message := stderr
+	if message == "" {
+		message = "Teleport session is missing or expired"
+	}
+	c.emit(EventTeleportLoginRequired, TeleportLoginRequired{
+		Context:      contextName,
+		Proxy:        info.Proxy,
+		Cluster:      info.TeleportCluster,
+		LoginCommand: tshLoginCommand(info),
+		Message:      message,
+	})
+	return true
+}
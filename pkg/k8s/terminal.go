@@ -0,0 +1,217 @@
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/utils/exec"
+)
+
+const (
+	// EventTerminalOutput carries base64-encoded TTY output.
+	EventTerminalOutput = "terminal:output"
+	EventTerminalExit   = "terminal:exit"
+)
+
+// defaultShell prefers bash and falls back to sh. Windows pods get
+// windowsShell instead.
+var defaultShell = []string{"/bin/sh", "-c", "command -v bash >/dev/null 2>&1 && exec bash || exec sh"}
+
+type TerminalOptions struct {
+	Namespace     string   `json:"namespace"`
+	PodName       string   `json:"podName"`
+	ContainerName string   `json:"containerName"`
+	Command       []string `json:"command"`
+	Cols          uint16   `json:"cols"`
+	Rows          uint16   `json:"rows"`
+}
+
+type TerminalOutput struct {
+	SessionID string `json:"session_id"`
+	Data      string `json:"data"`
+}
+
+type TerminalExit struct {
+	SessionID string `json:"session_id"`
+	ExitCode  int    `json:"exit_code"`
+	Error     string `json:"error,omitempty"`
+}
+
+type terminalSession struct {
+	stdin *io.PipeWriter
+	sizes chan remotecommand.TerminalSize
+	done  chan struct{}
+}
+
+// Next implements remotecommand.TerminalSizeQueue.
+func (s *terminalSession) Next() *remotecommand.TerminalSize {
+	select {
+	case size := <-s.sizes:
+		return &size
+	case <-s.done:
+		return nil
+	}
+}
+
+type terminalSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*terminalSession
+}
+
+func (t *terminalSessions) get(id string) (*terminalSession, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("terminal session %s not found", id)
+	}
+	return s, nil
+}
+
+// terminalWriter forwards TTY output as EventTerminalOutput events.
+type terminalWriter struct {
+	client *Client
+	id     string
+}
+
+func (w terminalWriter) Write(p []byte) (int, error) {
+	w.client.emit(EventTerminalOutput, TerminalOutput{
+		SessionID: w.id,
+		Data:      base64.StdEncoding.EncodeToString(p),
+	})
+	return len(p), nil
+}
+
+// StartTerminal opens an interactive TTY in a container, streamed over
+// Wails events so the frontend can render it with xterm.js. Output
+// arrives as EventTerminalOutput; WriteTerminal and ResizeTerminal feed
+// input and size changes. The session ID doubles as a subscription ID.
+func (c *Client) StartTerminal(opts TerminalOptions) (string, error) {
+	if c.RestConfig == nil {
+		return "", errors.New("exec is not available for this context")
+	}
+	command := opts.Command
+	if len(command) == 0 {
+		command = c.shellFor(opts.Namespace, opts.PodName)
+	}
+
+	req := c.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.PodName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.ContainerName,
+			Command:   command,
+			Stdin:     true,
+			Stdout:    true,
+			Stderr:    false,
+			TTY:       true,
+		}, scheme.ParameterCodec)
+
+	executor, err := newSPDYExecutor(c.RestConfig, "POST", req.URL())
+	if err != nil {
+		return "", err
+	}
+	// Prefer WebSockets and fall back to SPDY for older API servers. The
+	// WebSocket transport only takes TLS settings from the config itself,
+	// so contexts with a custom transport stick to SPDY.
+	if c.RestConfig.Transport == nil {
+		wsExec, err := remotecommand.NewWebSocketExecutor(c.RestConfig, "GET", req.URL().String())
+		if err != nil {
+			return "", err
+		}
+		executor, err = remotecommand.NewFallbackExecutor(wsExec, executor, func(err error) bool {
+			return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	stdinReader, stdinWriter := io.Pipe()
+	session := &terminalSession{
+		stdin: stdinWriter,
+		sizes: make(chan remotecommand.TerminalSize, 1),
+		done:  make(chan struct{}),
+	}
+	if opts.Cols > 0 && opts.Rows > 0 {
+		session.sizes <- remotecommand.TerminalSize{Width: opts.Cols, Height: opts.Rows}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	id := c.subs.add("terminal", cancel)
+	c.terminals.mu.Lock()
+	if c.terminals.sessions == nil {
+		c.terminals.sessions = make(map[string]*terminalSession)
+	}
+	c.terminals.sessions[id] = session
+	c.terminals.mu.Unlock()
+
+	go func() {
+		err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+			Stdin:             stdinReader,
+			Stdout:            terminalWriter{client: c, id: id},
+			Tty:               true,
+			TerminalSizeQueue: session,
+		})
+
+		close(session.done)
+		stdinWriter.Close()
+		c.terminals.mu.Lock()
+		delete(c.terminals.sessions, id)
+		c.terminals.mu.Unlock()
+		c.subs.remove(id)
+		cancel()
+
+		exit := TerminalExit{SessionID: id}
+		var exitErr exec.ExitError
+		switch {
+		case errors.As(err, &exitErr):
+			exit.ExitCode = exitErr.ExitStatus()
+		case err != nil && ctx.Err() == nil:
+			exit.ExitCode = -1
+			exit.Error = err.Error()
+		}
+		c.emit(EventTerminalExit, exit)
+	}()
+
+	return id, nil
+}
+
+// WriteTerminal sends keyboard input to a terminal session.
+func (c *Client) WriteTerminal(id, data string) error {
+	session, err := c.terminals.get(id)
+	if err != nil {
+		return err
+	}
+	_, err = session.stdin.Write([]byte(data))
+	return err
+}
+
+// ResizeTerminal propagates a new window size to a terminal session.
+func (c *Client) ResizeTerminal(id string, cols, rows uint16) error {
+	session, err := c.terminals.get(id)
+	if err != nil {
+		return err
+	}
+	size := remotecommand.TerminalSize{Width: cols, Height: rows}
+	// Only the latest size matters; replace a pending one.
+	select {
+	case <-session.sizes:
+	default:
+	}
+	select {
+	case session.sizes <- size:
+	case <-session.done:
+	}
+	return nil
+}
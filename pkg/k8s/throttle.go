@@ -0,0 +1,262 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// RequestPriority orders API requests competing for a context's slots.
+type RequestPriority string
+
+const (
+	// PriorityInteractive is for requests the user is waiting on.
+	PriorityInteractive RequestPriority = "interactive"
+	// PriorityBackground is for pollers and pre-fetching; it only runs
+	// when no interactive request is waiting.
+	PriorityBackground RequestPriority = "background"
+)
+
+// RequestLimits bounds the API requests in flight to one context. Watches,
+// followed logs and exec/port-forward streams are long-lived and don't
+// count.
+type RequestLimits struct {
+	MaxInFlight int `json:"max_in_flight"`
+	// MaxBackground caps background requests so some slots always stay
+	// free for the user.
+	MaxBackground int `json:"max_background"`
+}
+
+func DefaultRequestLimits() RequestLimits {
+	return RequestLimits{MaxInFlight: 16, MaxBackground: 4}
+}
+
+// RequestStats is a snapshot of one context's limiter.
+type RequestStats struct {
+	Context          string        `json:"context"`
+	Limits           RequestLimits `json:"limits"`
+	InFlight         int           `json:"in_flight"`
+	Background       int           `json:"background"`
+	Queued           int           `json:"queued"`
+	QueuedBackground int           `json:"queued_background"`
+}
+
+type priorityKey struct{}
+
+// withPriority marks the requests made with ctx.
+func withPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func requestPriority(ctx context.Context) RequestPriority {
+	if p, ok := ctx.Value(priorityKey{}).(RequestPriority); ok {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// requestLimiter is a semaphore with two queues: a free slot goes to the
+// oldest waiting interactive request, and to a background one only when no
+// interactive request waits.
+type requestLimiter struct {
+	mu         sync.Mutex
+	limits     RequestLimits
+	inFlight   int
+	background int
+	waiting    map[RequestPriority][]chan struct{}
+}
+
+func newRequestLimiter(limits RequestLimits) *requestLimiter {
+	return &requestLimiter{limits: limits, waiting: make(map[RequestPriority][]chan struct{})}
+}
+
+// canRun reports whether a request of priority may take a slot now. The
+// caller holds l.mu.
+func (l *requestLimiter) canRun(priority RequestPriority) bool {
+	if l.inFlight >= l.limits.MaxInFlight {
+		return false
+	}
+	if priority == PriorityInteractive {
+		return true
+	}
+	return l.background < l.limits.MaxBackground && len(l.waiting[PriorityInteractive]) == 0
+}
+
+func (l *requestLimiter) take(priority RequestPriority) {
+	l.inFlight++
+	if priority == PriorityBackground {
+		l.background++
+	}
+}
+
+func (l *requestLimiter) acquire(ctx context.Context, priority RequestPriority) error {
+	l.mu.Lock()
+	if len(l.waiting[priority]) == 0 && l.canRun(priority) {
+		l.take(priority)
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	l.waiting[priority] = append(l.waiting[priority], ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		queue := l.waiting[priority]
+		for i, ch := range queue {
+			if ch == ready {
+				l.waiting[priority] = append(queue[:i:i], queue[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// The slot was granted while we gave up; pass it on.
+		l.releaseLocked(priority)
+		return ctx.Err()
+	}
+}
+
+func (l *requestLimiter) release(priority RequestPriority) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked(priority)
+}
+
+func (l *requestLimiter) releaseLocked(priority RequestPriority) {
+	l.inFlight--
+	if priority == PriorityBackground {
+		l.background--
+	}
+	l.dispatch()
+}
+
+// dispatch hands free slots to waiting requests, interactive first. The
+// caller holds l.mu.
+func (l *requestLimiter) dispatch() {
+	for _, priority := range []RequestPriority{PriorityInteractive, PriorityBackground} {
+		for len(l.waiting[priority]) > 0 && l.canRun(priority) {
+			ready := l.waiting[priority][0]
+			l.waiting[priority] = l.waiting[priority][1:]
+			l.take(priority)
+			close(ready)
+		}
+	}
+}
+
+func (l *requestLimiter) setLimits(limits RequestLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+	l.dispatch()
+}
+
+func (l *requestLimiter) stats(contextName string) RequestStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RequestStats{
+		Context:          contextName,
+		Limits:           l.limits,
+		InFlight:         l.inFlight,
+		Background:       l.background,
+		Queued:           len(l.waiting[PriorityInteractive]),
+		QueuedBackground: len(l.waiting[PriorityBackground]),
+	}
+}
+
+// requestLimiters holds one limiter per context, so requests piling up
+// on a slow cluster never hold the slots of another.
+type requestLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*requestLimiter
+	limits   map[string]RequestLimits
+}
+
+func (r *requestLimiters) forContext(name string) *requestLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.limiters[name]; ok {
+		return l
+	}
+	if r.limiters == nil {
+		r.limiters = make(map[string]*requestLimiter)
+	}
+	l := newRequestLimiter(r.limitsFor(name))
+	r.limiters[name] = l
+	return l
+}
+
+// limitsFor returns the configured limits of a context. The caller holds
+// r.mu.
+func (r *requestLimiters) limitsFor(name string) RequestLimits {
+	limits, ok := r.limits[name]
+	if !ok {
+		return DefaultRequestLimits()
+	}
+	defaults := DefaultRequestLimits()
+	if limits.MaxInFlight <= 0 {
+		limits.MaxInFlight = defaults.MaxInFlight
+	}
+	if limits.MaxBackground <= 0 || limits.MaxBackground > limits.MaxInFlight {
+		limits.MaxBackground = min(defaults.MaxBackground, limits.MaxInFlight)
+	}
+	return limits
+}
+
+// SetRequestLimits replaces the per-context request limits; contexts not
+// in limits use DefaultRequestLimits. It applies immediately.
+func (c *Client) SetRequestLimits(limits map[string]RequestLimits) {
+	c.limiters.mu.Lock()
+	defer c.limiters.mu.Unlock()
+	c.limiters.limits = limits
+	for name, l := range c.limiters.limiters {
+		l.setLimits(c.limiters.limitsFor(name))
+	}
+}
+
+// GetRequestStats reports the in-flight and queued requests of every
+// context used since the app started.
+func (c *Client) GetRequestStats() []RequestStats {
+	c.limiters.mu.Lock()
+	limiters := make(map[string]*requestLimiter, len(c.limiters.limiters))
+	for name, l := range c.limiters.limiters {
+		limiters[name] = l
+	}
+	c.limiters.mu.Unlock()
+
+	stats := make([]RequestStats, 0, len(limiters))
+	for name, l := range limiters {
+		stats = append(stats, l.stats(name))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Context < stats[j].Context })
+	return stats
+}
+
+// limitedRoundTripper takes a slot of its context's limiter for each
+// short-lived request. The slot is released once the response headers
+// arrive; bodies are read quickly and holding the slot until Close would
+// leak it on any path that forgets to close.
+type limitedRoundTripper struct {
+	limiter *requestLimiter
+	next    http.RoundTripper
+}
+
+func (rt *limitedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if longRunningRequest(req) {
+		return rt.next.RoundTrip(req)
+	}
+	priority := requestPriority(req.Context())
+	if err := rt.limiter.acquire(req.Context(), priority); err != nil {
+		return nil, err
+	}
+	defer rt.limiter.release(priority)
+	return rt.next.RoundTrip(req)
+}
+
+func longRunningRequest(req *http.Request) bool {
+	q := req.URL.Query()
+	return q.Get("watch") == "true" || q.Get("follow") == "true" || req.Header.Get("Upgrade") != ""
+}
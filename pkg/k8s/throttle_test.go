@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiter(t *testing.T) {
+	l := newRequestLimiter(RequestLimits{MaxInFlight: 2, MaxBackground: 1})
+	ctx := context.Background()
+
+	if err := l.acquire(ctx, PriorityBackground); err != nil {
+		t.Fatal(err)
+	}
+	// The background cap leaves the other slot to interactive requests.
+	bgCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	if err := l.acquire(bgCtx, PriorityBackground); err == nil {
+		t.Fatal("second background request got a slot past MaxBackground")
+	}
+	cancel()
+	if err := l.acquire(ctx, PriorityInteractive); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both slots are taken: queue one request of each priority.
+	granted := make(chan RequestPriority, 2)
+	queue := func(p RequestPriority) {
+		go func() {
+			if err := l.acquire(ctx, p); err == nil {
+				granted <- p
+			}
+		}()
+	}
+	queue(PriorityBackground)
+	waitFor(t, func() bool { return l.stats("").QueuedBackground == 1 })
+	queue(PriorityInteractive)
+	waitFor(t, func() bool { return l.stats("").Queued == 1 })
+	if s := l.stats("test"); s.InFlight != 2 || s.Queued != 1 || s.QueuedBackground != 1 {
+		t.Fatalf("stats = %+v, want 2 in flight and one queued of each priority", s)
+	}
+
+	// A freed slot goes to the interactive request although the
+	// background one waited longer.
+	l.release(PriorityInteractive)
+	if p := <-granted; p != PriorityInteractive {
+		t.Fatalf("slot went to %s, want interactive", p)
+	}
+	l.release(PriorityBackground)
+	if p := <-granted; p != PriorityBackground {
+		t.Fatalf("slot went to %s, want background", p)
+	}
+	l.release(PriorityInteractive)
+	l.release(PriorityBackground)
+	if s := l.stats(""); s.InFlight != 0 || s.Background != 0 || s.Queued+s.QueuedBackground != 0 {
+		t.Errorf("stats after releasing everything = %+v", s)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
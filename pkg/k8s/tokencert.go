@@ -0,0 +1,252 @@
+package k8s
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	pkcs11ToolTimeout = 30 * time.Second
+	// pkcs11PINEnv passes the PIN to pkcs11-tool ("--pin env:VAR"), since
+	// its command line is readable by every local user.
+	pkcs11PINEnv = "TELESKOPE_PKCS11_PIN"
+)
+
+// TokenCertificate selects a client certificate and key held on a
+// PKCS#11 token, such as a smartcard or YubiKey, for a context. The key
+// never leaves the token: OpenSC's pkcs11-tool signs each TLS handshake.
+// Certificates in the OS keychain that come from a smartcard are reached
+// through the card's PKCS#11 module as well.
+type TokenCertificate struct {
+	// Module is the PKCS#11 library, e.g. /usr/lib/opensc-pkcs11.so.
+	Module     string `json:"module"`
+	TokenLabel string `json:"token_label,omitempty"`
+	// KeyID (hex) or KeyLabel names both the certificate and its key.
+	KeyID    string `json:"key_id,omitempty"`
+	KeyLabel string `json:"key_label,omitempty"`
+}
+
+// tokenCertificates holds the token configuration per context name and
+// the PINs entered this session, which are never persisted.
+type tokenCertificates struct {
+	mu      sync.Mutex
+	configs map[string]TokenCertificate
+	pins    map[string]string
+}
+
+// SetTokenCertificates replaces the per-context token configuration. It
+// applies the next time a context connects.
+func (c *Client) SetTokenCertificates(configs map[string]TokenCertificate) {
+	c.tokenCerts.mu.Lock()
+	defer c.tokenCerts.mu.Unlock()
+	c.tokenCerts.configs = configs
+}
+
+// SetTokenPIN keeps the PIN of a context's token in memory for signing;
+// an empty PIN forgets it. Tokens with a PIN pad need none.
+func (c *Client) SetTokenPIN(contextName, pin string) {
+	c.tokenCerts.mu.Lock()
+	defer c.tokenCerts.mu.Unlock()
+	if c.tokenCerts.pins == nil {
+		c.tokenCerts.pins = make(map[string]string)
+	}
+	if pin == "" {
+		delete(c.tokenCerts.pins, contextName)
+	} else {
+		c.tokenCerts.pins[contextName] = pin
+	}
+}
+
+func (c *Client) tokenCertificateFor(contextName string) (TokenCertificate, string, bool) {
+	c.tokenCerts.mu.Lock()
+	defer c.tokenCerts.mu.Unlock()
+	cfg, ok := c.tokenCerts.configs[contextName]
+	return cfg, c.tokenCerts.pins[contextName], ok && cfg.Module != ""
+}
+
+// useTokenCertificate makes restConfig authenticate with a certificate
+// on a PKCS#11 token. client-go only takes key material, so restConfig
+// gets a transport whose TLS configuration asks the token for the client
+// certificate; spdyRoundTripperFor reuses it for exec, port-forward and
+// terminals.
+func (c *Client) useTokenCertificate(restConfig *rest.Config, contextName string, cfg TokenCertificate, pin string) error {
+	host, err := url.Parse(restConfig.Host)
+	if err != nil {
+		return fmt.Errorf("invalid server %q: %v", restConfig.Host, err)
+	}
+	if host.Scheme != "https" {
+		return fmt.Errorf("context %s doesn't use TLS; a client certificate can't be used", contextName)
+	}
+	signer, err := newPKCS11Signer(cfg, pin)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := rest.TLSConfigFor(restConfig)
+	if err != nil {
+		return err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = host.Hostname()
+	}
+	tlsConfig.Certificates = nil
+	tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return &tls.Certificate{Certificate: [][]byte{signer.cert.Raw}, PrivateKey: signer, Leaf: signer.cert}, nil
+	}
+
+	dial := restConfig.Dial
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	restConfig.Transport = utilnet.SetTransportDefaults(&http.Transport{
+		Proxy:               restConfig.Proxy,
+		DialContext:         dial,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 25,
+	})
+	// client-go refuses TLS options next to a custom transport, which
+	// carries them now.
+	restConfig.TLSClientConfig = rest.TLSClientConfig{}
+	restConfig.Dial = nil
+	return nil
+}
+
+// pkcs11Signer is a crypto.Signer backed by a key on a PKCS#11 token,
+// driven through pkcs11-tool.
+type pkcs11Signer struct {
+	cfg  TokenCertificate
+	pin  string
+	cert *x509.Certificate
+}
+
+func newPKCS11Signer(cfg TokenCertificate, pin string) (*pkcs11Signer, error) {
+	if cfg.KeyID == "" && cfg.KeyLabel == "" {
+		return nil, errors.New("token certificate needs a key ID or label")
+	}
+	s := &pkcs11Signer{cfg: cfg, pin: pin}
+	der, err := s.run(nil, "--read-object", "--type", "cert")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the certificate from the token: %v", err)
+	}
+	if s.cert, err = x509.ParseCertificate(der); err != nil {
+		return nil, fmt.Errorf("invalid certificate on the token: %v", err)
+	}
+	switch s.cert.PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported key type %T on the token", s.cert.PublicKey)
+	}
+	return s, nil
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.cert.PublicKey
+}
+
+// Sign signs a digest on the token. TLS 1.3 asks RSA keys for PSS and
+// TLS 1.2 for PKCS #1 v1.5, which the token expects DigestInfo-wrapped.
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	hash := strings.ReplaceAll(opts.HashFunc().String(), "-", "")
+	args := []string{"--sign"}
+	input := digest
+	switch s.cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		args = append(args, "--mechanism", "ECDSA", "--signature-format", "openssl")
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			saltLen := pss.SaltLength
+			if saltLen == rsa.PSSSaltLengthEqualsHash || saltLen == rsa.PSSSaltLengthAuto {
+				saltLen = len(digest)
+			}
+			args = append(args, "--mechanism", "RSA-PKCS-PSS", "--hash-algorithm", hash,
+				"--mgf", "MGF1-"+hash, "--salt-len", fmt.Sprint(saltLen))
+		} else {
+			prefix, ok := digestInfoPrefixes[opts.HashFunc()]
+			if !ok {
+				return nil, fmt.Errorf("unsupported hash %v", opts.HashFunc())
+			}
+			args = append(args, "--mechanism", "RSA-PKCS")
+			input = append(append([]byte{}, prefix...), digest...)
+		}
+	}
+	return s.run(input, args...)
+}
+
+// digestInfoPrefixes are the DER DigestInfo headers PKCS #1 v1.5 signs.
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// run calls pkcs11-tool on the configured token and object with input,
+// if any, and returns what it wrote.
+func (s *pkcs11Signer) run(input []byte, args ...string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "teleskope-pkcs11-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	outPath := dir + "/out"
+
+	argv := []string{"--module", s.cfg.Module}
+	if s.cfg.TokenLabel != "" {
+		argv = append(argv, "--token-label", s.cfg.TokenLabel)
+	}
+	if s.cfg.KeyID != "" {
+		argv = append(argv, "--id", s.cfg.KeyID)
+	} else {
+		argv = append(argv, "--label", s.cfg.KeyLabel)
+	}
+	if input != nil {
+		inPath := dir + "/in"
+		if err := os.WriteFile(inPath, input, 0o600); err != nil {
+			return nil, err
+		}
+		argv = append(argv, "--login", "--input-file", inPath)
+		if s.pin != "" {
+			argv = append(argv, "--pin", "env:"+pkcs11PINEnv)
+		}
+	}
+	argv = append(append(argv, args...), "--output-file", outPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), pkcs11ToolTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "pkcs11-tool", argv...)
+	if s.pin != "" {
+		cmd.Env = append(os.Environ(), pkcs11PINEnv+"="+s.pin)
+	}
+	out, err := cmd.CombinedOutput()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, errors.New("pkcs11-tool (OpenSC) is required for token certificates")
+	}
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if s.pin != "" {
+			msg = strings.ReplaceAll(msg, s.pin, "****")
+		}
+		return nil, fmt.Errorf("pkcs11-tool failed: %v: %s", err, msg)
+	}
+	return os.ReadFile(outPath)
+}
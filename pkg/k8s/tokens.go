@@ -0,0 +1,357 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// legacyTokenLastUsedLabel is set by the API server (1.29+) on
+	// service account token Secrets, with day precision.
+	legacyTokenLastUsedLabel = "kubernetes.io/legacy-token-last-used"
+
+	// staleTokenAge is how long a token Secret must have gone unused
+	// before deleting it is offered.
+	staleTokenAge = 30 * 24 * time.Hour
+)
+
+// TokenRemediation is a one-click fix: either a patch of Target or, when
+// Delete is set, deleting it.
+type TokenRemediation struct {
+	Description string      `json:"description"`
+	Target      ResourceRef `json:"target"`
+	PatchType   string      `json:"patch_type,omitempty"`
+	Patch       string      `json:"patch,omitempty"`
+	Delete      bool        `json:"delete,omitempty"`
+}
+
+// AutomountFinding is a workload whose pods mount an API token for a
+// service account that isn't granted any RBAC permissions.
+type AutomountFinding struct {
+	Namespace      string            `json:"namespace"`
+	Kind           string            `json:"kind"`
+	Name           string            `json:"name"`
+	ServiceAccount string            `json:"service_account"`
+	Pods           []string          `json:"pods"`
+	Remediation    *TokenRemediation `json:"remediation,omitempty"`
+}
+
+// TokenSecretFinding is a long-lived kubernetes.io/service-account-token
+// Secret.
+type TokenSecretFinding struct {
+	Namespace      string            `json:"namespace"`
+	Name           string            `json:"name"`
+	ServiceAccount string            `json:"service_account"`
+	Created        time.Time         `json:"created"`
+	LastUsed       string            `json:"last_used,omitempty"`
+	MountedBy      []string          `json:"mounted_by"`
+	Remediation    *TokenRemediation `json:"remediation,omitempty"`
+}
+
+type TokenAuditReport struct {
+	Automount    []AutomountFinding   `json:"automount"`
+	TokenSecrets []TokenSecretFinding `json:"token_secrets"`
+}
+
+// GetTokenAudit supports the migration to bound service account tokens.
+// It reports workloads that automount a token although their service
+// account has no role bindings, and legacy token Secrets with the pods
+// still mounting them. Each finding carries a remediation when one can be
+// applied safely: disabling automount on the workload, replacing the
+// Secret volume with a projected token, or deleting a Secret unused for
+// 30 days.
+func (c *Client) GetTokenAudit(namespace string) (*TokenAuditReport, error) {
+	var pods *corev1.PodList
+	var accounts *corev1.ServiceAccountList
+	var secrets *corev1.SecretList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		if pods, err = c.Clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			return err
+		}
+		if accounts, err = c.Clientset.CoreV1().ServiceAccounts(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+			return err
+		}
+		secrets, err = c.Clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "type=" + string(corev1.SecretTypeServiceAccountToken),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	bound, err := c.boundServiceAccounts(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	saAutomount := make(map[string]*bool)
+	for _, sa := range accounts.Items {
+		saAutomount[sa.Namespace+"/"+sa.Name] = sa.AutomountServiceAccountToken
+	}
+
+	report := &TokenAuditReport{Automount: []AutomountFinding{}, TokenSecrets: []TokenSecretFinding{}}
+	owners := make(map[types.UID]ObjectNode)
+	automount := make(map[string]*AutomountFinding)
+	mounts := make(map[string][]corev1.Pod)
+
+	for _, pod := range pods.Items {
+		for _, vol := range pod.Spec.Volumes {
+			if vol.Secret != nil {
+				key := pod.Namespace + "/" + vol.Secret.SecretName
+				mounts[key] = append(mounts[key], pod)
+			}
+		}
+
+		sa := pod.Spec.ServiceAccountName
+		if sa == "" {
+			sa = "default"
+		}
+		if bound[pod.Namespace+"/"+sa] || !tokenAutomounted(&pod, saAutomount[pod.Namespace+"/"+sa]) {
+			continue
+		}
+		owner := c.podWorkload(&pod, owners)
+		key := owner.Namespace + "/" + owner.Kind + "/" + owner.Name
+		finding, ok := automount[key]
+		if !ok {
+			finding = &AutomountFinding{
+				Namespace:      pod.Namespace,
+				Kind:           owner.Kind,
+				Name:           owner.Name,
+				ServiceAccount: sa,
+				Remediation:    automountRemediation(owner),
+			}
+			automount[key] = finding
+		}
+		finding.Pods = append(finding.Pods, pod.Name)
+	}
+	for _, f := range automount {
+		sort.Strings(f.Pods)
+		report.Automount = append(report.Automount, *f)
+	}
+	sort.Slice(report.Automount, func(i, j int) bool {
+		if report.Automount[i].Namespace != report.Automount[j].Namespace {
+			return report.Automount[i].Namespace < report.Automount[j].Namespace
+		}
+		return report.Automount[i].Name < report.Automount[j].Name
+	})
+
+	for _, secret := range secrets.Items {
+		finding := TokenSecretFinding{
+			Namespace:      secret.Namespace,
+			Name:           secret.Name,
+			ServiceAccount: secret.Annotations[corev1.ServiceAccountNameKey],
+			Created:        secret.CreationTimestamp.Time,
+			LastUsed:       secret.Labels[legacyTokenLastUsedLabel],
+			MountedBy:      []string{},
+		}
+		mountedBy := mounts[secret.Namespace+"/"+secret.Name]
+		for _, pod := range mountedBy {
+			finding.MountedBy = append(finding.MountedBy, pod.Name)
+		}
+		sort.Strings(finding.MountedBy)
+
+		if len(mountedBy) > 0 {
+			finding.Remediation = c.projectedTokenRemediation(&mountedBy[0], secret.Name, owners)
+		} else if tokenStale(&secret) {
+			finding.Remediation = &TokenRemediation{
+				Description: "Delete the unused token Secret",
+				Target:      ResourceRef{Version: "v1", Kind: "Secret", Plural: "secrets", Namespace: secret.Namespace, Name: secret.Name},
+				Delete:      true,
+			}
+		}
+		report.TokenSecrets = append(report.TokenSecrets, finding)
+	}
+	sort.Slice(report.TokenSecrets, func(i, j int) bool {
+		if report.TokenSecrets[i].Namespace != report.TokenSecrets[j].Namespace {
+			return report.TokenSecrets[i].Namespace < report.TokenSecrets[j].Namespace
+		}
+		return report.TokenSecrets[i].Name < report.TokenSecrets[j].Name
+	})
+	return report, nil
+}
+
+// boundServiceAccounts returns "namespace/name" of every service account
+// that is a subject of a RoleBinding or ClusterRoleBinding.
+func (c *Client) boundServiceAccounts(namespace string) (map[string]bool, error) {
+	bound := make(map[string]bool)
+	err := c.do(OpList, func(ctx context.Context) error {
+		roleBindings, err := c.Clientset.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		clusterBindings, err := c.Clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, rb := range roleBindings.Items {
+			for _, s := range rb.Subjects {
+				if s.Kind == "ServiceAccount" {
+					ns := s.Namespace
+					if ns == "" {
+						ns = rb.Namespace
+					}
+					bound[ns+"/"+s.Name] = true
+				}
+			}
+		}
+		for _, crb := range clusterBindings.Items {
+			for _, s := range crb.Subjects {
+				if s.Kind == "ServiceAccount" {
+					bound[s.Namespace+"/"+s.Name] = true
+				}
+			}
+		}
+		return nil
+	})
+	return bound, err
+}
+
+// tokenAutomounted applies the pod-over-service-account precedence of
+// automountServiceAccountToken; it defaults to true.
+func tokenAutomounted(pod *corev1.Pod, saAutomount *bool) bool {
+	if pod.Spec.AutomountServiceAccountToken != nil {
+		return *pod.Spec.AutomountServiceAccountToken
+	}
+	if saAutomount != nil {
+		return *saAutomount
+	}
+	return true
+}
+
+func tokenStale(secret *corev1.Secret) bool {
+	since := secret.CreationTimestamp.Time
+	if lastUsed, err := time.Parse("2006-01-02", secret.Labels[legacyTokenLastUsedLabel]); err == nil {
+		since = lastUsed
+	}
+	return time.Since(since) > staleTokenAge
+}
+
+// podWorkload returns the top-level controller of a pod, or the pod itself
+// when it isn't controlled. Results are cached by the pod's direct
+// controller so sibling pods resolve once.
+func (c *Client) podWorkload(pod *corev1.Pod, cache map[types.UID]ObjectNode) ObjectNode {
+	self := ObjectNode{Version: "v1", Kind: "Pod", Plural: "pods", Namespace: pod.Namespace, Name: pod.Name, UID: string(pod.UID)}
+	controller := metav1.GetControllerOfNoCopy(pod)
+	if controller == nil {
+		return self
+	}
+	if owner, ok := cache[controller.UID]; ok {
+		return owner
+	}
+	// ownerChain only needs the namespace and owner references.
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetNamespace(pod.Namespace)
+	obj.SetOwnerReferences(pod.OwnerReferences)
+	owner := self
+	if chain := c.ownerChain(obj); len(chain) > 0 && chain[len(chain)-1].Plural != "" {
+		owner = chain[len(chain)-1]
+	}
+	cache[controller.UID] = owner
+	return owner
+}
+
+func workloadRef(n ObjectNode) ResourceRef {
+	return ResourceRef{Group: n.Group, Version: n.Version, Kind: n.Kind, Plural: n.Plural, Namespace: n.Namespace, Name: n.Name}
+}
+
+// podTemplatePath is where a workload kind keeps its pod spec.
+func podTemplatePath(kind string) []string {
+	switch kind {
+	case "Pod":
+		return []string{"spec"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	return []string{"spec", "template", "spec"}
+}
+
+// automountRemediation disables token automount on the workload's pod
+// template. Bare pods can't be changed in place.
+func automountRemediation(owner ObjectNode) *TokenRemediation {
+	if owner.Kind == "Pod" {
+		return nil
+	}
+	patch := map[string]interface{}{}
+	_ = unstructured.SetNestedField(patch, false, append(podTemplatePath(owner.Kind), "automountServiceAccountToken")...)
+	data, _ := json.Marshal(patch)
+	return &TokenRemediation{
+		Description: fmt.Sprintf("Set automountServiceAccountToken: false on %s %s", owner.Kind, owner.Name),
+		Target:      workloadRef(owner),
+		PatchType:   string(types.MergePatchType),
+		Patch:       string(data),
+	}
+}
+
+// projectedTokenRemediation replaces the Secret volume in the pod's
+// workload template with a projected volume carrying a bound token, the
+// cluster CA and the namespace, i.e. what the kubelet mounts by default.
+func (c *Client) projectedTokenRemediation(pod *corev1.Pod, secretName string, cache map[types.UID]ObjectNode) *TokenRemediation {
+	owner := c.podWorkload(pod, cache)
+	if owner.Kind == "Pod" {
+		return nil
+	}
+	res, err := c.GetResource(owner.Group, owner.Version, owner.Kind, owner.Plural, owner.Namespace, owner.Name)
+	if err != nil {
+		return nil
+	}
+	path := podTemplatePath(owner.Kind)
+	volumes, _, _ := unstructured.NestedSlice(res.(map[string]interface{}), append(path, "volumes")...)
+	for i, raw := range volumes {
+		vol, _ := raw.(map[string]interface{})
+		name, _ := vol["name"].(string)
+		secret, _, _ := unstructured.NestedString(vol, "secret", "secretName")
+		if secret != secretName {
+			continue
+		}
+		expiration := int64(3600)
+		projected := corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token", ExpirationSeconds: &expiration}},
+					{ConfigMap: &corev1.ConfigMapProjection{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "kube-root-ca.crt"},
+						Items:                []corev1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}},
+					}},
+					{DownwardAPI: &corev1.DownwardAPIProjection{
+						Items: []corev1.DownwardAPIVolumeFile{{
+							Path:     "namespace",
+							FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.namespace"},
+						}},
+					}},
+				},
+			}},
+		}
+		patch, _ := json.Marshal([]map[string]interface{}{{
+			"op":    "replace",
+			"path":  fmt.Sprintf("/%s/volumes/%d", strings.Join(path, "/"), i),
+			"value": projected,
+		}})
+		return &TokenRemediation{
+			Description: fmt.Sprintf("Replace Secret volume %s in %s %s with a projected bound token", name, owner.Kind, owner.Name),
+			Target:      workloadRef(owner),
+			PatchType:   string(types.JSONPatchType),
+			Patch:       string(patch),
+		}
+	}
+	return nil
+}
+
+// ApplyTokenRemediation applies a remediation returned by GetTokenAudit.
+func (c *Client) ApplyTokenRemediation(r TokenRemediation) error {
+	if r.Delete {
+		return c.DeleteResource(r.Target.Group, r.Target.Version, r.Target.Kind, r.Target.Plural, r.Target.Namespace, r.Target.Name)
+	}
+	_, err := c.PatchResource(r.Target, r.PatchType, r.Patch)
+	return err
+}
@@ -0,0 +1,351 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	// EventResourcesSync carries a full snapshot of a watched list. It is
+	// sent first and again whenever the watch had to re-list.
+	EventResourcesSync = "resources:sync"
+	// EventResourcesChange carries incremental add/update/delete changes.
+	EventResourcesChange = "resources:change"
+
+	changeBatchInterval = 100 * time.Millisecond
+)
+
+type WatchParams struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Plural    string `json:"plural"`
+	Namespace string `json:"namespace"`
+	// LabelSelector and FieldSelector limit the watch to matching objects,
+	// e.g. the pods of one app. An object that stops matching arrives as
+	// a DELETED change.
+	LabelSelector string `json:"label_selector"`
+	FieldSelector string `json:"field_selector"`
+}
+
+func (p WatchParams) GVR() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: p.Group, Version: p.Version, Resource: p.Plural}
+}
+
+func (p WatchParams) listOptions() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: p.LabelSelector, FieldSelector: p.FieldSelector}
+}
+
+type ResourceSync struct {
+	SubscriptionID  string        `json:"subscription_id"`
+	ResourceVersion string        `json:"resource_version"`
+	Items           []interface{} `json:"items"`
+}
+
+type ResourceChange struct {
+	Type   string      `json:"type"`
+	Object interface{} `json:"object"`
+}
+
+type ResourceChanges struct {
+	SubscriptionID string           `json:"subscription_id"`
+	Changes        []ResourceChange `json:"changes"`
+}
+
+// WatchResources keeps a list of one resource type (optionally limited to
+// a namespace and to objects matching label and field selectors) live. It
+// emits an EventResourcesSync snapshot followed by batched
+// EventResourcesChange events, resuming from the last seen
+// resourceVersion after disconnects and re-listing when the server
+// answers 410 Gone. When the OpWatch retries run out it emits
+// EventSubscriptionFailed. It returns a subscription ID for
+// StopSubscription.
+// GetWatchSnapshot returns the current list for subscribers that missed
+// the first snapshot.
+func (c *Client) WatchResources(params WatchParams) (string, error) {
+	gvr := params.GVR()
+	ctx, cancel := context.WithCancel(context.Background())
+	id := c.subs.add("watch", cancel, gvr)
+
+	var resource dynamic.ResourceInterface = c.DynamicClient.Resource(gvr)
+	if params.Namespace != "" {
+		resource = c.DynamicClient.Resource(gvr).Namespace(params.Namespace)
+	}
+
+	// The first list runs synchronously so callers get an error for bad
+	// GVRs or selectors instead of a silent subscription.
+	opts := params.listOptions()
+	rv, err := c.syncList(id, resource, opts)
+	if err != nil {
+		c.subs.stop(id)
+		if isResourceGone(err) {
+			go c.resourceGone(gvr)
+		}
+		return "", err
+	}
+
+	go c.runResourceWatch(ctx, id, gvr, resource, opts, rv)
+	return id, nil
+}
+
+func (c *Client) syncList(id string, resource dynamic.ResourceInterface, opts metav1.ListOptions) (string, error) {
+	var list *unstructured.UnstructuredList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		list, err = resource.List(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	items := make([]interface{}, 0, len(list.Items))
+	for _, item := range list.Items {
+		RedactSecret(item.Object)
+		TruncateConfigMap(item.Object)
+		items = append(items, item.Object)
+	}
+	c.snapshots.reset(id, list.GetResourceVersion(), list.Items)
+	c.emit(EventResourcesSync, ResourceSync{
+		SubscriptionID:  id,
+		ResourceVersion: list.GetResourceVersion(),
+		Items:           items,
+	})
+	return list.GetResourceVersion(), nil
+}
+
+func (c *Client) runResourceWatch(ctx context.Context, id string, gvr schema.GroupVersionResource, resource dynamic.ResourceInterface, opts metav1.ListOptions, rv string) {
+	defer c.subs.remove(id)
+	defer c.snapshots.remove(id)
+	policy := c.policies.get(OpWatch)
+
+	var pending []ResourceChange
+	flush := func() {
+		if len(pending) > 0 {
+			c.emit(EventResourcesChange, ResourceChanges{SubscriptionID: id, Changes: pending})
+			pending = nil
+		}
+	}
+	ticker := time.NewTicker(changeBatchInterval)
+	defer ticker.Stop()
+
+	relist := false
+	// failures counts consecutive failed lists and watch requests, for
+	// the backoff.
+	failures := 0
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if relist {
+			flush()
+			newRV, err := c.syncList(id, resource, opts)
+			if err != nil {
+				if isResourceGone(err) {
+					c.resourceGone(gvr)
+					return
+				}
+				failures++
+				if policy.Retries > 0 && failures > policy.Retries {
+					c.subscriptionFailed(id, watchGaveUp(failures, err))
+					return
+				}
+				if !sleepCtx(ctx, policy.delay(failures)) {
+					return
+				}
+				continue
+			}
+			rv = newRV
+			relist = false
+		}
+
+		attemptCtx, cancel := context.WithCancel(ctx)
+		if policy.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		}
+		watchOpts := opts
+		watchOpts.ResourceVersion = rv
+		watchOpts.AllowWatchBookmarks = true
+		w, err := resource.Watch(attemptCtx, watchOpts)
+		if err != nil {
+			cancel()
+			switch {
+			case isResourceGone(err):
+				c.resourceGone(gvr)
+				return
+			case apierrors.IsResourceExpired(err) || apierrors.IsGone(err):
+				relist = true
+			default:
+				failures++
+				if policy.Retries > 0 && failures > policy.Retries {
+					c.subscriptionFailed(id, watchGaveUp(failures, err))
+					return
+				}
+				if !sleepCtx(ctx, policy.delay(failures)) {
+					return
+				}
+			}
+			continue
+		}
+		failures = 0
+
+	events:
+		for {
+			select {
+			case <-ctx.Done():
+				w.Stop()
+				cancel()
+				return
+			case <-ticker.C:
+				flush()
+			case ev, ok := <-w.ResultChan():
+				if !ok {
+					break events
+				}
+				switch ev.Type {
+				case watch.Error:
+					// 410 Gone and friends: our resourceVersion is too old.
+					relist = true
+					break events
+				case watch.Bookmark:
+					if u, ok := asUnstructured(ev.Object); ok {
+						rv = u.GetResourceVersion()
+						c.snapshots.apply(id, rv, ev.Type, nil)
+					}
+				default:
+					u, ok := asUnstructured(ev.Object)
+					if !ok {
+						continue
+					}
+					rv = u.GetResourceVersion()
+					RedactSecret(u.Object)
+					TruncateConfigMap(u.Object)
+					c.snapshots.apply(id, rv, ev.Type, u)
+					pending = append(pending, ResourceChange{Type: string(ev.Type), Object: u.Object})
+				}
+			}
+		}
+		w.Stop()
+		cancel()
+		flush()
+	}
+}
+
+// asUnstructured returns the object of a dynamic watch event. The fake
+// dynamic client of the demo cluster sends the typed objects it was
+// seeded with, so those are converted, with their kind filled in.
+func asUnstructured(obj runtime.Object) (*unstructured.Unstructured, bool) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, true
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, false
+	}
+	u := &unstructured.Unstructured{Object: content}
+	if u.GetKind() == "" {
+		if gvks, _, err := scheme.Scheme.ObjectKinds(obj); err == nil {
+			u.SetGroupVersionKind(gvks[0])
+		}
+	}
+	return u, true
+}
+
+// sleepCtx waits for d and reports whether ctx is still alive.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// watchSnapshots keeps the current list of every running resource watch,
+// kept up to date with its changes, so a list can be picked up after its
+// EventResourcesSync was emitted, e.g. by a frontend subscribing to the
+// eager watches started on launch.
+type watchSnapshots struct {
+	mu    sync.Mutex
+	lists map[string]*watchSnapshot
+}
+
+type watchSnapshot struct {
+	resourceVersion string
+	// items are keyed by namespace/name.
+	items map[string]map[string]interface{}
+}
+
+func (s *watchSnapshots) reset(id, resourceVersion string, items []unstructured.Unstructured) {
+	snapshot := &watchSnapshot{resourceVersion: resourceVersion, items: make(map[string]map[string]interface{}, len(items))}
+	for _, item := range items {
+		snapshot.items[item.GetNamespace()+"/"+item.GetName()] = item.Object
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lists == nil {
+		s.lists = make(map[string]*watchSnapshot)
+	}
+	s.lists[id] = snapshot
+}
+
+// apply records a watch event; u is nil for bookmarks.
+func (s *watchSnapshots) apply(id, resourceVersion string, t watch.EventType, u *unstructured.Unstructured) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.lists[id]
+	if !ok {
+		return
+	}
+	snapshot.resourceVersion = resourceVersion
+	if u == nil {
+		return
+	}
+	key := u.GetNamespace() + "/" + u.GetName()
+	if t == watch.Deleted {
+		delete(snapshot.items, key)
+	} else {
+		snapshot.items[key] = u.Object
+	}
+}
+
+func (s *watchSnapshots) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lists, id)
+}
+
+// GetWatchSnapshot returns the current list of a running WatchResources
+// subscription, sorted by namespace and name, in the shape of an
+// EventResourcesSync. Changes emitted after it was taken are newer.
+func (c *Client) GetWatchSnapshot(id string) (*ResourceSync, error) {
+	s := &c.snapshots
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot, ok := s.lists[id]
+	if !ok {
+		return nil, fmt.Errorf("no running watch %s", id)
+	}
+	keys := make([]string, 0, len(snapshot.items))
+	for key := range snapshot.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	items := make([]interface{}, len(keys))
+	for i, key := range keys {
+		items[i] = snapshot.items[key]
+	}
+	return &ResourceSync{SubscriptionID: id, ResourceVersion: snapshot.resourceVersion, Items: items}, nil
+}
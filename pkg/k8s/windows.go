@@ -0,0 +1,159 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	osLabel = "kubernetes.io/os"
+	osLinux = "linux"
+	// osWindows is the value of kubernetes.io/os and
+	// status.nodeInfo.operatingSystem on Windows nodes.
+	osWindows = "windows"
+)
+
+// windowsShell prefers PowerShell and falls back to cmd, which is all
+// Nano Server images have.
+var windowsShell = []string{"cmd.exe", "/c", "where powershell.exe >nul 2>&1 && powershell.exe -NoLogo || cmd.exe"}
+
+// NodeSummary is a row of the node list.
+type NodeSummary struct {
+	Name          string   `json:"name"`
+	Roles         []string `json:"roles"`
+	Ready         bool     `json:"ready"`
+	Unschedulable bool     `json:"unschedulable"`
+	OS            string   `json:"os"`
+	OSImage       string   `json:"os_image"`
+	Architecture  string   `json:"architecture"`
+	// Runtime is the container runtime name, e.g. containerd or cri-o,
+	// and RuntimeVersion its version.
+	Runtime        string `json:"runtime"`
+	RuntimeVersion string `json:"runtime_version"`
+	KubeletVersion string `json:"kubelet_version"`
+	// LinuxDiagnostics is false on nodes where shell-based tools such as
+	// the container file browser don't work.
+	LinuxDiagnostics bool `json:"linux_diagnostics"`
+}
+
+// ListNodes returns the node list with OS and runtime information.
+func (c *Client) ListNodes() ([]NodeSummary, error) {
+	var nodes *corev1.NodeList
+	err := c.do(OpList, func(ctx context.Context) error {
+		var err error
+		nodes, err = c.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]NodeSummary, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		info := node.Status.NodeInfo
+		runtime, version := splitRuntimeVersion(info.ContainerRuntimeVersion)
+		summary := NodeSummary{
+			Name:           node.Name,
+			Roles:          nodeRoles(node.Labels),
+			Unschedulable:  node.Spec.Unschedulable,
+			OS:             nodeOS(&node),
+			OSImage:        info.OSImage,
+			Architecture:   info.Architecture,
+			Runtime:        runtime,
+			RuntimeVersion: version,
+			KubeletVersion: info.KubeletVersion,
+		}
+		summary.LinuxDiagnostics = summary.OS == osLinux
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady {
+				summary.Ready = cond.Status == corev1.ConditionTrue
+			}
+		}
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func nodeOS(node *corev1.Node) string {
+	if os := node.Status.NodeInfo.OperatingSystem; os != "" {
+		return os
+	}
+	if os := node.Labels[osLabel]; os != "" {
+		return os
+	}
+	return osLinux
+}
+
+func nodeRoles(labels map[string]string) []string {
+	roles := []string{}
+	for key := range labels {
+		if role, ok := strings.CutPrefix(key, nodeRoleLabelPrefix); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// splitRuntimeVersion splits "containerd://1.7.2" into name and version.
+func splitRuntimeVersion(v string) (string, string) {
+	name, version, ok := strings.Cut(v, "://")
+	if !ok {
+		return v, ""
+	}
+	return name, version
+}
+
+// podOS returns the operating system a pod runs on: spec.os when set,
+// otherwise the OS of its node, otherwise what its nodeSelector asks for.
+// Pods that give no hint are assumed to be Linux.
+func (c *Client) podOS(ctx context.Context, namespace, podName string) (string, error) {
+	pod, err := c.Clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if pod.Spec.OS != nil && pod.Spec.OS.Name != "" {
+		return string(pod.Spec.OS.Name), nil
+	}
+	if pod.Spec.NodeName != "" {
+		if node, err := c.Clientset.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{}); err == nil {
+			return nodeOS(node), nil
+		}
+	}
+	if os := pod.Spec.NodeSelector[osLabel]; os != "" {
+		return os, nil
+	}
+	return osLinux, nil
+}
+
+// shellFor picks the default interactive shell for a pod's OS. Lookup
+// failures fall back to the Linux shell; exec reports the real error.
+func (c *Client) shellFor(namespace, podName string) []string {
+	ctx, cancel := c.opContext(OpGet)
+	defer cancel()
+	if os, err := c.podOS(ctx, namespace, podName); err == nil && os == osWindows {
+		return windowsShell
+	}
+	return defaultShell
+}
+
+// requireLinuxPod fails for Windows pods, for diagnostics built on POSIX
+// shell tools.
+func (c *Client) requireLinuxPod(namespace, podName string) error {
+	ctx, cancel := c.opContext(OpGet)
+	defer cancel()
+	os, err := c.podOS(ctx, namespace, podName)
+	if err != nil {
+		return err
+	}
+	if os == osWindows {
+		return fmt.Errorf("%s/%s runs on Windows; this needs a Linux container", namespace, podName)
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+// Package scheduler runs recurring background tasks, such as refreshing
+// discovery or snapshotting namespaces, and keeps a history of their
+// runs. What a task does is up to the Runner registered for its kind.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"teleskope/pkg/appdir"
+)
+
+const (
+	// tickInterval is how often due tasks are looked for, and so the
+	// granularity of schedules.
+	tickInterval = time.Minute
+	// historyLimit is the number of runs kept per task.
+	historyLimit = 20
+)
+
+type Task struct {
+	Name     string
+	Kind     string
+	Interval time.Duration
+	// Params are passed to the runner as-is, e.g. the namespaces to
+	// snapshot.
+	Params []string
+}
+
+type Run struct {
+	Task     string    `json:"task"`
+	Context  string    `json:"context"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Summary  string    `json:"summary,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// TaskStatus describes a scheduled task for display.
+type TaskStatus struct {
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Interval string    `json:"interval"`
+	Running  bool      `json:"running"`
+	LastRun  *Run      `json:"last_run,omitempty"`
+	NextRun  time.Time `json:"next_run"`
+}
+
+// Runner performs one run of a task and returns a short summary of what
+// it did.
+type Runner func(ctx context.Context, task Task) (string, error)
+
+// Scheduler persists run history as one JSON file under dir, so schedules
+// carry over restarts: a nightly task that ran an hour before the app was
+// closed doesn't run again on the next launch.
+type Scheduler struct {
+	dir     string
+	runners map[string]Runner
+	// Context returns the name of the active context, recorded with each
+	// run.
+	Context func() string
+	// OnRun is called after every run, successful or not.
+	OnRun func(Run)
+
+	mu      sync.Mutex
+	tasks   []Task
+	history map[string][]Run
+	running map[string]bool
+	loaded  bool
+	cancel  context.CancelFunc
+}
+
+// DefaultDir returns the scheduler directory next to the settings file.
+func DefaultDir() string {
+	return appdir.Path("scheduler")
+}
+
+func New(dir string) *Scheduler {
+	return &Scheduler{
+		dir:     dir,
+		runners: make(map[string]Runner),
+		history: make(map[string][]Run),
+		running: make(map[string]bool),
+	}
+}
+
+// Register sets the runner for a task kind.
+func (s *Scheduler) Register(kind string, r Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runners[kind] = r
+}
+
+// Kinds lists the registered task kinds.
+func (s *Scheduler) Kinds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kinds := make([]string, 0, len(s.runners))
+	for kind := range s.runners {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// SetTasks replaces the schedule. Tasks of unknown kinds or without a
+// positive interval are rejected.
+func (s *Scheduler) SetTasks(tasks []Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make(map[string]bool)
+	for _, t := range tasks {
+		if t.Name == "" || names[t.Name] {
+			return fmt.Errorf("task names must be unique and non-empty")
+		}
+		names[t.Name] = true
+		if _, ok := s.runners[t.Kind]; !ok {
+			return fmt.Errorf("task %s: unknown kind %q", t.Name, t.Kind)
+		}
+		if t.Interval <= 0 {
+			return fmt.Errorf("task %s: interval must be positive", t.Name)
+		}
+	}
+	s.tasks = tasks
+	return nil
+}
+
+// Start checks for due tasks every minute until Stop is called.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		for {
+			s.runDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context) {
+	now := time.Now()
+	s.mu.Lock()
+	if err := s.load(); err != nil {
+		fmt.Printf("Error loading task history: %v\n", err)
+	}
+	var due []Task
+	for _, t := range s.tasks {
+		if !s.running[t.Name] && !now.Before(s.nextRun(t)) {
+			due = append(due, t)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, t := range due {
+		go s.run(ctx, t)
+	}
+}
+
+// RunNow starts a task immediately, regardless of its schedule.
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	var task *Task
+	for i := range s.tasks {
+		if s.tasks[i].Name == name {
+			task = &s.tasks[i]
+		}
+	}
+	running := s.running[name]
+	s.mu.Unlock()
+	if task == nil {
+		return fmt.Errorf("task %s not found", name)
+	}
+	if running {
+		return fmt.Errorf("task %s is already running", name)
+	}
+	go s.run(context.Background(), *task)
+	return nil
+}
+
+func (s *Scheduler) run(ctx context.Context, t Task) {
+	s.mu.Lock()
+	runner := s.runners[t.Kind]
+	if s.running[t.Name] || runner == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.running[t.Name] = true
+	s.mu.Unlock()
+
+	run := Run{Task: t.Name, Started: time.Now()}
+	if s.Context != nil {
+		run.Context = s.Context()
+	}
+	summary, err := runner(ctx, t)
+	run.Finished = time.Now()
+	run.Summary = summary
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	delete(s.running, t.Name)
+	if err := s.load(); err != nil {
+		fmt.Printf("Error loading task history: %v\n", err)
+	}
+	history := append(s.history[t.Name], run)
+	if len(history) > historyLimit {
+		history = history[len(history)-historyLimit:]
+	}
+	s.history[t.Name] = history
+	if err := s.save(); err != nil {
+		fmt.Printf("Error saving task history: %v\n", err)
+	}
+	s.mu.Unlock()
+
+	if s.OnRun != nil {
+		s.OnRun(run)
+	}
+}
+
+// nextRun is one interval after the last run, or now for tasks that never
+// ran. Callers must hold s.mu.
+func (s *Scheduler) nextRun(t Task) time.Time {
+	history := s.history[t.Name]
+	if len(history) == 0 {
+		return time.Now()
+	}
+	return history[len(history)-1].Started.Add(t.Interval)
+}
+
+// Status lists the scheduled tasks with their last and next run.
+func (s *Scheduler) Status() ([]TaskStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	statuses := make([]TaskStatus, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		status := TaskStatus{
+			Name:     t.Name,
+			Kind:     t.Kind,
+			Interval: t.Interval.String(),
+			Running:  s.running[t.Name],
+			NextRun:  s.nextRun(t),
+		}
+		if history := s.history[t.Name]; len(history) > 0 {
+			last := history[len(history)-1]
+			status.LastRun = &last
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// History returns the recorded runs of a task, newest first.
+func (s *Scheduler) History(name string) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	history := s.history[name]
+	runs := make([]Run, len(history))
+	for i, r := range history {
+		runs[len(history)-1-i] = r
+	}
+	return runs, nil
+}
+
+func (s *Scheduler) path() string {
+	return filepath.Join(s.dir, "history.json")
+}
+
+// load reads the history file once. Callers must hold s.mu.
+func (s *Scheduler) load() error {
+	if s.loaded {
+		return nil
+	}
+	data, err := os.ReadFile(s.path())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		history := make(map[string][]Run)
+		if err := json.Unmarshal(data, &history); err != nil {
+			return fmt.Errorf("corrupt task history: %v", err)
+		}
+		s.history = history
+	}
+	s.loaded = true
+	return nil
+}
+
+// save writes the history file. Callers must hold s.mu.
+func (s *Scheduler) save() error {
+	data, err := json.Marshal(s.history)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	return appdir.WriteFile(s.path(), data, 0o600)
+}
@@ -0,0 +1,310 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"teleskope/pkg/appdir"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Settings is the persisted application configuration, stored as YAML in
+// the user's config directory.
+type Settings struct {
+	// ContextTags maps a kubeconfig context name to user-defined tags,
+	// e.g. {"env": "prod", "team": "payments"}.
+	ContextTags map[string]map[string]string `json:"context_tags,omitempty"`
+
+	// ContextStartup holds per-context launch preferences keyed by
+	// context name.
+	ContextStartup map[string]ContextStartup `json:"context_startup,omitempty"`
+
+	SavedViews []SavedView `json:"saved_views,omitempty"`
+
+	// OperationPolicies overrides the timeout and retry budget per
+	// operation type (list, get, watch, exec, mutate).
+	OperationPolicies map[string]OperationPolicy `json:"operation_policies,omitempty"`
+
+	// EventArchive enables local event archival per context name.
+	EventArchive map[string]EventArchive `json:"event_archive,omitempty"`
+
+	// SSHTunnels routes a context's API traffic through a bastion host,
+	// keyed by context name.
+	SSHTunnels map[string]SSHTunnel `json:"ssh_tunnels,omitempty"`
+
+	// TokenCertificates authenticates a context with a client certificate
+	// on a PKCS#11 token (smartcard), keyed by context name.
+	TokenCertificates map[string]TokenCertificate `json:"token_certificates,omitempty"`
+
+	// RequestLimits caps concurrent API requests per context name.
+	RequestLimits map[string]RequestLimits `json:"request_limits,omitempty"`
+
+	// RequiredLabels are team-specific labels every workload must carry,
+	// checked by the label taxonomy report.
+	RequiredLabels []string `json:"required_labels,omitempty"`
+
+	// SecurityBaseline is the container securityContext workloads are
+	// checked against.
+	SecurityBaseline SecurityBaseline `json:"security_baseline,omitempty"`
+
+	// ExportRules turn environment-specific values into placeholder
+	// variables when exporting YAML.
+	ExportRules []ExportRule `json:"export_rules,omitempty"`
+
+	// ScheduledTasks are recurring background tasks run while the app is
+	// open.
+	ScheduledTasks []ScheduledTask `json:"scheduled_tasks,omitempty"`
+
+	// ResourceViews define list columns and health rules for custom
+	// resources, keyed by "<plural>.<group>".
+	ResourceViews map[string]ResourceView `json:"resource_views,omitempty"`
+
+	// KubeconfigPaths are kubeconfig files to merge instead of KUBECONFIG
+	// or ~/.kube/config.
+	KubeconfigPaths []string `json:"kubeconfig_paths,omitempty"`
+
+	// LastContext is the context last switched to. It is reconnected on
+	// launch unless a context is flagged for auto-connect.
+	LastContext string `json:"last_context,omitempty"`
+
+	// SelectedNamespaces are the namespaces last selected in the UI,
+	// keyed by context name.
+	SelectedNamespaces map[string][]string `json:"selected_namespaces,omitempty"`
+
+	// ColumnLayouts are the list column arrangements chosen in the UI,
+	// keyed by "<plural>.<group>".
+	ColumnLayouts map[string]ColumnLayout `json:"column_layouts,omitempty"`
+
+	// RefreshIntervals are polling periods in seconds keyed by view, e.g.
+	// "metrics" or "events"; 0 disables polling.
+	RefreshIntervals map[string]int `json:"refresh_intervals,omitempty"`
+
+	// Logs sets how streamed pod logs are shown.
+	Logs LogSettings `json:"logs,omitempty"`
+
+	// Updates configures the release check.
+	Updates UpdateSettings `json:"updates,omitempty"`
+}
+
+// LogSettings turn log stream options on for every stream.
+type LogSettings struct {
+	StripANSI      bool `json:"strip_ansi,omitempty"`
+	GroupMultiline bool `json:"group_multiline,omitempty"`
+}
+
+// SecurityBaseline holds the expected securityContext values; unset
+// fields aren't checked.
+type SecurityBaseline struct {
+	RunAsNonRoot             *bool    `json:"run_as_non_root,omitempty"`
+	ReadOnlyRootFilesystem   *bool    `json:"read_only_root_filesystem,omitempty"`
+	AllowPrivilegeEscalation *bool    `json:"allow_privilege_escalation,omitempty"`
+	Privileged               *bool    `json:"privileged,omitempty"`
+	DropCapabilities         []string `json:"drop_capabilities,omitempty"`
+	AllowedCapabilities      []string `json:"allowed_capabilities,omitempty"`
+	SeccompProfile           string   `json:"seccomp_profile,omitempty"`
+}
+
+// UpdateSettings choose the release channel ("stable" or "beta") and
+// whether to check for, and download, new versions on startup.
+type UpdateSettings struct {
+	Channel      string `json:"channel,omitempty"`
+	CheckOnStart bool   `json:"check_on_start,omitempty"`
+	AutoDownload bool   `json:"auto_download,omitempty"`
+}
+
+// ColumnLayout is the visible columns of a list in display order, with
+// their widths in pixels and the sort column.
+type ColumnLayout struct {
+	Columns  []string       `json:"columns"`
+	Widths   map[string]int `json:"widths,omitempty"`
+	SortBy   string         `json:"sort_by,omitempty"`
+	SortDesc bool           `json:"sort_desc,omitempty"`
+}
+
+type ResourceView struct {
+	Columns []ViewColumn `json:"columns,omitempty"`
+	Health  []HealthRule `json:"health,omitempty"`
+}
+
+// ViewColumn fills a list column from a JSONPath, e.g. ".status.phase".
+type ViewColumn struct {
+	Name     string `json:"name"`
+	JSONPath string `json:"json_path"`
+}
+
+// HealthRule requires either a status condition (ConditionType with
+// ConditionStatus, "True" by default) or the value at JSONPath to be one
+// of Values.
+type HealthRule struct {
+	ConditionType   string   `json:"condition_type,omitempty"`
+	ConditionStatus string   `json:"condition_status,omitempty"`
+	JSONPath        string   `json:"json_path,omitempty"`
+	Values          []string `json:"values,omitempty"`
+}
+
+// ScheduledTask runs Kind ("refresh-discovery", "snapshot-namespaces" or
+// "orphan-scan") every Interval, a duration such as "24h" or "168h".
+// Namespaces scopes snapshots and orphan scans.
+type ScheduledTask struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"`
+	Interval   string   `json:"interval"`
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// ExportRule replaces namespaces ("namespace"), image tags ("image-tag"),
+// hostnames ("host") or regex matches ("regex") with ${Variable}.
+type ExportRule struct {
+	Kind     string `json:"kind"`
+	Variable string `json:"variable"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+type SSHTunnel struct {
+	Host           string `json:"host"`
+	Port           int    `json:"port,omitempty"`
+	User           string `json:"user,omitempty"`
+	KeyPath        string `json:"key_path,omitempty"`
+	KnownHostsPath string `json:"known_hosts_path,omitempty"`
+}
+
+type TokenCertificate struct {
+	Module     string `json:"module"`
+	TokenLabel string `json:"token_label,omitempty"`
+	KeyID      string `json:"key_id,omitempty"`
+	KeyLabel   string `json:"key_label,omitempty"`
+}
+
+// EventArchive configures the background collector that keeps events
+// beyond the API server's TTL.
+type EventArchive struct {
+	Enabled bool `json:"enabled"`
+	// Namespaces to collect from; empty means all namespaces.
+	Namespaces    []string `json:"namespaces,omitempty"`
+	RetentionDays int      `json:"retention_days,omitempty"`
+}
+
+// RequestLimits mirrors k8s.RequestLimits; zero values use the defaults.
+type RequestLimits struct {
+	MaxInFlight   int `json:"max_in_flight,omitempty"`
+	MaxBackground int `json:"max_background,omitempty"`
+}
+
+type OperationPolicy struct {
+	TimeoutSeconds int `json:"timeout_seconds"`
+	Retries        int `json:"retries"`
+}
+
+// SavedView is a named resource query the user can re-run with one click.
+type SavedView struct {
+	Name          string   `json:"name"`
+	Group         string   `json:"group"`
+	Version       string   `json:"version"`
+	Kind          string   `json:"kind"`
+	Plural        string   `json:"plural"`
+	Namespace     string   `json:"namespace"`
+	LabelSelector string   `json:"label_selector,omitempty"`
+	FieldSelector string   `json:"field_selector,omitempty"`
+	Columns       []string `json:"columns,omitempty"`
+}
+
+func (s Settings) SavedView(name string) (SavedView, bool) {
+	for _, v := range s.SavedViews {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return SavedView{}, false
+}
+
+type ContextStartup struct {
+	// AutoConnect selects this context on launch. At most one context
+	// should have it set.
+	AutoConnect      bool   `json:"auto_connect"`
+	DefaultNamespace string `json:"default_namespace,omitempty"`
+	// EagerWatches lists resources ("group/version/plural", with an empty
+	// group for core) to start watching as soon as the context connects.
+	EagerWatches []string `json:"eager_watches,omitempty"`
+}
+
+// AutoConnectContext returns the context flagged for auto-connect, if any.
+func (s Settings) AutoConnectContext() (string, ContextStartup, bool) {
+	for name, pref := range s.ContextStartup {
+		if pref.AutoConnect {
+			return name, pref, true
+		}
+	}
+	return "", ContextStartup{}, false
+}
+
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	current Settings
+}
+
+// DefaultPath returns ~/.config/teleskope/config.yaml (or the platform
+// equivalent).
+func DefaultPath() string {
+	return appdir.Path("config.yaml")
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Load reads the settings file. A missing file yields empty settings.
+func (s *Store) Load() (Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.current = Settings{}
+		return s.current, nil
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+
+	var loaded Settings
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return Settings{}, err
+	}
+	s.current = loaded
+	return s.current, nil
+}
+
+// Get returns the in-memory settings. Callers must not mutate the maps it
+// contains; use Update instead.
+func (s *Store) Get() Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// Update applies fn to the settings and persists the result.
+func (s *Store) Update(fn func(*Settings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.current
+	fn(&next)
+	if err := s.write(next); err != nil {
+		return err
+	}
+	s.current = next
+	return nil
+}
+
+func (s *Store) write(st Settings) error {
+	data, err := yaml.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return appdir.WriteFile(s.path, data, 0o600)
+}
@@ -0,0 +1,173 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed files/*.yaml
+var files embed.FS
+
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamInt    ParamType = "int"
+)
+
+type Param struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Type        ParamType `json:"type"`
+	Default     string    `json:"default"`
+	Required    bool      `json:"required"`
+}
+
+type Template struct {
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+	Params      []Param `json:"params"`
+	file        string
+}
+
+var builtin = []Template{
+	{
+		Kind:        "Deployment",
+		Description: "Stateless application with a single container",
+		file:        "deployment.yaml",
+		Params: []Param{
+			{Name: "name", Description: "Deployment and container name", Type: ParamString, Required: true},
+			{Name: "namespace", Description: "Target namespace", Type: ParamString, Default: "default"},
+			{Name: "image", Description: "Container image", Type: ParamString, Required: true},
+			{Name: "replicas", Description: "Number of replicas", Type: ParamInt, Default: "1"},
+			{Name: "port", Description: "Container port", Type: ParamInt, Default: "8080"},
+		},
+	},
+	{
+		Kind:        "Service",
+		Description: "Service exposing pods selected by app name",
+		file:        "service.yaml",
+		Params: []Param{
+			{Name: "name", Description: "Service name", Type: ParamString, Required: true},
+			{Name: "namespace", Description: "Target namespace", Type: ParamString, Default: "default"},
+			{Name: "app", Description: "Value of the app.kubernetes.io/name selector", Type: ParamString, Required: true},
+			{Name: "type", Description: "ClusterIP, NodePort or LoadBalancer", Type: ParamString, Default: "ClusterIP"},
+			{Name: "port", Description: "Service port", Type: ParamInt, Default: "80"},
+			{Name: "targetPort", Description: "Container port", Type: ParamInt, Default: "8080"},
+		},
+	},
+	{
+		Kind:        "Ingress",
+		Description: "HTTP ingress routing a host to a Service",
+		file:        "ingress.yaml",
+		Params: []Param{
+			{Name: "name", Description: "Ingress name", Type: ParamString, Required: true},
+			{Name: "namespace", Description: "Target namespace", Type: ParamString, Default: "default"},
+			{Name: "host", Description: "Hostname", Type: ParamString, Required: true},
+			{Name: "path", Description: "Path prefix", Type: ParamString, Default: "/"},
+			{Name: "service", Description: "Backend Service name", Type: ParamString, Required: true},
+			{Name: "servicePort", Description: "Backend Service port", Type: ParamInt, Default: "80"},
+			{Name: "ingressClass", Description: "IngressClass name (optional)", Type: ParamString},
+		},
+	},
+	{
+		Kind:        "CronJob",
+		Description: "Scheduled job running a shell command",
+		file:        "cronjob.yaml",
+		Params: []Param{
+			{Name: "name", Description: "CronJob name", Type: ParamString, Required: true},
+			{Name: "namespace", Description: "Target namespace", Type: ParamString, Default: "default"},
+			{Name: "schedule", Description: "Cron schedule", Type: ParamString, Default: "0 * * * *"},
+			{Name: "image", Description: "Container image", Type: ParamString, Default: "busybox:stable"},
+			{Name: "command", Description: "Shell command to run", Type: ParamString, Default: "echo hello"},
+		},
+	},
+	{
+		Kind:        "PersistentVolumeClaim",
+		Description: "Volume claim with a single access mode",
+		file:        "persistentvolumeclaim.yaml",
+		Params: []Param{
+			{Name: "name", Description: "Claim name", Type: ParamString, Required: true},
+			{Name: "namespace", Description: "Target namespace", Type: ParamString, Default: "default"},
+			{Name: "size", Description: "Requested storage", Type: ParamString, Default: "1Gi"},
+			{Name: "accessMode", Description: "ReadWriteOnce, ReadOnlyMany or ReadWriteMany", Type: ParamString, Default: "ReadWriteOnce"},
+			{Name: "storageClass", Description: "StorageClass (empty for the cluster default)", Type: ParamString},
+		},
+	},
+}
+
+var funcs = template.FuncMap{
+	// quote renders a value as a JSON string, which is always valid YAML.
+	"quote": func(v interface{}) string {
+		b, _ := json.Marshal(fmt.Sprint(v))
+		return string(b)
+	},
+}
+
+func List() []Template {
+	result := make([]Template, len(builtin))
+	copy(result, builtin)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Kind < result[j].Kind
+	})
+	return result
+}
+
+func Get(kind string) (Template, bool) {
+	for _, t := range builtin {
+		if strings.EqualFold(t.Kind, kind) {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Render fills in the template for kind with params, applying defaults
+// and validating required and integer parameters. The output is checked
+// to be valid YAML.
+func Render(kind string, params map[string]string) (string, error) {
+	t, ok := Get(kind)
+	if !ok {
+		return "", fmt.Errorf("no template for kind %s", kind)
+	}
+
+	values := make(map[string]string, len(t.Params))
+	for _, p := range t.Params {
+		v := strings.TrimSpace(params[p.Name])
+		if v == "" {
+			v = p.Default
+		}
+		if v == "" && p.Required {
+			return "", fmt.Errorf("parameter %s is required", p.Name)
+		}
+		if v != "" && p.Type == ParamInt {
+			if _, err := strconv.Atoi(v); err != nil {
+				return "", fmt.Errorf("parameter %s must be an integer, got %q", p.Name, v)
+			}
+		}
+		values[p.Name] = v
+	}
+
+	tmpl, err := template.New(t.file).Funcs(funcs).Option("missingkey=zero").ParseFS(files, "files/"+t.file)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
+
+	if _, err := yaml.YAMLToJSON(buf.Bytes()); err != nil {
+		return "", fmt.Errorf("rendered template is not valid YAML: %v", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,449 @@
+// Package updater checks the project's release feed for newer versions
+// and downloads and stages their installers. Installing a staged update
+// is left to the user, since how an app is replaced differs per platform
+// and packaging.
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"teleskope/pkg/appdir"
+)
+
+// DefaultFeedURL is the GitHub releases API of the project.
+const DefaultFeedURL = "https://api.github.com/repos/p4block/teleskope/releases"
+
+// Release channels. The beta channel includes pre-releases.
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+const (
+	checkTimeout    = 30 * time.Second
+	downloadTimeout = 30 * time.Minute
+	stagedFile      = "staged.json"
+)
+
+// checksumAssets are the names release checksum files go by.
+var checksumAssets = []string{"checksums.txt", "SHA256SUMS", "sha256sums.txt"}
+
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// ReleaseNote is the changelog of one release.
+type ReleaseNote struct {
+	Version     string    `json:"version"`
+	Name        string    `json:"name"`
+	Notes       string    `json:"notes"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+	Prerelease  bool      `json:"prerelease"`
+}
+
+type UpdateInfo struct {
+	Current   string `json:"current"`
+	Latest    string `json:"latest"`
+	Channel   string `json:"channel"`
+	Available bool   `json:"available"`
+	// Changelog holds every release newer than Current, newest first.
+	Changelog []ReleaseNote `json:"changelog"`
+	// Asset is the download for this platform, if the release has one.
+	Asset     *Asset    `json:"asset,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// StagedUpdate is a downloaded installer waiting to be installed.
+type StagedUpdate struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	SHA256  string `json:"sha256"`
+	// Verified is set when the release published a checksum for the file.
+	Verified bool      `json:"verified"`
+	StagedAt time.Time `json:"staged_at"`
+}
+
+// Progress reports a download's progress; total is 0 when unknown.
+type Progress func(downloaded, total int64)
+
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+		Size int64  `json:"size"`
+	} `json:"assets"`
+}
+
+// Updater keeps the last check and stages downloads under dir.
+type Updater struct {
+	dir     string
+	current string
+	// FeedURL defaults to DefaultFeedURL.
+	FeedURL string
+	client  *http.Client
+
+	mu   sync.Mutex
+	last *UpdateInfo
+	// checksumURL is the checksum file of the last check's release.
+	checksumURL string
+}
+
+// DefaultDir returns the updates directory next to the settings file.
+func DefaultDir() string {
+	return appdir.Path("updates")
+}
+
+// New returns an Updater for the running version, e.g. "v1.4.0"; a
+// version that isn't semantic, such as "dev", never sees updates.
+func New(dir, current string) *Updater {
+	return &Updater{dir: dir, current: current, FeedURL: DefaultFeedURL, client: &http.Client{}}
+}
+
+// Check fetches the release feed and compares the newest release of the
+// channel with the running version.
+func (u *Updater) Check(ctx context.Context, channel string) (*UpdateInfo, error) {
+	if channel == "" {
+		channel = ChannelStable
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.FeedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check for updates: %s", resp.Status)
+	}
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("invalid release feed: %v", err)
+	}
+
+	info := &UpdateInfo{Current: u.current, Channel: channel, Changelog: []ReleaseNote{}, CheckedAt: time.Now()}
+	current, currentOK := parseVersion(u.current)
+	var latest *githubRelease
+	var latestVersion version
+	checksumURL := ""
+	for i := range releases {
+		r := &releases[i]
+		v, ok := parseVersion(r.TagName)
+		if !ok || r.Draft || (r.Prerelease && channel != ChannelBeta) {
+			continue
+		}
+		if latest == nil || latestVersion.less(v) {
+			latest, latestVersion = r, v
+		}
+		if currentOK && current.less(v) {
+			info.Changelog = append(info.Changelog, ReleaseNote{
+				Version:     r.TagName,
+				Name:        r.Name,
+				Notes:       r.Body,
+				URL:         r.HTMLURL,
+				PublishedAt: r.PublishedAt,
+				Prerelease:  r.Prerelease,
+			})
+		}
+	}
+	if latest != nil {
+		info.Latest = latest.TagName
+		info.Available = currentOK && current.less(latestVersion)
+		for _, a := range latest.Assets {
+			for _, name := range checksumAssets {
+				if strings.EqualFold(a.Name, name) {
+					checksumURL = a.URL
+				}
+			}
+		}
+		info.Asset = platformAsset(latest)
+	}
+	sortNotes(info.Changelog)
+
+	u.mu.Lock()
+	u.last = info
+	u.checksumURL = checksumURL
+	u.mu.Unlock()
+	return info, nil
+}
+
+// Last returns the result of the last check, or nil.
+func (u *Updater) Last() *UpdateInfo {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.last
+}
+
+// Download fetches the installer found by the last check into the
+// staging directory, verifying it against the release's checksum file
+// when there is one, and replaces any previously staged update.
+func (u *Updater) Download(ctx context.Context, progress Progress) (*StagedUpdate, error) {
+	u.mu.Lock()
+	info, checksumURL := u.last, u.checksumURL
+	u.mu.Unlock()
+	if info == nil || !info.Available {
+		return nil, errors.New("no update available; check for updates first")
+	}
+	if info.Asset == nil {
+		return nil, fmt.Errorf("release %s has no download for %s/%s", info.Latest, runtime.GOOS, runtime.GOARCH)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+	dir := filepath.Join(u.dir, info.Latest)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, filepath.Base(info.Asset.Name))
+	sum, err := u.fetch(ctx, info.Asset.URL, path, progress)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	staged := &StagedUpdate{Version: info.Latest, Path: path, SHA256: sum, StagedAt: time.Now()}
+	if checksumURL != "" {
+		want, err := u.expectedChecksum(ctx, checksumURL, info.Asset.Name)
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		if !strings.EqualFold(want, sum) {
+			os.Remove(path)
+			return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", info.Asset.Name, sum, want)
+		}
+		staged.Verified = true
+	}
+
+	if previous, err := u.Staged(); err == nil && previous != nil && previous.Version != staged.Version {
+		os.RemoveAll(filepath.Dir(previous.Path))
+	}
+	data, err := json.MarshalIndent(staged, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(u.dir, stagedFile), data, 0o644); err != nil {
+		return nil, err
+	}
+	return staged, nil
+}
+
+// Staged returns the staged update, or nil when there is none or it is
+// not newer than the running version.
+func (u *Updater) Staged() (*StagedUpdate, error) {
+	data, err := os.ReadFile(filepath.Join(u.dir, stagedFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var staged StagedUpdate
+	if err := json.Unmarshal(data, &staged); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(staged.Path); err != nil {
+		return nil, nil
+	}
+	current, ok := parseVersion(u.current)
+	v, _ := parseVersion(staged.Version)
+	if ok && !current.less(v) {
+		return nil, nil
+	}
+	return &staged, nil
+}
+
+// Discard removes the staged update.
+func (u *Updater) Discard() error {
+	staged, err := u.Staged()
+	if err != nil || staged == nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Dir(staged.Path)); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(u.dir, stagedFile))
+}
+
+// fetch downloads url to path and returns its SHA-256.
+func (u *Updater) fetch(ctx context.Context, url, path string, progress Progress) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download update: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download update: %s", resp.Status)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	buf := make([]byte, 64<<10)
+	var downloaded int64
+	total := max(resp.ContentLength, 0)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := f.Write(buf[:n]); werr != nil {
+				return "", werr
+			}
+			hash.Write(buf[:n])
+			downloaded += int64(n)
+			if progress != nil {
+				progress(downloaded, total)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to download update: %v", err)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), f.Close()
+}
+
+// expectedChecksum reads the SHA-256 of name from a sha256sum-style file.
+func (u *Updater) expectedChecksum(ctx context.Context, url, name string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksums: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch checksums: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("the release's checksums don't cover %s", name)
+}
+
+// platformAsset picks the download for the running OS and architecture.
+// macOS universal builds match either architecture.
+func platformAsset(r *githubRelease) *Asset {
+	osNames := map[string][]string{
+		"darwin":  {"darwin", "macos", "mac"},
+		"windows": {"windows", "win"},
+		"linux":   {"linux"},
+	}[runtime.GOOS]
+	archNames := map[string][]string{
+		"amd64": {"amd64", "x86_64", "x64"},
+		"arm64": {"arm64", "aarch64"},
+	}[runtime.GOARCH]
+	if runtime.GOOS == "darwin" {
+		archNames = append(archNames, "universal")
+	}
+	matches := func(name string, options []string) bool {
+		for _, o := range options {
+			if strings.Contains(name, o) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, a := range r.Assets {
+		name := strings.ToLower(a.Name)
+		if matches(name, osNames) && matches(name, archNames) {
+			return &Asset{Name: a.Name, URL: a.URL, Size: a.Size}
+		}
+	}
+	return nil
+}
+
+func sortNotes(notes []ReleaseNote) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		a, _ := parseVersion(notes[i].Version)
+		b, _ := parseVersion(notes[j].Version)
+		return b.less(a)
+	})
+}
+
+// version is a parsed semantic version; pre is the pre-release part.
+type version struct {
+	parts [3]int
+	pre   string
+}
+
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	core, pre, _ := strings.Cut(s, "-")
+	fields := strings.Split(core, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return version{}, false
+	}
+	var v version
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.parts[i] = n
+	}
+	v.pre = pre
+	return v, true
+}
+
+// less orders versions; a pre-release sorts before its release.
+// Pre-release identifiers are compared as strings, which is enough for
+// "beta.2" < "beta.3" but not for "beta.10".
+func (v version) less(o version) bool {
+	for i := range v.parts {
+		if v.parts[i] != o.parts[i] {
+			return v.parts[i] < o.parts[i]
+		}
+	}
+	if v.pre == "" || o.pre == "" {
+		return v.pre != "" && o.pre == ""
+	}
+	return v.pre < o.pre
+}
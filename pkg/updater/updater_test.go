@@ -0,0 +1,55 @@
+package updater
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   version
+		wantOK bool
+	}{
+		{in: "v1.4.2", want: version{parts: [3]int{1, 4, 2}}, wantOK: true},
+		{in: " 2.0 ", want: version{parts: [3]int{2, 0, 0}}, wantOK: true},
+		{in: "3", want: version{parts: [3]int{3, 0, 0}}, wantOK: true},
+		{in: "v1.5.0-beta.2", want: version{parts: [3]int{1, 5, 0}, pre: "beta.2"}, wantOK: true},
+		{in: "1.5.0+build.7", want: version{parts: [3]int{1, 5, 0}}, wantOK: true},
+		{in: "1.5.0-rc.1+build.7", want: version{parts: [3]int{1, 5, 0}, pre: "rc.1"}, wantOK: true},
+		{in: "1.2.3.4"},
+		{in: "1.x.0"},
+		{in: "1.-2.0"},
+		{in: ""},
+		{in: "dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := parseVersion(tt.in)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseVersion(%q) = (%+v, %v), want (%+v, %v)", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1.4.2", "1.10.0", true},
+		{"1.10.0", "1.4.2", false},
+		{"2.0.0", "2.0.0", false},
+		{"2.0.0-rc.1", "2.0.0", true},
+		{"2.0.0", "2.0.0-rc.1", false},
+		{"2.0.0-beta.2", "2.0.0-beta.3", true},
+		{"1.9.9", "2.0.0-alpha", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"<"+tt.b, func(t *testing.T) {
+			a, _ := parseVersion(tt.a)
+			b, _ := parseVersion(tt.b)
+			if got := a.less(b); got != tt.want {
+				t.Errorf("%s < %s = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
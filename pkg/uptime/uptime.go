@@ -0,0 +1,322 @@
+// Package uptime records availability transitions of selected workloads
+// and computes rough uptime figures from them, without any monitoring
+// stack in the cluster.
+package uptime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"teleskope/pkg/appdir"
+	"teleskope/pkg/k8s"
+)
+
+const (
+	StateUp      = "up"
+	StateDown    = "down"
+	StateUnknown = "unknown"
+
+	// HeartbeatInterval is how often callers should call Heartbeat while
+	// tracking; a gap of more than gapThreshold between observations is
+	// recorded as unknown (the app wasn't running or was disconnected).
+	HeartbeatInterval = time.Minute
+	gapThreshold      = 3 * HeartbeatInterval
+
+	retention = 7 * 24 * time.Hour
+)
+
+type Transition struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+}
+
+type record struct {
+	Target      k8s.ResourceRef `json:"target"`
+	Transitions []Transition    `json:"transitions"`
+	LastSeen    time.Time       `json:"last_seen"`
+}
+
+type Availability struct {
+	Window string `json:"window"`
+	// Availability is the fraction of the observed time the workload was
+	// up, between 0 and 1.
+	Availability    float64 `json:"availability"`
+	ObservedSeconds int64   `json:"observed_seconds"`
+	DowntimeSeconds int64   `json:"downtime_seconds"`
+	// Coverage is the fraction of the window that was observed at all.
+	Coverage float64 `json:"coverage"`
+	Outages  int     `json:"outages"`
+}
+
+type Report struct {
+	Target  k8s.ResourceRef `json:"target"`
+	State   string          `json:"state"`
+	Windows []Availability  `json:"windows"`
+}
+
+// Store keeps one JSON file per kubeconfig context under dir.
+type Store struct {
+	dir string
+
+	mu       sync.Mutex
+	contexts map[string]map[string]*record
+	// live holds the keys observed in this session, which Heartbeat keeps
+	// fresh.
+	live map[string]map[string]bool
+}
+
+// DefaultDir returns the uptime directory next to the settings file.
+func DefaultDir() string {
+	return appdir.Path("uptime")
+}
+
+func New(dir string) *Store {
+	return &Store{
+		dir:      dir,
+		contexts: make(map[string]map[string]*record),
+		live:     make(map[string]map[string]bool),
+	}
+}
+
+// Key identifies a workload within a context.
+func Key(ref k8s.ResourceRef) string {
+	return strings.Join([]string{ref.Group, ref.Version, ref.Plural, ref.Namespace, ref.Name}, "/")
+}
+
+func (s *Store) path(context string) string {
+	return filepath.Join(s.dir, appdir.SafeName(context)+".json")
+}
+
+func (s *Store) load(context string) (map[string]*record, error) {
+	if records, ok := s.contexts[context]; ok {
+		return records, nil
+	}
+	records := make(map[string]*record)
+	data, err := os.ReadFile(s.path(context))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("corrupt uptime file for %s: %v", context, err)
+		}
+	}
+	s.contexts[context] = records
+	return records, nil
+}
+
+func (s *Store) save(context string) error {
+	data, err := json.Marshal(s.contexts[context])
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	return appdir.WriteFile(s.path(context), data, 0o600)
+}
+
+// Track starts recording a workload. Tracking an already tracked workload
+// is a no-op.
+func (s *Store) Track(context string, ref k8s.ResourceRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load(context)
+	if err != nil {
+		return err
+	}
+	if _, ok := records[Key(ref)]; ok {
+		return nil
+	}
+	records[Key(ref)] = &record{Target: ref}
+	return s.save(context)
+}
+
+// Untrack stops recording a workload and drops its history.
+func (s *Store) Untrack(context string, ref k8s.ResourceRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load(context)
+	if err != nil {
+		return err
+	}
+	delete(records, Key(ref))
+	delete(s.live[context], Key(ref))
+	return s.save(context)
+}
+
+// Targets lists the workloads tracked for a context.
+func (s *Store) Targets(context string) ([]k8s.ResourceRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load(context)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]k8s.ResourceRef, 0, len(records))
+	for _, r := range records {
+		targets = append(targets, r.Target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return Key(targets[i]) < Key(targets[j]) })
+	return targets, nil
+}
+
+// Observe records the availability of a workload at a point in time.
+// Only state changes are stored.
+func (s *Store) Observe(context string, ref k8s.ResourceRef, up bool, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load(context)
+	if err != nil {
+		return err
+	}
+	r, ok := records[Key(ref)]
+	if !ok {
+		return nil
+	}
+	if s.live[context] == nil {
+		s.live[context] = make(map[string]bool)
+	}
+	s.live[context][Key(ref)] = true
+
+	state := StateDown
+	if up {
+		state = StateUp
+	}
+	changed := false
+	if !r.LastSeen.IsZero() && at.Sub(r.LastSeen) > gapThreshold && lastState(r) != StateUnknown {
+		r.Transitions = append(r.Transitions, Transition{Time: r.LastSeen, State: StateUnknown})
+		changed = true
+	}
+	if lastState(r) != state {
+		r.Transitions = append(r.Transitions, Transition{Time: at, State: state})
+		changed = true
+	}
+	r.LastSeen = at
+	if !changed {
+		return nil
+	}
+	prune(r, at)
+	return s.save(context)
+}
+
+// Heartbeat marks every workload observed in this session as still being
+// watched, so quiet periods aren't mistaken for gaps.
+func (s *Store) Heartbeat(context string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load(context)
+	if err != nil {
+		return err
+	}
+	for key := range s.live[context] {
+		if r, ok := records[key]; ok {
+			r.LastSeen = at
+		}
+	}
+	return s.save(context)
+}
+
+// EndSession forgets which workloads are live for a context, e.g. after
+// switching to another one.
+func (s *Store) EndSession(context string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.live, context)
+}
+
+// Report computes availability over each window, ending at now.
+func (s *Store) Report(context string, ref k8s.ResourceRef, windows []time.Duration, now time.Time) (*Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.load(context)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := records[Key(ref)]
+	if !ok {
+		return nil, fmt.Errorf("%s/%s is not tracked", ref.Namespace, ref.Name)
+	}
+
+	// The current state is only trustworthy while it is being refreshed.
+	end := now
+	if !s.live[context][Key(ref)] {
+		end = r.LastSeen
+	}
+	report := &Report{Target: r.Target, State: StateUnknown}
+	if s.live[context][Key(ref)] {
+		report.State = lastState(r)
+	}
+	for _, w := range windows {
+		report.Windows = append(report.Windows, availability(r.Transitions, now.Add(-w), now, end, w))
+	}
+	return report, nil
+}
+
+func availability(transitions []Transition, from, to, end time.Time, window time.Duration) Availability {
+	var up, down time.Duration
+	outages := 0
+	for i, t := range transitions {
+		next := end
+		if i+1 < len(transitions) {
+			next = transitions[i+1].Time
+		}
+		start, stop := t.Time, next
+		if start.Before(from) {
+			start = from
+		}
+		if stop.After(to) {
+			stop = to
+		}
+		if !stop.After(start) {
+			continue
+		}
+		switch t.State {
+		case StateUp:
+			up += stop.Sub(start)
+		case StateDown:
+			down += stop.Sub(start)
+			outages++
+		}
+	}
+
+	a := Availability{
+		Window:          window.String(),
+		ObservedSeconds: int64((up + down) / time.Second),
+		DowntimeSeconds: int64(down / time.Second),
+		Coverage:        float64(up+down) / float64(window),
+		Outages:         outages,
+	}
+	if up+down > 0 {
+		a.Availability = float64(up) / float64(up+down)
+	}
+	return a
+}
+
+func lastState(r *record) string {
+	if len(r.Transitions) == 0 {
+		return StateUnknown
+	}
+	return r.Transitions[len(r.Transitions)-1].State
+}
+
+// prune drops transitions older than the retention period, keeping the
+// state that was in effect at the cutoff.
+func prune(r *record, now time.Time) {
+	cutoff := now.Add(-retention)
+	i := sort.Search(len(r.Transitions), func(i int) bool {
+		return r.Transitions[i].Time.After(cutoff)
+	})
+	if i <= 1 {
+		return
+	}
+	carried := r.Transitions[i-1]
+	carried.Time = cutoff
+	r.Transitions = append([]Transition{carried}, r.Transitions[i:]...)
+}